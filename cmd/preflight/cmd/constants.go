@@ -1,16 +1,21 @@
 package cmd
 
+import "time"
+
 const (
 	preflightCmdName    = "preflight"
 	preflightRunCmdName = "run"
 	cleanupCmdName      = "cleanup"
+	jobCmdName          = "job"
+	jobResultsCmdName   = "results"
 
 	NamespaceFlag          = "namespace"
 	namespaceFlagShorthand = "n"
 	namespaceUsage         = "Namespace of the cluster in which the preflight checks will be performed"
 
 	StorageClassFlag  = "storage-class"
-	storageClassUsage = "Name of storage class to use for preflight checks"
+	storageClassUsage = "Comma separated list of storage classes to use for preflight checks. " +
+		"Volume snapshot and restore is checked for each of the storage classes"
 
 	SnapshotClassFlag  = "volume-snapshot-class"
 	snapshotClassUsage = "Name of volume snapshot class to use for preflight checks"
@@ -21,8 +26,30 @@ const (
 	imagePullSecFlag  = "image-pull-secret"
 	imagePullSecUsage = "Name of the secret for authentication while pulling the images from the local registry"
 
+	ImageOverrideFlag  = "image-override"
+	imageOverrideUsage = "Comma separated list of overrides of the images used by preflight pods - busybox, dnsutils and curl, " +
+		"e.g busybox=registry.example.com/busybox:1.36. The default image is pinned to a digest if the override is a " +
+		"sha256 digest, e.g dnsutils=sha256:<digest>"
+
+	TVKVersionFlag  = "tvk-version"
+	tvkVersionUsage = "Version of TVK whose images are pulled on a node, rewritten to the local registry if given, " +
+		"using check-image-availability. The images of version are approximate, the ones which can't be pulled are " +
+		"reported as warnings"
+
+	ImagesFileFlag  = "images-file"
+	imagesFileUsage = "Path of a file listing the images pulled on a node using check-image-availability, one per line"
+
+	DNSNamesFlag  = "dns-names"
+	dnsNamesUsage = "Comma separated list of names resolved inside a pod using check-dns-resolution, in addition to " +
+		"kubernetes.default, the host of backup target and the hosts of registries"
+
+	DNSAttemptsFlag  = "dns-attempts"
+	dnsAttemptsUsage = "Number of times each name is resolved using check-dns-resolution, to detect intermittent failures"
+
 	ServiceAccountFlag  = "service-account"
-	serviceAccountUsage = "Name of the service account to use for preflight checks and creating preflight resources"
+	serviceAccountUsage = "Name of the service account to use for preflight checks and creating preflight resources. " +
+		"Permissions needed by TVK are evaluated for this service account, instead of the current user, if given. " +
+		"With --in-cluster, e.g in preflight job, they are evaluated only if it's given"
 
 	CleanupOnFailureFlag  = "cleanup-on-failure"
 	cleanupOnFailureUsage = "Cleanup the resources on cluster if preflight checks fail. By-default it is false"
@@ -49,9 +76,91 @@ const (
 	PVCStorageRequestFlag  = "pvc-storage-request"
 	pvcStorageRequestUsage = "PVC storage request for volume snapshot preflight check"
 
+	DataSizeFlag  = "data-size"
+	dataSizeUsage = "Size of random data written to the source PVC of volume snapshot preflight check, e.g 500Mi. " +
+		"SHA-256 checksums of the data are verified on the PVCs restored from volume snapshots of the mounted source PVC, " +
+		"and of the source PVC unmounted by deleting its writer pod. " +
+		"If not specified, a single sample file is written and verified on the PVC restored from volume snapshot of the mounted source PVC"
+
+	DataFilesFlag  = "data-files"
+	dataFilesUsage = "Number of files across which the data of --data-size is written"
+
+	BlockVolumeFlag  = "block-volume"
+	blockVolumeUsage = "Perform volume snapshot and restore for raw block volumes too, using check-block-volume-snapshot"
+
+	TopologyFlag  = "topology"
+	topologyUsage = "Restore volume snapshots on a different node and in each of the other zones, using check-topology"
+
+	AccessModesFlag  = "access-modes"
+	accessModesUsage = "Comma separated list of access modes verified by check-access-modes for the storage classes - " +
+		"ReadWriteMany (RWX), ReadOnlyMany (ROX) and ReadWriteOncePod (RWOP)"
+
+	TargetS3EndpointFlag  = "target-s3-endpoint"
+	targetS3EndpointUsage = "Endpoint URL of S3 backup target verified by check-target, e.g https://s3.us-east-1.amazonaws.com"
+	TargetS3BucketFlag    = "target-s3-bucket"
+	targetS3BucketUsage   = "Bucket of S3 backup target"
+	TargetS3RegionFlag    = "target-s3-region"
+	targetS3RegionUsage   = "Region of S3 backup target. If not specified, us-east-1 is used"
+	TargetS3SecretFlag    = "target-s3-credentials-secret"
+	targetS3SecretUsage   = "Secret in the namespace having 'accessKey' and 'secretKey' of S3 backup target"
+	TargetS3CASecretFlag  = "target-s3-ca-secret"
+	targetS3CASecretUsage = "Secret in the namespace having CA certificate bundle with key 'ca.crt' of S3 backup target"
+	TargetS3InsecureFlag  = "target-s3-insecure-skip-verify"
+	targetS3InsecureUsage = "Skip verification of TLS certificate of S3 backup target"
+	TargetNFSServerFlag   = "target-nfs-server"
+	targetNFSServerUsage  = "Server of NFS backup target verified by check-target"
+	TargetNFSPathFlag     = "target-nfs-path"
+	targetNFSPathUsage    = "Exported path of NFS backup target"
+
 	NodeSelectorFlag  = "node-selector"
 	nodeSelectorUsage = "Node selector labels for pods to schedule on a specific nodes of cluster"
 
+	ChecksFlag  = "checks"
+	checksUsage = "Comma separated list of preflight checks to perform. Dependencies of the given checks are performed too. " +
+		"If not specified, all preflight checks are performed"
+
+	SkipChecksFlag  = "skip-checks"
+	skipChecksUsage = "Comma separated list of preflight checks to skip"
+
+	ParallelismFlag  = "parallelism"
+	parallelismUsage = "Maximum number of independent preflight checks to perform concurrently. " +
+		"Use 1 to perform preflight checks sequentially"
+
+	TimeoutFlag  = "timeout"
+	timeoutUsage = "Timeout of the whole preflight run, e.g 30m. Timeout of individual checks can be given " +
+		"using 'checkTimeouts' in run section of config file. By default, there is no timeout"
+
+	OutputFlag  = "output"
+	outputUsage = "Output format of the preflight report. Allowed values are - json, yaml, junit. " +
+		"Report is written to standard output, and logs to standard error, unless a report file is provided"
+	outputFlagShorthand = "o"
+
+	ReportFileFlag  = "report-file"
+	reportFileUsage = "File to write the preflight report to. Report is written in json format if output format is not specified"
+
+	DiscoverFlag  = "discover"
+	discoverUsage = "Evaluate all the storage classes of the cluster and recommend a storage class and volume snapshot class " +
+		"to use with TVK. No resource is created unless snapshot round trip is requested"
+
+	DiscoverRoundTripFlag  = "discover-round-trip"
+	discoverRoundTripUsage = "Perform volume snapshot and restore checks for the discovered storage classes having " +
+		"a volume snapshot class, to rank them. Can only be used with --discover"
+
+	DryRunFlag  = "dry-run"
+	dryRunUsage = "Print the resources created by preflight checks and the permissions they require, without persisting " +
+		"anything. Allowed values are - client, server. With 'server', resources are validated using server-side dry-run"
+
+	ImageFlag  = "image"
+	imageUsage = "Image of preflight, built from docker-images/preflight, with which the preflight job is run"
+
+	ApplyFlag  = "apply"
+	applyUsage = "Apply the resources of preflight job on cluster instead of printing them. By-default it is false"
+
+	JobNameFlag  = "job"
+	jobNameUsage = "Name of the preflight job whose result is shown. By-default results of all the preflight jobs are shown"
+
+	jobResultsOutputUsage = "Output format of the preflight job results. Allowed values are - json, yaml"
+
 	uidFlag  = "uid"
 	uidUsage = "UID of the preflight check whose resources must be cleaned"
 
@@ -72,18 +181,48 @@ var (
 	kubeconfig        string
 	namespace         string
 	logLevel          string
-	storageClass      string
+	storageClasses    []string
 	snapshotClass     string
 	localRegistry     string
 	imagePullSecret   string
+	imageOverrides    map[string]string
+	tvkVersion        string
+	imagesFile        string
+	dnsNames          []string
+	dnsAttempts       int
 	serviceAccount    string
 	cleanupOnFailure  bool
 	inputFileName     string
 	podLimits         string
 	podRequests       string
 	pvcStorageRequest string
+	dataSize          string
+	dataFiles         int
+	blockVolume       bool
+	topology          bool
+	accessModes       []string
+	targetS3Endpoint  string
+	targetS3Bucket    string
+	targetS3Region    string
+	targetS3Secret    string
+	targetS3CASecret  string
+	targetS3Insecure  bool
+	targetNFSServer   string
+	targetNFSPath     string
 	nodeSelector      string
 	cleanupUID        string
 	inCluster         bool
 	scope             string
+	checks            []string
+	skipChecks        []string
+	parallelism       int
+	timeout           time.Duration
+	outputFormat      string
+	reportFile        string
+	discover          bool
+	discoverRoundTrip bool
+	dryRun            string
+	jobImage          string
+	jobApply          bool
+	jobName           string
 )
@@ -13,6 +13,7 @@ import (
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 
 	"github.com/trilioData/tvk-plugins/internal"
@@ -20,8 +21,18 @@ import (
 )
 
 type preflightCmdOps struct {
-	Run     preflight.Run     `json:"run"`
-	Cleanup preflight.Cleanup `json:"cleanup"`
+	Run     preflight.Run        `json:"run"`
+	Cleanup preflight.Cleanup    `json:"cleanup"`
+	Job     preflight.JobOptions `json:"job,omitempty"`
+}
+
+// logOutput returns the console writer for logs. Logs are written to standard error when the preflight
+// report or the resources of dry-run are written to standard output, so that they can be consumed as is.
+func logOutput() io.Writer {
+	if (cmdOps.Run.OutputFormat != "" && cmdOps.Run.ReportFile == "") || cmdOps.Run.DryRun != "" {
+		return colorable.NewColorableStderr()
+	}
+	return colorable.NewColorableStdout()
 }
 
 // Returns the name of the logging file created and error if occurred any
@@ -33,7 +44,7 @@ func setupLogger(logFilePrefix, logLvl string) (logFilename string, err error) {
 		return "", err
 	}
 	defer logFile.Close()
-	logger.SetOutput(io.MultiWriter(colorable.NewColorableStdout(), logFile))
+	logger.SetOutput(io.MultiWriter(logOutput(), logFile))
 	logger.Infof("Created log file with name - %s", logFile.Name())
 	lvl, err := log.ParseLevel(logLvl)
 	if err != nil {
@@ -88,7 +99,10 @@ func overridePreflightFileInputsFromCLI(cmd *cobra.Command) error {
 	updateCommonInputsFromCLI(cmd, &cmdOps.Run.CommonOptions)
 
 	if cmd.Flags().Changed(StorageClassFlag) {
-		cmdOps.Run.StorageClass = storageClass
+		cmdOps.Run.StorageClass, cmdOps.Run.StorageClasses = "", nil
+		if len(storageClasses) != 0 {
+			cmdOps.Run.StorageClass, cmdOps.Run.StorageClasses = storageClasses[0], storageClasses[1:]
+		}
 	}
 	if cmd.Flags().Changed(SnapshotClassFlag) {
 		cmdOps.Run.SnapshotClass = snapshotClass
@@ -99,17 +113,97 @@ func overridePreflightFileInputsFromCLI(cmd *cobra.Command) error {
 	if cmd.Flags().Changed(imagePullSecFlag) {
 		cmdOps.Run.ImagePullSecret = imagePullSecret
 	}
+	if cmd.Flags().Changed(ImageOverrideFlag) {
+		cmdOps.Run.ImageOverrides = imageOverrides
+	}
+	if cmd.Flags().Changed(TVKVersionFlag) || cmd.Flags().Changed(ImagesFileFlag) {
+		if cmdOps.Run.ImageAvailability == nil {
+			cmdOps.Run.ImageAvailability = &preflight.ImageAvailabilityOptions{}
+		}
+		if cmd.Flags().Changed(TVKVersionFlag) {
+			cmdOps.Run.ImageAvailability.TVKVersion = tvkVersion
+		}
+		if cmd.Flags().Changed(ImagesFileFlag) {
+			cmdOps.Run.ImageAvailability.ImagesFile = imagesFile
+		}
+	}
+	if cmd.Flags().Changed(DNSNamesFlag) || cmd.Flags().Changed(DNSAttemptsFlag) {
+		if cmdOps.Run.DNS == nil {
+			cmdOps.Run.DNS = &preflight.DNSOptions{}
+		}
+		if cmd.Flags().Changed(DNSNamesFlag) {
+			cmdOps.Run.DNS.Names = dnsNames
+		}
+		if cmd.Flags().Changed(DNSAttemptsFlag) {
+			cmdOps.Run.DNS.Attempts = dnsAttempts
+		}
+	}
 	if cmd.Flags().Changed(ServiceAccountFlag) {
 		cmdOps.Run.ServiceAccountName = serviceAccount
 	}
 	if cmd.Flags().Changed(CleanupOnFailureFlag) {
 		cmdOps.Run.PerformCleanupOnFail = cleanupOnFailure
 	}
+	if cmd.Flags().Changed(ChecksFlag) {
+		cmdOps.Run.Checks = checks
+	}
+	if cmd.Flags().Changed(SkipChecksFlag) {
+		cmdOps.Run.SkipChecks = skipChecks
+	}
+	if cmd.Flags().Changed(ParallelismFlag) {
+		cmdOps.Run.Parallelism = parallelism
+	}
+	if cmd.Flags().Changed(TimeoutFlag) {
+		cmdOps.Run.Timeout = metav1.Duration{Duration: timeout}
+	}
+	if cmd.Flags().Changed(OutputFlag) {
+		cmdOps.Run.OutputFormat = outputFormat
+	}
+	if cmd.Flags().Changed(ReportFileFlag) {
+		cmdOps.Run.ReportFile = reportFile
+	}
+	if cmd.Flags().Changed(DiscoverFlag) {
+		cmdOps.Run.Discover = discover
+	}
+	if cmd.Flags().Changed(DiscoverRoundTripFlag) {
+		cmdOps.Run.DiscoverRoundTrip = discoverRoundTrip
+	}
+	if cmd.Flags().Changed(DryRunFlag) {
+		cmdOps.Run.DryRun = dryRun
+	}
 	if cmd.Flags().Changed(PVCStorageRequestFlag) {
 		cmdOps.Run.PVCStorageRequest = resource.MustParse(pvcStorageRequest)
 	} else if cmdOps.Run.PVCStorageRequest.Value() == 0 {
 		cmdOps.Run.PVCStorageRequest = resource.MustParse(DefaultPVCStorage)
 	}
+	if cmd.Flags().Changed(DataSizeFlag) {
+		cmdOps.Run.DataSize, err = resource.ParseQuantity(dataSize)
+		if err != nil {
+			return fmt.Errorf("invalid data size - %s :: %s", dataSize, err.Error())
+		}
+	}
+	if cmd.Flags().Changed(DataFilesFlag) || cmdOps.Run.DataFiles == 0 {
+		cmdOps.Run.DataFiles = dataFiles
+	}
+	if cmd.Flags().Changed(BlockVolumeFlag) {
+		cmdOps.Run.BlockVolume = blockVolume
+	}
+	if cmd.Flags().Changed(TopologyFlag) {
+		cmdOps.Run.Topology = topology
+	}
+	if cmd.Flags().Changed(AccessModesFlag) {
+		cmdOps.Run.AccessModes = nil
+		for _, mode := range accessModes {
+			cmdOps.Run.AccessModes = append(cmdOps.Run.AccessModes, corev1.PersistentVolumeAccessMode(mode))
+		}
+	}
+	// access modes are normalized, so that they can be given using their abbreviations in config file too
+	for idx, mode := range cmdOps.Run.AccessModes {
+		if cmdOps.Run.AccessModes[idx], err = preflight.ParseAccessMode(string(mode)); err != nil {
+			return err
+		}
+	}
+	updateTargetFromCLI(cmd)
 
 	err = updateNodeSelectorLabelsFromCLI(cmd)
 	if err != nil {
@@ -118,6 +212,45 @@ func overridePreflightFileInputsFromCLI(cmd *cobra.Command) error {
 	return updateResReqFromCLI()
 }
 
+// updateTargetFromCLI overrides the backup target of config file with the given --target-* flags.
+func updateTargetFromCLI(cmd *cobra.Command) {
+	s3Flags := map[string]func(s3 *preflight.S3TargetOptions){
+		TargetS3EndpointFlag: func(s3 *preflight.S3TargetOptions) { s3.Endpoint = targetS3Endpoint },
+		TargetS3BucketFlag:   func(s3 *preflight.S3TargetOptions) { s3.Bucket = targetS3Bucket },
+		TargetS3RegionFlag:   func(s3 *preflight.S3TargetOptions) { s3.Region = targetS3Region },
+		TargetS3SecretFlag:   func(s3 *preflight.S3TargetOptions) { s3.CredentialsSecret = targetS3Secret },
+		TargetS3CASecretFlag: func(s3 *preflight.S3TargetOptions) { s3.CASecret = targetS3CASecret },
+		TargetS3InsecureFlag: func(s3 *preflight.S3TargetOptions) { s3.InsecureSkipVerify = targetS3Insecure },
+	}
+	for flag, override := range s3Flags {
+		if !cmd.Flags().Changed(flag) {
+			continue
+		}
+		if cmdOps.Run.Target == nil {
+			cmdOps.Run.Target = &preflight.TargetOptions{}
+		}
+		if cmdOps.Run.Target.S3 == nil {
+			cmdOps.Run.Target.S3 = &preflight.S3TargetOptions{}
+		}
+		override(cmdOps.Run.Target.S3)
+	}
+
+	if cmd.Flags().Changed(TargetNFSServerFlag) || cmd.Flags().Changed(TargetNFSPathFlag) {
+		if cmdOps.Run.Target == nil {
+			cmdOps.Run.Target = &preflight.TargetOptions{}
+		}
+		if cmdOps.Run.Target.NFS == nil {
+			cmdOps.Run.Target.NFS = &preflight.NFSTargetOptions{}
+		}
+		if cmd.Flags().Changed(TargetNFSServerFlag) {
+			cmdOps.Run.Target.NFS.Server = targetNFSServer
+		}
+		if cmd.Flags().Changed(TargetNFSPathFlag) {
+			cmdOps.Run.Target.NFS.Path = targetNFSPath
+		}
+	}
+}
+
 func updateCommonInputsFromCLI(cmd *cobra.Command, comnOps *preflight.CommonOptions) {
 	if cmd.Flags().Changed(NamespaceFlag) || comnOps.Namespace == "" {
 		comnOps.Namespace = namespace
@@ -243,12 +376,57 @@ func validateRunOptions() error {
 	if cmdOps.Run.Namespace == "" {
 		return fmt.Errorf("namespace is required, cannot be empty")
 	}
-	if cmdOps.Run.StorageClass == "" {
-		return fmt.Errorf("storage-class is required, cannot be empty")
+	if err = validateDiscoverOptions(); err != nil {
+		return err
+	}
+	if err = validateDryRunOptions(); err != nil {
+		return err
 	}
 	if cmdOps.Run.ImagePullSecret != "" && cmdOps.Run.LocalRegistry == "" {
 		return fmt.Errorf("cannot give image pull secret if local registry is not provided.\nUse --local-registry flag to provide local registry")
 	}
+	if err = preflight.ValidateImageOverrides(cmdOps.Run.ImageOverrides); err != nil {
+		return err
+	}
+	if cmdOps.Run.ImageAvailability != nil {
+		if err = cmdOps.Run.ImageAvailability.Validate(); err != nil {
+			return fmt.Errorf("invalid image availability options :: %s", err.Error())
+		}
+	}
+	if cmdOps.Run.DNS != nil {
+		if err = cmdOps.Run.DNS.Validate(); err != nil {
+			return fmt.Errorf("invalid DNS options :: %s", err.Error())
+		}
+	}
+	if _, err = cmdOps.Run.SelectChecks(); err != nil {
+		return err
+	}
+	if cmdOps.Run.Parallelism < 0 {
+		return fmt.Errorf("parallelism cannot be negative")
+	}
+	if cmdOps.Run.Timeout.Duration < 0 {
+		return fmt.Errorf("timeout cannot be negative")
+	}
+	for name, checkTimeout := range cmdOps.Run.CheckTimeouts {
+		if checkTimeout.Duration < 0 {
+			return fmt.Errorf("timeout of check %s cannot be negative", name)
+		}
+	}
+	if err = validateDataIntegrityOptions(); err != nil {
+		return err
+	}
+	if err = validateStoragePerformanceOptions(); err != nil {
+		return err
+	}
+	if cmdOps.Run.Target != nil {
+		if err = cmdOps.Run.Target.Validate(); err != nil {
+			return fmt.Errorf("invalid backup target :: %s", err.Error())
+		}
+	}
+	if cmdOps.Run.OutputFormat != "" && !preflight.AllowedReportFormats.Has(cmdOps.Run.OutputFormat) {
+		return fmt.Errorf("invalid output format - %s. Allowed formats are - %s",
+			cmdOps.Run.OutputFormat, strings.Join(preflight.AllowedReportFormats.List(), ", "))
+	}
 
 	reqMem := cmdOps.Run.Requests.Memory()
 	limitMem := cmdOps.Run.Limits.Memory()
@@ -265,6 +443,84 @@ func validateRunOptions() error {
 	return nil
 }
 
+// validateDataIntegrityOptions validates that the data written for data integrity check fits in the source PVC.
+func validateDataIntegrityOptions() error {
+	if cmdOps.Run.DataFiles < 0 {
+		return fmt.Errorf("data-files cannot be negative")
+	}
+	if cmdOps.Run.DataSize.Sign() < 0 {
+		return fmt.Errorf("data-size cannot be negative")
+	}
+	if cmdOps.Run.DataSize.Sign() > 0 && cmdOps.Run.DataSize.Cmp(cmdOps.Run.PVCStorageRequest) >= 0 {
+		return fmt.Errorf("data-size - %s must be less than pvc storage request - %s",
+			cmdOps.Run.DataSize.String(), cmdOps.Run.PVCStorageRequest.String())
+	}
+	return nil
+}
+
+// validateStoragePerformanceOptions validates that the thresholds of storage performance check aren't negative, and
+// the data it measures fits in the PVC.
+func validateStoragePerformanceOptions() error {
+	perfOps := cmdOps.Run.StoragePerformance
+	if perfOps == nil {
+		return nil
+	}
+	if perfOps.MinWriteThroughput.Sign() < 0 || perfOps.MinReadThroughput.Sign() < 0 ||
+		perfOps.MaxSnapshotLatency.Duration < 0 || perfOps.MaxRestoreLatency.Duration < 0 {
+		return fmt.Errorf("thresholds of storage performance cannot be negative")
+	}
+	dataSize := perfOps.DataSize
+	if dataSize.Sign() <= 0 {
+		dataSize = resource.MustParse(preflight.DefaultPerformanceDataSize)
+	}
+	if dataSize.Cmp(cmdOps.Run.PVCStorageRequest) >= 0 {
+		return fmt.Errorf("data size of storage performance - %s must be less than pvc storage request - %s",
+			dataSize.String(), cmdOps.Run.PVCStorageRequest.String())
+	}
+	return nil
+}
+
+// validateDiscoverOptions validates that storage class is given unless storage classes are to be discovered.
+func validateDiscoverOptions() error {
+	hasStorageClass := cmdOps.Run.StorageClass != "" || len(cmdOps.Run.StorageClasses) != 0
+	if !cmdOps.Run.Discover {
+		if !hasStorageClass {
+			return fmt.Errorf("storage-class is required, cannot be empty")
+		}
+		if cmdOps.Run.DiscoverRoundTrip {
+			return fmt.Errorf("discover-round-trip can only be given with discover")
+		}
+		return nil
+	}
+
+	if hasStorageClass {
+		return fmt.Errorf("cannot give storage-class in discover mode, all the storage classes of cluster are evaluated")
+	}
+	if cmdOps.Run.SnapshotClass != "" {
+		return fmt.Errorf("cannot give volume-snapshot-class in discover mode, " +
+			"volume snapshot classes are matched with storage classes of cluster")
+	}
+	return nil
+}
+
+// validateDryRunOptions validates that dry-run mode is known and is not given with options producing a report.
+func validateDryRunOptions() error {
+	if cmdOps.Run.DryRun == "" {
+		return nil
+	}
+	if !preflight.AllowedDryRunModes.Has(cmdOps.Run.DryRun) {
+		return fmt.Errorf("invalid dry-run mode - %s. Allowed modes are - %s",
+			cmdOps.Run.DryRun, strings.Join(preflight.AllowedDryRunModes.List(), ", "))
+	}
+	if cmdOps.Run.Discover {
+		return fmt.Errorf("cannot give dry-run in discover mode")
+	}
+	if cmdOps.Run.OutputFormat != "" || cmdOps.Run.ReportFile != "" {
+		return fmt.Errorf("cannot give output or report-file with dry-run, resources are printed on standard output")
+	}
+	return nil
+}
+
 func validateCleanupFields() error {
 	if cmdOps.Cleanup.Namespace == "" {
 		return fmt.Errorf("namespace is required, cannot be empty")
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -12,6 +13,7 @@ import (
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/trilioData/tvk-plugins/internal"
 	"github.com/trilioData/tvk-plugins/tools/preflight"
@@ -60,6 +62,9 @@ var _ = Describe("Preflight cmd helper unit tests", func() {
 			Expect(cmdOps.Run.Requests.Cpu().String()).To(Equal("250m"))
 			Expect(cmdOps.Run.Limits.Memory().String()).To(Equal("128Mi"))
 			Expect(cmdOps.Run.Limits.Cpu().String()).To(Equal("500m"))
+			Expect(cmdOps.Run.Timeout.Duration).To(Equal(30 * time.Minute))
+			Expect(cmdOps.Run.CheckTimeouts).To(HaveKeyWithValue(preflight.CheckVolumeSnapshot,
+				metav1.Duration{Duration: 15 * time.Minute}))
 
 			//cleanup values
 			Expect(cmdOps.Cleanup.Namespace).To(Equal(internal.DefaultNs))
@@ -87,17 +92,22 @@ var _ = Describe("Preflight cmd helper unit tests", func() {
 				permYamlFilePath = filepath.Join(testDataDir, "file_permission.yaml")
 			)
 
+			if os.Geteuid() == 0 {
+				Skip("file permissions are not enforced for root user")
+			}
+
 			By("Create non-read permission file")
 			file, err = os.OpenFile(permYamlFilePath, os.O_CREATE, 0000)
 			Expect(err).Should(BeNil())
 			Expect(file.Close()).Should(BeNil())
+			DeferCleanup(func() {
+				By("Delete non-read permission file")
+				Expect(os.Remove(permYamlFilePath)).Should(BeNil())
+			})
 
 			terr := readFileInputOptions(permYamlFilePath)
 			Expect(terr).ShouldNot(BeNil())
 			Expect(terr.Error()).Should(Equal(fmt.Sprintf("open %s: permission denied", permYamlFilePath)))
-
-			By("Delete non-read permission file")
-			Expect(os.Remove(permYamlFilePath)).Should(BeNil())
 		})
 	})
 
@@ -276,6 +286,19 @@ var _ = Describe("Preflight cmd helper unit tests", func() {
 			Expect(cmdOps.Run.Kubeconfig).Should(Equal(testConfigPath))
 		})
 
+		It("Should normalize abbreviations of access modes and return error for unsupported access modes", func() {
+			cmdOps.Run.AccessModes = []corev1.PersistentVolumeAccessMode{"rwx", "ReadOnlyMany", "RWOP"}
+			Expect(managePreflightInputs(&cobra.Command{})).Should(BeNil())
+			Expect(cmdOps.Run.AccessModes).To(Equal([]corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany,
+				corev1.ReadOnlyMany, corev1.ReadWriteOncePod}))
+
+			cmdOps.Run.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+			err := managePreflightInputs(&cobra.Command{})
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("unsupported access mode - ReadWriteOnce"))
+			cmdOps.Run.AccessModes = nil
+		})
+
 	})
 
 	Context("validateRunOptions func test-cases", func() {
@@ -336,6 +359,182 @@ var _ = Describe("Preflight cmd helper unit tests", func() {
 			Expect(terr).ToNot(BeNil())
 			Expect(terr.Error()).To(ContainSubstring("request CPU cannot be greater than limit CPU"))
 		})
+
+		It("Should not return error when known preflight checks are selected and skipped", func() {
+			cmdOps.Run.Checks = []string{preflight.CheckDNSResolution, preflight.CheckStorageSnapshotClass}
+			cmdOps.Run.SkipChecks = []string{preflight.CheckKubectl}
+			terr := validateRunOptions()
+			Expect(terr).To(BeNil())
+		})
+
+		It("Should return error when unknown preflight check is selected", func() {
+			cmdOps.Run.Checks = []string{preflight.CheckDNSResolution, invalidCheckName}
+			terr := validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring(fmt.Sprintf("unknown preflight check(s) - %s", invalidCheckName)))
+		})
+
+		It("Should return error when unknown preflight check is skipped", func() {
+			cmdOps.Run.SkipChecks = []string{invalidCheckName}
+			terr := validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring(fmt.Sprintf("unknown preflight check(s) - %s", invalidCheckName)))
+		})
+
+		It("Should not return error when storage classes are given only as a list", func() {
+			cmdOps.Run.StorageClass = ""
+			cmdOps.Run.StorageClasses = []string{internal.DefaultTestStorageClass, "another-storage-class"}
+			Expect(validateRunOptions()).To(BeNil())
+		})
+
+		It("Should return error when parallelism is negative", func() {
+			cmdOps.Run.Parallelism = -1
+			terr := validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring("parallelism cannot be negative"))
+		})
+
+		It("Should return error when timeout of run or a check is negative", func() {
+			cmdOps.Run.Timeout = metav1.Duration{Duration: -time.Minute}
+			terr := validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring("timeout cannot be negative"))
+
+			cmdOps.Run.Timeout = metav1.Duration{}
+			cmdOps.Run.CheckTimeouts = map[string]metav1.Duration{preflight.CheckDNSResolution: {Duration: -time.Minute}}
+			terr = validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring(fmt.Sprintf("timeout of check %s cannot be negative", preflight.CheckDNSResolution)))
+		})
+
+		It("Should return error when timeout is given for unknown preflight check", func() {
+			cmdOps.Run.CheckTimeouts = map[string]metav1.Duration{invalidCheckName: {Duration: time.Minute}}
+			terr := validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring(fmt.Sprintf("unknown preflight check(s) - %s", invalidCheckName)))
+		})
+
+		It("Should not return error when output format is junit", func() {
+			cmdOps.Run.OutputFormat = preflight.FormatJUnit
+			Expect(validateRunOptions()).To(BeNil())
+		})
+
+		It("Should return error when output format is invalid", func() {
+			cmdOps.Run.OutputFormat = internal.FormatWIDE
+			terr := validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring(fmt.Sprintf("invalid output format - %s", internal.FormatWIDE)))
+		})
+
+		It("Should not return error when storage class is not given in discover mode", func() {
+			cmdOps.Run.StorageClass, cmdOps.Run.SnapshotClass = "", ""
+			cmdOps.Run.Discover, cmdOps.Run.DiscoverRoundTrip = true, true
+			Expect(validateRunOptions()).To(BeNil())
+		})
+
+		It("Should return error when storage class or volume snapshot class is given in discover mode", func() {
+			cmdOps.Run.Discover = true
+			terr := validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring("cannot give storage-class in discover mode"))
+
+			cmdOps.Run.StorageClass = ""
+			terr = validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring("cannot give volume-snapshot-class in discover mode"))
+		})
+
+		It("Should return error when discover round trip is given without discover", func() {
+			cmdOps.Run.DiscoverRoundTrip = true
+			terr := validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring("discover-round-trip can only be given with discover"))
+		})
+
+		It("Should not return error when dry-run mode is client or server", func() {
+			cmdOps.Run.DryRun = preflight.DryRunClient
+			Expect(validateRunOptions()).To(BeNil())
+			cmdOps.Run.DryRun = preflight.DryRunServer
+			Expect(validateRunOptions()).To(BeNil())
+		})
+
+		It("Should return error when dry-run mode is invalid or given with output format", func() {
+			cmdOps.Run.DryRun = "invalid"
+			terr := validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring("invalid dry-run mode - invalid"))
+
+			cmdOps.Run.DryRun = preflight.DryRunClient
+			cmdOps.Run.OutputFormat = internal.FormatJSON
+			terr = validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring("cannot give output or report-file with dry-run"))
+		})
+
+		It("Should not return error when data size is less than pvc storage request", func() {
+			cmdOps.Run.PVCStorageRequest = resource.MustParse("1Gi")
+			cmdOps.Run.DataSize = resource.MustParse("500Mi")
+			cmdOps.Run.DataFiles = 5
+			Expect(validateRunOptions()).To(BeNil())
+		})
+
+		It("Should return error when data size isn't less than pvc storage request or data files is negative", func() {
+			cmdOps.Run.PVCStorageRequest = resource.MustParse("1Gi")
+			cmdOps.Run.DataSize = resource.MustParse("1Gi")
+			terr := validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring("data-size - 1Gi must be less than pvc storage request - 1Gi"))
+
+			cmdOps.Run.DataSize = resource.MustParse("500Mi")
+			cmdOps.Run.DataFiles = -1
+			terr = validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring("data-files cannot be negative"))
+		})
+
+		It("Should validate thresholds and data size of storage performance", func() {
+			cmdOps.Run.PVCStorageRequest = resource.MustParse("1Gi")
+			cmdOps.Run.StoragePerformance = &preflight.StoragePerformanceOptions{
+				MinWriteThroughput: resource.MustParse("50Mi"),
+				MaxSnapshotLatency: metav1.Duration{Duration: time.Minute},
+			}
+			Expect(validateRunOptions()).To(BeNil())
+
+			cmdOps.Run.StoragePerformance.DataSize = resource.MustParse("2Gi")
+			terr := validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring("data size of storage performance - 2Gi must be less than pvc storage request - 1Gi"))
+
+			cmdOps.Run.StoragePerformance.DataSize = resource.Quantity{}
+			cmdOps.Run.StoragePerformance.MaxRestoreLatency = metav1.Duration{Duration: -time.Minute}
+			terr = validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring("thresholds of storage performance cannot be negative"))
+		})
+
+		It("Should validate that backup target is completely specified", func() {
+			cmdOps.Run.Target = &preflight.TargetOptions{NFS: &preflight.NFSTargetOptions{Server: "10.0.0.5", Path: "/exports"}}
+			Expect(validateRunOptions()).To(BeNil())
+
+			cmdOps.Run.Target = &preflight.TargetOptions{S3: &preflight.S3TargetOptions{Endpoint: "https://minio:9000", Bucket: "tvk"}}
+			terr := validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring("invalid backup target"))
+			Expect(terr.Error()).To(ContainSubstring("credentials secret of s3 backup target are required"))
+		})
+
+		It("Should validate names and attempts of DNS options", func() {
+			cmdOps.Run.DNS = &preflight.DNSOptions{Names: []string{"s3.us-east-1.amazonaws.com", "registry.local."}, Attempts: 5}
+			Expect(validateRunOptions()).To(BeNil())
+
+			cmdOps.Run.DNS.Names = []string{"registry.local;reboot"}
+			terr := validateRunOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring("invalid DNS name - registry.local;reboot"))
+
+			cmdOps.Run.DNS = &preflight.DNSOptions{Attempts: -1}
+			Expect(validateRunOptions()).ToNot(BeNil())
+		})
 	})
 
 	Context("validateCleanupFields func test-cases", func() {
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/onsi/ginkgo/reporters/stenographer/support/go-colorable"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/trilioData/tvk-plugins/internal"
+	"github.com/trilioData/tvk-plugins/tools/preflight"
+)
+
+// nolint:lll // ignore long line lint errors
+// jobCmd represents the job command
+var jobCmd = &cobra.Command{
+	Use:   jobCmdName,
+	Short: "Runs preflight checks as a kubernetes job inside the cluster",
+	Long: `Renders the Job, ServiceAccount and the minimal ClusterRole/Role needed to run preflight checks inside the cluster,
+along with a ConfigMap having the preflight run options. The resources are printed as yaml, or applied on the cluster with --apply flag.
+The preflight job writes the result of preflight run into a labelled ConfigMap, which can be read using 'job results' subcommand.`,
+	Example: ` # print the resources of preflight job
+  kubectl tvk-preflight job --storage-class <storage-class-name> --image <preflight image>
+
+  # apply the resources of preflight job on cluster in a particular namespace
+  kubectl tvk-preflight job --storage-class <storage-class-name> --image <preflight image> --namespace <namespace> --apply
+
+  # render the resources of preflight job with inputs from a config file
+  kubectl tvk-preflight job --config-file <config-file-path> > preflight-job.yaml
+`,
+	RunE: func(cmd *cobra.Command, _ []string) (err error) {
+		err = managePreflightInputs(cmd)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		overrideJobFileInputsFromCLI(cmd)
+
+		// resources of preflight job are printed on standard output, so logs are written to standard error
+		logger.SetOutput(colorable.NewColorableStderr())
+		setLogLevel(cmdOps.Run.LogLevel)
+		cmdOps.Run.Logger = logger
+
+		err = validateJobOptions()
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+
+		objs, err := cmdOps.Run.JobResources(cmdOps.Job)
+		if err != nil {
+			return err
+		}
+		if !cmdOps.Job.Apply {
+			var data []byte
+			data, err = preflight.RenderJobResources(objs)
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+
+		err = preflight.InitKubeEnv(cmdOps.Run.Kubeconfig)
+		if err != nil {
+			logger.Fatalf("Error initializing kubernetes clients :: %s", err.Error())
+		}
+		err = cmdOps.Run.ApplyJobResources(context.Background(), objs)
+		if err != nil {
+			return err
+		}
+		job := objs[len(objs)-1]
+		logger.Infof("Preflight job - %s started. Use 'kubectl tvk-preflight job results --job %s --namespace %s' "+
+			"to see its result once complete", job.GetName(), job.GetName(), job.GetNamespace())
+
+		return nil
+	},
+}
+
+// jobResultsCmd represents the job results command
+var jobResultsCmd = &cobra.Command{
+	Use:   jobResultsCmdName,
+	Short: "Shows the results of preflight jobs",
+	Long:  `Shows the results of preflight runs written by preflight jobs into labelled ConfigMaps of the namespace, the latest first.`,
+	Example: ` # show the results of all preflight jobs in a namespace
+  kubectl tvk-preflight job results --namespace <namespace>
+
+  # show the result of a particular preflight job
+  kubectl tvk-preflight job results --job <preflight job name> --namespace <namespace>
+
+  # show the result of a particular preflight job in json format
+  kubectl tvk-preflight job results --job <preflight job name> --output json
+`,
+	RunE: func(cmd *cobra.Command, _ []string) (err error) {
+		if outputFormat != "" && outputFormat != internal.FormatJSON && outputFormat != internal.FormatYAML {
+			return fmt.Errorf("invalid output format - %s. Allowed formats are - %s, %s",
+				outputFormat, internal.FormatJSON, internal.FormatYAML)
+		}
+		err = preflight.InitKubeEnv(kubeconfig)
+		if err != nil {
+			return fmt.Errorf("error initializing kubernetes clients :: %s", err.Error())
+		}
+
+		results, err := preflight.GetJobResults(context.Background(), namespace, jobName)
+		if err != nil {
+			return err
+		}
+
+		return printJobResults(os.Stdout, results, outputFormat, jobName != "")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(jobCmd)
+	jobCmd.AddCommand(jobResultsCmd)
+
+	addRunFlags(jobCmd)
+	jobCmd.Flags().StringVar(&jobImage, ImageFlag, "", imageUsage)
+	jobCmd.Flags().BoolVar(&jobApply, ApplyFlag, false, applyUsage)
+
+	jobResultsCmd.Flags().StringVar(&jobName, JobNameFlag, "", jobNameUsage)
+	jobResultsCmd.Flags().StringVarP(&outputFormat, OutputFlag, outputFlagShorthand, "", jobResultsOutputUsage)
+}
+
+func overrideJobFileInputsFromCLI(cmd *cobra.Command) {
+	if cmd.Flags().Changed(ImageFlag) {
+		cmdOps.Job.Image = jobImage
+	}
+	if cmd.Flags().Changed(ApplyFlag) {
+		cmdOps.Job.Apply = jobApply
+	}
+}
+
+func setLogLevel(logLvl string) {
+	lvl, lErr := log.ParseLevel(logLvl)
+	if lErr != nil {
+		logger.SetLevel(log.InfoLevel)
+		logger.Errorf("Failed to parse log-level flag. Setting log level as %s\n", internal.DefaultLogLevel)
+		return
+	}
+	logger.SetLevel(lvl)
+}
+
+func validateJobOptions() error {
+	if cmdOps.Job.Image == "" {
+		return fmt.Errorf("image is required, cannot be empty")
+	}
+	if cmdOps.Run.ResultConfigMap != "" {
+		return fmt.Errorf("cannot give result ConfigMap for preflight job, it is generated from name of the job")
+	}
+	if cmdOps.Run.DryRun != "" {
+		return fmt.Errorf("cannot give dry-run for preflight job, use dry-run with run subcommand instead")
+	}
+
+	return validateRunOptions()
+}
+
+// printJobResults writes the results of preflight jobs in the given format. Checks of each result are also
+// displayed if detailed is true.
+func printJobResults(w io.Writer, results []*preflight.JobResult, format string, detailed bool) error {
+	switch format {
+	case internal.FormatJSON:
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+
+	case internal.FormatYAML:
+		data, err := yaml.Marshal(results)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	if len(results) == 0 {
+		_, err := fmt.Fprintln(w, "No preflight job results found")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "CONFIGMAP\tUID\tSTORAGE CLASS\tSTATUS\tSTARTED\tDURATION")
+	for _, res := range results {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", res.ConfigMap, res.Report.UID, res.Report.StorageClass,
+			res.Report.Status, res.Report.StartTime.Format(time.RFC3339),
+			time.Duration(res.Report.DurationSeconds*float64(time.Second)).Round(time.Second))
+	}
+	if detailed {
+		for _, res := range results {
+			_, _ = fmt.Fprintf(tw, "\nCHECK\tSTATUS\tDURATION\tERROR\n")
+			for idx := range res.Report.Checks {
+				cr := &res.Report.Checks[idx]
+				_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", cr.ID, cr.Status,
+					time.Duration(cr.DurationSeconds*float64(time.Second)).Round(time.Second),
+					strings.ReplaceAll(cr.Error, "\n", " "))
+			}
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/trilioData/tvk-plugins/internal"
+	"github.com/trilioData/tvk-plugins/tools/preflight"
+)
+
+var _ = Describe("Preflight job cmd unit tests", func() {
+
+	Context("validateJobOptions func test-cases", func() {
+		BeforeEach(func() {
+			cmdOps.Job = preflight.JobOptions{Image: "registry.example.com/preflight:test"}
+			cmdOps.Run = preflight.Run{
+				RunOptions: preflight.RunOptions{
+					StorageClass: internal.DefaultTestStorageClass,
+					ResourceRequirements: corev1.ResourceRequirements{
+						Requests: map[corev1.ResourceName]resource.Quantity{},
+						Limits:   map[corev1.ResourceName]resource.Quantity{},
+					},
+				},
+				CommonOptions: preflight.CommonOptions{Namespace: internal.DefaultNs},
+			}
+		})
+
+		It("Should not return error when image and run options are given", func() {
+			Expect(validateJobOptions()).To(BeNil())
+		})
+
+		It("Should return error when image is empty", func() {
+			cmdOps.Job.Image = ""
+			terr := validateJobOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring("image is required, cannot be empty"))
+		})
+
+		It("Should return error when run options are invalid", func() {
+			cmdOps.Run.StorageClass = ""
+			terr := validateJobOptions()
+			Expect(terr).ToNot(BeNil())
+			Expect(terr.Error()).To(ContainSubstring("storage-class is required, cannot be empty"))
+		})
+	})
+
+	Context("printJobResults func test-cases", func() {
+		var results []*preflight.JobResult
+
+		BeforeEach(func() {
+			results = []*preflight.JobResult{{
+				ConfigMap:    preflight.JobResultConfigMapName("tvk-preflight-abcdef"),
+				CreationTime: time.Now(),
+				Report: &preflight.RunReport{
+					UID:          validPreflightUID,
+					StorageClass: internal.DefaultTestStorageClass,
+					Status:       preflight.CheckStatusFail,
+					Checks: []preflight.CheckReport{
+						{ID: preflight.CheckDNSResolution, Status: preflight.CheckStatusPass},
+						{ID: preflight.CheckVolumeSnapshot, Status: preflight.CheckStatusFail, Error: "snapshot not ready"},
+					},
+				},
+			}}
+		})
+
+		It("Should print summary of results, and checks only when detailed", func() {
+			var buf bytes.Buffer
+			Expect(printJobResults(&buf, results, "", false)).To(Succeed())
+			Expect(buf.String()).To(ContainSubstring("tvk-preflight-abcdef-result"))
+			Expect(buf.String()).ToNot(ContainSubstring(preflight.CheckDNSResolution))
+
+			buf.Reset()
+			Expect(printJobResults(&buf, results, "", true)).To(Succeed())
+			Expect(buf.String()).To(ContainSubstring(preflight.CheckVolumeSnapshot))
+			Expect(buf.String()).To(ContainSubstring("snapshot not ready"))
+		})
+
+		It("Should print results in json format", func() {
+			var buf bytes.Buffer
+			Expect(printJobResults(&buf, results, internal.FormatJSON, true)).To(Succeed())
+			var printed []*preflight.JobResult
+			Expect(json.Unmarshal(buf.Bytes(), &printed)).To(Succeed())
+			Expect(printed).To(HaveLen(1))
+			Expect(printed[0].Report.Checks).To(Equal(results[0].Report.Checks))
+		})
+
+		It("Should print message when there are no results", func() {
+			var buf bytes.Buffer
+			Expect(printJobResults(&buf, nil, "", false)).To(Succeed())
+			Expect(buf.String()).To(ContainSubstring("No preflight job results found"))
+		})
+	})
+})
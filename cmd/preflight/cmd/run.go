@@ -5,7 +5,6 @@ import (
 	"io"
 	"os"
 
-	"github.com/onsi/ginkgo/reporters/stenographer/support/go-colorable"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
@@ -17,7 +16,8 @@ import (
 var runCmd = &cobra.Command{
 	Use:   preflightRunCmdName,
 	Short: "Runs preflight checks on cluster",
-	Long:  `Runs all the preflight checks on cluster in a sequential manner in a particular namespace. If namespace is not provided then 'default' is used.`,
+	Long: `Runs all the preflight checks on cluster in a particular namespace. If namespace is not provided then 'default' is used.
+Independent preflight checks are performed concurrently, a check is started once the checks it depends on are complete.`,
 	Example: ` # run preflight checks
   kubectl tvk-preflight run --storage-class <storage-class-name>
 
@@ -49,6 +49,39 @@ var runCmd = &cobra.Command{
 
   # run preflight with pvc storage request flag for volume snapshot check
   kubectl tvk-preflight run --storage-class <storage-class-name> --pvc-storage-request <storage request value>
+
+  # run only particular preflight checks, along with the checks they depend on
+  kubectl tvk-preflight run --storage-class <storage-class-name> --checks check-dns-resolution,check-storage-snapshot-class
+
+  # run all preflight checks except the given ones
+  kubectl tvk-preflight run --storage-class <storage-class-name> --skip-checks check-volume-snapshot
+
+  # run preflight checks with multiple storage classes
+  kubectl tvk-preflight run --storage-class <storage-class-name-1>,<storage-class-name-2>
+
+  # run preflight checks sequentially
+  kubectl tvk-preflight run --storage-class <storage-class-name> --parallelism 1
+
+  # run preflight checks with a timeout for the whole run
+  kubectl tvk-preflight run --storage-class <storage-class-name> --timeout 30m
+
+  # run preflight checks and print the report in json format on standard output
+  kubectl tvk-preflight run --storage-class <storage-class-name> --output json
+
+  # run preflight checks and write the report in junit format to a file
+  kubectl tvk-preflight run --storage-class <storage-class-name> --output junit --report-file preflight-report.xml
+
+  # discover storage classes of the cluster and get a recommended storage class and volume snapshot class
+  kubectl tvk-preflight run --discover
+
+  # discover storage classes and rank them by performing volume snapshot and restore for each of them
+  kubectl tvk-preflight run --discover --discover-round-trip
+
+  # print the resources created by preflight checks and the permissions they require, without creating them
+  kubectl tvk-preflight run --storage-class <storage-class-name> --dry-run
+
+  # print the resources created by preflight checks and validate them using server-side dry-run
+  kubectl tvk-preflight run --storage-class <storage-class-name> --dry-run=server
 `,
 	RunE: func(cmd *cobra.Command, _ []string) (err error) {
 		err = managePreflightInputs(cmd)
@@ -66,7 +99,7 @@ var runCmd = &cobra.Command{
 			log.Fatalf("Failed to open preflight log file :: %s", err.Error())
 		}
 		defer logFile.Close()
-		logger.SetOutput(io.MultiWriter(colorable.NewColorableStdout(), logFile))
+		logger.SetOutput(io.MultiWriter(logOutput(), logFile))
 		cmdOps.Run.Logger = logger
 
 		err = preflight.InitKubeEnv(cmdOps.Run.Kubeconfig)
@@ -85,14 +118,47 @@ var runCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(runCmd)
 
-	runCmd.Flags().StringVar(&storageClass, StorageClassFlag, "", storageClassUsage)
-	runCmd.Flags().StringVar(&snapshotClass, SnapshotClassFlag, "", snapshotClassUsage)
-	runCmd.Flags().StringVar(&localRegistry, LocalRegistryFlag, "", localRegistryUsage)
-	runCmd.Flags().StringVar(&imagePullSecret, imagePullSecFlag, "", imagePullSecUsage)
-	runCmd.Flags().StringVar(&serviceAccount, ServiceAccountFlag, "", serviceAccountUsage)
-	runCmd.Flags().BoolVar(&cleanupOnFailure, CleanupOnFailureFlag, false, cleanupOnFailureUsage)
-	runCmd.Flags().StringVar(&podLimits, PodLimitFlag, "", podLimitUsage)
-	runCmd.Flags().StringVar(&podRequests, PodRequestFlag, "", podRequestUsage)
-	runCmd.Flags().StringVar(&pvcStorageRequest, PVCStorageRequestFlag, "", pvcStorageRequestUsage)
-	runCmd.Flags().StringVar(&nodeSelector, NodeSelectorFlag, "", nodeSelectorUsage)
+	addRunFlags(runCmd)
+	runCmd.Flags().StringVarP(&outputFormat, OutputFlag, outputFlagShorthand, "", outputUsage)
+	runCmd.Flags().StringVar(&reportFile, ReportFileFlag, "", reportFileUsage)
+	runCmd.Flags().StringVar(&dryRun, DryRunFlag, "", dryRunUsage)
+	runCmd.Flags().Lookup(DryRunFlag).NoOptDefVal = preflight.DryRunClient
+}
+
+// addRunFlags adds the flags of preflight run options to the command.
+func addRunFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&storageClasses, StorageClassFlag, nil, storageClassUsage)
+	cmd.Flags().StringVar(&snapshotClass, SnapshotClassFlag, "", snapshotClassUsage)
+	cmd.Flags().StringVar(&localRegistry, LocalRegistryFlag, "", localRegistryUsage)
+	cmd.Flags().StringVar(&imagePullSecret, imagePullSecFlag, "", imagePullSecUsage)
+	cmd.Flags().StringToStringVar(&imageOverrides, ImageOverrideFlag, nil, imageOverrideUsage)
+	cmd.Flags().StringVar(&tvkVersion, TVKVersionFlag, "", tvkVersionUsage)
+	cmd.Flags().StringVar(&imagesFile, ImagesFileFlag, "", imagesFileUsage)
+	cmd.Flags().StringSliceVar(&dnsNames, DNSNamesFlag, nil, dnsNamesUsage)
+	cmd.Flags().IntVar(&dnsAttempts, DNSAttemptsFlag, preflight.DefaultDNSAttempts, dnsAttemptsUsage)
+	cmd.Flags().StringVar(&serviceAccount, ServiceAccountFlag, "", serviceAccountUsage)
+	cmd.Flags().BoolVar(&cleanupOnFailure, CleanupOnFailureFlag, false, cleanupOnFailureUsage)
+	cmd.Flags().StringVar(&podLimits, PodLimitFlag, "", podLimitUsage)
+	cmd.Flags().StringVar(&podRequests, PodRequestFlag, "", podRequestUsage)
+	cmd.Flags().StringVar(&pvcStorageRequest, PVCStorageRequestFlag, "", pvcStorageRequestUsage)
+	cmd.Flags().StringVar(&dataSize, DataSizeFlag, "", dataSizeUsage)
+	cmd.Flags().IntVar(&dataFiles, DataFilesFlag, preflight.DefaultDataFiles, dataFilesUsage)
+	cmd.Flags().BoolVar(&blockVolume, BlockVolumeFlag, false, blockVolumeUsage)
+	cmd.Flags().BoolVar(&topology, TopologyFlag, false, topologyUsage)
+	cmd.Flags().StringSliceVar(&accessModes, AccessModesFlag, nil, accessModesUsage)
+	cmd.Flags().StringVar(&targetS3Endpoint, TargetS3EndpointFlag, "", targetS3EndpointUsage)
+	cmd.Flags().StringVar(&targetS3Bucket, TargetS3BucketFlag, "", targetS3BucketUsage)
+	cmd.Flags().StringVar(&targetS3Region, TargetS3RegionFlag, "", targetS3RegionUsage)
+	cmd.Flags().StringVar(&targetS3Secret, TargetS3SecretFlag, "", targetS3SecretUsage)
+	cmd.Flags().StringVar(&targetS3CASecret, TargetS3CASecretFlag, "", targetS3CASecretUsage)
+	cmd.Flags().BoolVar(&targetS3Insecure, TargetS3InsecureFlag, false, targetS3InsecureUsage)
+	cmd.Flags().StringVar(&targetNFSServer, TargetNFSServerFlag, "", targetNFSServerUsage)
+	cmd.Flags().StringVar(&targetNFSPath, TargetNFSPathFlag, "", targetNFSPathUsage)
+	cmd.Flags().StringVar(&nodeSelector, NodeSelectorFlag, "", nodeSelectorUsage)
+	cmd.Flags().StringSliceVar(&checks, ChecksFlag, nil, checksUsage)
+	cmd.Flags().StringSliceVar(&skipChecks, SkipChecksFlag, nil, skipChecksUsage)
+	cmd.Flags().IntVar(&parallelism, ParallelismFlag, preflight.DefaultParallelism, parallelismUsage)
+	cmd.Flags().DurationVar(&timeout, TimeoutFlag, 0, timeoutUsage)
+	cmd.Flags().BoolVar(&discover, DiscoverFlag, false, discoverUsage)
+	cmd.Flags().BoolVar(&discoverRoundTrip, DiscoverRoundTripFlag, false, discoverRoundTripUsage)
 }
@@ -24,6 +24,7 @@ const (
 	nodeSelValueSSD   = "ssd"
 
 	imagePullSecretStr = "image-pull-secret"
+	invalidCheckName   = "check-invalid"
 )
 
 var (
@@ -56,7 +56,8 @@ var _ = Describe("Preflight Tests", func() {
 				Expect(cmdOut.Out).To(
 					ContainSubstring(fmt.Sprintf("Preflight check for SnapshotClass failed :: "+
 						"not found storageclass - %s on cluster", internal.InvalidStorageClassName)))
-				Expect(cmdOut.Out).To(ContainSubstring("Skipping volume snapshot and restore check as preflight check for SnapshotClass failed"))
+				Expect(cmdOut.Out).To(ContainSubstring(fmt.Sprintf("Skipping %s scope volume snapshot and restore check as "+
+					"preflight check for SnapshotClass failed", inputFlags[scopeFlag])))
 				Expect(cmdOut.Out).To(ContainSubstring("Some preflight checks failed"))
 			})
 
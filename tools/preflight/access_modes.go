@@ -0,0 +1,368 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/trilioData/tvk-plugins/internal"
+	"github.com/trilioData/tvk-plugins/tools/preflight/wait"
+)
+
+const (
+	AccessModeNamePrefix = "access-mode-"
+
+	// LabelAccessModeGroupKey is set on the pods mounting the same pvc of access mode check, the second pod is
+	// scheduled on a different node than the first one using pod anti-affinity on it.
+	LabelAccessModeGroupKey = "preflight-access-mode-group"
+
+	accessModeFilePrefix = VolMountPath + "/access-mode-"
+	// second pod mounting a ReadWriteOncePod pvc is waited upon for a shorter duration, as it's expected not to start
+	accessModeExclusiveTimeout = 2 * time.Minute
+	// readOnlyFileSystemError is the error of writes to a volume mounted read-only, i.e EROFS
+	readOnlyFileSystemError = "Read-only file system"
+)
+
+// exclusiveAccessEventReasons are the reasons of warning events of the second pod mounting a ReadWriteOncePod pvc,
+// which report the conflict on the pvc.
+var exclusiveAccessEventReasons = []string{"FailedScheduling", "FailedMount", "FailedAttachVolume"}
+
+// SupportedAccessModes are the access modes verified by access mode check, with their abbreviations.
+var SupportedAccessModes = map[corev1.PersistentVolumeAccessMode]string{
+	corev1.ReadWriteMany:    "rwx",
+	corev1.ReadOnlyMany:     "rox",
+	corev1.ReadWriteOncePod: "rwop",
+}
+
+// AccessModeResult is the outcome of access mode check of a storage class for an access mode.
+type AccessModeResult struct {
+	StorageClass string                            `json:"storageClass"`
+	AccessMode   corev1.PersistentVolumeAccessMode `json:"accessMode"`
+	Status       CheckStatus                       `json:"status"`
+	// Nodes are the nodes on which the pods mounting the pvc were running.
+	Nodes []string `json:"nodes,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// ParseAccessMode returns the access mode for its name or abbreviation, e.g ReadWriteMany or RWX.
+func ParseAccessMode(mode string) (corev1.PersistentVolumeAccessMode, error) {
+	for accessMode, abbr := range SupportedAccessModes {
+		if strings.EqualFold(mode, string(accessMode)) || strings.EqualFold(mode, abbr) {
+			return accessMode, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported access mode - %s. Supported access modes are - ReadWriteMany (RWX), "+
+		"ReadOnlyMany (ROX) and ReadWriteOncePod (RWOP)", mode)
+}
+
+func (o *Run) accessModesString() string {
+	var modes []string
+	for _, mode := range o.AccessModes {
+		modes = append(modes, string(mode))
+	}
+	return strings.Join(modes, ",")
+}
+
+// accessModeNameSuffix returns the suffix of names of the resources created for the access mode.
+func (o *Run) accessModeNameSuffix(mode corev1.PersistentVolumeAccessMode, uid string) string {
+	return fmt.Sprintf("%s-%s", SupportedAccessModes[mode], o.resourceNameSuffix(uid))
+}
+
+func createAccessModePVCSpec(o *Run, mode corev1.PersistentVolumeAccessMode, uid string) *corev1.PersistentVolumeClaim {
+	pvcNsName := types.NamespacedName{Namespace: o.Namespace, Name: AccessModeNamePrefix + "pvc-" + o.accessModeNameSuffix(mode, uid)}
+	pvc := createVolumeSnapshotPVCSpec(o, pvcNsName, uid)
+	pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{mode}
+	return pvc
+}
+
+// createAccessModePodSpec returns the index'th pod mounting the pvc of access mode check. Pods other than the first one
+// are scheduled on a different node than the first pod, unless the access mode allows a single pod only.
+func createAccessModePodSpec(o *Run, pvc *corev1.PersistentVolumeClaim, index int, uid string) *corev1.Pod {
+	mode := pvc.Spec.AccessModes[0]
+	podNsName := types.NamespacedName{Namespace: pvc.GetNamespace(),
+		Name: fmt.Sprintf("%spod-%d-%s", AccessModeNamePrefix, index, o.accessModeNameSuffix(mode, uid))}
+	pod := getPodSpecWithPVC(podNsName, types.NamespacedName{Namespace: pvc.GetNamespace(), Name: pvc.GetName()}, o, uid)
+	pod.Spec.Containers[0].Command = CommandSleep3600
+
+	group := pvc.GetName()
+	pod.Labels[LabelAccessModeGroupKey] = group
+	if index != 0 && mode != corev1.ReadWriteOncePod {
+		pod.Spec.Affinity = withPodAntiAffinity(pod.Spec.Affinity, LabelAccessModeGroupKey, group)
+	}
+
+	return pod
+}
+
+// withPodAntiAffinity returns a copy of the affinity, requiring the pod not to be scheduled on the node of pods
+// having the label.
+func withPodAntiAffinity(affinity *corev1.Affinity, labelKey, labelValue string) *corev1.Affinity {
+	result := &corev1.Affinity{}
+	if affinity != nil {
+		result = affinity.DeepCopy()
+	}
+	if result.PodAntiAffinity == nil {
+		result.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	}
+	result.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+		result.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, corev1.PodAffinityTerm{
+			LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{labelKey: labelValue}},
+			TopologyKey:   corev1.LabelHostname,
+		})
+
+	return result
+}
+
+// accessModeRecommendation returns the recommendation when the storage class doesn't support the access mode.
+func accessModeRecommendation(mode corev1.PersistentVolumeAccessMode) string {
+	switch mode {
+	case corev1.ReadWriteMany, corev1.ReadOnlyMany:
+		return fmt.Sprintf("Use a storage class whose provisioner supports %s volumes, e.g. NFS or CephFS, and ensure the "+
+			"cluster has at least two schedulable nodes matching the pod scheduling options", mode)
+	default:
+		return fmt.Sprintf("Use a CSI storage class supporting %s volumes, on a cluster having the feature enabled", mode)
+	}
+}
+
+func runAccessModesCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	if len(o.AccessModes) == 0 {
+		res.Recommend("Give access modes using 'accessModes' in run section of config file, or --access-modes flag")
+		return fmt.Errorf("access modes to check are not given")
+	}
+
+	var (
+		mu          sync.Mutex
+		failedModes = make(map[corev1.PersistentVolumeAccessMode]bool)
+	)
+	err := o.forEachStorageClass(func(r *Run) error {
+		var errs []string
+		for _, mode := range r.AccessModes {
+			result := r.validateAccessMode(ctx, mode, resNameSuffix, kubeClient)
+			if r.scResult != nil {
+				r.scResult.AccessModes = append(r.scResult.AccessModes, result)
+			}
+			if result.Status == CheckStatusFail {
+				mu.Lock()
+				failedModes[mode] = true
+				mu.Unlock()
+				errs = append(errs, fmt.Sprintf("%s :: %s", mode, result.Error))
+			}
+		}
+		if len(errs) != 0 {
+			return fmt.Errorf("storage class - %s doesn't support access modes - [%s]", r.StorageClass, strings.Join(errs, "; "))
+		}
+		return nil
+	})
+	for _, mode := range o.AccessModes {
+		if failedModes[mode] {
+			res.Recommend(accessModeRecommendation(mode))
+		}
+	}
+
+	return err
+}
+
+// validateAccessMode provisions a pvc of the storage class with the access mode and mounts it from two pods. For
+// ReadWriteMany, the pods run on different nodes and each reads the data written by the other. For ReadOnlyMany,
+// the pods run on different nodes and writes are rejected. For ReadWriteOncePod, the second pod must not start
+// because of the conflict on the pvc.
+func (o *Run) validateAccessMode(ctx context.Context, mode corev1.PersistentVolumeAccessMode, uid string,
+	clients ServerClients) *AccessModeResult {
+	result := &AccessModeResult{StorageClass: o.StorageClass, AccessMode: mode, Status: CheckStatusPass}
+	o.Logger.Infof("Checking %s access mode of storage class - %s", mode, o.StorageClass)
+
+	var err error
+	switch mode {
+	case corev1.ReadWriteOncePod:
+		err = o.validateExclusiveAccess(ctx, mode, uid, result, clients)
+	default:
+		err = o.validateSharedAccess(ctx, mode, uid, result, clients)
+	}
+	if err != nil {
+		result.Status, result.Error = CheckStatusFail, err.Error()
+		o.Logger.Errorf("%s Storage class - %s doesn't support %s access mode :: %s", cross, o.StorageClass, mode, err.Error())
+		return result
+	}
+	o.Logger.Infof("%s Storage class - %s supports %s access mode", check, o.StorageClass, mode)
+
+	return result
+}
+
+// createAccessModePods creates the pvc and the given number of pods mounting it, recording the nodes of the pods.
+func (o *Run) createAccessModePods(ctx context.Context, mode corev1.PersistentVolumeAccessMode, count int, uid string,
+	result *AccessModeResult, clients ServerClients) ([]*corev1.Pod, error) {
+	pvc, err := o.createPVCFromSpec(ctx, createAccessModePVCSpec(o, mode, uid), clients.ClientSet)
+	if err != nil {
+		return nil, err
+	}
+
+	var pods []*corev1.Pod
+	for idx := 0; idx < count; idx++ {
+		pod, pErr := o.createPod(ctx, createAccessModePodSpec(o, pvc, idx, uid), clients.ClientSet)
+		if pErr != nil {
+			return nil, pErr
+		}
+		pods = append(pods, pod)
+		result.Nodes = append(result.Nodes, pod.Spec.NodeName)
+	}
+
+	return pods, nil
+}
+
+// validateSharedAccess verifies the pvc is mounted concurrently from pods on two different nodes.
+func (o *Run) validateSharedAccess(ctx context.Context, mode corev1.PersistentVolumeAccessMode, uid string,
+	result *AccessModeResult, clients ServerClients) error {
+	pods, err := o.createAccessModePods(ctx, mode, 2, uid, result, clients)
+	if err != nil {
+		return err
+	}
+	o.Logger.Infof("%s PVC of %s access mode is mounted from nodes - %s", check, mode, strings.Join(result.Nodes, ", "))
+
+	if mode == corev1.ReadOnlyMany {
+		for _, pod := range pods {
+			if _, err = o.execAccessModeCommand(ctx, pod, fmt.Sprintf("ls %s", VolMountPath), clients); err != nil {
+				return fmt.Errorf("error reading volume from pod - %s :: %w", pod.GetName(), err)
+			}
+			if err = o.validateWriteRejected(ctx, pod, clients); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// each pod writes a file, which is read by the other pod
+	for idx, pod := range pods {
+		if _, err = o.execAccessModeCommand(ctx, pod, fmt.Sprintf("echo '%s' > %s%d && sync", pod.GetName(),
+			accessModeFilePrefix, idx), clients); err != nil {
+			return fmt.Errorf("error writing to volume from pod - %s :: %w", pod.GetName(), err)
+		}
+	}
+	for idx, pod := range pods {
+		writer := pods[(idx+1)%len(pods)]
+		out, rErr := o.execAccessModeCommand(ctx, pod, fmt.Sprintf("cat %s%d", accessModeFilePrefix, (idx+1)%len(pods)), clients)
+		if rErr != nil {
+			return fmt.Errorf("error reading data written by pod - %s from pod - %s :: %w", writer.GetName(), pod.GetName(), rErr)
+		}
+		if strings.TrimSpace(out) != writer.GetName() {
+			return fmt.Errorf("data written by pod - %s is not visible to pod - %s", writer.GetName(), pod.GetName())
+		}
+	}
+	o.Logger.Infof("%s Data written from each node is read from the other node", check)
+
+	return nil
+}
+
+// validateWriteRejected verifies that writes to the volume of ReadOnlyMany access mode are rejected as to a read-only
+// file system. The pod doesn't mount the pvc with readOnly, so the writes must be rejected by the kubelet or CSI driver.
+func (o *Run) validateWriteRejected(ctx context.Context, pod *corev1.Pod, clients ServerClients) error {
+	execOp := o.dataExecOptions(ctx, pod, []string{"/bin/sh", "-c", fmt.Sprintf("touch %s0", accessModeFilePrefix)}, clients)
+	execRes, err := execInPodWithResponse(ctx, &execOp, o.Logger)
+	switch {
+	case err == nil:
+		return fmt.Errorf("volume of %s access mode is writable from pod - %s", corev1.ReadOnlyMany, pod.GetName())
+	case execRes == nil:
+		return fmt.Errorf("error writing to volume from pod - %s :: %w", pod.GetName(), err)
+	case !strings.Contains(execRes.Stderr, readOnlyFileSystemError):
+		return fmt.Errorf("write to volume from pod - %s failed, but not as to a read-only file system :: %s :: %w",
+			pod.GetName(), strings.TrimSpace(execRes.Stderr), err)
+	}
+	o.Logger.Infof("%s Write to volume from pod - %s is rejected :: %s", check, pod.GetName(), strings.TrimSpace(execRes.Stderr))
+
+	return nil
+}
+
+// validateExclusiveAccess verifies that the pvc mounted by a pod can't be mounted by another pod.
+func (o *Run) validateExclusiveAccess(ctx context.Context, mode corev1.PersistentVolumeAccessMode, uid string,
+	result *AccessModeResult, clients ServerClients) error {
+	pods, err := o.createAccessModePods(ctx, mode, 1, uid, result, clients)
+	if err != nil {
+		return err
+	}
+	if _, err = o.execAccessModeCommand(ctx, pods[0], fmt.Sprintf("echo '%s' > %s0 && sync", pods[0].GetName(),
+		accessModeFilePrefix), clients); err != nil {
+		return fmt.Errorf("error writing to volume from pod - %s :: %w", pods[0].GetName(), err)
+	}
+
+	pvc := types.NamespacedName{Namespace: pods[0].GetNamespace(), Name: pods[0].Spec.Volumes[0].PersistentVolumeClaim.ClaimName}
+	pod := createAccessModePodSpec(o, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: pvc.Name, Namespace: pvc.Namespace},
+		Spec:       corev1.PersistentVolumeClaimSpec{AccessModes: []corev1.PersistentVolumeAccessMode{mode}},
+	}, 1, uid)
+	pod, err = clients.ClientSet.CoreV1().Pods(pod.GetNamespace()).Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	o.recordCreatedResource(pod)
+	o.Logger.Infof("Created pod - %s mounting pvc - %s, which is expected not to start", pod.GetName(), pvc.String())
+
+	waitOptions := &wait.PodWaitOptions{
+		Name:         pod.GetName(),
+		Namespace:    pod.GetNamespace(),
+		PodCondition: corev1.PodReady,
+		ClientSet:    clients.ClientSet,
+		Timeout:      accessModeExclusiveTimeout,
+		Logger:       o.Logger,
+	}
+	// the pod is expected to be unschedulable, or to fail mounting the volume until the wait timeouts
+	switch err = waitUntilPodCondition(ctx, waitOptions); {
+	case err == nil:
+		return fmt.Errorf("pod - %s started while pvc - %s is mounted by pod - %s", pod.GetName(), pvc.String(), pods[0].GetName())
+	case ctx.Err() != nil:
+		return err
+	}
+	conflict := o.exclusiveAccessConflict(ctx, pod, pvc.Name, clients)
+	if conflict == "" {
+		return fmt.Errorf("pod - %s didn't start, but no scheduling or mount conflict on pvc - %s is reported :: %w",
+			pod.GetName(), pvc.String(), err)
+	}
+	o.Logger.Infof("%s Pod - %s didn't start :: %s", check, pod.GetName(), conflict)
+
+	return nil
+}
+
+// exclusiveAccessConflict returns the message of the unschedulable condition, or of the scheduling or mount failure
+// warning events of the pod, which reports the conflict on the pvc. Empty string is returned if none reports it.
+func (o *Run) exclusiveAccessConflict(ctx context.Context, pod *corev1.Pod, pvcName string, clients ServerClients) string {
+	var messages []string
+	latest, err := clients.ClientSet.CoreV1().Pods(pod.GetNamespace()).Get(ctx, pod.GetName(), metav1.GetOptions{})
+	if err != nil {
+		o.Logger.Warnf("error getting pod - %s :: %s", pod.GetName(), err.Error())
+	} else {
+		for _, cond := range latest.Status.Conditions {
+			if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse && cond.Reason == corev1.PodReasonUnschedulable {
+				messages = append(messages, cond.Message)
+			}
+		}
+	}
+	for _, reason := range exclusiveAccessEventReasons {
+		messages = append(messages, wait.LatestWarningEvent(ctx, clients.ClientSet, internal.PodKind, pod, reason))
+	}
+
+	return pvcConflictMessage(messages, pvcName)
+}
+
+// pvcConflictMessage returns the first message naming the pvc or the ReadWriteOncePod access mode, as the scheduler
+// reports the conflict on a ReadWriteOncePod pvc by its access mode without naming the pvc.
+func pvcConflictMessage(messages []string, pvcName string) string {
+	for _, msg := range messages {
+		if strings.Contains(msg, pvcName) || strings.Contains(msg, string(corev1.ReadWriteOncePod)) {
+			return msg
+		}
+	}
+	return ""
+}
+
+// execAccessModeCommand executes the shell command in the pod and returns its output.
+func (o *Run) execAccessModeCommand(ctx context.Context, pod *corev1.Pod, command string, clients ServerClients) (string, error) {
+	execOp := o.dataExecOptions(ctx, pod, []string{"/bin/sh", "-c", command}, clients)
+	execRes, err := execInPodWithResponse(ctx, &execOp, o.Logger)
+	if err != nil {
+		return "", err
+	}
+	return execRes.Stdout, nil
+}
@@ -0,0 +1,108 @@
+package preflight
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("Access modes unit tests", func() {
+
+	var run *Run
+
+	BeforeEach(func() {
+		run = runOps.copyRun()
+		run.StorageClass = "nfs-csi"
+		run.PodSchedOps = podSchedulingOptions{}
+	})
+
+	Context("ParseAccessMode func test-cases", func() {
+
+		It("Should parse access mode from its name or abbreviation, ignoring case", func() {
+			for _, mode := range []string{"ReadWriteMany", "rwx", "RWX"} {
+				accessMode, err := ParseAccessMode(mode)
+				Expect(err).To(BeNil())
+				Expect(accessMode).To(Equal(corev1.ReadWriteMany))
+			}
+			accessMode, err := ParseAccessMode("RWOP")
+			Expect(err).To(BeNil())
+			Expect(accessMode).To(Equal(corev1.ReadWriteOncePod))
+		})
+
+		It("Should return error for access modes which aren't verified", func() {
+			for _, mode := range []string{"ReadWriteOnce", "RWM"} {
+				_, err := ParseAccessMode(mode)
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring("unsupported access mode - " + mode))
+			}
+		})
+	})
+
+	Context("Access mode pvc and pod spec test-cases", func() {
+
+		It("Should create pvc of the storage class having only the access mode", func() {
+			pvc := createAccessModePVCSpec(run, corev1.ReadWriteMany, "abcdef")
+			Expect(pvc.GetName()).To(Equal("access-mode-pvc-rwx-abcdef"))
+			Expect(*pvc.Spec.StorageClassName).To(Equal("nfs-csi"))
+			Expect(pvc.Spec.AccessModes).To(Equal([]corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}))
+		})
+
+		It("Should schedule the second pod on a different node than the first pod, keeping the given affinity", func() {
+			nodeAffinity := &corev1.NodeAffinity{}
+			run.PodSchedOps.Affinity = &corev1.Affinity{NodeAffinity: nodeAffinity}
+			pvc := createAccessModePVCSpec(run, corev1.ReadWriteMany, "abcdef")
+
+			first := createAccessModePodSpec(run, pvc, 0, "abcdef")
+			Expect(first.Labels[LabelAccessModeGroupKey]).To(Equal(pvc.GetName()))
+			Expect(first.Spec.Affinity.PodAntiAffinity).To(BeNil())
+
+			second := createAccessModePodSpec(run, pvc, 1, "abcdef")
+			Expect(second.GetName()).To(Equal("access-mode-pod-1-rwx-abcdef"))
+			Expect(second.Spec.Affinity.NodeAffinity).To(Equal(nodeAffinity))
+			terms := second.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+			Expect(terms).To(HaveLen(1))
+			Expect(terms[0].TopologyKey).To(Equal(corev1.LabelHostname))
+			Expect(terms[0].LabelSelector.MatchLabels).To(Equal(map[string]string{LabelAccessModeGroupKey: pvc.GetName()}))
+			Expect(run.PodSchedOps.Affinity.PodAntiAffinity).To(BeNil())
+		})
+
+		It("Should not mount pvc of ReadOnlyMany access mode with readOnly, leaving writes to be rejected by storage", func() {
+			pvc := createAccessModePVCSpec(run, corev1.ReadOnlyMany, "abcdef")
+			pod := createAccessModePodSpec(run, pvc, 1, "abcdef")
+			Expect(pod.Spec.Volumes[0].PersistentVolumeClaim.ReadOnly).To(BeFalse())
+			Expect(pod.Spec.Affinity.PodAntiAffinity).ToNot(BeNil())
+		})
+
+		It("Should not schedule the second pod of ReadWriteOncePod access mode on a different node", func() {
+			pvc := createAccessModePVCSpec(run, corev1.ReadWriteOncePod, "abcdef")
+			pod := createAccessModePodSpec(run, pvc, 1, "abcdef")
+			Expect(pod.Spec.Affinity).To(BeNil())
+			Expect(pod.Spec.Volumes[0].PersistentVolumeClaim.ReadOnly).To(BeFalse())
+		})
+	})
+
+	Context("pvcConflictMessage func test-cases", func() {
+
+		It("Should return message reporting the conflict on the pvc by its name or ReadWriteOncePod access mode", func() {
+			schedMsg := "0/3 nodes are available: 1 node has pod using PersistentVolumeClaim with the same name and " +
+				"ReadWriteOncePod access mode, 2 node(s) didn't match Pod's node affinity/selector."
+			mountMsg := "Unable to attach or mount volumes: volume access-mode-pvc-rwop-abcdef is in use by another pod"
+			Expect(pvcConflictMessage([]string{"", schedMsg, mountMsg}, "access-mode-pvc-rwop-abcdef")).To(Equal(schedMsg))
+			Expect(pvcConflictMessage([]string{"", mountMsg}, "access-mode-pvc-rwop-abcdef")).To(Equal(mountMsg))
+		})
+
+		It("Should return empty string if no message reports the conflict on the pvc", func() {
+			Expect(pvcConflictMessage([]string{"", "0/3 nodes are available: 3 Insufficient cpu."},
+				"access-mode-pvc-rwop-abcdef")).To(BeEmpty())
+			Expect(pvcConflictMessage(nil, "access-mode-pvc-rwop-abcdef")).To(BeEmpty())
+		})
+	})
+
+	Context("accessModeRecommendation func test-cases", func() {
+
+		It("Should recommend shared file system storage for ReadWriteMany and ReadOnlyMany", func() {
+			Expect(accessModeRecommendation(corev1.ReadWriteMany)).To(ContainSubstring("NFS or CephFS"))
+			Expect(accessModeRecommendation(corev1.ReadWriteOncePod)).To(ContainSubstring("CSI storage class"))
+		})
+	})
+})
@@ -0,0 +1,432 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+const (
+	WebhookTypeValidating = "validating"
+	WebhookTypeMutating   = "mutating"
+
+	webhookPodPrefix = "webhook-"
+	allResources     = "*"
+
+	// serviceAccountVolumePrefix is the prefix of the token volume added to pods by the service account admission plugin.
+	serviceAccountVolumePrefix = "kube-api-access-"
+	// limitRangerAnnotation is set on pods by the LimitRanger admission plugin, listing the resources it defaulted.
+	limitRangerAnnotation = "kubernetes.io/limit-ranger"
+)
+
+// webhookTarget is a resource created by preflight checks or TVK, which admission webhooks may interfere with.
+type webhookTarget struct {
+	group    string
+	resource string
+}
+
+func (t webhookTarget) String() string {
+	if t.group == "" {
+		return t.resource
+	}
+	return t.resource + "." + t.group
+}
+
+// webhookTargets are the resources whose creation is verified against the rules of admission webhooks. All resources
+// of TVK group are matched.
+var webhookTargets = []webhookTarget{
+	{group: "", resource: "pods"},
+	{group: "", resource: "persistentvolumeclaims"},
+	{group: StorageSnapshotGroup, resource: "volumesnapshots"},
+	{group: internal.TriliovaultGroup, resource: allResources},
+}
+
+// defaultTolerationKeys are the keys of tolerations added to pods by the DefaultTolerationSeconds admission plugin.
+var defaultTolerationKeys = map[string]bool{
+	corev1.TaintNodeNotReady:    true,
+	corev1.TaintNodeUnreachable: true,
+}
+
+// AdmissionWebhooksResult holds the admission webhooks matching resources of TVK and the mutations of preflight pods.
+type AdmissionWebhooksResult struct {
+	Webhooks  []*WebhookResult     `json:"webhooks,omitempty"`
+	Mutations []*PodMutationResult `json:"mutations,omitempty"`
+}
+
+// WebhookResult is an admission webhook whose rules match resources of TVK in the namespace.
+type WebhookResult struct {
+	Name          string   `json:"name"`
+	Configuration string   `json:"configuration"`
+	Type          string   `json:"type"`
+	Resources     []string `json:"resources"`
+	FailurePolicy string   `json:"failurePolicy"`
+	// Service is the backing service of webhook, empty for webhooks called by URL.
+	Service        string      `json:"service,omitempty"`
+	ReadyEndpoints *int        `json:"readyEndpoints,omitempty"`
+	Status         CheckStatus `json:"status"`
+}
+
+// PodMutationResult holds the changes made by mutating admission to a preflight pod.
+type PodMutationResult struct {
+	Pod       string   `json:"pod"`
+	Mutations []string `json:"mutations"`
+}
+
+// ruleMatchesTarget returns whether the rule of webhook matches the creation of target resource.
+func ruleMatchesTarget(rule *admissionregistrationv1.RuleWithOperations, target webhookTarget) bool {
+	if rule.Scope != nil && *rule.Scope == admissionregistrationv1.ClusterScope {
+		return false
+	}
+	operationMatched := false
+	for _, op := range rule.Operations {
+		if op == admissionregistrationv1.Create || op == admissionregistrationv1.OperationAll {
+			operationMatched = true
+		}
+	}
+	groupMatched := false
+	for _, group := range rule.APIGroups {
+		if group == target.group || group == allResources {
+			groupMatched = true
+		}
+	}
+	resourceMatched := false
+	for _, resource := range rule.Resources {
+		// subresources aren't created by preflight checks
+		if strings.Contains(resource, "/") {
+			continue
+		}
+		if resource == allResources || target.resource == allResources || resource == target.resource {
+			resourceMatched = true
+		}
+	}
+	return operationMatched && groupMatched && resourceMatched
+}
+
+// matchedTargets returns the target resources in the namespace matched by the rules and namespace selector of webhook.
+// Namespace labels are nil if the namespace doesn't exist yet, when the namespace selector isn't evaluated.
+func matchedTargets(rules []admissionregistrationv1.RuleWithOperations, nsSelector *metav1.LabelSelector,
+	nsLabels map[string]string) ([]string, error) {
+	if nsSelector != nil && nsLabels != nil {
+		selector, err := metav1.LabelSelectorAsSelector(nsSelector)
+		if err != nil {
+			return nil, err
+		}
+		if !selector.Matches(labels.Set(nsLabels)) {
+			return nil, nil
+		}
+	}
+
+	var matched []string
+	for _, target := range webhookTargets {
+		for idx := range rules {
+			if ruleMatchesTarget(&rules[idx], target) {
+				matched = append(matched, target.String())
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+// newWebhookResult returns the webhook result if its rules match resources of TVK, nil otherwise.
+func newWebhookResult(configuration, webhookType, name string, rules []admissionregistrationv1.RuleWithOperations,
+	nsSelector *metav1.LabelSelector, failurePolicy *admissionregistrationv1.FailurePolicyType,
+	clientConfig admissionregistrationv1.WebhookClientConfig, nsLabels map[string]string) (*WebhookResult, error) {
+	matched, err := matchedTargets(rules, nsSelector, nsLabels)
+	if err != nil || len(matched) == 0 {
+		return nil, err
+	}
+
+	// failure policy defaults to Fail for admissionregistration.k8s.io/v1 webhooks
+	policy := admissionregistrationv1.Fail
+	if failurePolicy != nil {
+		policy = *failurePolicy
+	}
+	result := &WebhookResult{Name: name, Configuration: configuration, Type: webhookType, Resources: matched,
+		FailurePolicy: string(policy), Status: CheckStatusPass}
+	if clientConfig.Service != nil {
+		result.Service = types.NamespacedName{Namespace: clientConfig.Service.Namespace, Name: clientConfig.Service.Name}.String()
+	}
+	return result, nil
+}
+
+// listMatchingWebhooks returns the validating and mutating admission webhooks matching resources of TVK in namespace.
+func listMatchingWebhooks(ctx context.Context, clientSet kubernetes.Interface, nsLabels map[string]string) ([]*WebhookResult, error) {
+	var results []*WebhookResult
+	add := func(result *WebhookResult, err error) error {
+		if result != nil {
+			results = append(results, result)
+		}
+		return err
+	}
+
+	validating, err := clientSet.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for idx := range validating.Items {
+		cfg := &validating.Items[idx]
+		for wIdx := range cfg.Webhooks {
+			w := &cfg.Webhooks[wIdx]
+			if err = add(newWebhookResult(cfg.GetName(), WebhookTypeValidating, w.Name, w.Rules, w.NamespaceSelector,
+				w.FailurePolicy, w.ClientConfig, nsLabels)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	mutating, err := clientSet.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for idx := range mutating.Items {
+		cfg := &mutating.Items[idx]
+		for wIdx := range cfg.Webhooks {
+			w := &cfg.Webhooks[wIdx]
+			if err = add(newWebhookResult(cfg.GetName(), WebhookTypeMutating, w.Name, w.Rules, w.NamespaceSelector,
+				w.FailurePolicy, w.ClientConfig, nsLabels)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// countReadyEndpoints returns the number of ready endpoints of the service from its endpoint slices.
+func countReadyEndpoints(slices []discoveryv1.EndpointSlice) int {
+	ready := 0
+	for idx := range slices {
+		for _, endpoint := range slices[idx].Endpoints {
+			// nil ready condition is interpreted as ready
+			if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+				ready++
+			}
+		}
+	}
+	return ready
+}
+
+// serviceReadyEndpoints returns the number of ready endpoints backing the service.
+func serviceReadyEndpoints(ctx context.Context, clientSet kubernetes.Interface, service string) (int, error) {
+	parts := strings.SplitN(service, string(types.Separator), 2)
+	slices, err := clientSet.DiscoveryV1().EndpointSlices(parts[0]).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.Set{discoveryv1.LabelServiceName: parts[1]}.String(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return countReadyEndpoints(slices.Items), nil
+}
+
+// diffMapKeys returns the keys added to or changed in the stored map, in 'key=value' format.
+func diffMapKeys(kind string, submitted, stored map[string]string) []string {
+	var diffs []string
+	for key, value := range stored {
+		if old, ok := submitted[key]; !ok {
+			diffs = append(diffs, fmt.Sprintf("added %s %s=%s", kind, key, value))
+		} else if old != value {
+			diffs = append(diffs, fmt.Sprintf("changed %s %s from %s to %s", kind, key, old, value))
+		}
+	}
+	sort.Strings(diffs)
+	return diffs
+}
+
+// resourceListsEqual returns whether the resource lists have the same quantities, taking nil as empty.
+func resourceListsEqual(a, b corev1.ResourceList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		if other, ok := b[name]; !ok || value.Cmp(other) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// podMutations returns the changes made to the submitted pod by mutating admission, ignoring the defaults set by
+// the API server and by the built-in admission plugins. Resources defaulted by the LimitRanges of namespace aren't
+// mutations of webhooks, they're evaluated by resource quota check.
+func podMutations(submitted, stored *corev1.Pod, limitRanges []corev1.LimitRange) []string {
+	storedAnnotations := make(map[string]string)
+	for key, value := range stored.GetAnnotations() {
+		if key != limitRangerAnnotation {
+			storedAnnotations[key] = value
+		}
+	}
+	mutations := diffMapKeys("label", submitted.GetLabels(), stored.GetLabels())
+	mutations = append(mutations, diffMapKeys("annotation", submitted.GetAnnotations(), storedAnnotations)...)
+	mutations = append(mutations, diffMapKeys("node selector", submitted.Spec.NodeSelector, stored.Spec.NodeSelector)...)
+
+	submittedContainers := make(map[string]*corev1.Container)
+	for idx := range submitted.Spec.Containers {
+		submittedContainers[submitted.Spec.Containers[idx].Name] = &submitted.Spec.Containers[idx]
+	}
+	for idx := range stored.Spec.InitContainers {
+		mutations = append(mutations, fmt.Sprintf("added init container %s", stored.Spec.InitContainers[idx].Name))
+	}
+	for idx := range stored.Spec.Containers {
+		c := &stored.Spec.Containers[idx]
+		old, ok := submittedContainers[c.Name]
+		if !ok {
+			mutations = append(mutations, fmt.Sprintf("added container %s", c.Name))
+			continue
+		}
+		if old.Image != c.Image {
+			mutations = append(mutations, fmt.Sprintf("changed image of container %s from %s to %s", c.Name, old.Image, c.Image))
+		}
+		oldEnv := make(map[string]bool)
+		for _, env := range old.Env {
+			oldEnv[env.Name] = true
+		}
+		for _, env := range c.Env {
+			if !oldEnv[env.Name] {
+				mutations = append(mutations, fmt.Sprintf("added env %s to container %s", env.Name, c.Name))
+			}
+		}
+		requests, limits := effectiveResources(old.Resources, limitRanges)
+		if !resourceListsEqual(requests, c.Resources.Requests) || !resourceListsEqual(limits, c.Resources.Limits) {
+			mutations = append(mutations, fmt.Sprintf("changed resources of container %s", c.Name))
+		}
+	}
+
+	oldVolumes := make(map[string]bool)
+	for _, vol := range submitted.Spec.Volumes {
+		oldVolumes[vol.Name] = true
+	}
+	for _, vol := range stored.Spec.Volumes {
+		if !oldVolumes[vol.Name] && !strings.HasPrefix(vol.Name, serviceAccountVolumePrefix) {
+			mutations = append(mutations, fmt.Sprintf("added volume %s", vol.Name))
+		}
+	}
+	oldTolerations := make(map[string]bool)
+	for _, toleration := range submitted.Spec.Tolerations {
+		oldTolerations[toleration.Key] = true
+	}
+	for _, toleration := range stored.Spec.Tolerations {
+		if !oldTolerations[toleration.Key] && !defaultTolerationKeys[toleration.Key] {
+			mutations = append(mutations, fmt.Sprintf("added toleration %s", toleration.Key))
+		}
+	}
+	if submitted.Spec.SchedulerName != "" && submitted.Spec.SchedulerName != stored.Spec.SchedulerName {
+		mutations = append(mutations, fmt.Sprintf("changed scheduler to %s", stored.Spec.SchedulerName))
+	}
+
+	return mutations
+}
+
+// webhookDryRunPods returns the preflight pods created with server-side dry-run to find the mutations made to them.
+func webhookDryRunPods(o *Run, uid string) []*corev1.Pod {
+	nameSuffix := webhookPodPrefix + uid
+	pvcNsName := types.NamespacedName{Namespace: o.Namespace, Name: BackupPvcNamePrefix + nameSuffix}
+	return []*corev1.Pod{
+		createDNSPodSpec(o, nameSuffix),
+		createPVCDataReaderPodSpec(fmt.Sprintf("%s%s-%s", BackupPvcNamePrefix, "reader", nameSuffix), pvcNsName, o, uid),
+	}
+}
+
+// dryRunPodMutations creates the pod with server-side dry-run, and returns the mutations made to it.
+func dryRunPodMutations(ctx context.Context, pod *corev1.Pod, limitRanges []corev1.LimitRange,
+	runtimeClient client.Client) ([]string, error) {
+	stored := pod.DeepCopy()
+	if err := runtimeClient.Create(ctx, stored, client.DryRunAll); err != nil {
+		return nil, err
+	}
+	return podMutations(pod, stored, limitRanges), nil
+}
+
+func runAdmissionWebhooksCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	o.Logger.Infoln("Checking admission webhooks interfering with resources of TVK")
+
+	result := o.webhooksResult
+	if result == nil {
+		result = &AdmissionWebhooksResult{}
+		o.webhooksResult = result
+	}
+
+	var nsLabels map[string]string
+	nsFound := true
+	ns, err := kubeClient.ClientSet.CoreV1().Namespaces().Get(ctx, o.Namespace, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		nsLabels = ns.GetLabels()
+		if nsLabels == nil {
+			nsLabels = map[string]string{}
+		}
+	case k8serrors.IsNotFound(err):
+		nsFound = false
+	default:
+		return err
+	}
+
+	result.Webhooks, err = listMatchingWebhooks(ctx, kubeClient.ClientSet, nsLabels)
+	if err != nil {
+		return fmt.Errorf("error listing admission webhooks :: %w", err)
+	}
+	var unavailable []string
+	for _, webhook := range result.Webhooks {
+		o.Logger.Infof("%s webhook - %s of %s matches [%s] with failure policy %s", webhook.Type, webhook.Name,
+			webhook.Configuration, strings.Join(webhook.Resources, ", "), webhook.FailurePolicy)
+		if webhook.Service == "" || webhook.FailurePolicy != string(admissionregistrationv1.Fail) {
+			continue
+		}
+		ready, rErr := serviceReadyEndpoints(ctx, kubeClient.ClientSet, webhook.Service)
+		if rErr != nil {
+			res.Warn(fmt.Sprintf("Couldn't read endpoints of service - %s of webhook - %s :: %s", webhook.Service,
+				webhook.Name, rErr.Error()))
+			continue
+		}
+		webhook.ReadyEndpoints = &ready
+		if ready == 0 {
+			webhook.Status = CheckStatusFail
+			unavailable = append(unavailable, webhook.Name)
+			o.Logger.Errorf("%s Service - %s of webhook - %s has no ready endpoints, creation of [%s] will be rejected",
+				cross, webhook.Service, webhook.Name, strings.Join(webhook.Resources, ", "))
+		}
+	}
+
+	if nsFound {
+		limitRanges, lErr := kubeClient.ClientSet.CoreV1().LimitRanges(o.Namespace).List(ctx, metav1.ListOptions{})
+		if lErr != nil {
+			return fmt.Errorf("error listing LimitRanges of namespace - %s :: %w", o.Namespace, lErr)
+		}
+		for _, pod := range webhookDryRunPods(o, resNameSuffix) {
+			mutations, mErr := dryRunPodMutations(ctx, pod, limitRanges.Items, kubeClient.RuntimeClient)
+			if mErr != nil {
+				res.Warn(fmt.Sprintf("Server-side dry-run of pod - %s failed :: %s", pod.GetName(), mErr.Error()))
+				continue
+			}
+			if len(mutations) == 0 {
+				continue
+			}
+			result.Mutations = append(result.Mutations, &PodMutationResult{Pod: pod.GetName(), Mutations: mutations})
+			res.Warn(fmt.Sprintf("Pod - %s is mutated by admission webhooks :: %s", pod.GetName(), strings.Join(mutations, "; ")))
+		}
+	} else {
+		res.Warn(fmt.Sprintf("Namespace - %s doesn't exist, mutations of preflight pods aren't verified", o.Namespace))
+	}
+
+	if len(unavailable) != 0 {
+		res.Recommend("Restore the services of the admission webhooks, or exclude the namespace of TVK using " +
+			"their namespaceSelector, as webhooks with 'failurePolicy: Fail' reject requests when they are unavailable")
+		return fmt.Errorf("admission webhooks - [%s] matching resources of TVK have no ready endpoints",
+			strings.Join(unavailable, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package preflight
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+func webhookRule(groups, resources []string, ops ...admissionregistrationv1.OperationType) admissionregistrationv1.RuleWithOperations {
+	return admissionregistrationv1.RuleWithOperations{
+		Operations: ops,
+		Rule:       admissionregistrationv1.Rule{APIGroups: groups, APIVersions: []string{"*"}, Resources: resources},
+	}
+}
+
+var _ = Describe("Admission webhooks unit tests", func() {
+
+	Context("matchedTargets func test-cases", func() {
+
+		It("Should match pods, PVCs, volume snapshots and TVK resources created in the namespace", func() {
+			matched, err := matchedTargets([]admissionregistrationv1.RuleWithOperations{
+				webhookRule([]string{""}, []string{"pods", "pods/exec"}, admissionregistrationv1.Create),
+				webhookRule([]string{StorageSnapshotGroup}, []string{"*"}, admissionregistrationv1.OperationAll),
+				webhookRule([]string{internal.TriliovaultGroup}, []string{"restores"}, admissionregistrationv1.Create),
+			}, nil, nil)
+			Expect(err).To(BeNil())
+			Expect(matched).To(Equal([]string{"pods", "volumesnapshots." + StorageSnapshotGroup,
+				"*." + internal.TriliovaultGroup}))
+		})
+
+		It("Should not match rules of other operations, subresources or cluster scope", func() {
+			clusterRule := webhookRule([]string{"*"}, []string{"*"}, admissionregistrationv1.Create)
+			clusterRule.Scope = ptr.To(admissionregistrationv1.ClusterScope)
+			matched, err := matchedTargets([]admissionregistrationv1.RuleWithOperations{
+				webhookRule([]string{""}, []string{"pods"}, admissionregistrationv1.Update),
+				webhookRule([]string{""}, []string{"pods/exec"}, admissionregistrationv1.Create),
+				clusterRule,
+			}, nil, nil)
+			Expect(err).To(BeNil())
+			Expect(matched).To(BeEmpty())
+		})
+
+		It("Should not match namespaces excluded by namespace selector", func() {
+			rules := []admissionregistrationv1.RuleWithOperations{
+				webhookRule([]string{"*"}, []string{"*"}, admissionregistrationv1.Create)}
+			selector := &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key: "policy", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"exempt"}}}}
+
+			matched, err := matchedTargets(rules, selector, map[string]string{"policy": "exempt"})
+			Expect(err).To(BeNil())
+			Expect(matched).To(BeEmpty())
+
+			matched, err = matchedTargets(rules, selector, map[string]string{})
+			Expect(err).To(BeNil())
+			Expect(matched).To(HaveLen(len(webhookTargets)))
+		})
+	})
+
+	Context("newWebhookResult func test-cases", func() {
+
+		It("Should default failure policy to Fail and record the backing service", func() {
+			result, err := newWebhookResult("gatekeeper", WebhookTypeValidating, "validation.gatekeeper.sh",
+				[]admissionregistrationv1.RuleWithOperations{
+					webhookRule([]string{""}, []string{"pods"}, admissionregistrationv1.Create)},
+				nil, nil, admissionregistrationv1.WebhookClientConfig{
+					Service: &admissionregistrationv1.ServiceReference{Namespace: "gatekeeper-system", Name: "webhook"}}, nil)
+			Expect(err).To(BeNil())
+			Expect(result.FailurePolicy).To(Equal(string(admissionregistrationv1.Fail)))
+			Expect(result.Service).To(Equal("gatekeeper-system/webhook"))
+			Expect(result.Resources).To(Equal([]string{"pods"}))
+
+			result, err = newWebhookResult("istio", WebhookTypeMutating, "sidecar-injector.istio.io",
+				[]admissionregistrationv1.RuleWithOperations{
+					webhookRule([]string{"apps"}, []string{"deployments"}, admissionregistrationv1.Create)},
+				nil, nil, admissionregistrationv1.WebhookClientConfig{}, nil)
+			Expect(err).To(BeNil())
+			Expect(result).To(BeNil())
+		})
+	})
+
+	Context("countReadyEndpoints func test-cases", func() {
+
+		It("Should count endpoints which aren't marked not ready", func() {
+			Expect(countReadyEndpoints([]discoveryv1.EndpointSlice{
+				{Endpoints: []discoveryv1.Endpoint{{}, {Conditions: discoveryv1.EndpointConditions{Ready: ptr.To(false)}}}},
+				{Endpoints: []discoveryv1.Endpoint{{Conditions: discoveryv1.EndpointConditions{Ready: ptr.To(true)}}}},
+			})).To(Equal(2))
+			Expect(countReadyEndpoints(nil)).To(BeZero())
+		})
+	})
+
+	Context("podMutations func test-cases", func() {
+
+		It("Should report changes of mutating webhooks, ignoring defaults of built-in admission plugins", func() {
+			run := runOps.copyRun()
+			submitted := createDNSPodSpec(run, "webhook-abcdef")
+			stored := submitted.DeepCopy()
+			stored.Annotations = map[string]string{"sidecar.istio.io/status": "injected"}
+			stored.Spec.InitContainers = []corev1.Container{{Name: "istio-init"}}
+			stored.Spec.Containers = append(stored.Spec.Containers, corev1.Container{Name: "istio-proxy"})
+			stored.Spec.Containers[0].Image = "mirror.example.com/dnsutils"
+			stored.Spec.Volumes = append(stored.Spec.Volumes, corev1.Volume{Name: "kube-api-access-x7k2p"},
+				corev1.Volume{Name: "istio-envoy"})
+			stored.Spec.Tolerations = append(stored.Spec.Tolerations, corev1.Toleration{Key: corev1.TaintNodeNotReady})
+
+			Expect(podMutations(submitted, stored, nil)).To(ConsistOf(
+				"added annotation sidecar.istio.io/status=injected",
+				"added init container istio-init",
+				"added container istio-proxy",
+				"changed image of container "+dnsContainerName+" from "+submitted.Spec.Containers[0].Image+
+					" to mirror.example.com/dnsutils",
+				"added volume istio-envoy",
+			))
+			Expect(podMutations(submitted, submitted.DeepCopy(), nil)).To(BeEmpty())
+		})
+
+		It("Should not report resources defaulted by LimitRanges as mutations of webhooks", func() {
+			run := runOps.copyRun()
+			run.ResourceRequirements = corev1.ResourceRequirements{}
+			submitted := createDNSPodSpec(run, "webhook-abcdef")
+			limitRanges := []corev1.LimitRange{{Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+				Type:           corev1.LimitTypeContainer,
+				Default:        corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+				DefaultRequest: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+			}}}}}
+			stored := submitted.DeepCopy()
+			stored.Annotations = map[string]string{limitRangerAnnotation: "LimitRanger plugin set: cpu request for container " +
+				dnsContainerName + "; cpu limit for container " + dnsContainerName}
+			stored.Spec.Containers[0].Resources = corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("0.1")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+			}
+			Expect(podMutations(submitted, stored, limitRanges)).To(BeEmpty())
+
+			stored.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory] = resource.MustParse("64Mi")
+			Expect(podMutations(submitted, stored, limitRanges)).To(ConsistOf(
+				"changed resources of container " + dnsContainerName))
+		})
+	})
+})
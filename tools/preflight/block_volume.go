@@ -0,0 +1,77 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+const (
+	// BlockDevicePath is the path at which the raw block volume is attached to the pods of block volume snapshot check.
+	BlockDevicePath = "/dev/preflight-block"
+
+	blockNamePrefix  = "block-"
+	blockPatternSize = 1024 * 1024
+	blockPatternFile = "/tmp/preflight-pattern"
+)
+
+var (
+	// blockPatternCommand prints the known pattern written to the block device, the sample data repeated until its size.
+	blockPatternCommand = fmt.Sprintf("yes '%s' | head -c %d", VolSnapPodFileData, blockPatternSize)
+
+	// blockWriterArgs writes the pattern at the start of the block device and flushes it, before sleeping.
+	blockWriterArgs = []string{
+		fmt.Sprintf("%s > %s && dd if=%s of=%s bs=%d count=1 conv=fsync && sleep 3000",
+			blockPatternCommand, blockPatternFile, blockPatternFile, BlockDevicePath, blockPatternSize),
+	}
+
+	// blockDataCheckCommand verifies the block device starts with the pattern by comparing their SHA-256 checksums.
+	blockDataCheckCommand = []string{"/bin/sh", "-c",
+		fmt.Sprintf("dev=$(dd if=%s bs=%d count=1 2>/dev/null | sha256sum | cut -d' ' -f1); "+
+			"want=$(%s | sha256sum | cut -d' ' -f1); echo \"${dev}\"; [ \"${dev}\" = \"${want}\" ]",
+			BlockDevicePath, blockPatternSize, blockPatternCommand)}
+)
+
+// blockVolumeRun returns a copy of run options with which volume snapshot and restore are performed for raw block
+// volumes. Resources created for block volumes have names different from the ones of filesystem volumes.
+func (o *Run) blockVolumeRun() *Run {
+	r := o.copyRun()
+	r.blockMode = true
+	// data of integrity check is written to files, the known pattern is verified on block volumes instead
+	r.DataSize = resource.Quantity{}
+	return r
+}
+
+// dataCheckCommand returns the command verifying the data written by the writer pod.
+func (o *Run) dataCheckCommand() []string {
+	if o.blockMode {
+		return blockDataCheckCommand
+	}
+	return execDataCheckCommand
+}
+
+func runBlockVolumeSnapshotCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	o.Logger.Infoln("Checking if volume snapshot and restore is enabled for raw block volumes in cluster")
+
+	err := o.forEachStorageClass(func(r *Run) error {
+		if r.scResult != nil && r.scResult.SnapshotClassFound == CheckStatusFail {
+			return fmt.Errorf("volume snapshot class not found for storage class - %s", r.StorageClass)
+		}
+		blockRun := r.blockVolumeRun()
+		if r.Scope == internal.ClusterScope {
+			return blockRun.validateClusterScopeVolumeSnapshot(ctx, resNameSuffix, kubeClient)
+		}
+
+		return blockRun.validateNamespaceScopeVolumeSnapshot(ctx, resNameSuffix, kubeClient)
+	})
+	if err != nil {
+		res.Recommend("Verify the CSI driver of storage class supports raw block volumes and their snapshots. " +
+			"Workloads using 'volumeMode: Block' can't be backed up with snapshots otherwise")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package preflight
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Block volume unit tests", func() {
+
+	var (
+		run      *Run
+		pvcName  = types.NamespacedName{Name: "source-pvc-block-abcdef", Namespace: "ns"}
+		scResult *StorageClassResult
+	)
+
+	BeforeEach(func() {
+		scResult = &StorageClassResult{StorageClass: "csi-sc", DataVerified: CheckStatusPass, nameSuffix: "abcdef-1"}
+		run = runOps.copyRun()
+		run.StorageClass = "csi-sc"
+		run.DataSize = resource.MustParse("1Mi")
+		run.scResult = scResult
+		run = run.blockVolumeRun()
+	})
+
+	Context("blockVolumeRun func test-cases", func() {
+
+		It("Should use block prefixed names and not write data of integrity check", func() {
+			Expect(run.resourceNameSuffix("abcdef")).To(Equal("block-abcdef-1"))
+			Expect(run.dataIntegrityEnabled()).To(BeFalse())
+			Expect(run.dataWriterArgs()).To(Equal(blockWriterArgs))
+		})
+	})
+
+	Context("Block volume pvc and pod spec test-cases", func() {
+
+		It("Should create pvc of block volume mode, which is kept by the restored pvc", func() {
+			pvc := createVolumeSnapshotPVCSpec(run, pvcName, "abcdef")
+			Expect(*pvc.Spec.VolumeMode).To(Equal(corev1.PersistentVolumeBlock))
+
+			restored := createPVCFromSnapshotSpec(&metav1.ObjectMeta{Name: "backup-pvc-block-abcdef", Namespace: "ns"},
+				&pvc.Spec, "snapshot-source-pvc-block-abcdef")
+			Expect(*restored.Spec.VolumeMode).To(Equal(corev1.PersistentVolumeBlock))
+		})
+
+		It("Should not set volume mode of filesystem pvc", func() {
+			run.blockMode = false
+			Expect(createVolumeSnapshotPVCSpec(run, pvcName, "abcdef").Spec.VolumeMode).To(BeNil())
+		})
+
+		It("Should attach the block device to writer and reader pods, and verify the pattern written to it", func() {
+			writer := createPVCDataWriterPodSpec("source-pvc-writer-block-abcdef", pvcName, run, "abcdef")
+			container := writer.Spec.Containers[0]
+			Expect(container.VolumeMounts).To(BeEmpty())
+			Expect(container.VolumeDevices).To(Equal([]corev1.VolumeDevice{{Name: VolMountName, DevicePath: BlockDevicePath}}))
+			Expect(container.Args[0]).To(ContainSubstring("dd if=" + blockPatternFile + " of=" + BlockDevicePath))
+			Expect(container.ReadinessProbe.Exec.Command).To(Equal(blockDataCheckCommand))
+
+			reader := createPVCDataReaderPodSpec("backup-pvc-reader-block-abcdef", pvcName, run, "abcdef")
+			Expect(reader.Spec.Containers[0].VolumeDevices).To(HaveLen(1))
+			Expect(run.dataCheckCommand()).To(Equal(blockDataCheckCommand))
+		})
+	})
+
+	Context("markStorageStage func test-cases", func() {
+
+		It("Should record outcome of block volume without changing outcome of filesystem volume", func() {
+			run.markStorageStage(stageMountedSnapshot, nil)
+			Expect(scResult.BlockVolume).To(BeEmpty())
+			run.markStorageStage(stageDataVerified, nil)
+			Expect(scResult.BlockVolume).To(Equal(CheckStatusPass))
+
+			run.markStorageStage(stageRestore, errors.New("restore failed"))
+			Expect(scResult.BlockVolume).To(Equal(CheckStatusFail))
+			Expect(scResult.Restore).To(BeEmpty())
+			Expect(scResult.DataVerified).To(Equal(CheckStatusPass))
+		})
+	})
+})
@@ -0,0 +1,892 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/trilioData/tvk-plugins/internal"
+	"github.com/trilioData/tvk-plugins/tools/preflight/wait"
+)
+
+// CheckStatus is the outcome of a single preflight check.
+type CheckStatus string
+
+const (
+	CheckStatusPass    CheckStatus = "pass"
+	CheckStatusFail    CheckStatus = "fail"
+	CheckStatusWarn    CheckStatus = "warn"
+	CheckStatusSkipped CheckStatus = "skipped"
+)
+
+// Names of the preflight checks which can be selected using --checks and --skip-checks.
+const (
+	CheckKubectl              = "check-kubectl"
+	CheckClusterAccess        = "check-cluster-access"
+	CheckHelmVersion          = "check-helm-version"
+	CheckKubernetesVersion    = "check-kubernetes-version"
+	CheckKubernetesRBAC       = "check-kubernetes-rbac"
+	CheckRBACPermissions      = "check-rbac-permissions"
+	CheckCSI                  = "check-csi"
+	CheckStorageSnapshotClass = "check-storage-snapshot-class"
+	CheckNodeInventory        = "check-node-inventory"
+	CheckPodCapability        = "check-pod-capability"
+	CheckPodSecurity          = "check-pod-security"
+	CheckOpenShiftSCC         = "check-openshift-scc"
+	CheckAdmissionWebhooks    = "check-admission-webhooks"
+	CheckResourceQuota        = "check-resource-quota"
+	CheckDNSResolution        = "check-dns-resolution"
+	CheckNetworkPolicy        = "check-network-policy"
+	CheckNamespacePermissions = "check-namespace-permissions"
+	CheckVolumeSnapshot       = "check-volume-snapshot"
+	CheckBlockVolumeSnapshot  = "check-block-volume-snapshot"
+	CheckTopology             = "check-topology"
+	CheckStoragePerformance   = "check-storage-performance"
+	CheckTarget               = "check-target"
+	CheckAccessModes          = "check-access-modes"
+	CheckImageAvailability    = "check-image-availability"
+)
+
+// CheckResult holds the outcome of a preflight check.
+type CheckResult struct {
+	Status          CheckStatus
+	Err             error
+	Message         string
+	Warnings        []string
+	Recommendations []string
+	Resources       []ResourceRef
+	StartTime       time.Time
+	Duration        time.Duration
+	// TimedOut is set when the check was interrupted by its timeout or the timeout of preflight run.
+	TimedOut bool
+
+	// dependencyFailed is set when the check was skipped because one of its dependencies failed.
+	dependencyFailed bool
+	// mu guards the resources recorded by concurrently performed parts of the check.
+	mu sync.Mutex
+}
+
+// Skip marks the check as skipped with the given reason.
+func (r *CheckResult) Skip(reason string) {
+	r.Status = CheckStatusSkipped
+	r.Message = reason
+}
+
+// Warn records a warning. A check which passes with warnings has status 'warn'.
+func (r *CheckResult) Warn(warning string) {
+	r.Warnings = append(r.Warnings, warning)
+}
+
+// Recommend records an action recommended to the user if the check fails.
+func (r *CheckResult) Recommend(recommendations ...string) {
+	r.Recommendations = append(r.Recommendations, recommendations...)
+}
+
+// addResource records a resource created on the cluster while performing the check.
+func (r *CheckResult) addResource(obj client.Object) {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Empty() {
+		gvk = GetObjGVKFromStructuredType(obj)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Resources = append(r.Resources, ResourceRef{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	})
+}
+
+// DefaultParallelism is the number of preflight checks performed concurrently by default.
+const DefaultParallelism = 4
+
+// CheckFunc performs a preflight check. A non-nil error marks the check as failed.
+type CheckFunc func(ctx context.Context, o *Run, res *CheckResult) error
+
+// Check is a single named preflight check.
+type Check struct {
+	Name        string
+	Description string
+	DependsOn   []string
+	// Optional checks are performed only if selected explicitly, they aren't part of the default selection.
+	Optional bool
+	Run      CheckFunc
+	Result   *CheckResult
+}
+
+// CheckRegistry holds preflight checks in their order of execution.
+type CheckRegistry struct {
+	checks []*Check
+	index  map[string]*Check
+}
+
+// NewCheckRegistry returns a registry with the given checks registered in order.
+func NewCheckRegistry(checks ...*Check) (*CheckRegistry, error) {
+	r := &CheckRegistry{index: make(map[string]*Check)}
+	for _, c := range checks {
+		if err := r.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Register adds a check to the registry.
+// Dependencies of a check must be registered before the check itself.
+func (r *CheckRegistry) Register(c *Check) error {
+	if c.Name == "" {
+		return fmt.Errorf("preflight check name cannot be empty")
+	}
+	if c.Run == nil {
+		return fmt.Errorf("preflight check - %s has no run function", c.Name)
+	}
+	if _, ok := r.index[c.Name]; ok {
+		return fmt.Errorf("preflight check - %s is already registered", c.Name)
+	}
+	for _, dep := range c.DependsOn {
+		if _, ok := r.index[dep]; !ok {
+			return fmt.Errorf("dependency - %s of preflight check - %s is not registered", dep, c.Name)
+		}
+	}
+	r.checks = append(r.checks, c)
+	r.index[c.Name] = c
+
+	return nil
+}
+
+// Get returns the check registered with the given name.
+func (r *CheckRegistry) Get(name string) (*Check, bool) {
+	c, ok := r.index[name]
+	return c, ok
+}
+
+// Checks returns all registered checks in their order of execution.
+func (r *CheckRegistry) Checks() []*Check {
+	return r.checks
+}
+
+// Names returns names of all registered checks in their order of execution.
+func (r *CheckRegistry) Names() []string {
+	names := make([]string, 0, len(r.checks))
+	for _, c := range r.checks {
+		names = append(names, c.Name)
+	}
+
+	return names
+}
+
+// Select returns the checks to be performed in their order of execution.
+// If include is empty, all checks except the optional ones are selected, otherwise only the included checks and
+// their dependencies.
+// Checks present in exclude are removed from the selection.
+func (r *CheckRegistry) Select(include, exclude []string) ([]*Check, error) {
+	if err := r.validateNames(append(append([]string{}, include...), exclude...)); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]bool)
+	if len(include) == 0 {
+		for _, c := range r.checks {
+			if !c.Optional {
+				selected[c.Name] = true
+			}
+		}
+	} else {
+		for _, name := range include {
+			r.selectWithDependencies(name, selected)
+		}
+	}
+	for _, name := range exclude {
+		delete(selected, name)
+	}
+
+	var checks []*Check
+	for _, c := range r.checks {
+		if selected[c.Name] {
+			checks = append(checks, c)
+		}
+	}
+
+	return checks, nil
+}
+
+// validateNames returns error if any of the given names is not of a registered check.
+func (r *CheckRegistry) validateNames(names []string) error {
+	var unknown []string
+	for _, name := range names {
+		if _, ok := r.index[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) != 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown preflight check(s) - %s. Available checks are - %s",
+			strings.Join(unknown, ", "), strings.Join(r.Names(), ", "))
+	}
+
+	return nil
+}
+
+func (r *CheckRegistry) selectWithDependencies(name string, selected map[string]bool) {
+	if selected[name] {
+		return
+	}
+	selected[name] = true
+	for _, dep := range r.index[name].DependsOn {
+		r.selectWithDependencies(dep, selected)
+	}
+}
+
+// defaultChecks returns all preflight checks in their order of execution.
+func (o *Run) defaultChecks() []*Check {
+	return []*Check{
+		{
+			Name:        CheckKubectl,
+			Description: "kubectl utility",
+			Run:         runKubectlCheck,
+		},
+		{
+			Name:        CheckClusterAccess,
+			Description: "kubectl access",
+			Run:         runClusterAccessCheck,
+		},
+		{
+			Name:        CheckHelmVersion,
+			Description: "helm version",
+			Run:         runHelmVersionCheck,
+		},
+		{
+			Name:        CheckKubernetesVersion,
+			Description: "kubernetes version",
+			Run:         runKubernetesVersionCheck,
+		},
+		{
+			Name:        CheckKubernetesRBAC,
+			Description: "kubernetes RBAC",
+			Run:         runKubernetesRBACCheck,
+		},
+		{
+			Name:        CheckRBACPermissions,
+			Description: "TVK RBAC permissions",
+			DependsOn:   []string{CheckKubernetesRBAC},
+			Run:         runRBACPermissionsCheck,
+		},
+		{
+			Name:        CheckCSI,
+			Description: "VolumeSnapshot CRDs",
+			Run:         runCSICheck,
+		},
+		{
+			Name:        CheckStorageSnapshotClass,
+			Description: "SnapshotClass",
+			DependsOn:   []string{CheckCSI},
+			Run:         runStorageSnapshotClassCheck,
+		},
+		{
+			Name:        CheckNodeInventory,
+			Description: "node inventory",
+			Run:         runNodeInventoryCheck,
+		},
+		{
+			Name:        CheckPodCapability,
+			Description: "pod capability",
+			DependsOn:   []string{CheckNodeInventory},
+			Run:         runPodCapabilityCheck,
+		},
+		{
+			Name:        CheckPodSecurity,
+			Description: "pod security admission",
+			Run:         runPodSecurityCheck,
+		},
+		{
+			Name:        CheckOpenShiftSCC,
+			Description: "OpenShift SecurityContextConstraints",
+			Run:         runOpenShiftSCCCheck,
+		},
+		{
+			Name:        CheckAdmissionWebhooks,
+			Description: "admission webhooks",
+			Run:         runAdmissionWebhooksCheck,
+		},
+		{
+			Name:        CheckResourceQuota,
+			Description: "resource quota",
+			Run:         runResourceQuotaCheck,
+		},
+		{
+			Name:        CheckDNSResolution,
+			Description: "DNS resolution",
+			DependsOn:   []string{CheckNodeInventory},
+			Run:         runDNSResolutionCheck,
+		},
+		{
+			Name:        CheckNetworkPolicy,
+			Description: "network policies",
+			DependsOn:   []string{CheckNodeInventory},
+			Run:         runNetworkPolicyCheck,
+		},
+		{
+			Name:        CheckNamespacePermissions,
+			Description: "namespace permissions",
+			Run:         runNamespacePermissionsCheck,
+		},
+		{
+			Name:        CheckVolumeSnapshot,
+			Description: fmt.Sprintf("%s scope volume snapshot and restore", o.Scope),
+			DependsOn:   []string{CheckStorageSnapshotClass, CheckNodeInventory},
+			Run:         runVolumeSnapshotCheck,
+		},
+		{
+			Name:        CheckBlockVolumeSnapshot,
+			Description: fmt.Sprintf("%s scope raw block volume snapshot and restore", o.Scope),
+			DependsOn:   []string{CheckStorageSnapshotClass, CheckNodeInventory},
+			Optional:    true,
+			Run:         runBlockVolumeSnapshotCheck,
+		},
+		{
+			Name:        CheckTopology,
+			Description: "volume snapshot restore across nodes and zones",
+			DependsOn:   []string{CheckStorageSnapshotClass, CheckNodeInventory},
+			Optional:    true,
+			Run:         runTopologyCheck,
+		},
+		{
+			Name:        CheckStoragePerformance,
+			Description: "storage performance",
+			DependsOn:   []string{CheckStorageSnapshotClass, CheckNodeInventory},
+			Optional:    true,
+			Run:         runStoragePerformanceCheck,
+		},
+		{
+			Name:        CheckAccessModes,
+			Description: "storage class access modes",
+			DependsOn:   []string{CheckNodeInventory},
+			Optional:    true,
+			Run:         runAccessModesCheck,
+		},
+		{
+			Name:        CheckTarget,
+			Description: "backup target connectivity",
+			DependsOn:   []string{CheckNodeInventory},
+			Optional:    true,
+			Run:         runTargetCheck,
+		},
+		{
+			Name:        CheckImageAvailability,
+			Description: "image availability",
+			DependsOn:   []string{CheckNodeInventory},
+			Optional:    true,
+			Run:         runImageAvailabilityCheck,
+		},
+	}
+}
+
+// SelectChecks returns the preflight checks selected through run options in their order of execution.
+// It returns error if run options refer to a check which doesn't exist.
+func (o *Run) SelectChecks() ([]*Check, error) {
+	registry, err := NewCheckRegistry(o.defaultChecks()...)
+	if err != nil {
+		return nil, err
+	}
+
+	var timeoutChecks []string
+	for name := range o.CheckTimeouts {
+		timeoutChecks = append(timeoutChecks, name)
+	}
+	if err = registry.validateNames(timeoutChecks); err != nil {
+		return nil, fmt.Errorf("invalid check timeouts :: %s", err.Error())
+	}
+
+	// optional checks are performed along with the default checks if their options are configured
+	include := o.Checks
+	if optional := o.configuredOptionalChecks(); len(include) == 0 && len(optional) != 0 {
+		selected, sErr := registry.Select(nil, nil)
+		if sErr != nil {
+			return nil, sErr
+		}
+		for _, c := range selected {
+			include = append(include, c.Name)
+		}
+		include = append(include, optional...)
+	}
+
+	return registry.Select(include, o.SkipChecks)
+}
+
+// configuredOptionalChecks returns the optional checks whose options are configured through run options.
+func (o *Run) configuredOptionalChecks() []string {
+	var optional []string
+	if o.BlockVolume {
+		optional = append(optional, CheckBlockVolumeSnapshot)
+	}
+	if o.Topology {
+		optional = append(optional, CheckTopology)
+	}
+	if o.StoragePerformance != nil {
+		optional = append(optional, CheckStoragePerformance)
+	}
+	if len(o.AccessModes) != 0 {
+		optional = append(optional, CheckAccessModes)
+	}
+	if o.Target != nil {
+		optional = append(optional, CheckTarget)
+	}
+	if o.ImageAvailability != nil {
+		optional = append(optional, CheckImageAvailability)
+	}
+	return optional
+}
+
+// runChecks performs the given checks and returns true if none of them failed. Checks are started in the given order
+// as soon as the checks they depend on are complete, with at most 'parallelism' checks performed concurrently.
+// A check is skipped if any of its dependencies failed or was skipped due to a failed dependency.
+func (o *Run) runChecks(ctx context.Context, checks []*Check) bool {
+	var (
+		allPassed   = true
+		parallelism = o.parallelism()
+		selected    = make(map[string]*Check, len(checks))
+		completed   = make(map[string]bool, len(checks))
+		doneCh      = make(chan *checkRun)
+		running     int
+		pending     = checks
+	)
+	for _, c := range checks {
+		selected[c.Name] = c
+	}
+	if parallelism > 1 {
+		o.Logger.Infof("Performing preflight checks with parallelism - %d", parallelism)
+	}
+
+	for len(pending) != 0 || running != 0 {
+		var waiting []*Check
+		for _, c := range pending {
+			if !dependenciesCompleted(c, selected, completed) {
+				waiting = append(waiting, c)
+				continue
+			}
+			if ctx.Err() != nil {
+				c.Result = &CheckResult{StartTime: time.Now()}
+				c.Result.Skip(fmt.Sprintf("preflight run interrupted :: %s", ctx.Err().Error()))
+				o.Logger.Errorf("Skipping %s check as preflight run is interrupted :: %s", c.Description, ctx.Err().Error())
+				completed[c.Name] = true
+				allPassed = false
+				continue
+			}
+			if failedDep := failedDependency(c, selected); failedDep != nil {
+				c.Result = &CheckResult{StartTime: time.Now()}
+				c.Result.Skip(fmt.Sprintf("preflight check for %s failed", failedDep.Description))
+				c.Result.dependencyFailed = true
+				o.Logger.Errorf("Skipping %s check as preflight check for %s failed", c.Description, failedDep.Description)
+				completed[c.Name] = true
+				continue
+			}
+			if running >= parallelism {
+				waiting = append(waiting, c)
+				continue
+			}
+			running++
+			c.Result = &CheckResult{StartTime: time.Now()}
+			cr := o.newCheckRun(c, parallelism > 1)
+			go func() {
+				cr.performCheck(ctx, c)
+				c.Result.Duration = time.Since(c.Result.StartTime)
+				doneCh <- cr
+			}()
+		}
+		pending = waiting
+		// checks are ordered after their dependencies, so there is a running check whenever a check is pending
+		if running == 0 {
+			break
+		}
+
+		cr := <-doneCh
+		running--
+		completed[cr.check.Name] = true
+		o.completeCheckRun(cr)
+		if cr.check.Result.Status == CheckStatusFail {
+			allPassed = false
+		}
+	}
+
+	return allPassed
+}
+
+// parallelism returns the number of checks which can be performed concurrently.
+func (o *Run) parallelism() int {
+	if o.Parallelism <= 0 {
+		return DefaultParallelism
+	}
+	return o.Parallelism
+}
+
+// checkRun holds the options with which a check is performed and its buffered logs, if any.
+type checkRun struct {
+	*Run
+	check *Check
+	logs  *bytes.Buffer
+}
+
+// newCheckRun returns a copy of run options to perform the given check. When checks are performed concurrently,
+// logs of the check are buffered and displayed together once the check is complete, to keep them readable.
+func (o *Run) newCheckRun(c *Check, buffered bool) *checkRun {
+	cr := &checkRun{Run: o.copyRun(), check: c}
+	cr.checkResult = c.Result
+	if buffered {
+		cr.logs = &bytes.Buffer{}
+		cr.Logger = newBufferedLogger(o.Logger, cr.logs)
+	}
+
+	return cr
+}
+
+// completeCheckRun displays the buffered logs of a performed check and propagates the run options it updated.
+func (o *Run) completeCheckRun(cr *checkRun) {
+	if cr.logs != nil {
+		_, _ = o.Logger.Out.Write(cr.logs.Bytes())
+	}
+	// the user provided snapshot class is discarded when VolumeSnapshotClass CRD is created by 'check-csi',
+	// checks depending on it must observe the same. Other checks hold copies taken before 'check-csi' completed,
+	// so the snapshot class is propagated from 'check-csi' only.
+	if cr.check.Name == CheckCSI {
+		o.SnapshotClass = cr.SnapshotClass
+	}
+}
+
+// copyRun returns a shallow copy of the run options.
+func (o *Run) copyRun() *Run {
+	r := *o
+	return &r
+}
+
+// performConcurrently calls fn for each index from 0 to count-1 with a copy of run options, with at most
+// 'parallelism' calls in progress at a time. When calls are made concurrently, logs of each call are buffered
+// and displayed in order of index once all the calls are complete. It returns the errors returned by each call.
+func (o *Run) performConcurrently(count int, fn func(index int, r *Run) error) []error {
+	errs := make([]error, count)
+	if o.parallelism() == 1 || count == 1 {
+		for index := 0; index < count; index++ {
+			errs[index] = fn(index, o)
+		}
+		return errs
+	}
+
+	var (
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, o.parallelism())
+		logBufs = make([]*bytes.Buffer, count)
+	)
+	for index := 0; index < count; index++ {
+		logBufs[index] = &bytes.Buffer{}
+		r := o.copyRun()
+		r.Logger = newBufferedLogger(o.Logger, logBufs[index])
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			errs[index] = fn(index, r)
+		}(index)
+	}
+	wg.Wait()
+
+	for index := 0; index < count; index++ {
+		_, _ = o.Logger.Out.Write(logBufs[index].Bytes())
+	}
+	return errs
+}
+
+// newBufferedLogger returns a logger with the same configuration as the given logger, writing to the given buffer.
+func newBufferedLogger(l *logrus.Logger, buf *bytes.Buffer) *logrus.Logger {
+	return &logrus.Logger{
+		Out:          buf,
+		Hooks:        l.Hooks,
+		Formatter:    l.Formatter,
+		ReportCaller: l.ReportCaller,
+		Level:        l.GetLevel(),
+		ExitFunc:     l.ExitFunc,
+	}
+}
+
+// dependenciesCompleted returns true if all the selected dependencies of the check are complete.
+func dependenciesCompleted(c *Check, selected map[string]*Check, completed map[string]bool) bool {
+	for _, name := range c.DependsOn {
+		if _, ok := selected[name]; ok && !completed[name] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// performCheck runs a check within its timeout, if any, and records its status on the check's result.
+func (o *Run) performCheck(ctx context.Context, c *Check) {
+	o.Logger.Debugf("Performing preflight check - %s", c.Name)
+	res := c.Result
+	checkCtx := ctx
+	if timeout := o.CheckTimeouts[c.Name].Duration; timeout > 0 {
+		var cancel context.CancelFunc
+		checkCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err := c.Run(checkCtx, o, res)
+	if err != nil {
+		if tErr := o.checkTimeoutError(ctx, checkCtx, c.Name, res); tErr != nil {
+			err = fmt.Errorf("%s :: %w", tErr.Error(), err)
+		}
+		var podErr *wait.PodFailedError
+		if errors.As(err, &podErr) {
+			res.Recommend(podErr.Recommendation())
+		}
+	}
+	switch {
+	case err != nil:
+		res.Status = CheckStatusFail
+		res.Err = err
+		o.Logger.Errorf("%s Preflight check for %s failed :: %s\n", cross, c.Description, err.Error())
+		if len(res.Recommendations) != 0 {
+			o.Logger.Errorln("\nRecommendations:")
+			for idx, recommendation := range res.Recommendations {
+				o.Logger.Errorf("%d. %s", idx+1, recommendation)
+			}
+		}
+	case res.Status == CheckStatusSkipped:
+		o.Logger.Infof("Skipped preflight check for %s :: %s", c.Description, res.Message)
+	case len(res.Warnings) != 0:
+		res.Status = CheckStatusWarn
+		o.Logger.Infof("%s Preflight check for %s is successful\n", check, c.Description)
+	default:
+		res.Status = CheckStatusPass
+		o.Logger.Infof("%s Preflight check for %s is successful\n", check, c.Description)
+	}
+}
+
+// checkTimeoutError returns the exceeded timeout if the check was interrupted by its own timeout or
+// by the timeout of preflight run, and recommends increasing the same. It returns nil otherwise.
+func (o *Run) checkTimeoutError(runCtx, checkCtx context.Context, name string, res *CheckResult) error {
+	switch {
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		res.TimedOut = true
+		res.Recommend("Increase the timeout of preflight run using --timeout flag or 'timeout' in run section of config file")
+		return fmt.Errorf("preflight run exceeded its timeout of %s", o.Timeout.Duration)
+
+	case errors.Is(checkCtx.Err(), context.DeadlineExceeded):
+		res.TimedOut = true
+		res.Recommend(fmt.Sprintf("Increase the timeout of %s using 'checkTimeouts' in run section of config file", name))
+		return fmt.Errorf("preflight check %s exceeded its timeout of %s", name, o.CheckTimeouts[name].Duration)
+	}
+
+	return nil
+}
+
+// failedDependency returns the first performed dependency of the check which has failed.
+// Dependencies which are not selected for the run are considered satisfied.
+func failedDependency(c *Check, selected map[string]*Check) *Check {
+	for _, name := range c.DependsOn {
+		dep, ok := selected[name]
+		if !ok || dep.Result == nil {
+			continue
+		}
+		if dep.Result.Status == CheckStatusFail || dep.Result.dependencyFailed {
+			return dep
+		}
+	}
+
+	return nil
+}
+
+// recordCreatedResource records a resource created on the cluster in the result of the check being performed.
+func (o *Run) recordCreatedResource(obj client.Object) {
+	if o.checkResult != nil {
+		o.checkResult.addResource(obj)
+	}
+}
+
+// logCheckWarnings displays warnings recorded by the performed checks.
+func (o *Run) logCheckWarnings(checks []*Check) {
+	for _, c := range checks {
+		if c.Result == nil {
+			continue
+		}
+		for _, warning := range c.Result.Warnings {
+			o.Logger.Warnln("========================================")
+			o.Logger.Warnf("⚠ WARNING: %s", warning)
+			o.Logger.Warnln("========================================")
+		}
+	}
+}
+
+func runKubectlCheck(_ context.Context, o *Run, res *CheckResult) error {
+	if o.InCluster {
+		o.Logger.Infoln("In cluster flag enabled. Skipping check for kubectl...")
+		res.Skip("in cluster flag enabled")
+		return nil
+	}
+	o.Logger.Infoln("Checking for kubectl")
+
+	return o.validateKubectl(kubectlBinaryName)
+}
+
+func runClusterAccessCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	o.Logger.Infoln("Checking access to the default namespace of cluster")
+	err := o.validateClusterAccess(ctx, internal.DefaultNs, kubeClient.ClientSet)
+	if err != nil {
+		res.Recommend("Provide service account OR kubeconfig file user with privilege to access namespace resource")
+		return err
+	}
+
+	return nil
+}
+
+func runHelmVersionCheck(_ context.Context, o *Run, res *CheckResult) error {
+	if o.InCluster {
+		o.Logger.Infoln("In cluster flag enabled. Skipping check for helm...")
+		res.Skip("in cluster flag enabled")
+		return nil
+	}
+	o.Logger.Infof("Checking for required Helm version (>= %s)\n", minHelmVersion)
+
+	return o.validateSystemHelmVersion(HelmBinaryName, kubeClient.DiscClient)
+}
+
+func runKubernetesVersionCheck(_ context.Context, o *Run, res *CheckResult) error {
+	o.Logger.Infof("Checking for required kubernetes server version (>=%s)\n", minK8sVersion)
+	k8sVersionWarning, err := o.validateKubernetesVersion(minK8sVersion, kubeClient.ClientSet)
+	if err != nil {
+		return err
+	}
+	if k8sVersionWarning != "" {
+		res.Warn(k8sVersionWarning)
+	}
+
+	return nil
+}
+
+func runKubernetesRBACCheck(_ context.Context, o *Run, _ *CheckResult) error {
+	o.Logger.Infoln("Checking Kubernetes RBAC")
+
+	return o.validateKubernetesRBAC(RBACAPIGroup, RBACAPIVersion, kubeClient.DiscClient)
+}
+
+func runRBACPermissionsCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	if o.InCluster && o.ServiceAccountName == "" {
+		// the current user is the service account of preflight pod, e.g of preflight job, which TVK doesn't run as
+		o.Logger.Infoln("In cluster flag enabled and service account not given. Skipping check for permissions needed by TVK...")
+		res.Skip("in cluster flag enabled, give the service account of TVK using --service-account to evaluate its permissions")
+		return nil
+	}
+	subject := o.accessReviewSubject()
+	o.Logger.Infof("Checking permissions needed by TVK for %s\n", subject)
+	missing, err := o.validateTVKPermissions(ctx, o.newAccessReviewer(accessReviewClientSet(kubeClient)))
+	if err != nil {
+		return err
+	}
+	if len(missing) != 0 {
+		o.logMissingPermissions(missing)
+		res.Recommend(fmt.Sprintf("Grant the missing permissions to %s before installing TVK", subject))
+		return missingPermissionsError(subject, missing)
+	}
+	o.Logger.Infof("%s All the permissions needed by TVK are granted to %s\n", check, subject)
+
+	return nil
+}
+
+func runCSICheck(ctx context.Context, o *Run, res *CheckResult) error {
+	o.Logger.Infoln("Checking if VolumeSnapshot CRDs are installed in the cluster or else create")
+	serverVersion, err := kubeClient.DiscClient.ServerVersion()
+	if err != nil {
+		return fmt.Errorf("error getting server version: %s", err.Error())
+	}
+	err = o.checkAndCreateVolumeSnapshotCRDs(ctx, serverVersion.String(), kubeClient.RuntimeClient)
+	if err != nil {
+		res.Recommend("Create VolumeSnapshotClass, VolumeSnapshotContent, VolumeSnapshot CRDs")
+		return err
+	}
+
+	return nil
+}
+
+func runStorageSnapshotClassCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	o.Logger.Infoln("Checking if a StorageClass and VolumeSnapshotClass are present")
+	prefVersion, err := GetServerPreferredVersionForGroup(StorageSnapshotGroup, kubeClient.ClientSet)
+	if err != nil {
+		return fmt.Errorf("error getting preferred version for group - %s :: %s", StorageSnapshotGroup, err.Error())
+	}
+
+	err = o.forEachStorageClass(func(r *Run) error {
+		scErr := r.validateStorageClassSnapshotClass(ctx, prefVersion)
+		r.markStorageStage(stageSnapshotClassFound, scErr)
+		return scErr
+	})
+	if err != nil {
+		res.Recommend("Verify CSI driver supports snapshots",
+			"Check snapshot controller logs",
+			"Check CSI driver documentation for snapshot requirements")
+		// volume snapshot and restore is checked for the storage classes having a snapshot class,
+		// it reports the storage classes without one.
+		if o.storageMatrix().anySnapshotClassFound() {
+			res.Warn(err.Error())
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// validateStorageClassSnapshotClass validates that the storage class exists and has a volume snapshot class.
+func (o *Run) validateStorageClassSnapshotClass(ctx context.Context, prefVersion string) error {
+	sc, err := kubeClient.ClientSet.StorageV1().StorageClasses().Get(ctx, o.StorageClass, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return fmt.Errorf("not found storageclass - %s on cluster", o.StorageClass)
+		}
+		return err
+	}
+	o.warnIfLegacyNonSnapshotDriver(sc.Provisioner)
+
+	return o.validateStorageSnapshotClass(ctx, sc.Provisioner, prefVersion, kubeClient.ClientSet, kubeClient.RuntimeClient)
+}
+
+func runPodCapabilityCheck(ctx context.Context, o *Run, _ *CheckResult) error {
+	return o.validateRequiredPodCapabilities(ctx, resNameSuffix, kubeClient)
+}
+
+func runDNSResolutionCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	o.Logger.Infoln("Checking if DNS resolution is working in k8s cluster")
+
+	return o.validateDNSResolution(ctx, res, kubeClient)
+}
+
+func runNamespacePermissionsCheck(ctx context.Context, o *Run, _ *CheckResult) error {
+	o.Logger.Infoln("Checking create and delete namespace permissions")
+
+	return o.validateNamespacePermissions(ctx, kubeClient.ClientSet)
+}
+
+func runVolumeSnapshotCheck(ctx context.Context, o *Run, _ *CheckResult) error {
+	o.Logger.Infoln("Checking if volume snapshot and restore is enabled in cluster")
+
+	return o.forEachStorageClass(func(r *Run) error {
+		if r.scResult != nil && r.scResult.SnapshotClassFound == CheckStatusFail {
+			return fmt.Errorf("volume snapshot class not found for storage class - %s", r.StorageClass)
+		}
+		if r.Scope == internal.ClusterScope {
+			return r.validateClusterScopeVolumeSnapshot(ctx, resNameSuffix, kubeClient)
+		}
+
+		return r.validateNamespaceScopeVolumeSnapshot(ctx, resNameSuffix, kubeClient)
+	})
+}
@@ -0,0 +1,261 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Preflight check registry unit tests", func() {
+
+	var noopCheck CheckFunc = func(_ context.Context, _ *Run, _ *CheckResult) error { return nil }
+
+	getCheckNames := func(checks []*Check) []string {
+		var names []string
+		for _, c := range checks {
+			names = append(names, c.Name)
+		}
+		return names
+	}
+
+	Context("Registering preflight checks", func() {
+
+		It("Should register all default checks in order of execution", func() {
+			registry, err := NewCheckRegistry(runOps.defaultChecks()...)
+			Expect(err).To(BeNil())
+			Expect(registry.Names()).To(Equal([]string{CheckKubectl, CheckClusterAccess, CheckHelmVersion,
+				CheckKubernetesVersion, CheckKubernetesRBAC, CheckRBACPermissions, CheckCSI, CheckStorageSnapshotClass, CheckNodeInventory,
+				CheckPodCapability, CheckPodSecurity, CheckOpenShiftSCC, CheckAdmissionWebhooks,
+				CheckResourceQuota, CheckDNSResolution, CheckNetworkPolicy, CheckNamespacePermissions, CheckVolumeSnapshot, CheckBlockVolumeSnapshot, CheckTopology,
+				CheckStoragePerformance, CheckAccessModes, CheckTarget, CheckImageAvailability}))
+		})
+
+		It("Should return error when a check with same name is registered twice", func() {
+			_, err := NewCheckRegistry(&Check{Name: CheckKubectl, Run: noopCheck}, &Check{Name: CheckKubectl, Run: noopCheck})
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("is already registered"))
+		})
+
+		It("Should return error when a dependency is registered after the check", func() {
+			_, err := NewCheckRegistry(
+				&Check{Name: CheckVolumeSnapshot, DependsOn: []string{CheckStorageSnapshotClass}, Run: noopCheck},
+				&Check{Name: CheckStorageSnapshotClass, Run: noopCheck})
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("is not registered"))
+		})
+	})
+
+	Context("Selecting preflight checks", func() {
+
+		var registry *CheckRegistry
+
+		BeforeEach(func() {
+			var err error
+			registry, err = NewCheckRegistry(runOps.defaultChecks()...)
+			Expect(err).To(BeNil())
+		})
+
+		It("Should select all checks except optional ones when no check is included or excluded", func() {
+			checks, err := registry.Select(nil, nil)
+			Expect(err).To(BeNil())
+			Expect(getCheckNames(checks)).To(Equal(registry.Names()[:len(registry.Names())-6]))
+			Expect(getCheckNames(checks)).ToNot(ContainElements(CheckBlockVolumeSnapshot, CheckTopology,
+				CheckStoragePerformance, CheckAccessModes, CheckTarget, CheckImageAvailability))
+		})
+
+		It("Should select optional check when it's included", func() {
+			checks, err := registry.Select([]string{CheckStoragePerformance}, nil)
+			Expect(err).To(BeNil())
+			Expect(getCheckNames(checks)).To(Equal([]string{CheckCSI, CheckStorageSnapshotClass, CheckNodeInventory,
+				CheckStoragePerformance}))
+		})
+
+		It("Should select storage performance check along with default checks when its thresholds are configured", func() {
+			run := runOps.copyRun()
+			run.Checks, run.SkipChecks = nil, nil
+			run.StoragePerformance = &StoragePerformanceOptions{}
+			checks, err := run.SelectChecks()
+			Expect(err).To(BeNil())
+			Expect(getCheckNames(checks)).To(Equal(append(registry.Names()[:len(registry.Names())-6], CheckStoragePerformance)))
+		})
+
+		It("Should select target check along with default checks when backup target is configured", func() {
+			run := runOps.copyRun()
+			run.Checks, run.SkipChecks = nil, nil
+			run.Target = &TargetOptions{NFS: &NFSTargetOptions{Server: "nfs.example.com", Path: "/exports"}}
+			checks, err := run.SelectChecks()
+			Expect(err).To(BeNil())
+			names := getCheckNames(checks)
+			Expect(names).To(ContainElement(CheckTarget))
+			Expect(names).ToNot(ContainElement(CheckStoragePerformance))
+		})
+
+		It("Should select included checks along with their dependencies in order of execution", func() {
+			checks, err := registry.Select([]string{CheckDNSResolution, CheckStorageSnapshotClass}, nil)
+			Expect(err).To(BeNil())
+			Expect(getCheckNames(checks)).To(Equal([]string{CheckCSI, CheckStorageSnapshotClass, CheckNodeInventory,
+				CheckDNSResolution}))
+		})
+
+		It("Should not select excluded checks", func() {
+			checks, err := registry.Select(nil, []string{CheckVolumeSnapshot, CheckKubectl})
+			Expect(err).To(BeNil())
+			Expect(getCheckNames(checks)).ToNot(ContainElements(CheckVolumeSnapshot, CheckKubectl))
+			Expect(checks).To(HaveLen(len(registry.Names()) - 8))
+		})
+
+		It("Should return error when unknown check is included or excluded", func() {
+			_, err := registry.Select([]string{"check-unknown"}, nil)
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("unknown preflight check(s) - check-unknown"))
+
+			_, err = registry.Select(nil, []string{"check-unknown"})
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Running preflight checks", func() {
+
+		var run *Run
+
+		BeforeEach(func() {
+			run = runOps.copyRun()
+			run.Parallelism = 1
+		})
+
+		It("Should skip checks whose dependencies failed and report overall failure", func() {
+			var ran []string
+			record := func(name string, err error) CheckFunc {
+				return func(_ context.Context, _ *Run, _ *CheckResult) error {
+					ran = append(ran, name)
+					return err
+				}
+			}
+			checks := []*Check{
+				{Name: "check-a", Description: "a", Run: record("check-a", errors.New("a failed"))},
+				{Name: "check-b", Description: "b", DependsOn: []string{"check-a"}, Run: record("check-b", nil)},
+				{Name: "check-c", Description: "c", DependsOn: []string{"check-b"}, Run: record("check-c", nil)},
+				{Name: "check-d", Description: "d", Run: record("check-d", nil)},
+			}
+
+			Expect(run.runChecks(ctx, checks)).To(BeFalse())
+			Expect(ran).To(Equal([]string{"check-a", "check-d"}))
+			Expect(checks[0].Result.Status).To(Equal(CheckStatusFail))
+			Expect(checks[1].Result.Status).To(Equal(CheckStatusSkipped))
+			Expect(checks[2].Result.Status).To(Equal(CheckStatusSkipped))
+			Expect(checks[3].Result.Status).To(Equal(CheckStatusPass))
+		})
+
+		It("Should propagate snapshot class updated by CSI check only", func() {
+			run.SnapshotClass = "user-snapshot-class"
+			checks := []*Check{
+				{Name: "check-a", Description: "a", Run: noopCheck},
+				{Name: CheckCSI, Description: "csi", Run: func(_ context.Context, o *Run, _ *CheckResult) error {
+					o.SnapshotClass = ""
+					return nil
+				}},
+				{Name: "check-b", Description: "b", Run: func(_ context.Context, o *Run, _ *CheckResult) error {
+					o.SnapshotClass = "stale-snapshot-class"
+					return nil
+				}},
+			}
+
+			Expect(run.runChecks(ctx, checks)).To(BeTrue())
+			Expect(run.SnapshotClass).To(BeEmpty())
+		})
+
+		It("Should mark a check with warnings as warn and report overall success", func() {
+			checks := []*Check{{Name: "check-a", Description: "a", Run: func(_ context.Context, _ *Run, res *CheckResult) error {
+				res.Warn("a warning")
+				return nil
+			}}}
+
+			Expect(run.runChecks(ctx, checks)).To(BeTrue())
+			Expect(checks[0].Result.Status).To(Equal(CheckStatusWarn))
+			Expect(checks[0].Result.Warnings).To(ConsistOf("a warning"))
+		})
+
+		It("Should fail a check exceeding its timeout and report the exceeded timeout", func() {
+			run.CheckTimeouts = map[string]metav1.Duration{"check-a": {Duration: 10 * time.Millisecond}}
+			checks := []*Check{
+				{Name: "check-a", Description: "a", Run: func(ctx context.Context, _ *Run, _ *CheckResult) error {
+					<-ctx.Done()
+					return ctx.Err()
+				}},
+				{Name: "check-b", Description: "b", Run: noopCheck},
+			}
+
+			Expect(run.runChecks(ctx, checks)).To(BeFalse())
+			Expect(checks[0].Result.Status).To(Equal(CheckStatusFail))
+			Expect(checks[0].Result.TimedOut).To(BeTrue())
+			Expect(checks[0].Result.Err.Error()).To(ContainSubstring("preflight check check-a exceeded its timeout of 10ms"))
+			Expect(checks[0].Result.Recommendations).ToNot(BeEmpty())
+			Expect(checks[1].Result.Status).To(Equal(CheckStatusPass))
+		})
+
+		It("Should skip pending checks and report failure when preflight run exceeds its timeout", func() {
+			run.Timeout = metav1.Duration{Duration: 10 * time.Millisecond}
+			runCtx, cancel := context.WithTimeout(ctx, run.Timeout.Duration)
+			defer cancel()
+			checks := []*Check{
+				{Name: "check-a", Description: "a", Run: func(ctx context.Context, _ *Run, _ *CheckResult) error {
+					<-ctx.Done()
+					return ctx.Err()
+				}},
+				{Name: "check-b", Description: "b", Run: noopCheck},
+			}
+
+			Expect(run.runChecks(runCtx, checks)).To(BeFalse())
+			Expect(checks[0].Result.TimedOut).To(BeTrue())
+			Expect(checks[0].Result.Err.Error()).To(ContainSubstring("preflight run exceeded its timeout of 10ms"))
+			Expect(checks[1].Result.Status).To(Equal(CheckStatusSkipped))
+		})
+
+		It("Should perform independent checks concurrently within parallelism and dependents after dependencies", func() {
+			var (
+				mu                 sync.Mutex
+				active, maxActive  int
+				completed          = map[string]bool{}
+				dependencyComplete bool
+			)
+			track := func(name string) CheckFunc {
+				return func(_ context.Context, _ *Run, _ *CheckResult) error {
+					mu.Lock()
+					active++
+					if active > maxActive {
+						maxActive = active
+					}
+					if name == "check-d" {
+						dependencyComplete = completed["check-a"]
+					}
+					mu.Unlock()
+
+					time.Sleep(50 * time.Millisecond)
+
+					mu.Lock()
+					active--
+					completed[name] = true
+					mu.Unlock()
+					return nil
+				}
+			}
+			checks := []*Check{
+				{Name: "check-a", Description: "a", Run: track("check-a")},
+				{Name: "check-b", Description: "b", Run: track("check-b")},
+				{Name: "check-c", Description: "c", Run: track("check-c")},
+				{Name: "check-d", Description: "d", DependsOn: []string{"check-a"}, Run: track("check-d")},
+			}
+
+			run.Parallelism = 2
+			Expect(run.runChecks(ctx, checks)).To(BeTrue())
+			Expect(maxActive).To(Equal(2))
+			Expect(dependencyComplete).To(BeTrue())
+			Expect(completed).To(HaveLen(len(checks)))
+		})
+	})
+})
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/trilioData/tvk-plugins/internal"
+	"github.com/trilioData/tvk-plugins/tools/preflight/wait"
 
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -15,6 +17,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// namespaceDeletionTimeout is the duration for which deletion of a namespace created by preflight is waited upon.
+const namespaceDeletionTimeout = 2 * time.Minute
+
 type CleanupOptions struct {
 	UID string `json:"uid,omitempty"`
 }
@@ -94,7 +99,9 @@ func (co *Cleanup) CleanupPreflightResources(ctx context.Context) error {
 					allSuccess = false
 					co.Logger.Errorf("problem occurred deleting namespace - %s :: %s", ns.GetName(), err.Error())
 				}
+				continue
 			}
+			co.waitUntilNamespaceDeleted(ctx, ns.GetName())
 		}
 	}
 
@@ -106,6 +113,22 @@ func (co *Cleanup) CleanupPreflightResources(ctx context.Context) error {
 	return errors.New("deletion of some resources failed in cleanup process")
 }
 
+// waitUntilNamespaceDeleted waits for deletion of the namespace, a namespace which isn't deleted within
+// namespaceDeletionTimeout is only reported, as its deletion has already been requested.
+func (co *Cleanup) waitUntilNamespaceDeleted(ctx context.Context, name string) {
+	waitOptions := &wait.NamespaceWaitOptions{
+		Name:      name,
+		ClientSet: kubeClient.ClientSet,
+		Timeout:   namespaceDeletionTimeout,
+		Logger:    co.Logger,
+	}
+	if err := waitOptions.WaitUntilDeleted(ctx); err != nil {
+		co.Logger.Warnf("Namespace - %s is not deleted yet :: %s", name, err.Error())
+		return
+	}
+	co.Logger.Infof("Deleted namespace - %s", name)
+}
+
 func getCleanupResourceGVKList(cl *kubernetes.Clientset) ([]schema.GroupVersionKind, error) {
 	cleanupResourceList := make([]schema.GroupVersionKind, 0)
 
@@ -0,0 +1,163 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/trilioData/tvk-plugins/tools/preflight/exec"
+)
+
+const (
+	// DefaultDataFiles is the number of files across which data of the integrity check is written, if not given.
+	DefaultDataFiles = 10
+
+	integrityDataDir    = VolMountPath + "/integrity"
+	integrityFilePrefix = "file-"
+	sha256HexLength     = 64
+)
+
+// checksumCommand prints the SHA-256 checksums of the files written for the data integrity check.
+var checksumCommand = []string{"/bin/sh", "-c", fmt.Sprintf("cd %s && sha256sum %s*", integrityDataDir, integrityFilePrefix)}
+
+// dataIntegrityEnabled returns whether random data is written to the source pvc of volume snapshot check, and verified
+// on the restored pvc using checksums.
+func (o *Run) dataIntegrityEnabled() bool {
+	return o.DataSize.Value() > 0
+}
+
+func (o *Run) dataFiles() int {
+	if o.DataFiles > 0 {
+		return o.DataFiles
+	}
+	return DefaultDataFiles
+}
+
+// dataWriterArgs returns the args of data writer container. With data integrity check, random data of the given size is
+// split across the files before the sample file is written, so that the pod becomes ready once all the data is written.
+func (o *Run) dataWriterArgs() []string {
+	if o.blockMode {
+		return blockWriterArgs
+	}
+	if !o.dataIntegrityEnabled() {
+		return ArgsTouchDataFileSleep
+	}
+
+	files := int64(o.dataFiles())
+	size := o.DataSize.Value()
+	fileSize, lastFileSize := size/files, size/files+size%files
+	writeData := fmt.Sprintf("mkdir -p %[1]s && i=1 && while [ $i -lt %[2]d ]; do head -c %[3]d /dev/urandom > %[1]s/%[4]s$i "+
+		"|| exit 1; i=$((i+1)); done && head -c %[5]d /dev/urandom > %[1]s/%[4]s%[2]d && sync",
+		integrityDataDir, files, fileSize, integrityFilePrefix, lastFileSize)
+
+	return []string{writeData + " && " + ArgsTouchDataFileSleep[0]}
+}
+
+// parseChecksums parses the output of sha256sum into checksums of the files.
+func parseChecksums(out string) (map[string]string, error) {
+	checksums := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || len(fields[0]) != sha256HexLength {
+			return nil, fmt.Errorf("invalid sha256sum output - '%s'", line)
+		}
+		checksums[fields[1]] = fields[0]
+	}
+
+	return checksums, nil
+}
+
+// compareChecksums returns error listing the files of source data which are missing or have a different checksum
+// in the restored data.
+func compareChecksums(source, restored map[string]string) error {
+	var missing, corrupted []string
+	for file, checksum := range source {
+		restoredChecksum, ok := restored[file]
+		switch {
+		case !ok:
+			missing = append(missing, file)
+		case restoredChecksum != checksum:
+			corrupted = append(corrupted, file)
+		}
+	}
+	if len(missing) == 0 && len(corrupted) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+	sort.Strings(corrupted)
+	var problems []string
+	if len(missing) != 0 {
+		problems = append(problems, fmt.Sprintf("%d files missing - [%s]", len(missing), strings.Join(missing, ", ")))
+	}
+	if len(corrupted) != 0 {
+		problems = append(problems, fmt.Sprintf("%d files with checksum mismatch - [%s]", len(corrupted), strings.Join(corrupted, ", ")))
+	}
+	return fmt.Errorf("data restored from volume snapshot differs from source data of %d files :: %s",
+		len(source), strings.Join(problems, ", "))
+}
+
+// readChecksums returns the checksums of the files written for the data integrity check on volume mounted in the pod.
+func (o *Run) readChecksums(ctx context.Context, pod *corev1.Pod, clients ServerClients) (map[string]string, error) {
+	execOp := o.dataExecOptions(ctx, pod, checksumCommand, clients)
+	res, err := execInPodWithResponse(ctx, &execOp, o.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseChecksums(res.Stdout)
+}
+
+// recordSourceChecksums records the checksums of data written to the source pvc by the writer pod, if data integrity
+// check is enabled.
+func (o *Run) recordSourceChecksums(ctx context.Context, writerPod *corev1.Pod, clients ServerClients) (map[string]string, error) {
+	if !o.dataIntegrityEnabled() {
+		return nil, nil
+	}
+	checksums, err := o.readChecksums(ctx, writerPod, clients)
+	if err != nil {
+		return nil, fmt.Errorf("error recording checksums of source data :: %w", err)
+	}
+	o.Logger.Infof("Recorded SHA-256 checksums of %d files having %s of data written to source pvc",
+		len(checksums), o.DataSize.String())
+
+	return checksums, nil
+}
+
+// verifyRestoredData verifies data of pvc restored from volume snapshot mounted in the reader pod. The checksums of data
+// are compared with the ones of source data if data integrity check is enabled, else the sample file or the pattern
+// written to block volume is verified.
+func (o *Run) verifyRestoredData(ctx context.Context, readerPod *corev1.Pod, sourceChecksums map[string]string,
+	clients ServerClients) error {
+	if !o.dataIntegrityEnabled() {
+		execOp := o.dataExecOptions(ctx, readerPod, o.dataCheckCommand(), clients)
+		return execInPod(ctx, &execOp, o.Logger)
+	}
+
+	restored, err := o.readChecksums(ctx, readerPod, clients)
+	if err != nil {
+		return fmt.Errorf("error reading checksums of restored data :: %w", err)
+	}
+	if err = compareChecksums(sourceChecksums, restored); err != nil {
+		return err
+	}
+	o.Logger.Infof("%s Verified SHA-256 checksums of %d files of restored data", check, len(restored))
+
+	return nil
+}
+
+func (o *Run) dataExecOptions(ctx context.Context, pod *corev1.Pod, command []string, clients ServerClients) exec.Options {
+	return exec.Options{
+		Ctx:           ctx,
+		Namespace:     pod.GetNamespace(),
+		Command:       command,
+		PodName:       pod.GetName(),
+		ContainerName: pod.Spec.Containers[0].Name,
+		Executor:      &exec.DefaultRemoteExecutor{},
+		Config:        clients.RestConfig,
+		ClientSet:     clients.ClientSet,
+	}
+}
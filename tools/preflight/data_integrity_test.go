@@ -0,0 +1,94 @@
+package preflight
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Data integrity unit tests", func() {
+
+	var run *Run
+
+	checksumOf := func(c string) string {
+		return strings.Repeat(c, sha256HexLength)
+	}
+
+	BeforeEach(func() {
+		run = &Run{RunOptions: RunOptions{StorageClass: "csi-sc"}}
+	})
+
+	Context("dataWriterArgs func test-cases", func() {
+
+		It("Should write only the sample file when data size is not given", func() {
+			Expect(run.dataIntegrityEnabled()).To(BeFalse())
+			Expect(run.dataWriterArgs()).To(Equal(ArgsTouchDataFileSleep))
+		})
+
+		It("Should split data across the files with remainder in the last file, before writing the sample file", func() {
+			run.DataSize = resource.MustParse("1000")
+			run.DataFiles = 3
+			args := run.dataWriterArgs()
+			Expect(args).To(HaveLen(1))
+			Expect(args[0]).To(ContainSubstring("while [ $i -lt 3 ]; do head -c 333 /dev/urandom > " +
+				integrityDataDir + "/file-$i"))
+			Expect(args[0]).To(ContainSubstring("head -c 334 /dev/urandom > " + integrityDataDir + "/file-3 && sync"))
+			Expect(args[0]).To(HaveSuffix(ArgsTouchDataFileSleep[0]))
+		})
+
+		It("Should use default number of files when data files is not given", func() {
+			run.DataSize = resource.MustParse("1Ki")
+			Expect(run.dataFiles()).To(Equal(DefaultDataFiles))
+			Expect(run.dataWriterArgs()[0]).To(ContainSubstring(fmt.Sprintf("file-%d && sync", DefaultDataFiles)))
+		})
+
+		It("Should use data writer args in the writer pod spec", func() {
+			run.DataSize = resource.MustParse("1Mi")
+			pod := createPVCDataWriterPodSpec("writer", types.NamespacedName{Name: "pvc", Namespace: "ns"}, run, "abcdef")
+			Expect(pod.Spec.Containers[0].Args).To(Equal(run.dataWriterArgs()))
+		})
+	})
+
+	Context("parseChecksums func test-cases", func() {
+
+		It("Should parse the checksums of files from sha256sum output", func() {
+			out := fmt.Sprintf("%s  file-1\n%s  file-2\n", checksumOf("a"), checksumOf("b"))
+			checksums, err := parseChecksums(out)
+			Expect(err).To(BeNil())
+			Expect(checksums).To(Equal(map[string]string{"file-1": checksumOf("a"), "file-2": checksumOf("b")}))
+		})
+
+		It("Should return error when output is not of sha256sum", func() {
+			_, err := parseChecksums("sha256sum: file-*: No such file or directory")
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("invalid sha256sum output"))
+		})
+	})
+
+	Context("compareChecksums func test-cases", func() {
+
+		var source map[string]string
+
+		BeforeEach(func() {
+			source = map[string]string{"file-1": checksumOf("a"), "file-2": checksumOf("b"), "file-3": checksumOf("c")}
+		})
+
+		It("Should not return error when restored data matches source data", func() {
+			restored := map[string]string{"file-1": checksumOf("a"), "file-2": checksumOf("b"), "file-3": checksumOf("c")}
+			Expect(compareChecksums(source, restored)).To(BeNil())
+		})
+
+		It("Should return error listing missing and mismatched files", func() {
+			restored := map[string]string{"file-1": checksumOf("a"), "file-2": checksumOf("d")}
+			err := compareChecksums(source, restored)
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("differs from source data of 3 files"))
+			Expect(err.Error()).To(ContainSubstring("1 files missing - [file-3]"))
+			Expect(err.Error()).To(ContainSubstring("1 files with checksum mismatch - [file-2]"))
+		})
+	})
+})
@@ -0,0 +1,276 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+const (
+	storageClassIsDefaultAnnotation     = "storageclass.kubernetes.io/is-default-class"
+	betaStorageClassIsDefaultAnnotation = "storageclass.beta.kubernetes.io/is-default-class"
+
+	// scores used to rank the discovered storage classes
+	scoreSnapshotClassFound     = 100
+	scoreRoundTripPassed        = 50
+	scoreRoundTripFailed        = -150
+	scoreLegacyDriver           = -200
+	scoreDefaultSnapshotClass   = 10
+	scoreDefaultStorageClass    = 5
+	scoreWaitForFirstConsumer   = 2
+	scoreAllowVolumeExpansion   = 1
+	discoveryNoSnapshotClassMsg = "no volume snapshot class having driver same as provisioner"
+)
+
+// StorageClassCandidate is a storage class evaluated in discover mode for use with TVK.
+type StorageClassCandidate struct {
+	StorageClass           string      `json:"storageClass"`
+	Provisioner            string      `json:"provisioner"`
+	IsDefault              bool        `json:"isDefault,omitempty"`
+	SnapshotClass          string      `json:"snapshotClass,omitempty"`
+	SnapshotClassIsDefault bool        `json:"snapshotClassIsDefault,omitempty"`
+	LegacyDriver           bool        `json:"legacyDriver,omitempty"`
+	RoundTrip              CheckStatus `json:"roundTrip"`
+	Score                  int         `json:"score"`
+	Notes                  []string    `json:"notes,omitempty"`
+
+	volumeBindingMode    storagev1.VolumeBindingMode
+	allowVolumeExpansion bool
+}
+
+// discoverStorageClasses evaluates all the storage classes of the cluster without creating any resource.
+func (o *Run) discoverStorageClasses(ctx context.Context) ([]*StorageClassCandidate, error) {
+	o.Logger.Infoln("Discovering storage classes and volume snapshot classes present on cluster")
+	scList, err := kubeClient.ClientSet.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing storage classes :: %s", err.Error())
+	}
+	if len(scList.Items) == 0 {
+		return nil, fmt.Errorf("no storage class found on cluster")
+	}
+
+	var vsscList []unstructured.Unstructured
+	prefVersion, err := GetServerPreferredVersionForGroup(StorageSnapshotGroup, kubeClient.ClientSet)
+	if err != nil {
+		o.Logger.Warnf("VolumeSnapshot CRDs are not installed on cluster :: %s", err.Error())
+	} else {
+		vsscList, err = listVolumeSnapshotClasses(ctx, prefVersion, kubeClient.RuntimeClient)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var candidates []*StorageClassCandidate
+	for idx := range scList.Items {
+		candidates = append(candidates, o.evaluateStorageClass(&scList.Items[idx], vsscList, prefVersion != ""))
+	}
+	rankStorageClassCandidates(candidates)
+
+	return candidates, nil
+}
+
+func listVolumeSnapshotClasses(ctx context.Context, prefVersion string, cl client.Client) ([]unstructured.Unstructured, error) {
+	vsscList := unstructured.UnstructuredList{}
+	vsscList.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   StorageSnapshotGroup,
+		Version: prefVersion,
+		Kind:    internal.VolumeSnapshotClassKind,
+	})
+	if err := cl.List(ctx, &vsscList); err != nil {
+		return nil, fmt.Errorf("error listing volume snapshot classes :: %s", err.Error())
+	}
+
+	return vsscList.Items, nil
+}
+
+// evaluateStorageClass matches the provisioner of storage class against drivers of volume snapshot classes.
+func (o *Run) evaluateStorageClass(sc *storagev1.StorageClass, vsscList []unstructured.Unstructured,
+	snapshotCRDsInstalled bool) *StorageClassCandidate {
+	o.Logger.Infof("Evaluating storage class - %s having provisioner - %s", sc.GetName(), sc.Provisioner)
+	candidate := &StorageClassCandidate{
+		StorageClass: sc.GetName(),
+		Provisioner:  sc.Provisioner,
+		IsDefault: sc.GetAnnotations()[storageClassIsDefaultAnnotation] == "true" ||
+			sc.GetAnnotations()[betaStorageClassIsDefaultAnnotation] == "true",
+		LegacyDriver:         o.warnIfLegacyNonSnapshotDriver(sc.Provisioner),
+		RoundTrip:            CheckStatusSkipped,
+		allowVolumeExpansion: sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion,
+	}
+	if sc.VolumeBindingMode != nil {
+		candidate.volumeBindingMode = *sc.VolumeBindingMode
+	}
+
+	switch {
+	case candidate.LegacyDriver:
+		candidate.Notes = append(candidate.Notes, "legacy in-tree provisioner without snapshot support")
+	case !snapshotCRDsInstalled:
+		candidate.Notes = append(candidate.Notes, "VolumeSnapshot CRDs are not installed")
+	default:
+		candidate.SnapshotClass, candidate.SnapshotClassIsDefault = matchSnapshotClassForProvisioner(vsscList, sc.Provisioner)
+		if candidate.SnapshotClass == "" {
+			candidate.Notes = append(candidate.Notes, discoveryNoSnapshotClassMsg)
+		} else {
+			o.Logger.Infof("%s Volume snapshot class - %s driver matches with provisioner of storage class - %s",
+				check, candidate.SnapshotClass, candidate.StorageClass)
+		}
+	}
+
+	return candidate
+}
+
+// rankStorageClassCandidates scores the candidates and sorts them in decreasing order of their score.
+func rankStorageClassCandidates(candidates []*StorageClassCandidate) {
+	for _, c := range candidates {
+		c.Score = 0
+		if c.SnapshotClass != "" {
+			c.Score += scoreSnapshotClassFound
+		}
+		if c.SnapshotClassIsDefault {
+			c.Score += scoreDefaultSnapshotClass
+		}
+		if c.LegacyDriver {
+			c.Score += scoreLegacyDriver
+		}
+		switch c.RoundTrip {
+		case CheckStatusPass:
+			c.Score += scoreRoundTripPassed
+		case CheckStatusFail:
+			c.Score += scoreRoundTripFailed
+		}
+		if c.IsDefault {
+			c.Score += scoreDefaultStorageClass
+		}
+		if c.volumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer {
+			c.Score += scoreWaitForFirstConsumer
+		}
+		if c.allowVolumeExpansion {
+			c.Score += scoreAllowVolumeExpansion
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].StorageClass < candidates[j].StorageClass
+	})
+}
+
+// recommendedStorageClass returns the best ranked candidate if it can be used with TVK.
+func recommendedStorageClass(candidates []*StorageClassCandidate) *StorageClassCandidate {
+	if len(candidates) == 0 {
+		return nil
+	}
+	best := candidates[0]
+	if best.SnapshotClass == "" || best.RoundTrip == CheckStatusFail {
+		return nil
+	}
+	return best
+}
+
+// prepareDiscoveryRoundTrip selects the snapshot class and volume snapshot checks to be performed for the candidates
+// having a volume snapshot class. It returns false if no candidate has a volume snapshot class.
+func (o *Run) prepareDiscoveryRoundTrip(candidates []*StorageClassCandidate) bool {
+	var storageClasses []string
+	for _, c := range candidates {
+		if c.SnapshotClass != "" {
+			storageClasses = append(storageClasses, c.StorageClass)
+		}
+	}
+	if len(storageClasses) == 0 {
+		return false
+	}
+
+	o.StorageClass, o.StorageClasses = storageClasses[0], storageClasses[1:]
+	o.SnapshotClass = ""
+	o.Checks, o.SkipChecks = []string{CheckStorageSnapshotClass, CheckVolumeSnapshot}, nil
+	return true
+}
+
+// updateRoundTripResults records outcome of volume snapshot and restore checks on the candidates and ranks them again.
+func (o *Run) updateRoundTripResults(candidates []*StorageClassCandidate) {
+	if o.scMatrix == nil {
+		return
+	}
+	results := make(map[string]*StorageClassResult, len(o.scMatrix.results))
+	for _, res := range o.scMatrix.results {
+		results[res.StorageClass] = res
+	}
+	for _, c := range candidates {
+		res, ok := results[c.StorageClass]
+		if !ok {
+			continue
+		}
+		c.RoundTrip = res.DataVerified
+		if res.Error != "" {
+			c.RoundTrip = CheckStatusFail
+			c.Notes = append(c.Notes, fmt.Sprintf("snapshot round trip failed :: %s", res.Error))
+		}
+	}
+	rankStorageClassCandidates(candidates)
+}
+
+// logStorageClassRecommendation displays the ranked candidates and the recommended storage class and snapshot class.
+func (o *Run) logStorageClassRecommendation(candidates []*StorageClassCandidate) {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "RANK\tSTORAGE CLASS\tPROVISIONER\tSNAPSHOT CLASS\tROUND TRIP\tNOTES")
+	for idx, c := range candidates {
+		sc := c.StorageClass
+		if c.IsDefault {
+			sc += " (default)"
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", idx+1, sc, c.Provisioner, c.SnapshotClass,
+			statusGlyph(c.RoundTrip), strings.Join(c.Notes, "; "))
+	}
+	_ = w.Flush()
+
+	o.Logger.Infoln("Discovered storage classes, ranked for use with TVK:")
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		o.Logger.Infoln(line)
+	}
+
+	best := recommendedStorageClass(candidates)
+	if best == nil {
+		o.Logger.Errorf("%s No storage class on cluster supports volume snapshots. Install a CSI driver having snapshot "+
+			"support along with a volume snapshot class for it", cross)
+		return
+	}
+	o.Logger.Infof("%s Recommended storage class - %s with volume snapshot class - %s. Use '--storage-class %s "+
+		"--volume-snapshot-class %s' for preflight checks and TVK", check, best.StorageClass, best.SnapshotClass,
+		best.StorageClass, best.SnapshotClass)
+}
+
+// completeDiscovery displays the recommendation and writes the report of a discover mode run which doesn't perform
+// the snapshot round trip. It returns error if none of the storage classes can be used with TVK.
+func (o *Run) completeDiscovery(ctx context.Context, candidates []*StorageClassCandidate, startTime time.Time) error {
+	o.logStorageClassRecommendation(candidates)
+	discoveryErr := discoveryError(candidates)
+
+	report := o.newReport(nil, startTime, discoveryErr == nil)
+	report.Discovery = candidates
+	if err := o.outputReport(ctx, report); err != nil {
+		return err
+	}
+
+	return discoveryErr
+}
+
+func discoveryError(candidates []*StorageClassCandidate) error {
+	if recommendedStorageClass(candidates) == nil {
+		return fmt.Errorf("no storage class on cluster supports volume snapshots")
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package preflight
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Storage class discovery unit tests", func() {
+
+	var (
+		run      *Run
+		vsscList []unstructured.Unstructured
+	)
+
+	newStorageClass := func(name, provisioner string, isDefault bool) *storagev1.StorageClass {
+		sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: name}, Provisioner: provisioner}
+		if isDefault {
+			sc.Annotations = map[string]string{storageClassIsDefaultAnnotation: "true"}
+		}
+		return sc
+	}
+
+	newSnapshotClass := func(name, driver string, isDefault bool) unstructured.Unstructured {
+		vssc := unstructured.Unstructured{Object: map[string]interface{}{"driver": driver}}
+		vssc.SetName(name)
+		if isDefault {
+			vssc.SetAnnotations(map[string]string{SnapshotClassIsDefaultAnnotation: "true"})
+		}
+		return vssc
+	}
+
+	BeforeEach(func() {
+		run = runOps.copyRun()
+		vsscList = []unstructured.Unstructured{
+			newSnapshotClass("csi-vsc", "csi.example.com", false),
+			newSnapshotClass("csi-vsc-default", "csi.example.com", true),
+			newSnapshotClass("other-vsc", "other.csi.example.com", false),
+		}
+	})
+
+	It("Should prefer the default volume snapshot class having driver same as provisioner", func() {
+		name, isDefault := matchSnapshotClassForProvisioner(vsscList, "csi.example.com")
+		Expect(name).To(Equal("csi-vsc-default"))
+		Expect(isDefault).To(BeTrue())
+
+		name, isDefault = matchSnapshotClassForProvisioner(vsscList, "other.csi.example.com")
+		Expect(name).To(Equal("other-vsc"))
+		Expect(isDefault).To(BeFalse())
+
+		name, _ = matchSnapshotClassForProvisioner(vsscList, "unknown.csi.example.com")
+		Expect(name).To(BeEmpty())
+	})
+
+	It("Should rank storage classes having a volume snapshot class above legacy and unmatched ones", func() {
+		candidates := []*StorageClassCandidate{
+			run.evaluateStorageClass(newStorageClass("legacy", "kubernetes.io/aws-ebs", true), vsscList, true),
+			run.evaluateStorageClass(newStorageClass("unmatched", "unknown.csi.example.com", false), vsscList, true),
+			run.evaluateStorageClass(newStorageClass("other", "other.csi.example.com", false), vsscList, true),
+			run.evaluateStorageClass(newStorageClass("csi", "csi.example.com", false), vsscList, true),
+		}
+		rankStorageClassCandidates(candidates)
+
+		var names []string
+		for _, c := range candidates {
+			names = append(names, c.StorageClass)
+		}
+		Expect(names).To(Equal([]string{"csi", "other", "unmatched", "legacy"}))
+		Expect(candidates[0].SnapshotClass).To(Equal("csi-vsc-default"))
+		Expect(candidates[3].LegacyDriver).To(BeTrue())
+		Expect(candidates[3].SnapshotClass).To(BeEmpty())
+		Expect(candidates[2].Notes).To(ConsistOf(discoveryNoSnapshotClassMsg))
+
+		best := recommendedStorageClass(candidates)
+		Expect(best).ToNot(BeNil())
+		Expect(best.StorageClass).To(Equal("csi"))
+	})
+
+	It("Should not recommend any storage class when VolumeSnapshot CRDs are not installed", func() {
+		candidates := []*StorageClassCandidate{
+			run.evaluateStorageClass(newStorageClass("csi", "csi.example.com", true), nil, false),
+		}
+		rankStorageClassCandidates(candidates)
+		Expect(candidates[0].Notes).To(ConsistOf("VolumeSnapshot CRDs are not installed"))
+		Expect(recommendedStorageClass(candidates)).To(BeNil())
+		Expect(discoveryError(candidates)).ToNot(BeNil())
+	})
+
+	It("Should rank storage classes again by outcome of snapshot round trip", func() {
+		candidates := []*StorageClassCandidate{
+			run.evaluateStorageClass(newStorageClass("csi", "csi.example.com", false), vsscList, true),
+			run.evaluateStorageClass(newStorageClass("other", "other.csi.example.com", false), vsscList, true),
+			run.evaluateStorageClass(newStorageClass("legacy", "kubernetes.io/gce-pd", false), vsscList, true),
+		}
+		rankStorageClassCandidates(candidates)
+		Expect(run.prepareDiscoveryRoundTrip(candidates)).To(BeTrue())
+		Expect(run.storageClasses()).To(Equal([]string{"csi", "other"}))
+		Expect(run.Checks).To(Equal([]string{CheckStorageSnapshotClass, CheckVolumeSnapshot}))
+
+		run.scMatrix = newStorageClassMatrix(run.storageClasses(), "abcdef")
+		run.scMatrix.results[0].Error = "restored data does not match"
+		run.scMatrix.results[0].DataVerified = CheckStatusFail
+		run.scMatrix.results[1].DataVerified = CheckStatusPass
+		run.updateRoundTripResults(candidates)
+
+		Expect(candidates[0].StorageClass).To(Equal("other"))
+		Expect(candidates[0].RoundTrip).To(Equal(CheckStatusPass))
+		Expect(candidates[1].StorageClass).To(Equal("csi"))
+		Expect(candidates[1].RoundTrip).To(Equal(CheckStatusFail))
+		Expect(recommendedStorageClass(candidates).StorageClass).To(Equal("other"))
+	})
+})
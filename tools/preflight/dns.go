@@ -0,0 +1,404 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// DefaultDNSAttempts is the number of times each name is resolved by DNS resolution check.
+	DefaultDNSAttempts = 3
+	// DefaultDNSMaxLatency is the average resolution latency above which a name is reported as slow.
+	DefaultDNSMaxLatency = time.Second
+
+	// DNSSourceCluster, DNSSourceTarget, DNSSourceRegistry and DNSSourceGiven are the reasons for which a name is resolved.
+	DNSSourceCluster  = "cluster"
+	DNSSourceTarget   = "target"
+	DNSSourceRegistry = "registry"
+	DNSSourceGiven    = "given"
+
+	kubernetesServiceName = "kubernetes.default"
+	// kubeDNSService is the service of cluster DNS pods in kube-system, named kube-dns for CoreDNS as well.
+	kubeDNSService = "kube-dns"
+	resolvConfPath = "/etc/resolv.conf"
+	// defaultNdots is the ndots of the resolver when resolv.conf doesn't set it.
+	defaultNdots = 1
+
+	dockerHubHost         = "docker.io"
+	dockerHubRegistryHost = "registry-1.docker.io"
+)
+
+// dnsNameRegex matches a host name, optionally fully qualified with a trailing dot.
+var dnsNameRegex = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*\.?$`)
+
+// DNSOptions are the names resolved by check-dns-resolution and the thresholds of their resolution.
+type DNSOptions struct {
+	// Names are resolved in addition to kubernetes.default, the host of backup target and the hosts of registries.
+	Names []string `json:"names,omitempty"`
+	// Attempts is the number of times each name is resolved, DefaultDNSAttempts if not given.
+	Attempts int `json:"attempts,omitempty"`
+	// MaxLatency is the average resolution latency above which a name is reported as slow, DefaultDNSMaxLatency if not given.
+	MaxLatency metav1.Duration `json:"maxLatency,omitempty"`
+}
+
+// DNSResult holds the resolver configuration of the DNS pod, the readiness of cluster DNS pods and the outcome of
+// resolving each of the names.
+type DNSResult struct {
+	Nameservers []string `json:"nameservers,omitempty"`
+	Search      []string `json:"search,omitempty"`
+	Ndots       int      `json:"ndots,omitempty"`
+	// DNSPods and ReadyDNSPods are the number of cluster DNS pods in kube-system, and of those which are ready.
+	DNSPods      int              `json:"dnsPods"`
+	ReadyDNSPods int              `json:"readyDNSPods"`
+	Names        []*DNSNameResult `json:"names,omitempty"`
+}
+
+// DNSNameResult is the outcome of resolving a name a number of times. Latencies are of the attempts which resolved it.
+type DNSNameResult struct {
+	Name             string `json:"name"`
+	Source           string `json:"source"`
+	Attempts         int    `json:"attempts"`
+	Resolved         int    `json:"resolved"`
+	AverageLatencyMs int64  `json:"averageLatencyMs,omitempty"`
+	MaxLatencyMs     int64  `json:"maxLatencyMs,omitempty"`
+}
+
+// dnsName is a name to resolve and the reason for which it's resolved.
+type dnsName struct {
+	name   string
+	source string
+}
+
+// required returns whether the check fails if the name can't be resolved. Hosts of target and registries aren't
+// required, as images are pulled using the resolver of nodes, and the target is verified by check-target.
+func (n dnsName) required() bool {
+	return n.source == DNSSourceCluster || n.source == DNSSourceGiven
+}
+
+// Validate validates the names, attempts and max latency of DNS options.
+func (d *DNSOptions) Validate() error {
+	if d.Attempts < 0 {
+		return fmt.Errorf("DNS attempts cannot be negative")
+	}
+	if d.MaxLatency.Duration < 0 {
+		return fmt.Errorf("DNS max latency cannot be negative")
+	}
+	for _, name := range d.Names {
+		if !dnsNameRegex.MatchString(name) {
+			return fmt.Errorf("invalid DNS name - %s", name)
+		}
+	}
+	return nil
+}
+
+// dnsAttempts returns the number of times each name is resolved.
+func (o *Run) dnsAttempts() int {
+	if o.DNS != nil && o.DNS.Attempts > 0 {
+		return o.DNS.Attempts
+	}
+	return DefaultDNSAttempts
+}
+
+// dnsMaxLatency returns the average resolution latency above which a name is reported as slow.
+func (o *Run) dnsMaxLatency() time.Duration {
+	if o.DNS != nil && o.DNS.MaxLatency.Duration > 0 {
+		return o.DNS.MaxLatency.Duration
+	}
+	return DefaultDNSMaxLatency
+}
+
+// imageRegistryHost returns the host of the registry from which image is pulled.
+func imageRegistryHost(image string) string {
+	idx := strings.Index(image, "/")
+	if idx == -1 {
+		return dockerHubRegistryHost
+	}
+	host := image[:idx]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return dockerHubRegistryHost
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == dockerHubHost || host == "index."+dockerHubHost {
+		return dockerHubRegistryHost
+	}
+	return host
+}
+
+// dnsNames returns the names resolved by DNS resolution check - kubernetes.default, the host of backup target, the hosts
+// of registries from which preflight pods and the images to verify are pulled, and the names given, without duplicates.
+// A name given which is also a host of target or registry is resolved as given. IP addresses and invalid names are skipped.
+func (o *Run) dnsNames() []dnsName {
+	names := []dnsName{{name: kubernetesServiceName, source: DNSSourceCluster}}
+	if o.Target != nil && o.Target.S3 != nil {
+		if endpoint, err := url.Parse(o.Target.S3.Endpoint); err == nil {
+			names = append(names, dnsName{name: endpoint.Hostname(), source: DNSSourceTarget})
+		}
+	}
+	if o.Target != nil && o.Target.NFS != nil {
+		names = append(names, dnsName{name: o.Target.NFS.Server, source: DNSSourceTarget})
+	}
+	for _, image := range sets.StringKeySet(preflightImages).List() {
+		names = append(names, dnsName{name: imageRegistryHost(o.preflightImage(image)), source: DNSSourceRegistry})
+	}
+	if o.ImageAvailability != nil {
+		if results, err := o.imageAvailabilityResults(); err == nil {
+			for _, result := range results {
+				names = append(names, dnsName{name: imageRegistryHost(result.Image), source: DNSSourceRegistry})
+			}
+		}
+	}
+	if o.DNS != nil {
+		for _, name := range o.DNS.Names {
+			names = append(names, dnsName{name: name, source: DNSSourceGiven})
+		}
+	}
+
+	var unique []dnsName
+	seen := make(map[string]int)
+	for _, name := range names {
+		if name.name == "" || net.ParseIP(name.name) != nil || !dnsNameRegex.MatchString(name.name) {
+			continue
+		}
+		if idx, ok := seen[name.name]; ok {
+			if name.source == DNSSourceGiven {
+				unique[idx].source = DNSSourceGiven
+			}
+			continue
+		}
+		seen[name.name] = len(unique)
+		unique = append(unique, name)
+	}
+	return unique
+}
+
+// dnsLookupCommand returns the command resolving name the given number of times. For each attempt it prints whether
+// the name is resolved, and the latency in milliseconds measured using /proc/uptime, in units of 10ms.
+func dnsLookupCommand(name string, attempts int) []string {
+	return []string{"sh", "-c", fmt.Sprintf("now() { cut -d' ' -f1 /proc/uptime | tr -d .; }; i=0; "+
+		"while [ $i -lt %d ]; do s=$(now); if nslookup %s >/dev/null 2>&1; then r=ok; else r=fail; fi; e=$(now); "+
+		"echo \"$r $(( (e - s) * 10 ))\"; i=$((i + 1)); done", attempts, name)}
+}
+
+// newDNSNameResult returns the outcome of resolving the name from the output of dnsLookupCommand.
+func newDNSNameResult(name dnsName, attempts int, output string) *DNSNameResult {
+	result := &DNSNameResult{Name: name.name, Source: name.source, Attempts: attempts}
+	var totalLatencyMs int64
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "ok" {
+			continue
+		}
+		latencyMs, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		result.Resolved++
+		totalLatencyMs += latencyMs
+		if latencyMs > result.MaxLatencyMs {
+			result.MaxLatencyMs = latencyMs
+		}
+	}
+	if result.Resolved != 0 {
+		result.AverageLatencyMs = totalLatencyMs / int64(result.Resolved)
+	}
+	return result
+}
+
+// parseResolvConf returns the nameservers, search domains and ndots of the resolv.conf data.
+func parseResolvConf(data string) (nameservers, search []string, ndots int) {
+	ndots = defaultNdots
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "nameserver":
+			nameservers = append(nameservers, fields[1])
+		case "search":
+			search = fields[1:]
+		case "options":
+			for _, option := range fields[1:] {
+				if value, ok := strings.CutPrefix(option, "ndots:"); ok {
+					if n, err := strconv.Atoi(value); err == nil {
+						ndots = n
+					}
+				}
+			}
+		}
+	}
+	return nameservers, search, ndots
+}
+
+// searchedBeforeAbsolute returns whether the search domains are tried before name itself, which is the case for names
+// which aren't fully qualified and have fewer dots than ndots.
+func searchedBeforeAbsolute(name string, ndots int) bool {
+	return !strings.HasSuffix(name, ".") && strings.Count(name, ".") < ndots
+}
+
+// dnsEndpointsReadiness returns the number of cluster DNS pods backing the endpoint slices of DNS service, and the
+// names of those which aren't ready. Endpoints with unknown readiness are taken as ready.
+func dnsEndpointsReadiness(slices []discoveryv1.EndpointSlice) (total int, notReady []string) {
+	seen := sets.NewString()
+	for idx := range slices {
+		for _, endpoint := range slices[idx].Endpoints {
+			name := strings.Join(endpoint.Addresses, ",")
+			if endpoint.TargetRef != nil {
+				name = endpoint.TargetRef.Name
+			}
+			// endpoints of a pod are repeated in the slices of each IP family
+			if seen.Has(name) {
+				continue
+			}
+			seen.Insert(name)
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				notReady = append(notReady, name)
+			}
+		}
+	}
+	return seen.Len(), notReady
+}
+
+// checkDNSPods records the readiness of cluster DNS pods, warning if they can't be listed or aren't all ready.
+func (o *Run) checkDNSPods(ctx context.Context, res *CheckResult) {
+	slices, err := kubeClient.ClientSet.DiscoveryV1().EndpointSlices(kubeSystemNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.Set{discoveryv1.LabelServiceName: kubeDNSService}.String(),
+	})
+	if err != nil {
+		if k8serrors.IsForbidden(err) {
+			res.Warn("Not permitted to list endpoints of DNS service in namespace - kube-system, readiness of " +
+				"cluster DNS pods isn't checked")
+		} else {
+			res.Warn(fmt.Sprintf("Error listing endpoints of DNS service in namespace - kube-system :: %s", err.Error()))
+		}
+		return
+	}
+	total, notReady := dnsEndpointsReadiness(slices.Items)
+	o.dnsResult.DNSPods, o.dnsResult.ReadyDNSPods = total, total-len(notReady)
+	switch {
+	case total == 0:
+		res.Warn(fmt.Sprintf("No endpoints of DNS service - %s found in namespace - kube-system", kubeDNSService))
+	case len(notReady) != 0:
+		res.Warn(fmt.Sprintf("%d of %d cluster DNS pods aren't ready - %s", len(notReady), total,
+			strings.Join(notReady, ", ")))
+		res.Recommend("Verify the cluster DNS pods in namespace - kube-system are running, using " +
+			"'kubectl -n kube-system describe pods -l k8s-app=kube-dns'")
+	default:
+		o.Logger.Infof("%s All %d cluster DNS pods are ready", check, total)
+	}
+}
+
+// inspectResolvConf records the resolver configuration of the DNS pod, warning if it has no nameserver.
+func (o *Run) inspectResolvConf(ctx context.Context, pod *corev1.Pod, res *CheckResult, clients ServerClients) {
+	execOp := o.dataExecOptions(ctx, pod, []string{"cat", resolvConfPath}, clients)
+	execRes, err := execInPodWithResponse(ctx, &execOp, o.Logger)
+	if err != nil {
+		res.Warn(fmt.Sprintf("Error reading %s of DNS pod, resolver configuration isn't inspected :: %s",
+			resolvConfPath, err.Error()))
+		return
+	}
+	o.dnsResult.Nameservers, o.dnsResult.Search, o.dnsResult.Ndots = parseResolvConf(execRes.Stdout)
+	o.Logger.Infof("DNS pod resolver configuration - nameservers: %s, search: %s, ndots: %d",
+		strings.Join(o.dnsResult.Nameservers, " "), strings.Join(o.dnsResult.Search, " "), o.dnsResult.Ndots)
+	if len(o.dnsResult.Nameservers) == 0 {
+		res.Warn(fmt.Sprintf("No nameserver is configured in %s of DNS pod", resolvConfPath))
+	}
+}
+
+// validateDNSResolution resolves each of the names a number of times inside the DNS pod, and reports the names which
+// can't be resolved, are resolved intermittently or slowly, along with the resolver configuration and readiness of
+// cluster DNS pods.
+func (o *Run) validateDNSResolution(ctx context.Context, res *CheckResult, clients ServerClients) error {
+	if o.dnsResult == nil {
+		o.dnsResult = &DNSResult{}
+	}
+	*o.dnsResult = DNSResult{}
+	o.checkDNSPods(ctx, res)
+
+	pod, err := o.createDNSPodOnCluster(ctx, resNameSuffix, clients.ClientSet)
+	if err != nil {
+		return err
+	}
+	o.inspectResolvConf(ctx, pod, res, clients)
+	ndots := o.dnsResult.Ndots
+	if ndots == 0 {
+		ndots = defaultNdots
+	}
+
+	attempts, maxLatency := o.dnsAttempts(), o.dnsMaxLatency()
+	var failed []string
+	for _, name := range o.dnsNames() {
+		execOp := o.dataExecOptions(ctx, pod, dnsLookupCommand(name.name, attempts), clients)
+		execRes, eErr := execInPodWithResponse(ctx, &execOp, o.Logger)
+		if eErr != nil {
+			return fmt.Errorf("error resolving '%s' inside DNS pod :: %s", name.name, eErr.Error())
+		}
+		result := newDNSNameResult(name, attempts, execRes.Stdout)
+		o.dnsResult.Names = append(o.dnsResult.Names, result)
+
+		switch {
+		case result.Resolved == 0 && !name.required():
+			res.Warn(fmt.Sprintf("%s name - %s can't be resolved inside pods in %d attempts", name.source, name.name,
+				attempts))
+			res.Recommend(fmt.Sprintf("Verify the upstream nameservers of cluster DNS can resolve '%s'", name.name))
+			continue
+		case result.Resolved == 0:
+			o.Logger.Errorf("%s Not able to resolve %s name - %s in %d attempts", cross, name.source, name.name, attempts)
+			failed = append(failed, name.name)
+			if name.source == DNSSourceCluster {
+				res.Recommend("Verify the cluster DNS pods are ready, and the DNS service in namespace - kube-system " +
+					"has their endpoints")
+			} else {
+				res.Recommend(fmt.Sprintf("Verify the upstream nameservers of cluster DNS can resolve '%s'", name.name))
+			}
+			continue
+		case result.Resolved < result.Attempts:
+			res.Warn(fmt.Sprintf("%s name - %s is resolved intermittently, in %d of %d attempts", name.source,
+				name.name, result.Resolved, result.Attempts))
+		default:
+			o.Logger.Infof("%s Resolved %s name - %s in %d attempts, average latency %dms", check, name.source,
+				name.name, attempts, result.AverageLatencyMs)
+		}
+
+		if time.Duration(result.AverageLatencyMs)*time.Millisecond > maxLatency {
+			res.Warn(fmt.Sprintf("Average latency %dms of resolving %s name - %s is above %s", result.AverageLatencyMs,
+				name.source, name.name, maxLatency))
+			if name.source != DNSSourceCluster && searchedBeforeAbsolute(name.name, ndots) {
+				res.Recommend(fmt.Sprintf("'%s' is looked up in %d search domains before itself as it has fewer dots "+
+					"than ndots %d, use the fully qualified name '%s.' or lower ndots using dnsConfig of TVK pods",
+					name.name, len(o.dnsResult.Search), ndots, name.name))
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("not able to resolve names inside pods - %s", strings.Join(failed, ", "))
+	}
+
+	// Delete DNS pod when resolution is successful
+	err = deleteK8sResource(ctx, pod, clients.RuntimeClient)
+	if err != nil {
+		o.Logger.Warnf("Problem occurred deleting DNS pod - '%s' :: %s", pod.GetName(), err.Error())
+	} else {
+		o.Logger.Infof("Deleted DNS pod - '%s' successfully", pod.GetName())
+	}
+
+	return nil
+}
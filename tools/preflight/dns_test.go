@@ -0,0 +1,146 @@
+package preflight
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("DNS diagnostics unit tests", func() {
+
+	Context("dnsNames func test-cases", func() {
+
+		var run *Run
+
+		BeforeEach(func() {
+			run = runOps.copyRun()
+			run.LocalRegistry, run.ImageOverrides, run.ImageAvailability, run.Target, run.DNS = "", nil, nil, nil, nil
+		})
+
+		It("Should resolve kubernetes.default and hosts of registries of preflight images", func() {
+			Expect(run.dnsNames()).To(Equal([]dnsName{
+				{name: kubernetesServiceName, source: DNSSourceCluster},
+				{name: "quay.io", source: DNSSourceRegistry},
+				{name: dockerHubRegistryHost, source: DNSSourceRegistry},
+				{name: "gcr.io", source: DNSSourceRegistry},
+			}))
+		})
+
+		It("Should resolve hosts of target, local registry and names given, skipping IP addresses and duplicates", func() {
+			// registry.local is given as well, so it's resolved as given
+			run.LocalRegistry = "registry.local:5000"
+			run.Target = &TargetOptions{S3: &S3TargetOptions{Endpoint: "https://s3.us-east-1.amazonaws.com"}}
+			run.DNS = &DNSOptions{Names: []string{"10.0.0.5", "registry.local", "example.com."}}
+			Expect(run.dnsNames()).To(Equal([]dnsName{
+				{name: kubernetesServiceName, source: DNSSourceCluster},
+				{name: "s3.us-east-1.amazonaws.com", source: DNSSourceTarget},
+				{name: "registry.local", source: DNSSourceGiven},
+				{name: "example.com.", source: DNSSourceGiven},
+			}))
+		})
+
+		It("Should require resolution of kubernetes.default and names given only", func() {
+			Expect(dnsName{name: kubernetesServiceName, source: DNSSourceCluster}.required()).To(BeTrue())
+			Expect(dnsName{name: "example.com", source: DNSSourceGiven}.required()).To(BeTrue())
+			Expect(dnsName{name: "quay.io", source: DNSSourceRegistry}.required()).To(BeFalse())
+			Expect(dnsName{name: "s3.us-east-1.amazonaws.com", source: DNSSourceTarget}.required()).To(BeFalse())
+		})
+
+		It("Should return host of registry of image", func() {
+			Expect(imageRegistryHost("busybox")).To(Equal(dockerHubRegistryHost))
+			Expect(imageRegistryHost("trilio/k8s-triliovault-web:4.0.2")).To(Equal(dockerHubRegistryHost))
+			Expect(imageRegistryHost("docker.io/trilio/k8s-triliovault-web:4.0.2")).To(Equal(dockerHubRegistryHost))
+			Expect(imageRegistryHost("localhost:5000/busybox")).To(Equal("localhost"))
+			Expect(imageRegistryHost("quay.io/example/log-shipper:1.0")).To(Equal("quay.io"))
+		})
+	})
+
+	Context("newDNSNameResult func test-cases", func() {
+
+		name := dnsName{name: "registry.local", source: DNSSourceRegistry}
+
+		It("Should report attempts resolved and their average and max latency", func() {
+			result := newDNSNameResult(name, 3, "ok 20\nok 40\nok 90\n")
+			Expect(result).To(Equal(&DNSNameResult{Name: "registry.local", Source: DNSSourceRegistry, Attempts: 3,
+				Resolved: 3, AverageLatencyMs: 50, MaxLatencyMs: 90}))
+		})
+
+		It("Should exclude failed attempts from latency", func() {
+			result := newDNSNameResult(name, 3, "fail 5000\nok 30\nfail 5010\n")
+			Expect(result.Resolved).To(Equal(1))
+			Expect(result.AverageLatencyMs).To(Equal(int64(30)))
+			Expect(result.MaxLatencyMs).To(Equal(int64(30)))
+
+			result = newDNSNameResult(name, 3, "")
+			Expect(result.Resolved).To(BeZero())
+			Expect(result.AverageLatencyMs).To(BeZero())
+		})
+
+		It("Should resolve name the given number of times", func() {
+			Expect(dnsLookupCommand("registry.local", 5)[2]).To(ContainSubstring("while [ $i -lt 5 ]"))
+			Expect(dnsLookupCommand("registry.local", 5)[2]).To(ContainSubstring("nslookup registry.local "))
+		})
+	})
+
+	Context("Resolver configuration func test-cases", func() {
+
+		It("Should parse nameservers, search domains and ndots of resolv.conf", func() {
+			nameservers, search, ndots := parseResolvConf("# generated\nsearch tvk.svc.cluster.local svc.cluster.local " +
+				"cluster.local\nnameserver 10.96.0.10\noptions ndots:5 timeout:2\n")
+			Expect(nameservers).To(Equal([]string{"10.96.0.10"}))
+			Expect(search).To(Equal([]string{"tvk.svc.cluster.local", "svc.cluster.local", "cluster.local"}))
+			Expect(ndots).To(Equal(5))
+
+			nameservers, search, ndots = parseResolvConf("nameserver 8.8.8.8\nnameserver 8.8.4.4\n")
+			Expect(nameservers).To(Equal([]string{"8.8.8.8", "8.8.4.4"}))
+			Expect(search).To(BeNil())
+			Expect(ndots).To(Equal(defaultNdots))
+		})
+
+		It("Should return whether search domains are tried before name", func() {
+			Expect(searchedBeforeAbsolute("s3.us-east-1.amazonaws.com", 5)).To(BeTrue())
+			Expect(searchedBeforeAbsolute("s3.us-east-1.amazonaws.com.", 5)).To(BeFalse())
+			Expect(searchedBeforeAbsolute("s3.us-east-1.amazonaws.com", 2)).To(BeFalse())
+		})
+	})
+
+	Context("dnsEndpointsReadiness func test-cases", func() {
+
+		It("Should count cluster DNS pods once across endpoint slices, taking unknown readiness as ready", func() {
+			endpoint := func(pod string, ready *bool) discoveryv1.Endpoint {
+				return discoveryv1.Endpoint{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1.EndpointConditions{Ready: ready},
+					TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: pod}}
+			}
+			slices := []discoveryv1.EndpointSlice{
+				{Endpoints: []discoveryv1.Endpoint{endpoint("coredns-1", ptr.To(true)), endpoint("coredns-2", ptr.To(false))}},
+				{Endpoints: []discoveryv1.Endpoint{endpoint("coredns-1", ptr.To(true)), endpoint("coredns-3", nil)}},
+			}
+			total, notReady := dnsEndpointsReadiness(slices)
+			Expect(total).To(Equal(3))
+			Expect(notReady).To(Equal([]string{"coredns-2"}))
+		})
+	})
+
+	Context("DNSOptions func test-cases", func() {
+
+		It("Should return default attempts and max latency if not given", func() {
+			run := runOps.copyRun()
+			run.DNS = nil
+			Expect(run.dnsAttempts()).To(Equal(DefaultDNSAttempts))
+			Expect(run.dnsMaxLatency()).To(Equal(DefaultDNSMaxLatency))
+
+			run.DNS = &DNSOptions{Attempts: 10, MaxLatency: metav1.Duration{Duration: 200 * time.Millisecond}}
+			Expect(run.dnsAttempts()).To(Equal(10))
+			Expect(run.dnsMaxLatency()).To(Equal(200 * time.Millisecond))
+			Expect(run.DNS.Validate()).To(BeNil())
+
+			run.DNS.MaxLatency.Duration = -time.Second
+			Expect(run.DNS.Validate()).ToNot(BeNil())
+		})
+	})
+})
@@ -5,6 +5,7 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"regexp"
 	gort "runtime"
 	"strings"
@@ -13,7 +14,9 @@ import (
 	semVersion "github.com/hashicorp/go-version"
 	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
 	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -23,10 +26,11 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	k8swait "k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
 	goclient "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -66,6 +70,11 @@ const (
 	VolumeSnapSrcNamePrefix           = "snapshot-source-pvc-"
 	VolumeSnapBackupNamePrefix        = "snapshot-backup-pvc-"
 
+	// volume snapshot of the source pvc is taken again once it's unmounted, and restored
+	UnmountedVolumeSnapSrcNamePrefix     = "unmounted-snapshot-source-pvc-"
+	UnmountedRestorePvcNamePrefix        = "unmounted-restore-pvc-"
+	UnmountedVolumeSnapRestoreNamePrefix = "snapshot-unmounted-restore-pvc-"
+
 	StorageSnapshotGroup = "snapshot.storage.k8s.io"
 	BusyboxContainerName = "busybox"
 	BusyBoxRegistry      = "quay.io/triliodata"
@@ -76,10 +85,8 @@ const (
 	GcrRegistryPath  = "gcr.io/kubernetes-e2e-test-images"
 	DNSUtilsImage    = "dnsutils:1.3"
 
-	defaultRetrySteps    = 120
-	defaultRetryInterval = 5 * time.Second
-	defaultRetryFactor   = 1.0
-	defaultRetryJitter   = 0.1
+	// pods and volume snapshots are waited upon for defaultWaitTimeout to reach the desired state
+	defaultWaitTimeout = 10 * time.Minute
 
 	VolMountName = "source-data"
 	VolMountPath = "/demo/data"
@@ -107,7 +114,6 @@ var (
 		"volumesnapshots." + StorageSnapshotGroup,
 	}
 
-	storageVolSnapClass    string
 	scheme                 = runtime.NewScheme()
 	resNameSuffix          string
 	CommandBinSh           = []string{"bin/sh", "-c"}
@@ -125,8 +131,6 @@ var (
 			VolSnapPodFilePath, VolSnapPodFileData),
 	}
 
-	execDNSResolutionCmd = []string{"nslookup", "kubernetes.default"}
-
 	kubectlBinaryName = "kubectl"
 	HelmBinaryName    = "helm"
 
@@ -140,6 +144,7 @@ type ServerClients struct {
 	ClientSet     *goclient.Clientset
 	RuntimeClient client.Client
 	DiscClient    *discovery.DiscoveryClient
+	DynamicClient dynamic.Interface
 	RestConfig    *rest.Config
 }
 
@@ -152,6 +157,10 @@ type CommonOptions struct {
 	Logger     *logrus.Logger
 }
 
+// podCapabilities are the capabilities added to the pods of pod capability check.
+var podCapabilities = []corev1.Capability{"KILL", "AUDIT_WRITE", "NET_BIND_SERVICE", "CHOWN", "FOWNER", "DAC_OVERRIDE",
+	"SETGID", "SETUID", "SYS_ADMIN"}
+
 type capability struct {
 	userID                   int64
 	allowPrivilegeEscalation bool
@@ -181,6 +190,8 @@ func InitKubeEnv(kubeconfig string) error {
 	utilruntime.Must(corev1.AddToScheme(scheme))
 	utilruntime.Must(apiextensions.AddToScheme(scheme))
 	utilruntime.Must(snapshotv1.AddToScheme(scheme))
+	utilruntime.Must(batchv1.AddToScheme(scheme))
+	utilruntime.Must(rbacv1.AddToScheme(scheme))
 	var config *rest.Config
 	if kubeconfig == "" {
 		config = ctrl.GetConfigOrDie()
@@ -199,6 +210,10 @@ func InitKubeEnv(kubeconfig string) error {
 	}
 	kubeClient.DiscClient = kubeEnv.GetDiscoveryClient()
 	kubeClient.RestConfig = kubeEnv.GetRestConfig()
+	kubeClient.DynamicClient, err = dynamic.NewForConfig(kubeClient.RestConfig)
+	if err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -366,12 +381,6 @@ func clusterHasVolumeSnapshotClass(ctx context.Context, snapshotClass string,
 
 // createDNSPodSpec returns a corev1.Pod instance.
 func createDNSPodSpec(op *Run, podNameSuffix string) *corev1.Pod {
-	var imagePath string
-	if op.LocalRegistry != "" {
-		imagePath = op.LocalRegistry
-	} else {
-		imagePath = GcrRegistryPath
-	}
 	nsName := types.NamespacedName{
 		Name:      dnsUtils + podNameSuffix,
 		Namespace: op.Namespace,
@@ -380,7 +389,7 @@ func createDNSPodSpec(op *Run, podNameSuffix string) *corev1.Pod {
 	pod.Spec.Containers = []corev1.Container{
 		{
 			Name:            dnsContainerName,
-			Image:           imagePath + "/" + DNSUtilsImage,
+			Image:           op.preflightImage(PreflightImageDNSUtils),
 			Command:         CommandSleep3600,
 			ImagePullPolicy: corev1.PullIfNotPresent,
 			Resources:       op.ResourceRequirements,
@@ -403,6 +412,9 @@ func createVolumeSnapshotPVCSpec(o *Run, pvcNsName types.NamespacedName, uid str
 			},
 		},
 	}
+	if o.blockMode {
+		pvc.Spec.VolumeMode = ptr.To(corev1.PersistentVolumeBlock)
+	}
 
 	return pvc
 }
@@ -415,12 +427,12 @@ func createPVCDataWriterPodSpec(podName string, pvcNsName types.NamespacedName,
 
 	pod := getPodSpecWithPVC(podNsName, pvcNsName, op, nameSuffix)
 	pod.Spec.Containers[0].Command = CommandBinSh
-	pod.Spec.Containers[0].Args = ArgsTouchDataFileSleep
+	pod.Spec.Containers[0].Args = op.dataWriterArgs()
 	pod.Spec.Containers[0].ReadinessProbe = &corev1.Probe{
 		InitialDelaySeconds: 30,
 		ProbeHandler: corev1.ProbeHandler{
 			Exec: &corev1.ExecAction{
-				Command: execDataCheckCommand,
+				Command: op.dataCheckCommand(),
 			},
 		},
 	}
@@ -443,12 +455,7 @@ func createPVCDataReaderPodSpec(podName string, pvcNsName types.NamespacedName,
 }
 
 func getPodSpecWithPVC(podNsName, pvcNsName types.NamespacedName, op *Run, nameSuffix string) *corev1.Pod {
-	var containerImage string
-	if op.LocalRegistry != "" {
-		containerImage = op.LocalRegistry + "/" + BusyboxImageName
-	} else {
-		containerImage = BusyBoxRegistry + "/" + BusyboxImageName
-	}
+	containerImage := op.preflightImage(PreflightImageBusybox)
 	pod := getPodTemplate(podNsName, nameSuffix, op)
 	pod.Spec.Containers = []corev1.Container{
 		{
@@ -475,17 +482,17 @@ func getPodSpecWithPVC(podNsName, pvcNsName types.NamespacedName, op *Run, nameS
 			},
 		},
 	}
+	if op.blockMode {
+		// raw block volume is attached to the container as a device instead of being mounted
+		pod.Spec.Containers[0].VolumeMounts = nil
+		pod.Spec.Containers[0].VolumeDevices = []corev1.VolumeDevice{{Name: VolMountName, DevicePath: BlockDevicePath}}
+	}
 
 	return pod
 }
 
 func createPodSpecWithPVC(pvcNsName types.NamespacedName, op *Run, nameSuffix string) *corev1.Pod {
-	var containerImage string
-	if op.LocalRegistry != "" {
-		containerImage = op.LocalRegistry + "/" + BusyboxImageName
-	} else {
-		containerImage = BusyBoxRegistry + "/" + BusyboxImageName
-	}
+	containerImage := op.preflightImage(PreflightImageBusybox)
 	nsName := types.NamespacedName{
 		Name:      SourcePodNamePrefix + nameSuffix,
 		Namespace: pvcNsName.Namespace,
@@ -496,7 +503,7 @@ func createPodSpecWithPVC(pvcNsName types.NamespacedName, op *Run, nameSuffix st
 			Name:      BusyboxContainerName,
 			Image:     containerImage,
 			Command:   CommandBinSh,
-			Args:      ArgsTouchDataFileSleep,
+			Args:      op.dataWriterArgs(),
 			Resources: op.ResourceRequirements,
 			VolumeMounts: []corev1.VolumeMount{
 				{
@@ -554,6 +561,118 @@ func createVolumeSnapsotSpec(nsName types.NamespacedName, snapshotClass, snapVer
 	return volSnap
 }
 
+// createVolumeSnapshotClassSpec returns a volume snapshot class having the given driver.
+func createVolumeSnapshotClassSpec(name, driver, prefVersion string) *unstructured.Unstructured {
+	vscUnstrObj := &unstructured.Unstructured{}
+	vscUnstrObj.SetUnstructuredContent(map[string]interface{}{
+		"driver":         driver,
+		"deletionPolicy": "Delete",
+	})
+	vscUnstrObj.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   StorageSnapshotGroup,
+		Version: prefVersion,
+		Kind:    internal.VolumeSnapshotClassKind,
+	})
+	vscUnstrObj.SetName(name)
+
+	return vscUnstrObj
+}
+
+// volumeSnapshotClassCRD returns the name of volume snapshot class CRD.
+func volumeSnapshotClassCRD() string {
+	return "volumesnapshotclasses." + StorageSnapshotGroup
+}
+
+// readVolumeSnapshotCRDSpec returns the volume snapshot CRD of the given version from embedded yamls.
+func readVolumeSnapshotCRDSpec(prefCRDVersion, crd string) (*apiextensions.CustomResourceDefinition, error) {
+	fileBytes, err := crdYamlFiles.ReadFile(filepath.Join(volumeSnapshotCRDYamlDir, prefCRDVersion, crd+".yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	crdObj := &apiextensions.CustomResourceDefinition{}
+	if err = yaml.Unmarshal(fileBytes, crdObj); err != nil {
+		return nil, err
+	}
+
+	return crdObj, nil
+}
+
+// createClonedSnapshotAndContentSpec returns a volume snapshot content pointing to the snapshot handle of the
+// source volume snapshot content, and a volume snapshot bound to it.
+func createClonedSnapshotAndContentSpec(srcVolSnapContent *snapshotv1.VolumeSnapshotContent,
+	cloneVolSnapMeta *metav1.ObjectMeta) (*snapshotv1.VolumeSnapshotContent, *snapshotv1.VolumeSnapshot) {
+	volSnapCont := &snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cloneVolSnapMeta.GetName(),
+			Namespace: cloneVolSnapMeta.GetNamespace(),
+			Labels:    cloneVolSnapMeta.GetLabels(),
+		},
+		Spec: snapshotv1.VolumeSnapshotContentSpec{
+			Source: snapshotv1.VolumeSnapshotContentSource{
+				SnapshotHandle: srcVolSnapContent.Status.SnapshotHandle,
+			},
+			VolumeSnapshotRef: corev1.ObjectReference{
+				Name:      cloneVolSnapMeta.GetName(),
+				Namespace: cloneVolSnapMeta.GetNamespace(),
+			},
+			DeletionPolicy:          snapshotv1.VolumeSnapshotContentDelete,
+			Driver:                  srcVolSnapContent.Spec.Driver,
+			VolumeSnapshotClassName: srcVolSnapContent.Spec.VolumeSnapshotClassName,
+		},
+	}
+
+	volSnap := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cloneVolSnapMeta.GetName(),
+			Namespace: cloneVolSnapMeta.GetNamespace(),
+			Labels:    cloneVolSnapMeta.GetLabels(),
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source: snapshotv1.VolumeSnapshotSource{
+				VolumeSnapshotContentName: &volSnapCont.Name,
+			},
+			VolumeSnapshotClassName: volSnapCont.Spec.VolumeSnapshotClassName,
+		},
+	}
+
+	return volSnapCont, volSnap
+}
+
+// createPVCFromSnapshotSpec returns a pvc restored from the given volume snapshot, having spec same as the source pvc.
+func createPVCFromSnapshotSpec(newPVCMeta *metav1.ObjectMeta, sourcePVCSpec *corev1.PersistentVolumeClaimSpec,
+	sourceSnapshotName string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      newPVCMeta.GetName(),
+			Namespace: newPVCMeta.GetNamespace(),
+			Labels:    newPVCMeta.GetLabels(),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      sourcePVCSpec.AccessModes,
+			StorageClassName: sourcePVCSpec.StorageClassName,
+			VolumeMode:       sourcePVCSpec.VolumeMode,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: sourcePVCSpec.Resources.Requests[corev1.ResourceStorage]},
+			},
+			DataSource: &corev1.TypedLocalObjectReference{
+				APIGroup: ptr.To(snapshotv1.GroupName),
+				Kind:     internal.VolumeSnapshotKind,
+				Name:     sourceSnapshotName,
+			},
+		},
+	}
+}
+
+func createNamespaceSpec(nsName, uid string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   nsName,
+			Labels: getPreflightResourceLabels(uid),
+		},
+	}
+}
+
 func getPodTemplate(nsName types.NamespacedName, uid string, op *Run) *corev1.Pod {
 	pod := &corev1.Pod{
 		ObjectMeta: getObjectMetaTemplate(nsName.Name, nsName.Namespace, uid),
@@ -591,10 +710,15 @@ func getPreflightResourceLabels(uid string) map[string]string {
 	}
 }
 
-// waitUntilPodCondition waits until pod reaches the given condition or timeouts.
+// waitUntilPodCondition waits until pod reaches the given condition, fails or timeouts.
+// The reason due to which the pod was pending is included in the error if the wait timeouts.
 func waitUntilPodCondition(ctx context.Context, wop *wait.PodWaitOptions) error {
-	res := wop.WaitOnPod(ctx, getDefaultRetryBackoffParams())
+	res := wop.WaitOnPod(ctx)
 	if res.Err != nil {
+		if res.PendingReason != "" {
+			return fmt.Errorf("pod %s hasn't reached into %s state, pending due to %s :: %w",
+				wop.Name, string(wop.PodCondition), res.PendingReason, res.Err)
+		}
 		return res.Err
 	} else if !res.ReachedCondn {
 		return fmt.Errorf("pod %s hasn't reached into %s state", wop.Name, string(wop.PodCondition))
@@ -603,63 +727,58 @@ func waitUntilPodCondition(ctx context.Context, wop *wait.PodWaitOptions) error
 	return nil
 }
 
-// waitUntilVolSnapReadyToUse waits until volume snapshot becomes ready or timeouts
-func waitUntilVolSnapReadyToUse(volSnap *unstructured.Unstructured, snapshotVer string,
-	retryBackoff k8swait.Backoff, runtimeClient client.Client) error {
-	retErr := k8swait.ExponentialBackoff(retryBackoff, func() (done bool, err error) {
-		volSnapSrc := &unstructured.Unstructured{}
-		volSnapSrc.SetGroupVersionKind(schema.GroupVersionKind{
-			Group:   StorageSnapshotGroup,
-			Version: snapshotVer,
-			Kind:    internal.VolumeSnapshotKind,
-		})
-		err = runtimeClient.Get(context.Background(), client.ObjectKey{
-			Namespace: volSnap.GetNamespace(),
-			Name:      volSnap.GetName(),
-		}, volSnapSrc)
-		if err != nil {
-			return false, err
-		}
-
-		ready, found, err := unstructured.NestedBool(volSnapSrc.Object, "status", "readyToUse")
-		if found && err == nil && ready {
-			return true, nil
-		}
-		if err != nil {
-			return false, err
-		}
-		return false, nil
-	})
-	if retErr != nil {
-		return retErr
+// waitUntilVolSnapReadyToUse waits until volume snapshot becomes ready, timeouts or the context is done
+func waitUntilVolSnapReadyToUse(ctx context.Context, volSnap *unstructured.Unstructured, snapshotVer string,
+	dynamicClient dynamic.Interface, logger *logrus.Logger) error {
+	waitOptions := &wait.VolumeSnapshotWaitOptions{
+		Name:          volSnap.GetName(),
+		Namespace:     volSnap.GetNamespace(),
+		Version:       snapshotVer,
+		DynamicClient: dynamicClient,
+		Timeout:       defaultWaitTimeout,
+		Logger:        logger,
 	}
-	return nil
+	return waitOptions.WaitUntilReadyToUse(ctx)
+}
 
+// execInPod executes exec command on a container of a pod, until it takes too long or the context is done.
+func execInPod(ctx context.Context, execOp *exec.Options, logger *logrus.Logger) error {
+	_, err := execInPodWithResponse(ctx, execOp, logger)
+	return err
 }
 
-// execInPod executes exec command on a container of a pod.
-func execInPod(execOp *exec.Options, logger *logrus.Logger) error {
+// execInPodWithResponse executes exec command on a container of a pod like execInPod, and returns the response of
+// command. The response is returned along with the error if the command fails.
+func execInPodWithResponse(ctx context.Context, execOp *exec.Options, logger *logrus.Logger) (*exec.Response, error) {
 	var execRes *exec.Response
-	var execChan = make(chan *exec.Response)
+	// buffered, so that exec goroutine doesn't block if the context is done before it responds
+	var execChan = make(chan *exec.Response, 1)
 	logger.Infof("Executing command 'exec %s' in container - '%s' of pod - '%s'\n",
 		strings.Join(execOp.Command, " "), execOp.ContainerName, execOp.PodName)
 	go execOp.ExecInContainer(execChan)
 	select {
 	case execRes = <-execChan:
-		if execRes != nil && execRes.Err != nil {
+		if execRes == nil {
+			return nil, fmt.Errorf("exec operation on container %s in pod %s returned no response", execOp.ContainerName, execOp.PodName)
+		}
+		if execRes.Err != nil {
 			logger.Warnf("exec command failed on %s in pod %s :: %s\n",
 				execOp.ContainerName, execOp.PodName, execRes.Stderr)
-			return execRes.Err
+			return execRes, execRes.Err
 		}
 
 	case <-time.After(execTimeoutDuration):
-		return fmt.Errorf("exec operation took too long on container %s in pod %s", execOp.ContainerName, execOp.PodName)
+		return nil, fmt.Errorf("exec operation took too long on container %s in pod %s", execOp.ContainerName, execOp.PodName)
+
+	case <-ctx.Done():
+		return nil, fmt.Errorf("exec operation on container %s in pod %s interrupted :: %w",
+			execOp.ContainerName, execOp.PodName, ctx.Err())
 	}
 
 	logger.Infof("%s Command 'exec %s' in container - '%s' of pod - '%s' executed successfully\n",
 		check, strings.Join(execOp.Command, " "), execOp.ContainerName, execOp.PodName)
 
-	return nil
+	return execRes, nil
 }
 
 func removeFinalizer(ctx context.Context, obj client.Object, cl client.Client) error {
@@ -720,17 +839,18 @@ func GetObjGVKFromStructuredType(obj client.Object) schema.GroupVersionKind {
 
 	case *corev1.PersistentVolumeClaim:
 		return corev1.SchemeGroupVersion.WithKind(internal.PersistentVolumeClaimKind)
-	}
 
-	return schema.GroupVersionKind{}
-}
+	case *corev1.Namespace:
+		return corev1.SchemeGroupVersion.WithKind(internal.NamespaceKind)
 
-// getDefaultRetryBackoffParams returns a backoff object with timeout of approx. 300s ~ 5 min
-func getDefaultRetryBackoffParams() k8swait.Backoff {
-	return k8swait.Backoff{
-		Steps: defaultRetrySteps, Duration: defaultRetryInterval,
-		Factor: defaultRetryFactor, Jitter: defaultRetryJitter,
+	case *snapshotv1.VolumeSnapshot:
+		return snapshotv1.SchemeGroupVersion.WithKind(internal.VolumeSnapshotKind)
+
+	case *snapshotv1.VolumeSnapshotContent:
+		return snapshotv1.SchemeGroupVersion.WithKind(internal.VolumeSnapshotContentKind)
 	}
+
+	return schema.GroupVersionKind{}
 }
 
 func logPodScheduleStmt(pod *corev1.Pod, logger *logrus.Logger) {
@@ -747,12 +867,7 @@ func objToYAML(o interface{}) ([]byte, error) {
 }
 
 func createPodSpecWithCapability(op *Run, podName string, capability capability) *corev1.Pod {
-	var containerImage string
-	if op.LocalRegistry != "" {
-		containerImage = op.LocalRegistry + "/" + BusyboxImageName
-	} else {
-		containerImage = BusyBoxRegistry + "/" + BusyboxImageName
-	}
+	containerImage := op.preflightImage(PreflightImageBusybox)
 	nsName := types.NamespacedName{
 		Name:      podCapability + podName,
 		Namespace: op.Namespace,
@@ -768,17 +883,7 @@ func createPodSpecWithCapability(op *Run, podName string, capability capability)
 			Resources:       op.ResourceRequirements,
 			SecurityContext: &corev1.SecurityContext{
 				Capabilities: &corev1.Capabilities{
-					Add: []corev1.Capability{
-						"KILL",
-						"AUDIT_WRITE",
-						"NET_BIND_SERVICE",
-						"CHOWN",
-						"FOWNER",
-						"DAC_OVERRIDE",
-						"SETGID",
-						"SETUID",
-						"SYS_ADMIN",
-					},
+					Add: podCapabilities,
 				},
 				AllowPrivilegeEscalation: &capability.allowPrivilegeEscalation,
 				ReadOnlyRootFilesystem:   &readOnlyRootFSFlag,
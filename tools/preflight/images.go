@@ -0,0 +1,333 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/trilioData/tvk-plugins/tools/preflight/wait"
+)
+
+const (
+	// PreflightImageBusybox, PreflightImageDNSUtils and PreflightImageCurl are the names by which images used by
+	// preflight pods are overridden.
+	PreflightImageBusybox  = "busybox"
+	PreflightImageDNSUtils = "dnsutils"
+	PreflightImageCurl     = "curl"
+
+	// TVKImageRegistry is the registry from which images of TVK are pulled by default.
+	TVKImageRegistry = "docker.io/trilio"
+
+	ImagePullPodNamePrefix = "image-pull-"
+	imagePullContainerName = "image"
+)
+
+// imageDigestRegex matches a digest by which an image is pinned.
+var imageDigestRegex = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// preflightImages are the default images used by preflight pods.
+var preflightImages = map[string]string{
+	PreflightImageBusybox:  BusyBoxRegistry + "/" + BusyboxImageName,
+	PreflightImageDNSUtils: GcrRegistryPath + "/" + DNSUtilsImage,
+	PreflightImageCurl:     CurlRegistry + "/" + CurlImage,
+}
+
+// TVKImageNames are the names of images of TVK components in TVKImageRegistry, which are tagged with the TVK version.
+// The list is approximate, the images of an installation can be given exactly using an images file. Images of the list
+// which can't be pulled are reported as warnings.
+var TVKImageNames = []string{
+	"k8s-triliovault-operator",
+	"k8s-triliovault-control-plane",
+	"k8s-triliovault-admission-webhook",
+	"k8s-triliovault-datamover",
+	"k8s-triliovault-metamover",
+	"k8s-triliovault-backup-scheduler",
+	"k8s-triliovault-backup-cleaner",
+	"k8s-triliovault-backup-retention",
+	"k8s-triliovault-resource-cleaner",
+	"k8s-triliovault-target-browser",
+	"k8s-triliovault-web",
+	"k8s-triliovault-web-backend",
+	"k8s-triliovault-exporter",
+}
+
+// ImageAvailabilityOptions are the images whose availability is verified by check-image-availability.
+type ImageAvailabilityOptions struct {
+	// TVKVersion is the version of TVK, TVKImageNames tagged with it are verified if given.
+	TVKVersion string `json:"tvkVersion,omitempty"`
+	// ImagesFile is the path of a file listing images, one per line. Empty lines and lines starting with # are ignored.
+	ImagesFile string   `json:"imagesFile,omitempty"`
+	Images     []string `json:"images,omitempty"`
+}
+
+// ImageAvailabilityResult holds the outcome of pulling each of the images.
+type ImageAvailabilityResult struct {
+	Images []*ImageResult `json:"images,omitempty"`
+}
+
+// ImageResult is the outcome of pulling an image on a node.
+type ImageResult struct {
+	// Source is the image as given or of the TVK version, Image is the one pulled, rewritten to the local registry if given.
+	Source string `json:"source"`
+	Image  string `json:"image"`
+	Pulled bool   `json:"pulled"`
+	// Required is true for images given in images or images file. Images of TVK version which aren't given are not
+	// required, as TVKImageNames is approximate.
+	Required bool   `json:"required"`
+	Node     string `json:"node,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Validate validates that TVK version or images to verify are given.
+func (a *ImageAvailabilityOptions) Validate() error {
+	if a.TVKVersion == "" && a.ImagesFile == "" && len(a.Images) == 0 {
+		return fmt.Errorf("TVK version, images file or images must be given")
+	}
+	return nil
+}
+
+// imageList returns the images of TVK version, images and images file in that order, without duplicates, along with
+// the images which are required - the ones given in images or images file.
+func (a *ImageAvailabilityOptions) imageList() (unique []string, required sets.String, err error) {
+	var images, given []string
+	if a.TVKVersion != "" {
+		for _, name := range TVKImageNames {
+			images = append(images, fmt.Sprintf("%s/%s:%s", TVKImageRegistry, name, a.TVKVersion))
+		}
+	}
+	if a.ImagesFile != "" {
+		inlined, iErr := a.withImagesFileInlined()
+		if iErr != nil {
+			return nil, nil, iErr
+		}
+		given = inlined.Images
+	} else {
+		given = a.Images
+	}
+
+	required = sets.NewString()
+	for _, image := range given {
+		if image = strings.TrimSpace(image); image != "" {
+			required.Insert(image)
+		}
+	}
+	seen := sets.NewString()
+	for _, image := range append(images, given...) {
+		if image = strings.TrimSpace(image); image != "" && !seen.Has(image) {
+			seen.Insert(image)
+			unique = append(unique, image)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, nil, fmt.Errorf("no image to verify is given")
+	}
+	return unique, required, nil
+}
+
+// withImagesFileInlined returns a copy of options in which the images of images file are appended to images.
+func (a *ImageAvailabilityOptions) withImagesFileInlined() (*ImageAvailabilityOptions, error) {
+	data, err := os.ReadFile(a.ImagesFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading images file - %s :: %s", a.ImagesFile, err.Error())
+	}
+	inlined := &ImageAvailabilityOptions{TVKVersion: a.TVKVersion}
+	inlined.Images = append(append(inlined.Images, a.Images...), parseImagesFile(string(data))...)
+	return inlined, nil
+}
+
+// parseImagesFile returns the images listed in data, one per line, ignoring empty lines and comments.
+func parseImagesFile(data string) []string {
+	var images []string
+	for _, line := range strings.Split(data, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			images = append(images, line)
+		}
+	}
+	return images
+}
+
+// localRegistryImage rewrites the image to the local registry, keeping its name and tag or digest.
+func localRegistryImage(image, registry string) string {
+	return strings.TrimSuffix(registry, "/") + "/" + image[strings.LastIndex(image, "/")+1:]
+}
+
+// pinImageDigest replaces the tag or digest of image with the given digest.
+func pinImageDigest(image, digest string) string {
+	if idx := strings.Index(image, "@"); idx != -1 {
+		image = image[:idx]
+	}
+	if idx := strings.LastIndex(image, ":"); idx > strings.LastIndex(image, "/") {
+		image = image[:idx]
+	}
+	return image + "@" + digest
+}
+
+// ValidateImageOverrides validates that overrides are given for images used by preflight pods only, and are either
+// an image or a sha256 digest.
+func ValidateImageOverrides(overrides map[string]string) error {
+	for name, override := range overrides {
+		if _, ok := preflightImages[name]; !ok {
+			return fmt.Errorf("invalid image override - %s. Images which can be overridden are - %s",
+				name, strings.Join(sets.StringKeySet(preflightImages).List(), ", "))
+		}
+		if override == "" {
+			return fmt.Errorf("override of image - %s cannot be empty", name)
+		}
+		if strings.HasPrefix(override, "sha256:") && !imageDigestRegex.MatchString(override) {
+			return fmt.Errorf("invalid digest - %s of image - %s", override, name)
+		}
+	}
+	return nil
+}
+
+// preflightImage returns the image used by preflight pods for the given image name. The default image is rewritten to
+// the local registry if given, and is replaced by its override, or pinned to the digest given as override.
+func (o *Run) preflightImage(name string) string {
+	image := preflightImages[name]
+	if o.LocalRegistry != "" {
+		image = localRegistryImage(image, o.LocalRegistry)
+	}
+	if override := o.ImageOverrides[name]; imageDigestRegex.MatchString(override) {
+		image = pinImageDigest(image, override)
+	} else if override != "" {
+		image = override
+	}
+	return image
+}
+
+// createImagePullPodSpec returns the pod pulling the image. Its command may not exist in the image, the pod is only
+// used to pull the image on a node.
+func createImagePullPodSpec(op *Run, image string, idx int, uid string) *corev1.Pod {
+	nsName := types.NamespacedName{
+		Name:      fmt.Sprintf("%s%d-%s", ImagePullPodNamePrefix, idx, uid),
+		Namespace: op.Namespace,
+	}
+	pod := getPodTemplate(nsName, uid, op)
+	pod.Spec.RestartPolicy = corev1.RestartPolicyNever
+	pod.Spec.Containers = []corev1.Container{
+		{
+			Name:            imagePullContainerName,
+			Image:           image,
+			Command:         []string{"true"},
+			ImagePullPolicy: corev1.PullAlways,
+			Resources:       op.ResourceRequirements,
+		},
+	}
+	return pod
+}
+
+// imageAvailabilityResults returns the results of the images of options, rewritten to the local registry if given.
+func (o *Run) imageAvailabilityResults() ([]*ImageResult, error) {
+	images, required, err := o.ImageAvailability.imageList()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*ImageResult, 0, len(images))
+	for _, image := range images {
+		result := &ImageResult{Source: image, Image: image, Required: required.Has(image)}
+		if o.LocalRegistry != "" {
+			result.Image = localRegistryImage(image, o.LocalRegistry)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// waitUntilImagePulled waits until the image of pod is pulled, and records the outcome on result.
+func (o *Run) waitUntilImagePulled(ctx context.Context, pod *corev1.Pod, result *ImageResult) {
+	waitOptions := &wait.PodWaitOptions{
+		Name:      pod.GetName(),
+		Namespace: pod.GetNamespace(),
+		ClientSet: kubeClient.ClientSet,
+		Timeout:   defaultWaitTimeout,
+		Logger:    o.Logger,
+	}
+	wRes := waitOptions.WaitUntilImagesPulled(ctx)
+	if wRes.Err != nil {
+		result.Reason = wRes.Err.Error()
+		if wRes.PendingReason != "" {
+			result.Reason = fmt.Sprintf("pending due to %s :: %s", wRes.PendingReason, result.Reason)
+		}
+		return
+	}
+	result.Pulled = true
+	if pulledPod, err := kubeClient.ClientSet.CoreV1().Pods(pod.GetNamespace()).Get(ctx, pod.GetName(),
+		metav1.GetOptions{}); err == nil {
+		result.Node = pulledPod.Spec.NodeName
+	}
+}
+
+func runImageAvailabilityCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	if o.ImageAvailability == nil {
+		res.Recommend("Give TVK version using --tvk-version flag, or images using --images-file flag or " +
+			"'imageAvailability' in run section of config file")
+		return fmt.Errorf("TVK version or images to verify are not given")
+	}
+	results, err := o.imageAvailabilityResults()
+	if err != nil {
+		return err
+	}
+	if o.imagesResult == nil {
+		o.imagesResult = &ImageAvailabilityResult{}
+	}
+	o.imagesResult.Images = results
+	o.Logger.Infof("Checking availability of %d images by pulling them on nodes", len(results))
+
+	// all pods are created before waiting, so that the images are pulled concurrently
+	pods := make([]*corev1.Pod, len(results))
+	for idx, result := range results {
+		pod := createImagePullPodSpec(o, result.Image, idx, resNameSuffix)
+		pod, err = kubeClient.ClientSet.CoreV1().Pods(o.Namespace).Create(ctx, pod, metav1.CreateOptions{})
+		if err != nil {
+			result.Reason = err.Error()
+			continue
+		}
+		o.recordCreatedResource(pod)
+		o.Logger.Infof("Created pod - %s pulling image - %s", pod.GetName(), result.Image)
+		pods[idx] = pod
+	}
+
+	var failed, notPulled []string
+	for idx, result := range results {
+		if pods[idx] != nil {
+			o.waitUntilImagePulled(ctx, pods[idx], result)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		switch {
+		case result.Pulled:
+			o.Logger.Infof("%s Image - %s is pulled on node - %s", check, result.Image, result.Node)
+		case result.Required:
+			o.Logger.Errorf("%s Image - %s can't be pulled :: %s", cross, result.Image, result.Reason)
+			failed = append(failed, result.Image)
+		default:
+			res.Warn(fmt.Sprintf("Image - %s of TVK version %s can't be pulled :: %s", result.Image,
+				o.ImageAvailability.TVKVersion, result.Reason))
+			notPulled = append(notPulled, result.Image)
+		}
+	}
+	if len(notPulled) != 0 {
+		res.Recommend("Images of TVK version are approximate, give the exact images of the TVK installation using " +
+			"--images-file flag")
+	}
+
+	if len(failed) != 0 {
+		if o.LocalRegistry != "" {
+			res.Recommend(fmt.Sprintf("Mirror the images to the local registry - %s, and verify the image pull secret "+
+				"given using --image-pull-secret is valid for it", o.LocalRegistry))
+		} else {
+			res.Recommend("Verify the images exist, and their registries are reachable from the nodes")
+		}
+		return fmt.Errorf("%d of %d images can't be pulled - %s", len(failed), len(results), strings.Join(failed, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package preflight
+
+import (
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("Image availability unit tests", func() {
+
+	const (
+		imagesFile = "images_file"
+		tvkVersion = "4.0.2"
+	)
+
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	Context("imageList func test-cases", func() {
+
+		It("Should list images of TVK version, images and images file without duplicates", func() {
+			options := &ImageAvailabilityOptions{
+				TVKVersion: tvkVersion,
+				ImagesFile: filepath.Join(testDataDirRelPath, imagesFile),
+				Images:     []string{"registry.example.com/addon:1.0"},
+			}
+			images, required, err := options.imageList()
+			Expect(err).To(BeNil())
+			Expect(images).To(HaveLen(len(TVKImageNames) + 2))
+			Expect(required.List()).To(Equal([]string{TVKImageRegistry + "/k8s-triliovault-web:" + tvkVersion,
+				"quay.io/example/log-shipper@" + digest, "registry.example.com/addon:1.0"}))
+			Expect(images[0]).To(Equal(TVKImageRegistry + "/k8s-triliovault-operator:" + tvkVersion))
+			Expect(images[len(images)-2:]).To(Equal([]string{"registry.example.com/addon:1.0",
+				"quay.io/example/log-shipper@" + digest}))
+		})
+
+		It("Should require images given in images or images file only", func() {
+			webImage := TVKImageRegistry + "/k8s-triliovault-web:" + tvkVersion
+			run := runOps.copyRun()
+			run.LocalRegistry = ""
+			run.ImageAvailability = &ImageAvailabilityOptions{TVKVersion: tvkVersion, Images: []string{webImage}}
+			results, err := run.imageAvailabilityResults()
+			Expect(err).To(BeNil())
+			Expect(results).To(HaveLen(len(TVKImageNames)))
+			for _, result := range results {
+				Expect(result.Required).To(Equal(result.Source == webImage))
+			}
+		})
+
+		It("Should return error when images file can't be read or no image is given", func() {
+			_, _, err := (&ImageAvailabilityOptions{ImagesFile: filepath.Join(testDataDirRelPath, nonExistentFile)}).imageList()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("error reading images file"))
+
+			_, _, err = (&ImageAvailabilityOptions{ImagesFile: filepath.Join(testDataDirRelPath, emptyFile)}).imageList()
+			Expect(err).ToNot(BeNil())
+			Expect((&ImageAvailabilityOptions{}).Validate()).ToNot(BeNil())
+		})
+
+		It("Should inline images of images file into images", func() {
+			options := &ImageAvailabilityOptions{TVKVersion: tvkVersion, ImagesFile: filepath.Join(testDataDirRelPath, imagesFile)}
+			inlined, err := options.withImagesFileInlined()
+			Expect(err).To(BeNil())
+			Expect(inlined.ImagesFile).To(BeEmpty())
+			Expect(inlined.TVKVersion).To(Equal(tvkVersion))
+			Expect(inlined.Images).To(Equal([]string{TVKImageRegistry + "/k8s-triliovault-web:" + tvkVersion,
+				"quay.io/example/log-shipper@" + digest}))
+		})
+	})
+
+	Context("Image rewrite func test-cases", func() {
+
+		It("Should rewrite image to local registry keeping its name and tag or digest", func() {
+			Expect(localRegistryImage("docker.io/trilio/k8s-triliovault-web:4.0.2", "registry.local:5000/")).To(
+				Equal("registry.local:5000/k8s-triliovault-web:4.0.2"))
+			Expect(localRegistryImage("busybox", "registry.local")).To(Equal("registry.local/busybox"))
+		})
+
+		It("Should pin image to digest replacing its tag or digest", func() {
+			Expect(pinImageDigest("registry.local:5000/dnsutils:1.3", digest)).To(Equal("registry.local:5000/dnsutils@" + digest))
+			Expect(pinImageDigest("registry.local:5000/busybox", digest)).To(Equal("registry.local:5000/busybox@" + digest))
+			Expect(pinImageDigest("quay.io/busybox@sha256:0", digest)).To(Equal("quay.io/busybox@" + digest))
+		})
+	})
+
+	Context("preflightImage func test-cases", func() {
+
+		var run *Run
+
+		BeforeEach(func() {
+			run = runOps.copyRun()
+			run.LocalRegistry, run.ImageOverrides = "", nil
+		})
+
+		It("Should return default images, rewritten to local registry if given", func() {
+			Expect(run.preflightImage(PreflightImageBusybox)).To(Equal(BusyBoxRegistry + "/" + BusyboxImageName))
+			run.LocalRegistry = "registry.local"
+			Expect(run.preflightImage(PreflightImageDNSUtils)).To(Equal("registry.local/" + DNSUtilsImage))
+			Expect(createDNSPodSpec(run, resNameSuffix).Spec.Containers[0].Image).To(Equal("registry.local/" + DNSUtilsImage))
+		})
+
+		It("Should return override of image, or default image pinned to the digest given as override", func() {
+			run.LocalRegistry = "registry.local"
+			run.ImageOverrides = map[string]string{
+				PreflightImageCurl:     "registry.example.com/tools/curl:8.11.0",
+				PreflightImageDNSUtils: digest,
+			}
+			Expect(run.preflightImage(PreflightImageCurl)).To(Equal("registry.example.com/tools/curl:8.11.0"))
+			Expect(run.preflightImage(PreflightImageDNSUtils)).To(Equal("registry.local/dnsutils@" + digest))
+			Expect(run.preflightImage(PreflightImageBusybox)).To(Equal("registry.local/" + BusyboxImageName))
+		})
+
+		It("Should return error for overrides of unknown images or invalid digests", func() {
+			Expect(ValidateImageOverrides(map[string]string{PreflightImageBusybox: digest})).To(BeNil())
+			err := ValidateImageOverrides(map[string]string{"nginx": "nginx:1.27"})
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("busybox, curl, dnsutils"))
+			Expect(ValidateImageOverrides(map[string]string{PreflightImageBusybox: "sha256:abc"})).ToNot(BeNil())
+			Expect(ValidateImageOverrides(map[string]string{PreflightImageBusybox: ""})).ToNot(BeNil())
+		})
+	})
+
+	Context("createImagePullPodSpec func test-cases", func() {
+
+		It("Should always pull the image using the image pull secret", func() {
+			run := runOps.copyRun()
+			run.ImagePullSecret = "registry-creds"
+			pod := createImagePullPodSpec(run, "registry.local/k8s-triliovault-web:4.0.2", 3, resNameSuffix)
+			Expect(pod.GetName()).To(Equal(ImagePullPodNamePrefix + "3-" + resNameSuffix))
+			Expect(pod.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+			Expect(pod.Spec.ImagePullSecrets).To(Equal([]corev1.LocalObjectReference{{Name: "registry-creds"}}))
+			Expect(pod.Spec.Containers[0].ImagePullPolicy).To(Equal(corev1.PullAlways))
+			Expect(pod.Spec.Containers[0].Image).To(Equal("registry.local/k8s-triliovault-web:4.0.2"))
+		})
+	})
+})
@@ -0,0 +1,357 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+const (
+	LabelTvkPreflightJobValue    = "tvk-preflight-job"
+	LabelTvkPreflightResultValue = "tvk-preflight-result"
+	LabelPreflightJobKey         = "preflight-job"
+	LabelPreflightStatusKey      = "preflight-status"
+
+	jobNamePrefix          = "tvk-preflight-"
+	jobServiceAccountName  = "tvk-preflight-job"
+	jobRoleName            = "tvk-preflight-job"
+	jobContainerName       = "preflight"
+	jobBinaryPath          = "/opt/tvk-plugins/preflight"
+	jobWorkingDir          = "/tmp"
+	jobConfigVolumeName    = "preflight-config"
+	jobConfigMountPath     = "/etc/tvk-preflight"
+	jobConfigFileKey       = "preflight.yaml"
+	jobConfigMapNameSuffix = "-config"
+	jobResultNameSuffix    = "-result"
+
+	// ResultReportKey is the key of the json report in data of the result ConfigMap of a preflight job.
+	ResultReportKey = "report.json"
+)
+
+// JobOptions input options required for running preflight as a kubernetes job inside the cluster.
+type JobOptions struct {
+	Image string `json:"image,omitempty"`
+	Apply bool   `json:"apply,omitempty"`
+}
+
+// JobResult is the result of a preflight run performed by a preflight job.
+type JobResult struct {
+	ConfigMap    string     `json:"configMap"`
+	CreationTime time.Time  `json:"creationTime"`
+	Report       *RunReport `json:"report"`
+}
+
+// jobRunOptions is the config file of preflight run performed by the preflight job.
+type jobRunOptions struct {
+	Run RunOptions `json:"run"`
+}
+
+// JobResources returns the resources to run preflight with the given run options as a kubernetes job inside
+// the cluster. The run options are passed to the job through a ConfigMap, and the job writes the result of
+// preflight run into another ConfigMap which can be read using GetJobResults.
+func (o *Run) JobResources(jobOps JobOptions) ([]client.Object, error) {
+	suffix, err := CreateResourceNameSuffix()
+	if err != nil {
+		return nil, err
+	}
+	jobName := jobNamePrefix + suffix
+
+	runOps := o.RunOptions
+	runOps.ResultConfigMap = JobResultConfigMapName(jobName)
+	runOps.OutputFormat, runOps.ReportFile = "", ""
+	if runOps.ImageAvailability != nil && runOps.ImageAvailability.ImagesFile != "" {
+		// images file isn't available inside the job, its images are passed through the run options instead
+		if runOps.ImageAvailability, err = runOps.ImageAvailability.withImagesFileInlined(); err != nil {
+			return nil, err
+		}
+	}
+	config, err := yaml.Marshal(jobRunOptions{Run: runOps})
+	if err != nil {
+		return nil, fmt.Errorf("error rendering preflight run options of job :: %s", err.Error())
+	}
+
+	configMap := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: "ConfigMap"},
+		ObjectMeta: o.jobObjectMeta(jobName+jobConfigMapNameSuffix, jobName),
+		Data:       map[string]string{jobConfigFileKey: string(config)},
+	}
+
+	objs := []client.Object{o.jobServiceAccount()}
+	objs = append(objs, o.jobRBACResources()...)
+	objs = append(objs, configMap, o.jobTemplate(jobName, configMap.GetName(), jobOps.Image))
+
+	return objs, nil
+}
+
+func (o *Run) jobObjectMeta(name, jobName string) metav1.ObjectMeta {
+	meta := metav1.ObjectMeta{
+		Name:      name,
+		Namespace: o.Namespace,
+		Labels: map[string]string{
+			LabelK8sName:   LabelK8sNameValue,
+			LabelTrilioKey: LabelTvkPreflightJobValue,
+			LabelK8sPartOf: LabelK8sPartOfValue,
+		},
+	}
+	if jobName != "" {
+		meta.Labels[LabelPreflightJobKey] = jobName
+	}
+
+	return meta
+}
+
+func (o *Run) jobServiceAccount() *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{
+		TypeMeta:   metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: "ServiceAccount"},
+		ObjectMeta: o.jobObjectMeta(jobServiceAccountName, ""),
+	}
+}
+
+// jobRBACResources returns the minimal roles needed by the preflight checks and their bindings to the job service account.
+// Namespaced resources are created in the backup namespace with cluster scope, so they are granted cluster wide.
+// With namespace scope, they are granted only in the namespace of the job.
+func (o *Run) jobRBACResources() []client.Object {
+	clusterRules := preflightClusterRules(o.Scope)
+	if o.Scope == internal.ClusterScope {
+		clusterRules = append(clusterRules, preflightNamespacedRules(o.Scope)...)
+	}
+
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: jobServiceAccountName, Namespace: o.Namespace}}
+	clusterRoleMeta := o.jobObjectMeta(jobRoleName+"-"+o.Namespace, "")
+	clusterRoleMeta.Namespace = ""
+
+	objs := []client.Object{
+		&rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+			ObjectMeta: clusterRoleMeta,
+			Rules:      clusterRules,
+		},
+		&rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+			ObjectMeta: clusterRoleMeta,
+			Subjects:   subjects,
+			RoleRef:    rbacv1.RoleRef{APIGroup: RBACAPIGroup, Kind: "ClusterRole", Name: clusterRoleMeta.Name},
+		},
+	}
+	if o.Target != nil && o.Target.S3 != nil {
+		// only the secrets of backup target are allowed to be read, in the namespace of the job
+		targetRoleMeta := o.jobObjectMeta(jobRoleName+"-target", "")
+		objs = append(objs,
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+				ObjectMeta: targetRoleMeta,
+				Rules: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"},
+					ResourceNames: o.Target.secretNames(), Verbs: []string{"get"}}},
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+				ObjectMeta: targetRoleMeta,
+				Subjects:   subjects,
+				RoleRef:    rbacv1.RoleRef{APIGroup: RBACAPIGroup, Kind: "Role", Name: targetRoleMeta.Name},
+			})
+	}
+	if o.Scope == internal.ClusterScope {
+		return objs
+	}
+
+	return append(objs,
+		&rbacv1.Role{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+			ObjectMeta: o.jobObjectMeta(jobRoleName, ""),
+			Rules:      preflightNamespacedRules(o.Scope),
+		},
+		&rbacv1.RoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+			ObjectMeta: o.jobObjectMeta(jobRoleName, ""),
+			Subjects:   subjects,
+			RoleRef:    rbacv1.RoleRef{APIGroup: RBACAPIGroup, Kind: "Role", Name: jobRoleName},
+		})
+}
+
+func (o *Run) jobTemplate(jobName, configMapName, image string) *batchv1.Job {
+	meta := o.jobObjectMeta(jobName, jobName)
+	podSpec := corev1.PodSpec{
+		ServiceAccountName: jobServiceAccountName,
+		RestartPolicy:      corev1.RestartPolicyNever,
+		NodeSelector:       o.PodSchedOps.NodeSelector,
+		Affinity:           o.PodSchedOps.Affinity,
+		Tolerations:        o.PodSchedOps.Tolerations,
+		Containers: []corev1.Container{{
+			Name:       jobContainerName,
+			Image:      image,
+			WorkingDir: jobWorkingDir,
+			Command:    []string{jobBinaryPath},
+			Args: []string{"run", "--in-cluster",
+				"--config-file", path.Join(jobConfigMountPath, jobConfigFileKey),
+				"--namespace", o.Namespace,
+				"--scope", o.Scope,
+				"--log-level", o.LogLevel,
+			},
+			VolumeMounts: []corev1.VolumeMount{{Name: jobConfigVolumeName, MountPath: jobConfigMountPath, ReadOnly: true}},
+		}},
+		Volumes: []corev1.Volume{{
+			Name: jobConfigVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: configMapName}},
+			},
+		}},
+	}
+	if o.ImagePullSecret != "" {
+		podSpec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: o.ImagePullSecret}}
+	}
+
+	return &batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{APIVersion: batchv1.SchemeGroupVersion.String(), Kind: "Job"},
+		ObjectMeta: meta,
+		Spec: batchv1.JobSpec{
+			BackoffLimit: ptr.To[int32](0),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: meta.Labels},
+				Spec:       podSpec,
+			},
+		},
+	}
+}
+
+// RenderJobResources returns the given resources as a multi-document yaml.
+func RenderJobResources(objs []client.Object) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, obj := range objs {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString("---\n")
+		buf.Write(data)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ApplyJobResources creates the given resources on the cluster. Existing resources other than the job are updated.
+func (o *Run) ApplyJobResources(ctx context.Context, objs []client.Object) error {
+	for _, obj := range objs {
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		err := kubeClient.RuntimeClient.Create(ctx, obj)
+		if k8serrors.IsAlreadyExists(err) {
+			err = updateJobResource(ctx, obj)
+		}
+		if err != nil {
+			o.Logger.Errorf("%s Error applying %s - %s :: %s", cross, kind, obj.GetName(), err.Error())
+			return err
+		}
+		o.Logger.Infof("%s Applied %s - %s", check, kind, obj.GetName())
+	}
+
+	return nil
+}
+
+func updateJobResource(ctx context.Context, obj client.Object) error {
+	existing := obj.DeepCopyObject().(client.Object)
+	if err := kubeClient.RuntimeClient.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+
+	return kubeClient.RuntimeClient.Update(ctx, obj)
+}
+
+// JobResultConfigMapName returns the name of ConfigMap to which the preflight job writes the result of preflight run.
+func JobResultConfigMapName(jobName string) string {
+	return jobName + jobResultNameSuffix
+}
+
+// persistResult writes the report of preflight run into the result ConfigMap.
+func (o *Run) persistResult(ctx context.Context, report *RunReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      o.ResultConfigMap,
+			Namespace: o.Namespace,
+			Labels: map[string]string{
+				LabelK8sName:            LabelK8sNameValue,
+				LabelTrilioKey:          LabelTvkPreflightResultValue,
+				LabelPreflightRunKey:    report.UID,
+				LabelPreflightStatusKey: string(report.Status),
+				LabelK8sPartOf:          LabelK8sPartOfValue,
+			},
+		},
+		Data: map[string]string{ResultReportKey: string(data)},
+	}
+	err = kubeClient.RuntimeClient.Create(ctx, cm)
+	if k8serrors.IsAlreadyExists(err) {
+		err = updateJobResource(ctx, cm)
+	}
+	if err != nil {
+		return err
+	}
+	o.Logger.Infof("Preflight result written to ConfigMap - %s", internal.GetNamespacedName(o.Namespace, o.ResultConfigMap))
+
+	return nil
+}
+
+// GetJobResults returns the results of preflight runs written by preflight jobs in the namespace, the latest first.
+// If job name is given, only the result of that job is returned.
+func GetJobResults(ctx context.Context, namespace, jobName string) ([]*JobResult, error) {
+	var cms []corev1.ConfigMap
+	if jobName != "" {
+		cm := corev1.ConfigMap{}
+		err := kubeClient.RuntimeClient.Get(ctx, internal.GetNamespacedName(namespace, JobResultConfigMapName(jobName)), &cm)
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				return nil, fmt.Errorf("result of preflight job - %s not found, job may not be complete yet", jobName)
+			}
+			return nil, err
+		}
+		cms = append(cms, cm)
+	} else {
+		cmList := corev1.ConfigMapList{}
+		if err := kubeClient.RuntimeClient.List(ctx, &cmList, client.InNamespace(namespace),
+			client.MatchingLabels{LabelTrilioKey: LabelTvkPreflightResultValue}); err != nil {
+			return nil, err
+		}
+		cms = cmList.Items
+	}
+
+	var results []*JobResult
+	for idx := range cms {
+		result, err := newJobResult(&cms[idx])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].CreationTime.After(results[j].CreationTime)
+	})
+
+	return results, nil
+}
+
+func newJobResult(cm *corev1.ConfigMap) (*JobResult, error) {
+	report := &RunReport{}
+	if err := json.Unmarshal([]byte(cm.Data[ResultReportKey]), report); err != nil {
+		return nil, fmt.Errorf("error reading preflight result from ConfigMap - %s :: %s", cm.GetName(), err.Error())
+	}
+
+	return &JobResult{ConfigMap: cm.GetName(), CreationTime: cm.GetCreationTimestamp().Time, Report: report}, nil
+}
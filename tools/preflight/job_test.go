@@ -0,0 +1,186 @@
+package preflight
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+var _ = Describe("Preflight job unit tests", func() {
+
+	const testJobImage = "registry.example.com/preflight:test"
+
+	var run *Run
+
+	BeforeEach(func() {
+		run = runOps.copyRun()
+		run.Scope = internal.NamespaceScope
+		run.OutputFormat = internal.FormatJSON
+	})
+
+	It("Should render job with run options in a ConfigMap and namespaced role for namespace scope", func() {
+		// service account of TVK is passed to the job, whose permissions are evaluated by RBAC permissions check
+		run.ServiceAccountName = DefaultTVKServiceAccount
+		objs, err := run.JobResources(JobOptions{Image: testJobImage})
+		Expect(err).To(BeNil())
+		Expect(objs).To(HaveLen(7))
+
+		Expect(objs[0]).To(BeAssignableToTypeOf(&corev1.ServiceAccount{}))
+		Expect(objs[1]).To(BeAssignableToTypeOf(&rbacv1.ClusterRole{}))
+		Expect(objs[3]).To(BeAssignableToTypeOf(&rbacv1.Role{}))
+		clusterRole := objs[1].(*rbacv1.ClusterRole)
+		Expect(clusterRole.GetNamespace()).To(BeEmpty())
+		for _, rule := range clusterRole.Rules {
+			Expect(rule.Resources).ToNot(ContainElement("pods"))
+		}
+
+		job := objs[6].(*batchv1.Job)
+		Expect(job.GetNamespace()).To(Equal(run.Namespace))
+		Expect(job.Spec.Template.Spec.ServiceAccountName).To(Equal(jobServiceAccountName))
+		Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal(testJobImage))
+		Expect(job.Spec.Template.Spec.Containers[0].Args).To(ContainElements("--in-cluster", run.Namespace))
+
+		configMap := objs[5].(*corev1.ConfigMap)
+		Expect(job.Spec.Template.Spec.Volumes[0].ConfigMap.Name).To(Equal(configMap.GetName()))
+		config := &jobRunOptions{}
+		Expect(yaml.UnmarshalStrict([]byte(configMap.Data[jobConfigFileKey]), config)).To(Succeed())
+		Expect(config.Run.StorageClass).To(Equal(run.StorageClass))
+		Expect(config.Run.ServiceAccountName).To(Equal(DefaultTVKServiceAccount))
+		Expect(config.Run.ResultConfigMap).To(Equal(JobResultConfigMapName(job.GetName())))
+		Expect(config.Run.OutputFormat).To(BeEmpty())
+	})
+
+	It("Should grant permissions on namespaced resources cluster wide for cluster scope", func() {
+		run.Scope = internal.ClusterScope
+		objs, err := run.JobResources(JobOptions{Image: testJobImage})
+		Expect(err).To(BeNil())
+		Expect(objs).To(HaveLen(5))
+
+		var resources []string
+		for _, rule := range objs[1].(*rbacv1.ClusterRole).Rules {
+			resources = append(resources, rule.Resources...)
+			if rule.Resources[0] == "namespaces" {
+				Expect(rule.Verbs).To(ContainElements(internal.CreateVerb, internal.DeleteVerb))
+			}
+		}
+		Expect(resources).To(ContainElements("pods", "pods/exec", "configmaps", "volumesnapshots"))
+	})
+
+	It("Should grant the job every permission required by the plan of all checks, for both scopes", func() {
+		run.Target = &TargetOptions{S3: &S3TargetOptions{Endpoint: "https://minio:9000", Bucket: "tvk",
+			CredentialsSecret: "s3-creds"}}
+		run.ImageAvailability = &ImageAvailabilityOptions{Images: []string{"registry.example.com/addon:1.0"}}
+		run.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+		run.scMatrix = newStorageClassMatrix(run.storageClasses(), resNameSuffix)
+		registry, err := NewCheckRegistry(run.defaultChecks()...)
+		Expect(err).To(BeNil())
+		run.Checks = registry.Names()
+		state := &dryRunClusterState{crdVersion: snapshotClassVersionV1, snapshotVersion: snapshotClassVersionV1,
+			installedCRDs: sets.NewString(), provisioners: map[string]string{defaultStorageClass: testDriver}}
+
+		grants := func(rules []rbacv1.PolicyRule, perm *permission) bool {
+			for _, rule := range rules {
+				if sets.NewString(rule.APIGroups...).Has(perm.apiGroup) && sets.NewString(rule.Resources...).Has(perm.resource) &&
+					sets.NewString(rule.Verbs...).HasAll(perm.verbs.List()...) {
+					return true
+				}
+			}
+			return false
+		}
+
+		for _, scope := range []string{internal.NamespaceScope, internal.ClusterScope} {
+			run.Scope = scope
+			checks, sErr := run.SelectChecks()
+			Expect(sErr).To(BeNil())
+			plan := run.planResources(checks, state)
+
+			objs, jErr := run.JobResources(JobOptions{Image: testJobImage})
+			Expect(jErr).To(BeNil())
+			var clusterRules, namespaceRules []rbacv1.PolicyRule
+			for _, obj := range objs {
+				switch role := obj.(type) {
+				case *rbacv1.ClusterRole:
+					clusterRules = append(clusterRules, role.Rules...)
+				case *rbacv1.Role:
+					namespaceRules = append(namespaceRules, role.Rules...)
+				}
+			}
+
+			for _, perm := range plan.sortedPermissions() {
+				granted := grants(clusterRules, perm) || (perm.namespace == run.Namespace && grants(namespaceRules, perm))
+				Expect(granted).To(BeTrue(), "%s scope: %s/%s in namespace '%s' with verbs %v isn't granted to job",
+					scope, perm.apiGroup, perm.resource, perm.namespace, perm.verbs.List())
+			}
+		}
+	})
+
+	It("Should allow the access reviewed by namespace permissions check to the job, for both scopes", func() {
+		for _, scope := range []string{internal.NamespaceScope, internal.ClusterScope} {
+			run.Scope = scope
+			objs, err := run.JobResources(JobOptions{Image: testJobImage})
+			Expect(err).To(BeNil())
+
+			// the review is evaluated as the RBAC authorizer does, against the rules bound to the job service account
+			var clusterRules []rbacv1.PolicyRule
+			for _, obj := range objs {
+				if role, ok := obj.(*rbacv1.ClusterRole); ok {
+					clusterRules = append(clusterRules, role.Rules...)
+				}
+			}
+			for _, verb := range namespacePermissionVerbs {
+				allowed := false
+				for _, rule := range clusterRules {
+					groups, resources, verbs := sets.NewString(rule.APIGroups...), sets.NewString(rule.Resources...),
+						sets.NewString(rule.Verbs...)
+					allowed = allowed || ((groups.Has("") || groups.Has(rbacv1.APIGroupAll)) &&
+						(resources.Has("namespaces") || resources.Has(rbacv1.ResourceAll)) &&
+						(verbs.Has(verb) || verbs.Has(rbacv1.VerbAll)) && len(rule.ResourceNames) == 0)
+				}
+				Expect(allowed).To(BeTrue(), "%s scope: %s of namespaces isn't allowed to job", scope, verb)
+			}
+		}
+	})
+
+	It("Should render job resources as multi-document yaml", func() {
+		objs, err := run.JobResources(JobOptions{Image: testJobImage})
+		Expect(err).To(BeNil())
+		data, err := RenderJobResources(objs)
+		Expect(err).To(BeNil())
+
+		docs := strings.Split(string(data), "---\n")[1:]
+		Expect(docs).To(HaveLen(len(objs)))
+		Expect(docs[len(docs)-1]).To(ContainSubstring("kind: Job"))
+		Expect(docs[len(docs)-1]).To(ContainSubstring("apiVersion: batch/v1"))
+	})
+
+	It("Should read the report of preflight run from result ConfigMap", func() {
+		report := &RunReport{UID: "abcdef", Status: CheckStatusPass, StartTime: time.Now().UTC().Truncate(time.Second),
+			Checks: []CheckReport{{ID: CheckDNSResolution, Status: CheckStatusPass}}}
+		data, err := json.Marshal(report)
+		Expect(err).To(BeNil())
+
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: JobResultConfigMapName("tvk-preflight-abcdef")},
+			Data:       map[string]string{ResultReportKey: string(data)},
+		}
+		result, err := newJobResult(cm)
+		Expect(err).To(BeNil())
+		Expect(result.ConfigMap).To(Equal("tvk-preflight-abcdef-result"))
+		Expect(result.Report).To(Equal(report))
+
+		cm.Data[ResultReportKey] = "invalid"
+		_, err = newJobResult(cm)
+		Expect(err).ToNot(BeNil())
+	})
+})
@@ -0,0 +1,459 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	NetworkProbePodNamePrefix  = "network-probe-"
+	NetworkServerPodNamePrefix = "network-server-"
+	networkServerContainerName = "network-server"
+	networkProbeContainerName  = "network-probe"
+
+	// networkServerPort is the port on which the server pod serves HTTP, standing in for TVK control plane.
+	networkServerPort int32 = 8080
+	// tvkWebhookPort is the port on which the admission webhook server of TVK listens.
+	tvkWebhookPort int32 = 9443
+	dnsPort        int32 = 53
+
+	kubeSystemNamespace = "kube-system"
+	apiServerService    = "kubernetes"
+
+	// FlowPodToPod, FlowPodToKubeDNS, FlowPodToAPIServer and FlowAPIServerToWebhook are the network flows needed by TVK.
+	FlowPodToPod           = "pod-to-pod"
+	FlowPodToKubeDNS       = "pod-to-kube-dns"
+	FlowPodToAPIServer     = "pod-to-api-server"
+	FlowAPIServerToWebhook = "api-server-to-webhook"
+)
+
+// kubeDNSPodLabels are the labels of cluster DNS pods.
+var kubeDNSPodLabels = map[string]string{"k8s-app": "kube-dns"}
+
+// networkFlow is a network flow of TVK pods, the preflight pods stand in for them.
+type networkFlow struct {
+	name      string
+	direction networkingv1.PolicyType
+	// peerNamespace, peerNamespaceLabels and peerPodLabels describe the remote end of the flow if it's a pod,
+	// peerIPs if it isn't.
+	peerNamespace       string
+	peerNamespaceLabels map[string]string
+	peerPodLabels       map[string]string
+	peerIPs             []string
+	port                int32
+	protocol            corev1.Protocol
+	// purpose is the TVK traffic for which the flow is needed.
+	purpose string
+}
+
+// NetworkPolicyResult holds the NetworkPolicies of namespace and the outcome of each network flow needed by TVK.
+type NetworkPolicyResult struct {
+	Policies []string             `json:"policies,omitempty"`
+	Flows    []*NetworkFlowResult `json:"flows,omitempty"`
+}
+
+// NetworkFlowResult is the outcome of NetworkPolicies and of testing connectivity for a network flow.
+type NetworkFlowResult struct {
+	Name      string `json:"name"`
+	Direction string `json:"direction"`
+	Peer      string `json:"peer"`
+	Port      string `json:"port"`
+	// BlockedBy are the NetworkPolicies selecting preflight pods for the direction of flow, none of which allows it.
+	BlockedBy []string `json:"blockedBy,omitempty"`
+	// Reachable is the outcome of testing the flow from a preflight pod, nil if the flow isn't tested.
+	Reachable *bool  `json:"reachable,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// peer returns the remote end of flow in human-readable form.
+func (f *networkFlow) peer() string {
+	if f.peerPodLabels == nil {
+		return strings.Join(f.peerIPs, ",")
+	}
+	return fmt.Sprintf("pods %s in namespace %s", labels.Set(f.peerPodLabels).String(), f.peerNamespace)
+}
+
+// policyHasType returns whether the policy restricts traffic of the given direction.
+// Ingress is always restricted if policy types aren't given, egress only if it has egress rules.
+func policyHasType(policy *networkingv1.NetworkPolicy, direction networkingv1.PolicyType) bool {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return direction == networkingv1.PolicyTypeIngress || len(policy.Spec.Egress) != 0
+	}
+	for _, policyType := range policy.Spec.PolicyTypes {
+		if policyType == direction {
+			return true
+		}
+	}
+	return false
+}
+
+// ipBlockMatches returns whether any of the IPs is in the CIDR of block and none of its exceptions.
+func ipBlockMatches(block *networkingv1.IPBlock, ips []string) bool {
+	_, cidr, err := net.ParseCIDR(block.CIDR)
+	if err != nil {
+		return false
+	}
+	for _, ip := range ips {
+		parsed := net.ParseIP(ip)
+		if parsed == nil || !cidr.Contains(parsed) {
+			continue
+		}
+		excepted := false
+		for _, except := range block.Except {
+			if _, exceptCIDR, eErr := net.ParseCIDR(except); eErr == nil && exceptCIDR.Contains(parsed) {
+				excepted = true
+				break
+			}
+		}
+		if !excepted {
+			return true
+		}
+	}
+	return false
+}
+
+// selectorMatches returns whether the label selector matches the labels, an invalid selector matches nothing.
+func selectorMatches(selector *metav1.LabelSelector, set map[string]string) bool {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	return err == nil && s.Matches(labels.Set(set))
+}
+
+// peersMatch returns whether the remote end of flow is one of the peers of a policy rule. No peers match all.
+func peersMatch(peers []networkingv1.NetworkPolicyPeer, flow *networkFlow, policyNamespace string) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	for idx := range peers {
+		peer := &peers[idx]
+		if peer.IPBlock != nil {
+			if ipBlockMatches(peer.IPBlock, flow.peerIPs) {
+				return true
+			}
+			continue
+		}
+		if flow.peerPodLabels == nil {
+			continue
+		}
+		nsMatches := flow.peerNamespace == policyNamespace
+		if peer.NamespaceSelector != nil {
+			nsMatches = selectorMatches(peer.NamespaceSelector, flow.peerNamespaceLabels)
+		}
+		if nsMatches && (peer.PodSelector == nil || selectorMatches(peer.PodSelector, flow.peerPodLabels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// portsMatch returns whether the port of flow is one of the ports of a policy rule. No ports match all.
+// Named ports aren't resolved, and don't match.
+func portsMatch(ports []networkingv1.NetworkPolicyPort, flow *networkFlow) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for idx := range ports {
+		port := &ports[idx]
+		protocol := corev1.ProtocolTCP
+		if port.Protocol != nil {
+			protocol = *port.Protocol
+		}
+		if protocol != flow.protocol {
+			continue
+		}
+		if port.Port == nil {
+			return true
+		}
+		if port.Port.Type != intstr.Int {
+			continue
+		}
+		start, end := port.Port.IntVal, port.Port.IntVal
+		if port.EndPort != nil {
+			end = *port.EndPort
+		}
+		if flow.port >= start && flow.port <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// blockingPolicies returns the policies selecting pods with the labels for the direction of flow, if none of them
+// allows the flow. Nil is returned if the flow is allowed.
+func blockingPolicies(policies []networkingv1.NetworkPolicy, podLabels map[string]string, flow *networkFlow) []string {
+	var applicable []string
+	for idx := range policies {
+		policy := &policies[idx]
+		if !selectorMatches(&policy.Spec.PodSelector, podLabels) || !policyHasType(policy, flow.direction) {
+			continue
+		}
+		applicable = append(applicable, policy.GetName())
+
+		if flow.direction == networkingv1.PolicyTypeIngress {
+			for _, rule := range policy.Spec.Ingress {
+				if peersMatch(rule.From, flow, policy.GetNamespace()) && portsMatch(rule.Ports, flow) {
+					return nil
+				}
+			}
+			continue
+		}
+		for _, rule := range policy.Spec.Egress {
+			if peersMatch(rule.To, flow, policy.GetNamespace()) && portsMatch(rule.Ports, flow) {
+				return nil
+			}
+		}
+	}
+	return applicable
+}
+
+// networkFlows returns the network flows needed by TVK pods in namespace, for the given namespace labels, labels of
+// kube-system namespace, and endpoint IPs and port of API server. Flows to and from API server are omitted if its
+// endpoints aren't known.
+func networkFlows(namespace string, nsLabels, kubeSystemLabels, podLabels map[string]string, apiServerIPs []string,
+	apiServerPort int32) []*networkFlow {
+	flows := []*networkFlow{
+		{name: FlowPodToPod, direction: networkingv1.PolicyTypeEgress, peerNamespace: namespace, peerNamespaceLabels: nsLabels,
+			peerPodLabels: podLabels, port: networkServerPort, protocol: corev1.ProtocolTCP,
+			purpose: "datamover and control plane pods of TVK to connect to each other"},
+		{name: FlowPodToPod, direction: networkingv1.PolicyTypeIngress, peerNamespace: namespace, peerNamespaceLabels: nsLabels,
+			peerPodLabels: podLabels, port: networkServerPort, protocol: corev1.ProtocolTCP,
+			purpose: "datamover and control plane pods of TVK to connect to each other"},
+		{name: FlowPodToKubeDNS, direction: networkingv1.PolicyTypeEgress, peerNamespace: kubeSystemNamespace,
+			peerNamespaceLabels: kubeSystemLabels, peerPodLabels: kubeDNSPodLabels, port: dnsPort, protocol: corev1.ProtocolUDP,
+			purpose: "pods of TVK to resolve services and backup targets"},
+	}
+	if len(apiServerIPs) != 0 {
+		flows = append(flows,
+			&networkFlow{name: FlowPodToAPIServer, direction: networkingv1.PolicyTypeEgress, peerIPs: apiServerIPs,
+				port: apiServerPort, protocol: corev1.ProtocolTCP, purpose: "control plane of TVK to reach API server"},
+			&networkFlow{name: FlowAPIServerToWebhook, direction: networkingv1.PolicyTypeIngress, peerIPs: apiServerIPs,
+				port: tvkWebhookPort, protocol: corev1.ProtocolTCP, purpose: "API server to call admission webhooks of TVK"})
+	}
+	return flows
+}
+
+// networkPolicyFix returns the rule to add to a blocking policy for the flow to be allowed.
+func networkPolicyFix(flow *networkFlow, blockedBy []string) string {
+	direction := "egress to"
+	if flow.direction == networkingv1.PolicyTypeIngress {
+		direction = "ingress from"
+	}
+	return fmt.Sprintf("Allow %s %s on %s port %d in NetworkPolicies - %s, for %s", direction, flow.peer(),
+		flow.protocol, flow.port, strings.Join(blockedBy, ", "), flow.purpose)
+}
+
+// apiServerEndpoints returns the endpoint IPs and port of API server from the endpoint slices of kubernetes service.
+func apiServerEndpoints(slices []discoveryv1.EndpointSlice) (ips []string, port int32) {
+	for idx := range slices {
+		for _, p := range slices[idx].Ports {
+			if p.Port != nil {
+				port = *p.Port
+			}
+		}
+		for _, endpoint := range slices[idx].Endpoints {
+			ips = append(ips, endpoint.Addresses...)
+		}
+	}
+	return ips, port
+}
+
+// createNetworkServerPodSpec returns the pod serving HTTP, to which connectivity from the probe pod is tested.
+func createNetworkServerPodSpec(op *Run, uid string) *corev1.Pod {
+	nsName := types.NamespacedName{Name: NetworkServerPodNamePrefix + uid, Namespace: op.Namespace}
+	pod := getPodTemplate(nsName, uid, op)
+	pod.Spec.Containers = []corev1.Container{
+		{
+			Name:    networkServerContainerName,
+			Image:   op.preflightImage(PreflightImageBusybox),
+			Command: CommandBinSh,
+			Args: []string{fmt.Sprintf("mkdir -p /tmp/www && echo ok > /tmp/www/index.html && httpd -f -p %d -h /tmp/www",
+				networkServerPort)},
+			Ports:     []corev1.ContainerPort{{ContainerPort: networkServerPort, Protocol: corev1.ProtocolTCP}},
+			Resources: op.ResourceRequirements,
+			ReadinessProbe: &corev1.Probe{ProbeHandler: corev1.ProbeHandler{
+				TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt32(networkServerPort)}}},
+		},
+	}
+	return pod
+}
+
+// createNetworkProbePodSpec returns the pod from which connectivity of network flows is tested.
+func createNetworkProbePodSpec(op *Run, uid string) *corev1.Pod {
+	nsName := types.NamespacedName{Name: NetworkProbePodNamePrefix + uid, Namespace: op.Namespace}
+	pod := getPodTemplate(nsName, uid, op)
+	pod.Spec.Containers = []corev1.Container{
+		{
+			Name:            networkProbeContainerName,
+			Image:           op.preflightImage(PreflightImageCurl),
+			Command:         CommandSleep3600,
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			Resources:       op.ResourceRequirements,
+		},
+	}
+	return pod
+}
+
+// networkProbeCommands returns the commands executed in the probe pod to test the flows, by flow name.
+func networkProbeCommands(serverIP string) map[string][]string {
+	return map[string][]string{
+		FlowPodToPod: {"curl", "-s", "-o", "/dev/null", "-m", "10",
+			fmt.Sprintf("http://%s/", net.JoinHostPort(serverIP, fmt.Sprint(networkServerPort)))},
+		FlowPodToKubeDNS: {"nslookup", "kubernetes.default"},
+		FlowPodToAPIServer: {"sh", "-c",
+			"curl -sk -o /dev/null -m 10 https://${KUBERNETES_SERVICE_HOST}:${KUBERNETES_SERVICE_PORT}/version"},
+	}
+}
+
+// listNetworkPolicies returns the NetworkPolicies of namespace, nil with a warning if the user isn't permitted to.
+func listNetworkPolicies(ctx context.Context, namespace string, res *CheckResult) ([]networkingv1.NetworkPolicy, error) {
+	policies, err := kubeClient.ClientSet.NetworkingV1().NetworkPolicies(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if k8serrors.IsForbidden(err) {
+			res.Warn(fmt.Sprintf("Not permitted to list NetworkPolicies in namespace - %s, they aren't evaluated", namespace))
+			return nil, nil
+		}
+		return nil, err
+	}
+	return policies.Items, nil
+}
+
+// namespaceLabels returns the labels of namespace, or its well-known name label if it can't be read.
+func namespaceLabels(ctx context.Context, namespace string) map[string]string {
+	ns, err := kubeClient.ClientSet.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		return map[string]string{corev1.LabelMetadataName: namespace}
+	}
+	return ns.GetLabels()
+}
+
+// testNetworkFlows creates the server and probe pods, and tests connectivity of the flows from the probe pod.
+// The outcome of each test is recorded on the results of flows having the same name.
+func (o *Run) testNetworkFlows(ctx context.Context, results []*NetworkFlowResult, clients ServerClients) error {
+	serverPod, err := o.createPod(ctx, createNetworkServerPodSpec(o, resNameSuffix), clients.ClientSet)
+	if err != nil {
+		return err
+	}
+	probePod, err := o.createPod(ctx, createNetworkProbePodSpec(o, resNameSuffix), clients.ClientSet)
+	if err != nil {
+		return err
+	}
+
+	outcomes := map[string]error{}
+	for name, command := range networkProbeCommands(serverPod.Status.PodIP) {
+		execOp := o.dataExecOptions(ctx, probePod, command, clients)
+		execRes, eErr := execInPodWithResponse(ctx, &execOp, o.Logger)
+		if eErr != nil && execRes != nil && strings.TrimSpace(execRes.Stderr) != "" {
+			eErr = fmt.Errorf("%s :: %w", strings.TrimSpace(execRes.Stderr), eErr)
+		}
+		outcomes[name] = eErr
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	for _, result := range results {
+		outcome, tested := outcomes[result.Name]
+		if !tested {
+			continue
+		}
+		result.Reachable = ptr.To(outcome == nil)
+		if outcome != nil {
+			result.Error = outcome.Error()
+		}
+	}
+	return nil
+}
+
+func runNetworkPolicyCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	o.Logger.Infof("Checking NetworkPolicies and connectivity needed by TVK in namespace - %s", o.Namespace)
+
+	policies, err := listNetworkPolicies(ctx, o.Namespace, res)
+	if err != nil {
+		return err
+	}
+	if o.networkResult == nil {
+		o.networkResult = &NetworkPolicyResult{}
+	}
+	o.networkResult.Policies, o.networkResult.Flows = nil, nil
+	for idx := range policies {
+		o.networkResult.Policies = append(o.networkResult.Policies, policies[idx].GetName())
+	}
+
+	var apiServerIPs []string
+	var apiServerPort int32
+	slices, err := kubeClient.ClientSet.DiscoveryV1().EndpointSlices(metav1.NamespaceDefault).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.Set{discoveryv1.LabelServiceName: apiServerService}.String(),
+	})
+	if err != nil {
+		o.Logger.Warnf("Error listing endpoints of API server, flows to and from it aren't evaluated :: %s", err.Error())
+	} else {
+		apiServerIPs, apiServerPort = apiServerEndpoints(slices.Items)
+	}
+
+	podLabels := getPreflightResourceLabels(resNameSuffix)
+	flows := networkFlows(o.Namespace, namespaceLabels(ctx, o.Namespace), namespaceLabels(ctx, kubeSystemNamespace),
+		podLabels, apiServerIPs, apiServerPort)
+	for _, flow := range flows {
+		o.networkResult.Flows = append(o.networkResult.Flows, &NetworkFlowResult{
+			Name:      flow.name,
+			Direction: string(flow.direction),
+			Peer:      flow.peer(),
+			Port:      fmt.Sprintf("%s/%d", flow.protocol, flow.port),
+			BlockedBy: blockingPolicies(policies, podLabels, flow),
+		})
+	}
+
+	if err = o.testNetworkFlows(ctx, o.networkResult.Flows, kubeClient); err != nil {
+		return err
+	}
+
+	var failures []string
+	// pod-to-pod flow is evaluated for both directions but tested once, its unreachability is reported once
+	unreachable := sets.NewString()
+	for idx, result := range o.networkResult.Flows {
+		flow := flows[idx]
+		switch {
+		case result.Reachable != nil && !*result.Reachable && len(result.BlockedBy) != 0:
+			o.Logger.Errorf("%s %s %s is blocked by NetworkPolicies - %s", cross, result.Direction, result.Name,
+				strings.Join(result.BlockedBy, ", "))
+			res.Recommend(networkPolicyFix(flow, result.BlockedBy))
+			failures = append(failures, fmt.Sprintf("%s is blocked by NetworkPolicies - %s", result.Name,
+				strings.Join(result.BlockedBy, ", ")))
+		case result.Reachable != nil && !*result.Reachable:
+			if unreachable.Has(result.Name) {
+				continue
+			}
+			unreachable.Insert(result.Name)
+			o.Logger.Errorf("%s %s isn't reachable :: %s", cross, result.Name, result.Error)
+			res.Recommend(fmt.Sprintf("Verify cluster-wide network policies of the CNI and firewall rules allow %s, "+
+				"for %s", result.Name, flow.purpose))
+			failures = append(failures, fmt.Sprintf("%s isn't reachable, though NetworkPolicies of namespace allow it",
+				result.Name))
+		case len(result.BlockedBy) != 0 && result.Reachable == nil:
+			res.Warn(fmt.Sprintf("%s %s is blocked by NetworkPolicies - %s", result.Direction, result.Name,
+				strings.Join(result.BlockedBy, ", ")))
+			res.Recommend(networkPolicyFix(flow, result.BlockedBy))
+		case len(result.BlockedBy) != 0:
+			res.Warn(fmt.Sprintf("%s %s is reachable though NetworkPolicies - %s block it, the CNI may not enforce "+
+				"NetworkPolicies", result.Direction, result.Name, strings.Join(result.BlockedBy, ", ")))
+		default:
+			o.Logger.Infof("%s %s %s is allowed", check, result.Direction, result.Name)
+		}
+	}
+	if len(failures) != 0 {
+		return fmt.Errorf("network connectivity needed by TVK is blocked :: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package preflight
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("Network policy unit tests", func() {
+
+	const (
+		namespace   = "tvk"
+		apiServerIP = "10.0.0.10"
+	)
+
+	var (
+		podLabels        = getPreflightResourceLabels("abcdef")
+		nsLabels         = map[string]string{corev1.LabelMetadataName: namespace}
+		kubeSystemLabels = map[string]string{corev1.LabelMetadataName: kubeSystemNamespace}
+		flows            []*networkFlow
+	)
+
+	flowByName := func(name string, direction networkingv1.PolicyType) *networkFlow {
+		for _, flow := range flows {
+			if flow.name == name && flow.direction == direction {
+				return flow
+			}
+		}
+		return nil
+	}
+
+	newPolicy := func(name string, spec networkingv1.NetworkPolicySpec) networkingv1.NetworkPolicy {
+		return networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}, Spec: spec}
+	}
+
+	defaultDenyAll := newPolicy("default-deny-all", networkingv1.NetworkPolicySpec{
+		PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+	})
+
+	BeforeEach(func() {
+		flows = networkFlows(namespace, nsLabels, kubeSystemLabels, podLabels, []string{apiServerIP}, 6443)
+	})
+
+	Context("networkFlows func test-cases", func() {
+
+		It("Should return flows to and from API server only if its endpoints are known", func() {
+			Expect(flows).To(HaveLen(5))
+			Expect(networkFlows(namespace, nsLabels, kubeSystemLabels, podLabels, nil, 0)).To(HaveLen(3))
+			Expect(flowByName(FlowPodToAPIServer, networkingv1.PolicyTypeEgress).peer()).To(Equal(apiServerIP))
+			Expect(flowByName(FlowPodToKubeDNS, networkingv1.PolicyTypeEgress).peer()).To(Equal(
+				"pods k8s-app=kube-dns in namespace kube-system"))
+		})
+
+		It("Should return API server endpoints from its endpoint slices", func() {
+			slices := []discoveryv1.EndpointSlice{{
+				Endpoints: []discoveryv1.Endpoint{{Addresses: []string{apiServerIP}}, {Addresses: []string{"10.0.0.11"}}},
+				Ports:     []discoveryv1.EndpointPort{{Port: ptr.To(int32(6443))}},
+			}}
+			ips, port := apiServerEndpoints(slices)
+			Expect(ips).To(Equal([]string{apiServerIP, "10.0.0.11"}))
+			Expect(port).To(Equal(int32(6443)))
+		})
+	})
+
+	Context("blockingPolicies func test-cases", func() {
+
+		It("Should allow all flows when no policy selects preflight pods", func() {
+			policy := newPolicy("web-only", networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			})
+			for _, flow := range flows {
+				Expect(blockingPolicies([]networkingv1.NetworkPolicy{policy}, podLabels, flow)).To(BeNil())
+			}
+		})
+
+		It("Should block all flows by default deny policy", func() {
+			for _, flow := range flows {
+				Expect(blockingPolicies([]networkingv1.NetworkPolicy{defaultDenyAll}, podLabels, flow)).To(
+					Equal([]string{"default-deny-all"}))
+			}
+		})
+
+		It("Should restrict ingress only when policy types and egress rules aren't given", func() {
+			denyIngress := newPolicy("deny-ingress", networkingv1.NetworkPolicySpec{})
+			policies := []networkingv1.NetworkPolicy{denyIngress}
+			Expect(blockingPolicies(policies, podLabels, flowByName(FlowPodToPod, networkingv1.PolicyTypeIngress))).To(
+				Equal([]string{"deny-ingress"}))
+			Expect(blockingPolicies(policies, podLabels, flowByName(FlowPodToPod, networkingv1.PolicyTypeEgress))).To(BeNil())
+		})
+
+		It("Should allow flows matching namespace selector, pod selector, ports and IP blocks of rules", func() {
+			udp := corev1.ProtocolUDP
+			allowDNSAndAPIServer := newPolicy("allow-dns", networkingv1.NetworkPolicySpec{
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+				Egress: []networkingv1.NetworkPolicyEgressRule{
+					{
+						To: []networkingv1.NetworkPolicyPeer{{
+							NamespaceSelector: &metav1.LabelSelector{MatchLabels: kubeSystemLabels},
+							PodSelector:       &metav1.LabelSelector{MatchLabels: kubeDNSPodLabels},
+						}},
+						Ports: []networkingv1.NetworkPolicyPort{{Protocol: &udp, Port: ptr.To(intstr.FromInt32(dnsPort))}},
+					},
+					{
+						To: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/24",
+							Except: []string{"10.0.0.128/25"}}}},
+						Ports: []networkingv1.NetworkPolicyPort{{Port: ptr.To(intstr.FromInt32(6000)), EndPort: ptr.To(int32(7000))}},
+					},
+				},
+			})
+			policies := []networkingv1.NetworkPolicy{defaultDenyAll, allowDNSAndAPIServer}
+			Expect(blockingPolicies(policies, podLabels, flowByName(FlowPodToKubeDNS, networkingv1.PolicyTypeEgress))).To(BeNil())
+			Expect(blockingPolicies(policies, podLabels, flowByName(FlowPodToAPIServer, networkingv1.PolicyTypeEgress))).To(BeNil())
+			Expect(blockingPolicies(policies, podLabels, flowByName(FlowPodToPod, networkingv1.PolicyTypeEgress))).To(
+				Equal([]string{"default-deny-all", "allow-dns"}))
+
+			apiServerFlow := flowByName(FlowPodToAPIServer, networkingv1.PolicyTypeEgress)
+			apiServerFlow.peerIPs = []string{"10.0.0.200"}
+			Expect(blockingPolicies(policies, podLabels, apiServerFlow)).ToNot(BeNil())
+		})
+
+		It("Should match pod peers without namespace selector in the namespace of policy only", func() {
+			allowSameNamespace := newPolicy("allow-same-namespace", networkingv1.NetworkPolicySpec{
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{From: []networkingv1.NetworkPolicyPeer{
+					{PodSelector: &metav1.LabelSelector{}}}}},
+			})
+			policies := []networkingv1.NetworkPolicy{allowSameNamespace}
+			Expect(blockingPolicies(policies, podLabels, flowByName(FlowPodToPod, networkingv1.PolicyTypeIngress))).To(BeNil())
+			Expect(blockingPolicies(policies, podLabels, flowByName(FlowAPIServerToWebhook, networkingv1.PolicyTypeIngress))).To(
+				Equal([]string{"allow-same-namespace"}))
+		})
+	})
+
+	Context("networkPolicyFix func test-cases", func() {
+
+		It("Should recommend the rule allowing the flow", func() {
+			fix := networkPolicyFix(flowByName(FlowAPIServerToWebhook, networkingv1.PolicyTypeIngress), []string{"deny-all"})
+			Expect(fix).To(Equal("Allow ingress from 10.0.0.10 on TCP port 9443 in NetworkPolicies - deny-all, " +
+				"for API server to call admission webhooks of TVK"))
+		})
+	})
+})
@@ -0,0 +1,309 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	osLinux   = "linux"
+	osWindows = "windows"
+)
+
+// TVKDataMoverFootprint is the approximate resources requested by a TVK data mover pod, one of which is created for
+// each PVC backed up or restored.
+var TVKDataMoverFootprint = corev1.ResourceList{
+	corev1.ResourceCPU:    resource.MustParse("100m"),
+	corev1.ResourceMemory: resource.MustParse("800Mi"),
+}
+
+// nodeSelectorOperators maps the operators of node selector requirements to the ones of label selector.
+var nodeSelectorOperators = map[corev1.NodeSelectorOperator]selection.Operator{
+	corev1.NodeSelectorOpIn:           selection.In,
+	corev1.NodeSelectorOpNotIn:        selection.NotIn,
+	corev1.NodeSelectorOpExists:       selection.Exists,
+	corev1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	corev1.NodeSelectorOpGt:           selection.GreaterThan,
+	corev1.NodeSelectorOpLt:           selection.LessThan,
+}
+
+// NodeInventoryResult is the inventory of nodes and their capacity for preflight and TVK pods.
+type NodeInventoryResult struct {
+	Nodes            []*NodeInventory `json:"nodes,omitempty"`
+	Architectures    []string         `json:"architectures,omitempty"`
+	OperatingSystems []string         `json:"operatingSystems,omitempty"`
+	// MatchingNodes is the number of schedulable nodes satisfying the pod scheduling options of preflight.
+	MatchingNodes int `json:"matchingNodes"`
+	// Allocatable is the sum of allocatable resources of matching nodes.
+	Allocatable map[string]string `json:"allocatable,omitempty"`
+}
+
+// NodeInventory is the architecture, OS and allocatable resources of a node.
+type NodeInventory struct {
+	Name             string `json:"name"`
+	Architecture     string `json:"architecture"`
+	OS               string `json:"os"`
+	CPU              string `json:"cpu"`
+	Memory           string `json:"memory"`
+	EphemeralStorage string `json:"ephemeralStorage,omitempty"`
+	Schedulable      bool   `json:"schedulable"`
+	// Matches is set if preflight pods can be scheduled on the node, Reason holds why they can't otherwise.
+	Matches bool   `json:"matches"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// nodeArchitecture returns the architecture of node from its well-known label, or from node info.
+func nodeArchitecture(node *corev1.Node) string {
+	if arch, ok := node.Labels[corev1.LabelArchStable]; ok {
+		return arch
+	}
+	return node.Status.NodeInfo.Architecture
+}
+
+// nodeOS returns the operating system of node from its well-known label, or from node info.
+func nodeOS(node *corev1.Node) string {
+	if os, ok := node.Labels[corev1.LabelOSStable]; ok {
+		return os
+	}
+	return node.Status.NodeInfo.OperatingSystem
+}
+
+// nodeSelectorTermMatches returns whether the node satisfies all requirements of node selector term.
+// A term without requirements matches no node.
+func nodeSelectorTermMatches(node *corev1.Node, term *corev1.NodeSelectorTerm) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+	for _, expr := range term.MatchExpressions {
+		req, err := labels.NewRequirement(expr.Key, nodeSelectorOperators[expr.Operator], expr.Values)
+		if err != nil || !req.Matches(labels.Set(node.Labels)) {
+			return false
+		}
+	}
+	for _, field := range term.MatchFields {
+		// metadata.name is the only field supported by node affinity
+		req, err := labels.NewRequirement(field.Key, nodeSelectorOperators[field.Operator], field.Values)
+		if err != nil || !req.Matches(labels.Set{"metadata.name": node.GetName()}) {
+			return false
+		}
+	}
+	return true
+}
+
+// untoleratedTaint returns the first taint of node preventing scheduling which isn't tolerated, nil if all are.
+func untoleratedTaint(node *corev1.Node, tolerations []corev1.Toleration) *corev1.Taint {
+	for idx := range node.Spec.Taints {
+		taint := &node.Spec.Taints[idx]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for tIdx := range tolerations {
+			if tolerations[tIdx].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return taint
+		}
+	}
+	return nil
+}
+
+// schedulingMismatch returns why pods with the scheduling options can't be scheduled on the node, empty if they can.
+func schedulingMismatch(node *corev1.Node, ops podSchedulingOptions) string {
+	if !isNodeSchedulable(node) {
+		return "node is not ready or is cordoned"
+	}
+	if os := nodeOS(node); os != "" && os != osLinux {
+		return fmt.Sprintf("preflight pods run on linux nodes only, node OS is %s", os)
+	}
+	if !labels.SelectorFromSet(ops.NodeSelector).Matches(labels.Set(node.Labels)) {
+		return "node doesn't match node selector"
+	}
+	if ops.Affinity != nil && ops.Affinity.NodeAffinity != nil &&
+		ops.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		terms := ops.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		matched := false
+		for idx := range terms {
+			if nodeSelectorTermMatches(node, &terms[idx]) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return "node doesn't match required node affinity"
+		}
+	}
+	if taint := untoleratedTaint(node, ops.Tolerations); taint != nil {
+		return fmt.Sprintf("taint %s isn't tolerated", taint.ToString())
+	}
+	return ""
+}
+
+// newNodeInventory returns the inventory of nodes, evaluated against the pod scheduling options of preflight.
+func newNodeInventory(nodes []corev1.Node, ops podSchedulingOptions) *NodeInventoryResult {
+	var (
+		result      = &NodeInventoryResult{}
+		archs       = sets.NewString()
+		oses        = sets.NewString()
+		allocatable = corev1.ResourceList{}
+	)
+	for idx := range nodes {
+		node := &nodes[idx]
+		inventory := &NodeInventory{
+			Name:         node.GetName(),
+			Architecture: nodeArchitecture(node),
+			OS:           nodeOS(node),
+			CPU:          node.Status.Allocatable.Cpu().String(),
+			Memory:       node.Status.Allocatable.Memory().String(),
+			Schedulable:  isNodeSchedulable(node),
+			Reason:       schedulingMismatch(node, ops),
+		}
+		if ephemeral, ok := node.Status.Allocatable[corev1.ResourceEphemeralStorage]; ok {
+			inventory.EphemeralStorage = ephemeral.String()
+		}
+		inventory.Matches = inventory.Reason == ""
+		if inventory.Architecture != "" {
+			archs.Insert(inventory.Architecture)
+		}
+		if inventory.OS != "" {
+			oses.Insert(inventory.OS)
+		}
+		if inventory.Matches {
+			result.MatchingNodes++
+			for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage} {
+				if value, ok := node.Status.Allocatable[name]; ok {
+					sum := allocatable[name]
+					sum.Add(value)
+					allocatable[name] = sum
+				}
+			}
+		}
+		result.Nodes = append(result.Nodes, inventory)
+	}
+	sort.Slice(result.Nodes, func(i, j int) bool { return result.Nodes[i].Name < result.Nodes[j].Name })
+	result.Architectures, result.OperatingSystems = archs.List(), oses.List()
+	if len(allocatable) != 0 {
+		result.Allocatable = make(map[string]string, len(allocatable))
+		for name, value := range allocatable {
+			result.Allocatable[string(name)] = value.String()
+		}
+	}
+	return result
+}
+
+// capacityShortfalls returns the resources of which the matching nodes have less allocatable than requested by TVK
+// control plane and a data mover pod, or a shortfall if none of them can fit a data mover pod.
+func capacityShortfalls(nodes []corev1.Node, inventory *NodeInventoryResult) []string {
+	matching := sets.NewString()
+	for _, inv := range inventory.Nodes {
+		if inv.Matches {
+			matching.Insert(inv.Name)
+		}
+	}
+	if matching.Len() == 0 {
+		return nil
+	}
+
+	var (
+		shortfalls []string
+		fitsMover  bool
+		total      = corev1.ResourceList{}
+	)
+	for idx := range nodes {
+		if !matching.Has(nodes[idx].GetName()) {
+			continue
+		}
+		fitsNode := true
+		for name, moverValue := range TVKDataMoverFootprint {
+			value := nodes[idx].Status.Allocatable[name]
+			sum := total[name]
+			sum.Add(value)
+			total[name] = sum
+			fitsNode = fitsNode && value.Cmp(moverValue) >= 0
+		}
+		fitsMover = fitsMover || fitsNode
+	}
+
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		needed := TVKControlPlaneFootprint[corev1.ResourceName(corev1.DefaultResourceRequestsPrefix+string(name))]
+		needed.Add(TVKDataMoverFootprint[name])
+		if totalValue := total[name]; totalValue.Cmp(needed) < 0 {
+			shortfalls = append(shortfalls, fmt.Sprintf("allocatable %s %s of matching nodes is less than %s needed "+
+				"by TVK control plane and a data mover pod", name, totalValue.String(), needed.String()))
+		}
+	}
+	if !fitsMover {
+		moverCPU, moverMemory := TVKDataMoverFootprint[corev1.ResourceCPU], TVKDataMoverFootprint[corev1.ResourceMemory]
+		shortfalls = append(shortfalls, fmt.Sprintf("no matching node has allocatable %s cpu and %s memory "+
+			"needed by a data mover pod", moverCPU.String(), moverMemory.String()))
+	}
+	return shortfalls
+}
+
+func runNodeInventoryCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	o.Logger.Infoln("Checking nodes matching the pod scheduling options of preflight pods")
+
+	nodes, err := kubeClient.ClientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if k8serrors.IsForbidden(err) {
+			res.Warn("Not permitted to list nodes, nodes matching the pod scheduling options aren't verified")
+			return nil
+		}
+		return err
+	}
+
+	inventory := newNodeInventory(nodes.Items, o.PodSchedOps)
+	// the inventory is shared with the run options of preflight run, so that it's included in the report
+	if o.nodeInventory == nil {
+		o.nodeInventory = &NodeInventoryResult{}
+	}
+	*o.nodeInventory = *inventory
+	for _, node := range inventory.Nodes {
+		o.Logger.Infof("Node - %s (%s/%s) allocatable cpu: %s, memory: %s, ephemeral-storage: %s", node.Name, node.OS,
+			node.Architecture, node.CPU, node.Memory, node.EphemeralStorage)
+		if !node.Matches {
+			o.Logger.Infof("Preflight pods can't be scheduled on node - %s :: %s", node.Name, node.Reason)
+		}
+	}
+	if len(inventory.Architectures) > 1 {
+		res.Warn(fmt.Sprintf("Cluster has nodes of multiple architectures - [%s], images used by TVK and preflight must "+
+			"be available for all of them", strings.Join(inventory.Architectures, ", ")))
+	}
+	if sets.NewString(inventory.OperatingSystems...).Has(osWindows) {
+		res.Warn("Cluster has Windows nodes, TVK and preflight pods run on linux nodes only")
+		if _, ok := o.PodSchedOps.NodeSelector[corev1.LabelOSStable]; !ok {
+			res.Recommend(fmt.Sprintf("Set node selector %s=%s for pods of TVK", corev1.LabelOSStable, osLinux))
+		}
+	}
+
+	if inventory.MatchingNodes == 0 {
+		res.Recommend("Verify the node selector, affinity and tolerations of preflight pods match at least one " +
+			"ready and schedulable linux node")
+		return fmt.Errorf("no schedulable node satisfies the node selector, affinity and tolerations of preflight pods, " +
+			"its pods would remain Pending")
+	}
+	o.Logger.Infof("%s %d of %d nodes satisfy the pod scheduling options of preflight pods", check,
+		inventory.MatchingNodes, len(inventory.Nodes))
+
+	// preflight pods may still be scheduled when capacity for TVK is short, so the checks depending on this one
+	// aren't skipped for it
+	for _, shortfall := range capacityShortfalls(nodes.Items, inventory) {
+		res.Warn(fmt.Sprintf("Nodes may not have capacity for TVK :: %s", shortfall))
+		res.Recommend("Add nodes, or nodes with more allocatable resources, matching the pod scheduling options")
+	}
+
+	return nil
+}
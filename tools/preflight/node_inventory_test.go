@@ -0,0 +1,114 @@
+package preflight
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Node inventory unit tests", func() {
+
+	newNode := func(name, arch, os, cpu, memory string) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{
+				corev1.LabelArchStable: arch, corev1.LabelOSStable: os, "pool": "backup"}},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				},
+			},
+		}
+	}
+
+	var node corev1.Node
+
+	BeforeEach(func() {
+		node = newNode("node-1", "amd64", osLinux, "2", "4Gi")
+	})
+
+	Context("schedulingMismatch func test-cases", func() {
+
+		It("Should match a ready linux node when no scheduling options are set", func() {
+			Expect(schedulingMismatch(&node, podSchedulingOptions{})).To(BeEmpty())
+		})
+
+		It("Should not match a node which is cordoned or a windows node", func() {
+			node.Spec.Unschedulable = true
+			Expect(schedulingMismatch(&node, podSchedulingOptions{})).To(ContainSubstring("not ready or is cordoned"))
+
+			node = newNode("node-2", "amd64", osWindows, "2", "4Gi")
+			Expect(schedulingMismatch(&node, podSchedulingOptions{})).To(ContainSubstring("node OS is windows"))
+		})
+
+		It("Should match node selector and required node affinity", func() {
+			Expect(schedulingMismatch(&node, podSchedulingOptions{NodeSelector: map[string]string{"pool": "apps"}})).To(
+				Equal("node doesn't match node selector"))
+
+			affinity := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{"arm64"}}}},
+				}}}}
+			Expect(schedulingMismatch(&node, podSchedulingOptions{Affinity: affinity})).To(
+				Equal("node doesn't match required node affinity"))
+
+			terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+			terms.NodeSelectorTerms = append(terms.NodeSelectorTerms, corev1.NodeSelectorTerm{
+				MatchFields: []corev1.NodeSelectorRequirement{
+					{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"node-1"}}}})
+			Expect(schedulingMismatch(&node, podSchedulingOptions{Affinity: affinity})).To(BeEmpty())
+		})
+
+		It("Should not match a node with taints which aren't tolerated", func() {
+			node.Spec.Taints = []corev1.Taint{
+				{Key: "dedicated", Value: "db", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "spot", Effect: corev1.TaintEffectPreferNoSchedule},
+			}
+			Expect(schedulingMismatch(&node, podSchedulingOptions{})).To(Equal("taint dedicated=db:NoSchedule isn't tolerated"))
+
+			tolerations := []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "db"}}
+			Expect(schedulingMismatch(&node, podSchedulingOptions{Tolerations: tolerations})).To(BeEmpty())
+		})
+	})
+
+	Context("newNodeInventory func test-cases", func() {
+
+		It("Should report architectures, OSes and allocatable resources of matching nodes", func() {
+			nodes := []corev1.Node{
+				newNode("node-2", "arm64", osLinux, "4", "8Gi"),
+				newNode("node-3", "amd64", osWindows, "8", "16Gi"),
+				node,
+			}
+			inventory := newNodeInventory(nodes, podSchedulingOptions{})
+			Expect(inventory.Architectures).To(Equal([]string{"amd64", "arm64"}))
+			Expect(inventory.OperatingSystems).To(Equal([]string{osLinux, osWindows}))
+			Expect(inventory.MatchingNodes).To(Equal(2))
+			Expect(inventory.Allocatable).To(Equal(map[string]string{"cpu": "6", "memory": "12Gi"}))
+			Expect(inventory.Nodes).To(HaveLen(3))
+			Expect(inventory.Nodes[0].Name).To(Equal("node-1"))
+			Expect(inventory.Nodes[2].Matches).To(BeFalse())
+			Expect(inventory.Nodes[2].Reason).ToNot(BeEmpty())
+		})
+	})
+
+	Context("capacityShortfalls func test-cases", func() {
+
+		It("Should not report shortfalls when matching nodes fit TVK control plane and a data mover pod", func() {
+			nodes := []corev1.Node{node}
+			Expect(capacityShortfalls(nodes, newNodeInventory(nodes, podSchedulingOptions{}))).To(BeEmpty())
+		})
+
+		It("Should report shortfalls when matching nodes don't have capacity for TVK", func() {
+			nodes := []corev1.Node{newNode("node-small", "amd64", osLinux, "500m", "512Mi"), newNode("node-large", "amd64",
+				osWindows, "16", "64Gi")}
+			shortfalls := capacityShortfalls(nodes, newNodeInventory(nodes, podSchedulingOptions{}))
+			Expect(shortfalls).To(HaveLen(3))
+			Expect(shortfalls[0]).To(ContainSubstring("allocatable cpu 500m of matching nodes is less than 1100m"))
+			Expect(shortfalls[2]).To(ContainSubstring("no matching node has allocatable 100m cpu and 800Mi memory"))
+		})
+	})
+})
@@ -0,0 +1,460 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+const (
+	// DefaultTVKServiceAccount is the service account of TVK pods installed with default values, whose SCCs are
+	// evaluated when service account isn't given.
+	DefaultTVKServiceAccount = "k8s-triliovault"
+
+	sccGroup    = "security.openshift.io"
+	sccResource = "securitycontextconstraints"
+	sccKind     = "SecurityContextConstraints"
+	sccUseVerb  = "use"
+
+	sccRunAsAny           = "RunAsAny"
+	sccMustRunAs          = "MustRunAs"
+	sccMustRunAsRange     = "MustRunAsRange"
+	sccMustRunAsNonRoot   = "MustRunAsNonRoot"
+	sccAllCapabilities    = "*"
+	sccAllVolumes         = "*"
+	sccUIDRangeAnnotation = "openshift.io/sa.scc.uid-range"
+)
+
+// points of the settings of SCC, as given by OpenShift to order SCCs of the same priority from the most restrictive,
+// i.e having the lowest points, to the least restrictive one.
+const (
+	sccPrivilegedPoints       = 1000000
+	sccHostPortsPoints        = 400000
+	sccHostNetworkPoints      = 200000
+	sccHostVolumePoints       = 100000
+	sccHostPIDPoints          = 100000
+	sccHostIPCPoints          = 100000
+	sccNonTrivialVolumePoints = 50000
+	sccRunAsAnyUserPoints     = 40000
+	sccRunAsNonRootPoints     = 30000
+	sccRunAsRangePoints       = 20000
+	sccRunAsUserPoints        = 10000
+	sccCapDefaultPoints       = 5000
+	sccCapAllowAllPoints      = 4000
+	sccCapAddOnePoints        = 300
+	sccCapAllowOnePoints      = 10
+	sccCapDropAllPoints       = -3
+	sccCapDropOnePoints       = -1
+)
+
+// sccTrivialVolumes are the volume types which don't add points to the SCC allowing them.
+var sccTrivialVolumes = map[string]bool{"configMap": true, "downwardAPI": true, "emptyDir": true, "projected": true,
+	"secret": true, "none": true}
+
+// securityContextConstraints holds the fields of OpenShift SCC with which the pods of capability cases are admitted.
+type securityContextConstraints struct {
+	metav1.ObjectMeta        `json:"metadata,omitempty"`
+	Priority                 *int32   `json:"priority,omitempty"`
+	AllowPrivilegedContainer bool     `json:"allowPrivilegedContainer"`
+	AllowPrivilegeEscalation *bool    `json:"allowPrivilegeEscalation,omitempty"`
+	DefaultAddCapabilities   []string `json:"defaultAddCapabilities,omitempty"`
+	AllowedCapabilities      []string `json:"allowedCapabilities,omitempty"`
+	RequiredDropCapabilities []string `json:"requiredDropCapabilities,omitempty"`
+	AllowHostNetwork         bool     `json:"allowHostNetwork"`
+	AllowHostPorts           bool     `json:"allowHostPorts"`
+	AllowHostPID             bool     `json:"allowHostPID"`
+	AllowHostIPC             bool     `json:"allowHostIPC"`
+	Volumes                  []string `json:"volumes,omitempty"`
+	SELinuxContext           struct {
+		Type string `json:"type,omitempty"`
+	} `json:"seLinuxContext,omitempty"`
+	RunAsUser struct {
+		Type        string `json:"type,omitempty"`
+		UID         *int64 `json:"uid,omitempty"`
+		UIDRangeMin *int64 `json:"uidRangeMin,omitempty"`
+		UIDRangeMax *int64 `json:"uidRangeMax,omitempty"`
+	} `json:"runAsUser,omitempty"`
+	Users  []string `json:"users,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// OpenShiftSCCResult is the outcome of SCC admission of the pod capability cases for the TVK service account.
+type OpenShiftSCCResult struct {
+	ServiceAccount string `json:"serviceAccount"`
+	// UsableSCCs are the SCCs the service account can use, in the order they are evaluated by OpenShift.
+	UsableSCCs []string         `json:"usableSCCs,omitempty"`
+	Cases      []*SCCCaseResult `json:"cases,omitempty"`
+}
+
+// SCCCaseResult is the outcome of SCC admission of a pod capability case.
+type SCCCaseResult struct {
+	Case       string      `json:"case"`
+	Status     CheckStatus `json:"status"`
+	AdmittedBy string      `json:"admittedBy,omitempty"`
+	// Reasons are the reasons the case isn't admitted by each usable SCC, if none admits it.
+	Reasons []string `json:"reasons,omitempty"`
+	Fix     string   `json:"fix,omitempty"`
+}
+
+// capabilityCaseString describes the security context of pod capability validation case.
+func capabilityCaseString(c capability) string {
+	return fmt.Sprintf("userID: %d, privileged: %t, allowPrivilegeEscalation: %t", c.userID, c.privileged,
+		c.allowPrivilegeEscalation)
+}
+
+// uidRange is the range of user IDs allowed by MustRunAsRange strategy, [min, max].
+type uidRange struct {
+	min, max int64
+}
+
+// parseUIDRange parses the UID range of namespace annotation in 'start/size' format.
+func parseUIDRange(annotation string) (*uidRange, error) {
+	parts := strings.Split(annotation, "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid uid range - %s", annotation)
+	}
+	start, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uid range - %s :: %s", annotation, err.Error())
+	}
+	size, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || size <= 0 {
+		return nil, fmt.Errorf("invalid uid range - %s", annotation)
+	}
+	return &uidRange{min: start, max: start + size - 1}, nil
+}
+
+// sccAdmissionViolations returns the reasons the SCC doesn't admit the pod of capability case. nsRange is the UID
+// range of namespace used by MustRunAsRange strategy when the SCC doesn't set one.
+func sccAdmissionViolations(scc *securityContextConstraints, c capability, nsRange *uidRange) []string {
+	var violations []string
+	if c.privileged && !scc.AllowPrivilegedContainer {
+		violations = append(violations, "privileged containers aren't allowed")
+	}
+	if c.allowPrivilegeEscalation && scc.AllowPrivilegeEscalation != nil && !*scc.AllowPrivilegeEscalation {
+		violations = append(violations, "privilege escalation isn't allowed")
+	}
+
+	allowedCaps := make(map[string]bool)
+	for _, capName := range scc.AllowedCapabilities {
+		allowedCaps[capName] = true
+	}
+	for _, capName := range scc.DefaultAddCapabilities {
+		allowedCaps[capName] = true
+	}
+	requiredDrop := make(map[string]bool)
+	for _, capName := range scc.RequiredDropCapabilities {
+		requiredDrop[capName] = true
+	}
+	var disallowed []string
+	for _, capName := range podCapabilities {
+		name := string(capName)
+		if requiredDrop[name] || requiredDrop["ALL"] || (!allowedCaps[name] && !allowedCaps[sccAllCapabilities]) {
+			disallowed = append(disallowed, name)
+		}
+	}
+	if len(disallowed) != 0 {
+		violations = append(violations, fmt.Sprintf("capabilities - %s aren't allowed", strings.Join(disallowed, ", ")))
+	}
+
+	switch scc.RunAsUser.Type {
+	case sccMustRunAsNonRoot:
+		if c.userID == 0 {
+			violations = append(violations, "running as root isn't allowed")
+		}
+	case sccMustRunAs:
+		if scc.RunAsUser.UID != nil && *scc.RunAsUser.UID != c.userID {
+			violations = append(violations, fmt.Sprintf("user ID must be %d", *scc.RunAsUser.UID))
+		}
+	case sccMustRunAsRange:
+		allowed := nsRange
+		if scc.RunAsUser.UIDRangeMin != nil && scc.RunAsUser.UIDRangeMax != nil {
+			allowed = &uidRange{min: *scc.RunAsUser.UIDRangeMin, max: *scc.RunAsUser.UIDRangeMax}
+		}
+		if allowed == nil {
+			violations = append(violations, "user ID range of namespace isn't known")
+		} else if c.userID < allowed.min || c.userID > allowed.max {
+			violations = append(violations, fmt.Sprintf("user ID must be in range %d-%d", allowed.min, allowed.max))
+		}
+	}
+
+	return violations
+}
+
+// sccRestrictionPoints returns the points of the SCC as OpenShift computes them to rank how restrictive the SCC is, the
+// lower the points the more restrictive the SCC is.
+func sccRestrictionPoints(scc *securityContextConstraints) int {
+	points := 0
+	if scc.AllowPrivilegedContainer {
+		points += sccPrivilegedPoints
+	}
+	points += sccVolumePoints(scc.Volumes)
+	if scc.AllowHostNetwork {
+		points += sccHostNetworkPoints
+	}
+	if scc.AllowHostPorts {
+		points += sccHostPortsPoints
+	}
+	if scc.AllowHostPID {
+		points += sccHostPIDPoints
+	}
+	if scc.AllowHostIPC {
+		points += sccHostIPCPoints
+	}
+
+	switch scc.SELinuxContext.Type {
+	case sccRunAsAny:
+		points += sccRunAsAnyUserPoints
+	case sccMustRunAs:
+		points += sccRunAsUserPoints
+	}
+	switch scc.RunAsUser.Type {
+	case sccRunAsAny:
+		points += sccRunAsAnyUserPoints
+	case sccMustRunAsNonRoot:
+		points += sccRunAsNonRootPoints
+	case sccMustRunAsRange:
+		points += sccRunAsRangePoints
+	case sccMustRunAs:
+		points += sccRunAsUserPoints
+	}
+
+	return points + sccCapabilityPoints(scc)
+}
+
+// sccVolumePoints returns the points of the volume types allowed by SCC, host path volumes outweigh the other
+// non-trivial volumes.
+func sccVolumePoints(volumes []string) int {
+	nonTrivial := false
+	for _, volume := range volumes {
+		switch {
+		case volume == "hostPath" || volume == sccAllVolumes:
+			return sccHostVolumePoints
+		case !sccTrivialVolumes[volume]:
+			nonTrivial = true
+		}
+	}
+	if nonTrivial {
+		return sccNonTrivialVolumePoints
+	}
+	return 0
+}
+
+// sccCapabilityPoints returns the points of the capabilities added, allowed and required to be dropped by SCC.
+func sccCapabilityPoints(scc *securityContextConstraints) int {
+	hasCapability := func(capabilities []string, names ...string) bool {
+		for _, capName := range capabilities {
+			for _, name := range names {
+				if capName == name {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	points := sccCapDefaultPoints + sccCapAddOnePoints*len(scc.DefaultAddCapabilities)
+	if hasCapability(scc.AllowedCapabilities, sccAllCapabilities, "ALL") {
+		points += sccCapAllowAllPoints
+	} else {
+		points += sccCapAllowOnePoints * len(scc.AllowedCapabilities)
+	}
+	if hasCapability(scc.RequiredDropCapabilities, "ALL") {
+		points += sccCapDropAllPoints
+	} else {
+		points += sccCapDropOnePoints * len(scc.RequiredDropCapabilities)
+	}
+	return points
+}
+
+// sortSCCs sorts the SCCs in the order OpenShift evaluates them, by descending priority, then from the most
+// restrictive one to the least restrictive one, and then by name.
+func sortSCCs(sccs []*securityContextConstraints) {
+	priority := func(scc *securityContextConstraints) int32 {
+		if scc.Priority == nil {
+			return 0
+		}
+		return *scc.Priority
+	}
+	sort.SliceStable(sccs, func(i, j int) bool {
+		if priority(sccs[i]) != priority(sccs[j]) {
+			return priority(sccs[i]) > priority(sccs[j])
+		}
+		if iPoints, jPoints := sccRestrictionPoints(sccs[i]), sccRestrictionPoints(sccs[j]); iPoints != jPoints {
+			return iPoints < jPoints
+		}
+		return sccs[i].GetName() < sccs[j].GetName()
+	})
+}
+
+// evaluateSCCCase returns the outcome of SCC admission of the capability case using the usable SCCs, suggesting
+// the least permissive one of all SCCs admitting it if none of the usable SCCs does.
+func evaluateSCCCase(c capability, usable, all []*securityContextConstraints, nsRange *uidRange,
+	serviceAccount, namespace string) *SCCCaseResult {
+	result := &SCCCaseResult{Case: capabilityCaseString(c), Status: CheckStatusFail}
+	for _, scc := range usable {
+		violations := sccAdmissionViolations(scc, c, nsRange)
+		if len(violations) == 0 {
+			result.Status, result.AdmittedBy, result.Reasons = CheckStatusPass, scc.GetName(), nil
+			return result
+		}
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%s :: %s", scc.GetName(), strings.Join(violations, "; ")))
+	}
+
+	var suggested *securityContextConstraints
+	for _, scc := range all {
+		if len(sccAdmissionViolations(scc, c, nsRange)) != 0 {
+			continue
+		}
+		if suggested == nil || sccRestrictionPoints(scc) < sccRestrictionPoints(suggested) {
+			suggested = scc
+		}
+	}
+	if suggested != nil {
+		result.Fix = fmt.Sprintf("oc adm policy add-scc-to-user %s -z %s -n %s", suggested.GetName(), serviceAccount, namespace)
+	}
+	return result
+}
+
+// tvkServiceAccount returns the service account whose SCCs are evaluated.
+func (o *Run) tvkServiceAccount() string {
+	if o.ServiceAccountName != "" {
+		return o.ServiceAccountName
+	}
+	return DefaultTVKServiceAccount
+}
+
+// listSCCs returns the SCCs of the cluster.
+func listSCCs(ctx context.Context, clients ServerClients) ([]*securityContextConstraints, error) {
+	sccList := unstructured.UnstructuredList{}
+	sccList.SetGroupVersionKind(schema.GroupVersionKind{Group: sccGroup, Version: "v1", Kind: sccKind})
+	if err := clients.RuntimeClient.List(ctx, &sccList); err != nil {
+		return nil, err
+	}
+
+	sccs := make([]*securityContextConstraints, 0, len(sccList.Items))
+	for idx := range sccList.Items {
+		scc := &securityContextConstraints{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(sccList.Items[idx].Object, scc); err != nil {
+			return nil, fmt.Errorf("error parsing SCC - %s :: %w", sccList.Items[idx].GetName(), err)
+		}
+		sccs = append(sccs, scc)
+	}
+	sortSCCs(sccs)
+	return sccs, nil
+}
+
+// usableSCCs returns the SCCs which the service account can use, either through RBAC on 'use' verb or by being
+// listed in users or groups of the SCC.
+func (o *Run) usableSCCs(ctx context.Context, sccs []*securityContextConstraints, review accessReviewer,
+	serviceAccount string) ([]*securityContextConstraints, error) {
+	user := serviceAccountUserPrefix + o.Namespace + ":" + serviceAccount
+	groups := map[string]bool{serviceAccountsGroup: true, serviceAccountsGroup + ":" + o.Namespace: true,
+		"system:authenticated": true}
+
+	var usable []*securityContextConstraints
+	for _, scc := range sccs {
+		listed := false
+		for _, u := range scc.Users {
+			listed = listed || u == user
+		}
+		for _, g := range scc.Groups {
+			listed = listed || groups[g]
+		}
+		if !listed {
+			allowed, _, err := review(ctx, &authorizationv1.ResourceAttributes{Namespace: o.Namespace, Verb: sccUseVerb,
+				Group: sccGroup, Resource: sccResource, Name: scc.GetName()})
+			if err != nil {
+				return nil, err
+			}
+			listed = allowed
+		}
+		if listed {
+			usable = append(usable, scc)
+		}
+	}
+	return usable, nil
+}
+
+func runOpenShiftSCCCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	if !internal.CheckIsOpenshift(kubeClient.DiscClient, internal.OcpAPIVersion) {
+		o.Logger.Infof("APIVersion - %s not found on cluster, skipping check for SCCs", internal.OcpAPIVersion)
+		res.Skip("not an OpenShift cluster")
+		return nil
+	}
+	serviceAccount := o.tvkServiceAccount()
+	o.Logger.Infof("Checking SecurityContextConstraints usable by service account - %s",
+		internal.GetNamespacedName(o.Namespace, serviceAccount))
+
+	result := o.sccResult
+	if result == nil {
+		result = &OpenShiftSCCResult{}
+		o.sccResult = result
+	}
+	result.ServiceAccount = serviceAccount
+
+	sccs, err := listSCCs(ctx, kubeClient)
+	if err != nil {
+		return fmt.Errorf("error listing SCCs :: %w", err)
+	}
+	saRun := o.copyRun()
+	saRun.ServiceAccountName = serviceAccount
+	usable, err := o.usableSCCs(ctx, sccs, saRun.newAccessReviewer(accessReviewClientSet(kubeClient)), serviceAccount)
+	if err != nil {
+		return err
+	}
+	for _, scc := range usable {
+		result.UsableSCCs = append(result.UsableSCCs, scc.GetName())
+	}
+	o.Logger.Infof("Service account can use SCCs - [%s]", strings.Join(result.UsableSCCs, ", "))
+
+	var nsRange *uidRange
+	ns, err := kubeClient.ClientSet.CoreV1().Namespaces().Get(ctx, o.Namespace, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		if annotation, ok := ns.GetAnnotations()[sccUIDRangeAnnotation]; ok {
+			if nsRange, err = parseUIDRange(annotation); err != nil {
+				res.Warn(fmt.Sprintf("Namespace - %s has %s", o.Namespace, err.Error()))
+			}
+		}
+	case !k8serrors.IsNotFound(err):
+		return err
+	}
+
+	var failed []string
+	for idx := range podCapabilityValidationCases {
+		caseResult := evaluateSCCCase(podCapabilityValidationCases[idx], usable, sccs, nsRange, serviceAccount, o.Namespace)
+		result.Cases = append(result.Cases, caseResult)
+		if caseResult.Status == CheckStatusPass {
+			o.Logger.Infof("%s Pod capability validation case %d/3 (%s) is admitted by SCC - %s", check, idx+1,
+				caseResult.Case, caseResult.AdmittedBy)
+			continue
+		}
+		failed = append(failed, strconv.Itoa(idx+1))
+		o.Logger.Errorf("%s Pod capability validation case %d/3 (%s) isn't admitted by any SCC usable by the service account",
+			cross, idx+1, caseResult.Case)
+		for _, reason := range caseResult.Reasons {
+			o.Logger.Errorf("  %s", reason)
+		}
+		if caseResult.Fix != "" {
+			res.Recommend(fmt.Sprintf("Allow pod capability validation case %d/3 :: %s", idx+1, caseResult.Fix))
+		}
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("pod capability validation cases - [%s] aren't admitted by SCCs usable by service account - %s",
+			strings.Join(failed, ", "), internal.GetNamespacedName(o.Namespace, serviceAccount))
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package preflight
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// testSCC parses the SCC from its unstructured content, as listed from the cluster.
+func testSCC(obj map[string]interface{}) *securityContextConstraints {
+	scc := &securityContextConstraints{}
+	Expect(runtime.DefaultUnstructuredConverter.FromUnstructured(obj, scc)).To(Succeed())
+	return scc
+}
+
+var _ = Describe("OpenShift SCC unit tests", func() {
+
+	var restricted, anyuid, privileged *securityContextConstraints
+
+	BeforeEach(func() {
+		restricted = testSCC(map[string]interface{}{
+			"metadata":                 map[string]interface{}{"name": "restricted-v2"},
+			"allowPrivilegedContainer": false,
+			"allowPrivilegeEscalation": false,
+			"requiredDropCapabilities": []interface{}{"ALL"},
+			"allowedCapabilities":      []interface{}{"NET_BIND_SERVICE"},
+			"runAsUser":                map[string]interface{}{"type": "MustRunAsRange"},
+			"groups":                   []interface{}{"system:authenticated"},
+		})
+		anyuid = testSCC(map[string]interface{}{
+			"metadata":                 map[string]interface{}{"name": "anyuid"},
+			"priority":                 int64(10),
+			"allowPrivilegedContainer": false,
+			"allowedCapabilities":      []interface{}{"*"},
+			"runAsUser":                map[string]interface{}{"type": "RunAsAny"},
+		})
+		privileged = testSCC(map[string]interface{}{
+			"metadata":                 map[string]interface{}{"name": "privileged"},
+			"allowPrivilegedContainer": true,
+			"allowedCapabilities":      []interface{}{"*"},
+			"runAsUser":                map[string]interface{}{"type": "RunAsAny"},
+			"users":                    []interface{}{"system:admin"},
+		})
+	})
+
+	Context("parseUIDRange func test-cases", func() {
+
+		It("Should parse uid range of namespace annotation", func() {
+			uids, err := parseUIDRange("1000680000/10000")
+			Expect(err).To(BeNil())
+			Expect(*uids).To(Equal(uidRange{min: 1000680000, max: 1000689999}))
+
+			for _, annotation := range []string{"1000680000", "abc/10", "1000/0"} {
+				_, err = parseUIDRange(annotation)
+				Expect(err).ToNot(BeNil())
+			}
+		})
+	})
+
+	Context("sccAdmissionViolations func test-cases", func() {
+
+		It("Should not admit pod capability cases with restricted SCC", func() {
+			nsRange := &uidRange{min: 1000680000, max: 1000689999}
+			violations := sccAdmissionViolations(restricted, podCapabilityValidationCases[0], nsRange)
+			Expect(violations).To(ConsistOf("privileged containers aren't allowed", "privilege escalation isn't allowed",
+				ContainSubstring("SYS_ADMIN"), "user ID must be in range 1000680000-1000689999"))
+
+			violations = sccAdmissionViolations(restricted, podCapabilityValidationCases[1], nil)
+			Expect(violations).To(ContainElement("user ID range of namespace isn't known"))
+		})
+
+		It("Should admit only the non-privileged cases with anyuid SCC, and all cases with privileged SCC", func() {
+			Expect(sccAdmissionViolations(anyuid, podCapabilityValidationCases[0], nil)).To(
+				Equal([]string{"privileged containers aren't allowed"}))
+			Expect(sccAdmissionViolations(anyuid, podCapabilityValidationCases[1], nil)).To(BeEmpty())
+			Expect(sccAdmissionViolations(anyuid, podCapabilityValidationCases[2], nil)).To(BeEmpty())
+			for idx := range podCapabilityValidationCases {
+				Expect(sccAdmissionViolations(privileged, podCapabilityValidationCases[idx], nil)).To(BeEmpty())
+			}
+		})
+	})
+
+	Context("evaluateSCCCase func test-cases", func() {
+
+		It("Should sort SCCs by priority, then from the most restrictive one, and then by name", func() {
+			sccs := []*securityContextConstraints{restricted, privileged, anyuid}
+			sortSCCs(sccs)
+			Expect(sccs).To(Equal([]*securityContextConstraints{anyuid, restricted, privileged}))
+
+			restrictedCopy := testSCC(map[string]interface{}{
+				"metadata":                 map[string]interface{}{"name": "a-restricted"},
+				"requiredDropCapabilities": []interface{}{"ALL"},
+				"allowedCapabilities":      []interface{}{"NET_BIND_SERVICE"},
+				"runAsUser":                map[string]interface{}{"type": "MustRunAsRange"},
+			})
+			sccs = []*securityContextConstraints{privileged, restricted, restrictedCopy}
+			sortSCCs(sccs)
+			Expect(sccs).To(Equal([]*securityContextConstraints{restrictedCopy, restricted, privileged}))
+		})
+
+		It("Should give more points to less restrictive SCCs", func() {
+			Expect(sccRestrictionPoints(restricted)).To(Equal(sccRunAsRangePoints + sccCapDefaultPoints +
+				sccCapAllowOnePoints + sccCapDropAllPoints))
+			Expect(sccRestrictionPoints(anyuid)).To(Equal(sccRunAsAnyUserPoints + sccCapDefaultPoints + sccCapAllowAllPoints))
+
+			hostAccess := testSCC(map[string]interface{}{
+				"metadata":         map[string]interface{}{"name": "hostaccess"},
+				"allowHostNetwork": true,
+				"allowHostPID":     true,
+				"volumes":          []interface{}{"configMap", "hostPath", "persistentVolumeClaim"},
+				"seLinuxContext":   map[string]interface{}{"type": "MustRunAs"},
+				"runAsUser":        map[string]interface{}{"type": "MustRunAsRange"},
+			})
+			Expect(sccRestrictionPoints(hostAccess)).To(Equal(sccHostNetworkPoints + sccHostPIDPoints + sccHostVolumePoints +
+				sccRunAsUserPoints + sccRunAsRangePoints + sccCapDefaultPoints))
+			Expect(sccVolumePoints([]string{"configMap", "persistentVolumeClaim"})).To(Equal(sccNonTrivialVolumePoints))
+			Expect(sccVolumePoints([]string{"configMap", "secret"})).To(BeZero())
+		})
+
+		It("Should report the usable SCC admitting the case", func() {
+			all := []*securityContextConstraints{anyuid, privileged, restricted}
+			res := evaluateSCCCase(podCapabilityValidationCases[1], []*securityContextConstraints{anyuid, restricted}, all,
+				nil, "k8s-triliovault", "tvk")
+			Expect(res.Status).To(Equal(CheckStatusPass))
+			Expect(res.AdmittedBy).To(Equal("anyuid"))
+			Expect(res.Reasons).To(BeEmpty())
+		})
+
+		It("Should suggest the least permissive SCC admitting the case if no usable SCC does", func() {
+			all := []*securityContextConstraints{anyuid, privileged, restricted}
+			res := evaluateSCCCase(podCapabilityValidationCases[2], []*securityContextConstraints{restricted}, all,
+				nil, "k8s-triliovault", "tvk")
+			Expect(res.Status).To(Equal(CheckStatusFail))
+			Expect(res.Reasons).To(HaveLen(1))
+			Expect(res.Reasons[0]).To(HavePrefix("restricted-v2 :: "))
+			Expect(res.Fix).To(Equal("oc adm policy add-scc-to-user anyuid -z k8s-triliovault -n tvk"))
+
+			res = evaluateSCCCase(podCapabilityValidationCases[0], nil, all, nil, "k8s-triliovault", "tvk")
+			Expect(res.Fix).To(Equal("oc adm policy add-scc-to-user privileged -z k8s-triliovault -n tvk"))
+		})
+	})
+
+	Context("usableSCCs func test-cases", func() {
+
+		It("Should return SCCs listing the service account groups, or allowing it to use them", func() {
+			run := runOps.copyRun()
+			run.Namespace = "tvk"
+			var reviewed []string
+			review := func(_ context.Context, attrs *authorizationv1.ResourceAttributes) (bool, string, error) {
+				Expect(attrs.Verb).To(Equal(sccUseVerb))
+				Expect(attrs.Resource).To(Equal(sccResource))
+				reviewed = append(reviewed, attrs.Name)
+				return attrs.Name == "anyuid", "", nil
+			}
+			usable, err := run.usableSCCs(context.Background(), []*securityContextConstraints{anyuid, privileged, restricted},
+				review, "k8s-triliovault")
+			Expect(err).To(BeNil())
+			Expect(usable).To(Equal([]*securityContextConstraints{anyuid, restricted}))
+			Expect(reviewed).To(Equal([]string{"anyuid", "privileged"}))
+		})
+	})
+})
@@ -0,0 +1,102 @@
+package preflight
+
+import (
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+// patchVerb is needed to remove finalizers of the resources deleted by preflight.
+const patchVerb = "patch"
+
+// createdResourceVerbs are the verbs needed on resources which the preflight checks create and clean up. They are
+// watched while waiting for them to reach the desired state, and their finalizers are removed once deleted.
+var createdResourceVerbs = []string{"get", "list", "watch", internal.CreateVerb, internal.DeleteVerb, patchVerb}
+
+// preflightPermission is the access to resources needed by the preflight checks.
+type preflightPermission struct {
+	apiGroup  string
+	resources []string
+	// namespaced resources are accessed in the namespace of preflight, and in the backup namespace with cluster scope.
+	namespaced bool
+	verbs      []string
+	// clusterScopeVerbs are needed in addition to verbs with cluster scope only.
+	clusterScopeVerbs []string
+}
+
+// preflightPermissions are the permissions needed by the preflight checks. The roles of preflight job are built from
+// them, and the permissions printed in dry-run mode take the verbs of the resources created by the checks from them.
+var preflightPermissions = []preflightPermission{
+	// create and delete of namespaces are reviewed by namespace permissions check with both scopes
+	{apiGroup: "", resources: []string{"namespaces"},
+		verbs: []string{"get", "list", "watch", internal.CreateVerb, internal.DeleteVerb, patchVerb}},
+	{apiGroup: "storage.k8s.io", resources: []string{"storageclasses"}, verbs: []string{"get", "list"}},
+	{apiGroup: "", resources: []string{"nodes"}, verbs: []string{"get", "list"}},
+	// zone of the volume bound immediately is read by the topology check
+	{apiGroup: "", resources: []string{"persistentvolumes"}, verbs: []string{"get"}},
+	{apiGroup: StorageSnapshotGroup, resources: []string{"volumesnapshotclasses"},
+		verbs: []string{"get", "list", internal.CreateVerb}},
+	// volume snapshot content is cloned into the install namespace with cluster scope
+	{apiGroup: StorageSnapshotGroup, resources: []string{"volumesnapshotcontents"}, verbs: []string{"get", "list"},
+		clusterScopeVerbs: []string{internal.CreateVerb, internal.DeleteVerb, patchVerb}},
+	{apiGroup: "apiextensions.k8s.io", resources: []string{"customresourcedefinitions"},
+		verbs: []string{"get", "list", internal.CreateVerb}},
+	{apiGroup: "authorization.k8s.io", resources: []string{"selfsubjectaccessreviews", "subjectaccessreviews"},
+		verbs: []string{internal.CreateVerb}},
+	{apiGroup: sccGroup, resources: []string{sccResource}, verbs: []string{"list"}},
+	{apiGroup: "admissionregistration.k8s.io",
+		resources: []string{"validatingwebhookconfigurations", "mutatingwebhookconfigurations"}, verbs: []string{"list"}},
+	{apiGroup: "discovery.k8s.io", resources: []string{"endpointslices"}, verbs: []string{"list"}},
+
+	{apiGroup: "", resources: []string{"pods", "persistentvolumeclaims"}, namespaced: true, verbs: createdResourceVerbs},
+	{apiGroup: "", resources: []string{"pods/exec"}, namespaced: true, verbs: []string{internal.CreateVerb}},
+	{apiGroup: "", resources: []string{"events"}, namespaced: true, verbs: []string{"get", "list", "watch"}},
+	{apiGroup: "", resources: []string{"resourcequotas", "limitranges"}, namespaced: true, verbs: []string{"list"}},
+	{apiGroup: "networking.k8s.io", resources: []string{"networkpolicies"}, namespaced: true, verbs: []string{"list"}},
+	// the result of preflight job is written into a ConfigMap
+	{apiGroup: "", resources: []string{"configmaps"}, namespaced: true, verbs: []string{"get", internal.CreateVerb, "update"}},
+	{apiGroup: StorageSnapshotGroup, resources: []string{"volumesnapshots"}, namespaced: true, verbs: createdResourceVerbs},
+}
+
+// policyRules returns the rules of preflightPermissions on namespaced or cluster scoped resources, for the given scope.
+func policyRules(namespaced bool, scope string) []rbacv1.PolicyRule {
+	var rules []rbacv1.PolicyRule
+	for _, perm := range preflightPermissions {
+		if perm.namespaced != namespaced {
+			continue
+		}
+		verbs := append([]string{}, perm.verbs...)
+		if scope == internal.ClusterScope {
+			verbs = append(verbs, perm.clusterScopeVerbs...)
+		}
+		rules = append(rules, rbacv1.PolicyRule{APIGroups: []string{perm.apiGroup}, Resources: perm.resources, Verbs: verbs})
+	}
+	return rules
+}
+
+// preflightClusterRules returns the permissions on cluster scoped resources needed by the preflight checks.
+func preflightClusterRules(scope string) []rbacv1.PolicyRule {
+	return policyRules(false, scope)
+}
+
+// preflightNamespacedRules returns the permissions on namespaced resources needed by the preflight checks,
+// and to write the result of preflight job.
+func preflightNamespacedRules(scope string) []rbacv1.PolicyRule {
+	return policyRules(true, scope)
+}
+
+// permittedVerbs returns the verbs needed on the resource created by the preflight checks. Resources are
+// created with cluster scope only if they need verbs specific to it, so those are included.
+func permittedVerbs(apiGroup, resource string) []string {
+	for _, perm := range preflightPermissions {
+		if perm.apiGroup != apiGroup {
+			continue
+		}
+		for _, res := range perm.resources {
+			if res == resource {
+				return append(append([]string{}, perm.verbs...), perm.clusterScopeVerbs...)
+			}
+		}
+	}
+	return []string{internal.CreateVerb, "get"}
+}
@@ -0,0 +1,676 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+const (
+	// DryRunClient renders the resources created by preflight checks without sending them to the cluster.
+	DryRunClient = "client"
+	// DryRunServer renders the resources created by preflight checks and validates them with server-side dry-run.
+	DryRunServer = "server"
+
+	dryRunPassed        = "passed"
+	allNamespaces       = "*"
+	clusterScopedColumn = "-"
+	coreAPIGroupColumn  = "core"
+
+	placeholderProvisioner    = "<provisioner of storage class - %s>"
+	placeholderSnapshotHandle = "<snapshot handle of volume snapshot - %s>"
+)
+
+// AllowedDryRunModes are the modes in which preflight run can be performed without persisting anything.
+var AllowedDryRunModes = sets.NewString(DryRunClient, DryRunServer)
+
+// plannedResource is a resource which a preflight check creates on the cluster.
+type plannedResource struct {
+	check  string
+	object client.Object
+	// note describes when the resource is created, or how its fields are filled at run time.
+	note string
+	// serverDryRun is the outcome of creating the resource with server-side dry-run.
+	serverDryRun string
+}
+
+// permission is the access to a resource required by the preflight checks. Namespace is empty for cluster
+// scoped resources.
+type permission struct {
+	apiGroup  string
+	resource  string
+	namespace string
+	verbs     sets.String
+}
+
+// resourcePlan holds the resources created by the preflight checks and the permissions they require.
+type resourcePlan struct {
+	resources   []*plannedResource
+	permissions map[string]*permission
+	// namespaces created by the preflight checks, resources in them are created in a random namespace at run time.
+	namespaces sets.String
+	// crds created by the preflight checks.
+	crds sets.String
+}
+
+// dryRunClusterState is the state of cluster on which the resources created by preflight checks depend.
+type dryRunClusterState struct {
+	crdVersion      string
+	snapshotVersion string
+	installedCRDs   sets.String
+	// provisioners of the storage classes found on cluster.
+	provisioners    map[string]string
+	snapshotClasses []unstructured.Unstructured
+}
+
+func newResourcePlan() *resourcePlan {
+	return &resourcePlan{
+		permissions: make(map[string]*permission),
+		namespaces:  sets.NewString(),
+		crds:        sets.NewString(),
+	}
+}
+
+// add records the resource created by the check, along with the permissions needed to create it, wait on it
+// and delete it during cleanup, as granted to preflight job.
+func (p *resourcePlan) add(checkName string, obj client.Object, note string) {
+	setTypeMeta(obj)
+	p.resources = append(p.resources, &plannedResource{check: checkName, object: obj, note: note})
+
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	p.permit(gvk.Group, gvr.Resource, obj.GetNamespace(), permittedVerbs(gvk.Group, gvr.Resource)...)
+	if gvk.Kind == internal.PodKind || gvk.Kind == internal.PersistentVolumeClaimKind {
+		// events are listed to find the reason if they fail to reach the desired state
+		p.permit("", "events", obj.GetNamespace(), "list")
+	}
+}
+
+// permit records the verbs required on the resource. Resources in namespaces created by the preflight checks
+// require the verbs in all the namespaces.
+func (p *resourcePlan) permit(apiGroup, resource, namespace string, verbs ...string) {
+	if p.namespaces.Has(namespace) {
+		namespace = allNamespaces
+	}
+	key := strings.Join([]string{apiGroup, resource, namespace}, "/")
+	if _, ok := p.permissions[key]; !ok {
+		p.permissions[key] = &permission{apiGroup: apiGroup, resource: resource, namespace: namespace, verbs: sets.NewString()}
+	}
+	p.permissions[key].verbs.Insert(verbs...)
+}
+
+// sortedPermissions returns the permissions sorted by API group, resource and namespace.
+func (p *resourcePlan) sortedPermissions() []*permission {
+	var perms []*permission
+	for _, perm := range p.permissions {
+		perms = append(perms, perm)
+	}
+	sort.Slice(perms, func(i, j int) bool {
+		if perms[i].apiGroup != perms[j].apiGroup {
+			return perms[i].apiGroup < perms[j].apiGroup
+		}
+		if perms[i].resource != perms[j].resource {
+			return perms[i].resource < perms[j].resource
+		}
+		return perms[i].namespace < perms[j].namespace
+	})
+
+	return perms
+}
+
+// setTypeMeta sets the api version and kind of typed objects, which are not set by their spec builders.
+func setTypeMeta(obj client.Object) {
+	if !obj.GetObjectKind().GroupVersionKind().Empty() {
+		return
+	}
+	switch obj.(type) {
+	case *corev1.Pod:
+		obj.GetObjectKind().SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind(internal.PodKind))
+	case *corev1.PersistentVolumeClaim:
+		obj.GetObjectKind().SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind(internal.PersistentVolumeClaimKind))
+	case *corev1.Namespace:
+		obj.GetObjectKind().SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind(internal.NamespaceKind))
+	case *snapshotv1.VolumeSnapshot:
+		obj.GetObjectKind().SetGroupVersionKind(snapshotv1.SchemeGroupVersion.WithKind(internal.VolumeSnapshotKind))
+	case *snapshotv1.VolumeSnapshotContent:
+		obj.GetObjectKind().SetGroupVersionKind(snapshotv1.SchemeGroupVersion.WithKind(internal.VolumeSnapshotContentKind))
+	case *apiextensions.CustomResourceDefinition:
+		obj.GetObjectKind().SetGroupVersionKind(apiextensions.SchemeGroupVersion.WithKind(internal.CustomResourceDefinitionKind))
+	}
+}
+
+// performDryRun prints the resources which the selected preflight checks create on the cluster, along with the
+// permissions they require, without persisting anything. Resources are validated with server-side dry-run if requested.
+func (o *Run) performDryRun(ctx context.Context, checks []*Check) error {
+	state, err := o.readDryRunClusterState(ctx)
+	if err != nil {
+		o.Logger.Errorf("%s Error reading cluster state for dry run :: %s", cross, err.Error())
+		return err
+	}
+	plan := o.planResources(checks, state)
+
+	var failed int
+	if o.DryRun == DryRunServer {
+		failed = o.serverDryRun(ctx, plan)
+	}
+	if err = plan.write(os.Stdout, resNameSuffix); err != nil {
+		return err
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("server-side dry-run failed for %d of %d resources. Check logs for more details",
+			failed, len(plan.resources))
+	}
+	o.Logger.Infof("%s Dry run complete, %d resources would be created by preflight checks", check, len(plan.resources))
+
+	return nil
+}
+
+// readDryRunClusterState reads the server version, installed volume snapshot CRDs, provisioners of the storage classes
+// and volume snapshot classes, from which the resources created by preflight checks are derived. Nothing is created.
+func (o *Run) readDryRunClusterState(ctx context.Context) (*dryRunClusterState, error) {
+	serverVersion, err := kubeClient.DiscClient.ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("error getting server version: %s", err.Error())
+	}
+	state := &dryRunClusterState{installedCRDs: sets.NewString(), provisioners: make(map[string]string)}
+	state.crdVersion, err = getPrefSnapshotClassVersion(serverVersion.String())
+	if err != nil {
+		return nil, err
+	}
+	// volume snapshot group is not served until its CRDs are installed, the CRDs created by preflight are used then
+	state.snapshotVersion, err = GetServerPreferredVersionForGroup(StorageSnapshotGroup, kubeClient.ClientSet)
+	if err != nil {
+		state.snapshotVersion = state.crdVersion
+	}
+
+	for _, crd := range VolumeSnapshotCRDs {
+		if err = kubeClient.RuntimeClient.Get(ctx, client.ObjectKey{Name: crd}, &apiextensions.CustomResourceDefinition{}); err != nil {
+			if !k8serrors.IsNotFound(err) {
+				return nil, fmt.Errorf("error getting volume snapshot class CRD :: %s", err.Error())
+			}
+			continue
+		}
+		state.installedCRDs.Insert(crd)
+	}
+
+	for _, scName := range o.storageClasses() {
+		sc, gErr := kubeClient.ClientSet.StorageV1().StorageClasses().Get(ctx, scName, metav1.GetOptions{})
+		if gErr != nil {
+			if k8serrors.IsNotFound(gErr) {
+				o.Logger.Warnf("not found storageclass - %s on cluster", scName)
+				continue
+			}
+			return nil, gErr
+		}
+		state.provisioners[scName] = sc.Provisioner
+	}
+
+	if o.SnapshotClass == "" && state.installedCRDs.Has(volumeSnapshotClassCRD()) {
+		state.snapshotClasses, err = listVolumeSnapshotClasses(ctx, state.snapshotVersion, kubeClient.RuntimeClient)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return state, nil
+}
+
+// planResources returns the resources created by the given checks using the same spec builders as the checks,
+// for the given state of cluster.
+func (o *Run) planResources(checks []*Check, state *dryRunClusterState) *resourcePlan {
+	p := newResourcePlan()
+	for _, c := range checks {
+		switch c.Name {
+		case CheckClusterAccess:
+			p.permit("", "namespaces", "", "get")
+
+		case CheckCSI:
+			o.planVolumeSnapshotCRDs(p, state)
+
+		case CheckStorageSnapshotClass:
+			o.planSnapshotClasses(p, state)
+
+		case CheckPodCapability:
+			for idx := range podCapabilityValidationCases {
+				p.add(c.Name, createPodSpecWithCapability(o, fmt.Sprintf("%d-%s", idx, resNameSuffix),
+					podCapabilityValidationCases[idx]), "")
+			}
+
+		case CheckPodSecurity:
+			p.permit("", "namespaces", "", "get")
+			// pods are created with server-side dry-run only, so that Pod Security Admission evaluates them
+			p.permit("", "pods", o.Namespace, internal.CreateVerb)
+
+		case CheckOpenShiftSCC:
+			p.permit(sccGroup, sccResource, "", "list")
+			p.permit("", "namespaces", "", "get")
+			p.permit("authorization.k8s.io", "subjectaccessreviews", "", internal.CreateVerb)
+
+		case CheckAdmissionWebhooks:
+			p.permit("admissionregistration.k8s.io", "validatingwebhookconfigurations", "", "list")
+			p.permit("admissionregistration.k8s.io", "mutatingwebhookconfigurations", "", "list")
+			p.permit("discovery.k8s.io", "endpointslices", allNamespaces, "list")
+			p.permit("", "namespaces", "", "get")
+			// pods are created with server-side dry-run only, to find the mutations made to them
+			p.permit("", "pods", o.Namespace, internal.CreateVerb)
+			p.permit("", "limitranges", o.Namespace, "list")
+
+		case CheckNodeInventory:
+			p.permit("", "nodes", "", "list")
+
+		case CheckResourceQuota:
+			p.permit("", "resourcequotas", o.Namespace, "list")
+			p.permit("", "limitranges", o.Namespace, "list")
+
+		case CheckDNSResolution:
+			p.add(c.Name, createDNSPodSpec(o, resNameSuffix), "")
+			p.permit("", "pods/exec", o.Namespace, internal.CreateVerb)
+			p.permit("discovery.k8s.io", "endpointslices", kubeSystemNamespace, "list")
+
+		case CheckNetworkPolicy:
+			p.add(c.Name, createNetworkServerPodSpec(o, resNameSuffix), "")
+			p.add(c.Name, createNetworkProbePodSpec(o, resNameSuffix), "")
+			p.permit("networking.k8s.io", "networkpolicies", o.Namespace, "list")
+			p.permit("", "namespaces", "", "get")
+			p.permit("discovery.k8s.io", "endpointslices", metav1.NamespaceDefault, "list")
+			p.permit("", "pods/exec", o.Namespace, internal.CreateVerb)
+
+		case CheckNamespacePermissions:
+			p.permit("authorization.k8s.io", "selfsubjectaccessreviews", "", internal.CreateVerb)
+
+		case CheckRBACPermissions:
+			if o.ServiceAccountName == "" {
+				p.permit("authorization.k8s.io", "selfsubjectaccessreviews", "", internal.CreateVerb)
+			} else {
+				p.permit("authorization.k8s.io", "subjectaccessreviews", "", internal.CreateVerb)
+			}
+
+		case CheckVolumeSnapshot:
+			for _, res := range o.storageMatrix().results {
+				scRun := o.copyRun()
+				scRun.StorageClass = res.StorageClass
+				scRun.scResult = res
+				scRun.planVolumeSnapshot(p, state)
+			}
+
+		case CheckBlockVolumeSnapshot:
+			for _, res := range o.storageMatrix().results {
+				scRun := o.copyRun()
+				scRun.StorageClass = res.StorageClass
+				scRun.scResult = res
+				scRun.blockVolumeRun().planVolumeSnapshot(p, state)
+			}
+
+		case CheckTopology:
+			for _, res := range o.storageMatrix().results {
+				scRun := o.copyRun()
+				scRun.StorageClass = res.StorageClass
+				scRun.scResult = res
+				scRun.planTopology(p, state)
+			}
+
+		case CheckStoragePerformance:
+			for _, res := range o.storageMatrix().results {
+				scRun := o.copyRun()
+				scRun.StorageClass = res.StorageClass
+				scRun.scResult = res
+				scRun.DataSize = resource.Quantity{}
+				scRun.planStoragePerformance(p, state)
+			}
+
+		case CheckAccessModes:
+			for _, res := range o.storageMatrix().results {
+				scRun := o.copyRun()
+				scRun.StorageClass = res.StorageClass
+				scRun.scResult = res
+				scRun.planAccessModes(p)
+			}
+
+		case CheckTarget:
+			o.planTarget(p)
+
+		case CheckImageAvailability:
+			o.planImageAvailability(p)
+		}
+	}
+
+	return p
+}
+
+// planVolumeSnapshotCRDs plans the volume snapshot CRDs which are not installed on cluster.
+func (o *Run) planVolumeSnapshotCRDs(p *resourcePlan, state *dryRunClusterState) {
+	for _, crd := range VolumeSnapshotCRDs {
+		p.permit("apiextensions.k8s.io", "customresourcedefinitions", "", "get")
+		if state.installedCRDs.Has(crd) {
+			o.Logger.Infof("%s Volume snapshot CRD: %s already exists, it won't be created", check, crd)
+			continue
+		}
+
+		crdObj, err := readVolumeSnapshotCRDSpec(state.crdVersion, crd)
+		if err != nil {
+			o.Logger.Errorf("%s Error reading volume snapshot CRD: %s :: %s", cross, crd, err.Error())
+			continue
+		}
+		p.crds.Insert(crd)
+		p.add(CheckCSI, crdObj, fmt.Sprintf("created as volume snapshot CRD: %s is not installed on cluster", crd))
+	}
+}
+
+// planSnapshotClasses plans the volume snapshot classes created for the storage classes having no volume snapshot class
+// with driver same as their provisioner, and records the volume snapshot class used for each storage class.
+func (o *Run) planSnapshotClasses(p *resourcePlan, state *dryRunClusterState) {
+	p.permit("storage.k8s.io", "storageclasses", "", "get")
+	if o.SnapshotClass != "" {
+		p.permit(StorageSnapshotGroup, "volumesnapshotclasses", "", "get")
+	} else {
+		p.permit(StorageSnapshotGroup, "volumesnapshotclasses", "", "list")
+	}
+
+	for _, res := range o.storageMatrix().results {
+		provisioner, found := state.provisioners[res.StorageClass]
+		if !found {
+			continue
+		}
+		if o.SnapshotClass != "" {
+			res.SnapshotClass = o.SnapshotClass
+			continue
+		}
+
+		if name, _ := matchSnapshotClassForProvisioner(state.snapshotClasses, provisioner); name != "" {
+			o.Logger.Infof("%s Volume snapshot class - %s driver matches with provisioner of storage class - %s, "+
+				"it won't be created", check, name, res.StorageClass)
+			res.SnapshotClass = name
+			continue
+		}
+		res.SnapshotClass = defaultVSCNamePrefix + res.nameSuffix
+		p.add(CheckStorageSnapshotClass, createVolumeSnapshotClassSpec(res.SnapshotClass, provisioner, state.snapshotVersion),
+			fmt.Sprintf("created as no volume snapshot class has driver same as provisioner of storage class - %s. "+
+				"Name is suffixed with a random string at run time", res.StorageClass))
+	}
+}
+
+// planVolumeSnapshot plans the resources of volume snapshot and restore flow of the storage class being checked,
+// as performed by validateClusterScopeVolumeSnapshot and validateNamespaceScopeVolumeSnapshot.
+func (o *Run) planVolumeSnapshot(p *resourcePlan, state *dryRunClusterState) {
+	var (
+		uid        = resNameSuffix
+		nameSuffix = o.resourceNameSuffix(uid)
+		sourceNs   = o.Namespace
+		checkName  = CheckVolumeSnapshot
+	)
+	if o.blockMode {
+		checkName = CheckBlockVolumeSnapshot
+	}
+
+	if o.Scope == internal.ClusterScope {
+		sourceNs = BackupNamespacePrefix + nameSuffix
+		p.add(checkName, createNamespaceSpec(sourceNs, uid), "")
+		p.namespaces.Insert(sourceNs)
+	}
+
+	sourcePvcNsName := types.NamespacedName{Namespace: sourceNs, Name: SourcePvcNamePrefix + nameSuffix}
+	pvc := createVolumeSnapshotPVCSpec(o, sourcePvcNsName, uid)
+	p.add(checkName, pvc, "")
+	writerPodName := fmt.Sprintf("%s%s-%s", SourcePvcNamePrefix, "writer", nameSuffix)
+	p.add(checkName, createPVCDataWriterPodSpec(writerPodName, sourcePvcNsName, o, uid), "")
+
+	snapshotNameNs := types.NamespacedName{Namespace: sourceNs, Name: VolumeSnapSrcNamePrefix + nameSuffix}
+	p.add(checkName, createVolumeSnapsotSpec(snapshotNameNs, o.snapshotClass(), state.snapshotVersion,
+		pvc.GetName(), uid), "")
+
+	o.planSnapshotRestore(p, state, checkName, snapshotNameNs, pvc, BackupPvcNamePrefix, VolumeSnapBackupNamePrefix)
+
+	p.permit("", "pods/exec", o.Namespace, internal.CreateVerb)
+	if !o.dataIntegrityEnabled() {
+		return
+	}
+	// checksums of source data are recorded by exec in the writer pod
+	p.permit("", "pods/exec", sourceNs, internal.CreateVerb)
+
+	// volume snapshot is taken again and restored once the writer pod is deleted, unmounting the source pvc
+	unmountedSnapshotNameNs := types.NamespacedName{Namespace: sourceNs, Name: UnmountedVolumeSnapSrcNamePrefix + nameSuffix}
+	p.add(checkName, createVolumeSnapsotSpec(unmountedSnapshotNameNs, o.snapshotClass(), state.snapshotVersion,
+		pvc.GetName(), uid), "created once the writer pod is deleted")
+	o.planSnapshotRestore(p, state, checkName, unmountedSnapshotNameNs, pvc, UnmountedRestorePvcNamePrefix,
+		UnmountedVolumeSnapRestoreNamePrefix)
+}
+
+// planSnapshotRestore plans the restore of volume snapshot of the source pvc to a pvc attached to a reader pod in the
+// install namespace. Volume snapshot is cloned into the install namespace with cluster scope.
+func (o *Run) planSnapshotRestore(p *resourcePlan, state *dryRunClusterState, checkName string,
+	snapshotNameNs types.NamespacedName, pvc *corev1.PersistentVolumeClaim, restorePvcPrefix, cloneSnapshotPrefix string) {
+	var (
+		uid        = resNameSuffix
+		nameSuffix = o.resourceNameSuffix(uid)
+	)
+	restorePVCMeta := &metav1.ObjectMeta{Name: restorePvcPrefix + nameSuffix, Namespace: o.Namespace, Labels: pvc.Labels}
+	restoreSnapshotName := snapshotNameNs.Name
+	if o.Scope == internal.ClusterScope {
+		provisioner, found := state.provisioners[o.StorageClass]
+		if !found {
+			provisioner = fmt.Sprintf(placeholderProvisioner, o.StorageClass)
+		}
+		srcVolSnapContent := &snapshotv1.VolumeSnapshotContent{
+			Spec: snapshotv1.VolumeSnapshotContentSpec{
+				Driver:                  provisioner,
+				VolumeSnapshotClassName: ptr.To(o.snapshotClass()),
+			},
+			Status: &snapshotv1.VolumeSnapshotContentStatus{
+				SnapshotHandle: ptr.To(fmt.Sprintf(placeholderSnapshotHandle, snapshotNameNs.String())),
+			},
+		}
+		cloneVolSnapMeta := &metav1.ObjectMeta{
+			Name:      cloneSnapshotPrefix + nameSuffix,
+			Namespace: o.Namespace,
+			Labels:    getPreflightResourceLabels(uid),
+		}
+		volSnapContent, volSnap := createClonedSnapshotAndContentSpec(srcVolSnapContent, cloneVolSnapMeta)
+		p.add(checkName, volSnapContent, fmt.Sprintf("snapshot handle is taken from the volume snapshot "+
+			"content bound to volume snapshot - %s at run time", snapshotNameNs.String()))
+		p.add(checkName, volSnap, "")
+		p.permit(StorageSnapshotGroup, "volumesnapshotcontents", "", "get")
+		restoreSnapshotName = volSnap.GetName()
+	}
+
+	p.add(checkName, createPVCFromSnapshotSpec(restorePVCMeta, &pvc.Spec, restoreSnapshotName), "")
+	readerPodName := fmt.Sprintf("%s%s-%s", restorePvcPrefix, "reader", nameSuffix)
+	p.add(checkName, createPVCDataReaderPodSpec(readerPodName,
+		types.NamespacedName{Namespace: o.Namespace, Name: restorePVCMeta.GetName()}, o, uid), "")
+}
+
+// planTopology plans the resources of topology check of the storage class being checked, as performed by
+// validateTopologyRestore. Nodes of the pods are chosen at run time, a restored pvc and a reader pod are created
+// for each target node.
+func (o *Run) planTopology(p *resourcePlan, state *dryRunClusterState) {
+	var (
+		uid        = resNameSuffix
+		nameSuffix = o.resourceNameSuffix(uid)
+	)
+	p.permit("", "nodes", "", "list")
+	p.permit("storage.k8s.io", "storageclasses", "", "get")
+	// zone of the writer pod is taken from the volume bound to the source pvc, if storage class binds volumes immediately
+	p.permit("", "persistentvolumes", "", "get")
+
+	sourcePvcNsName := types.NamespacedName{Namespace: o.Namespace, Name: TopologyPvcNamePrefix + nameSuffix}
+	pvc := createVolumeSnapshotPVCSpec(o, sourcePvcNsName, uid)
+	p.add(CheckTopology, pvc, "")
+	writerPodName := fmt.Sprintf("%s%s-%s", TopologyPvcNamePrefix, "writer", nameSuffix)
+	p.add(CheckTopology, createPVCDataWriterPodSpec(writerPodName, sourcePvcNsName, o, uid),
+		"pinned to the source node chosen at run time")
+
+	snapshotNameNs := types.NamespacedName{Namespace: o.Namespace, Name: TopologyVolumeSnapNamePrefix + nameSuffix}
+	p.add(CheckTopology, createVolumeSnapsotSpec(snapshotNameNs, o.snapshotClass(), state.snapshotVersion,
+		pvc.GetName(), uid), "")
+
+	targetSuffix := "0-" + nameSuffix
+	restoredPvcMeta := &metav1.ObjectMeta{Name: TopologyRestoredPvcNamePrefix + targetSuffix, Namespace: o.Namespace,
+		Labels: pvc.Labels}
+	p.add(CheckTopology, createPVCFromSnapshotSpec(restoredPvcMeta, &pvc.Spec, snapshotNameNs.Name),
+		"one for each target node")
+	readerPodName := fmt.Sprintf("%s%s-%s", TopologyRestoredPvcNamePrefix, "reader", targetSuffix)
+	p.add(CheckTopology, createPVCDataReaderPodSpec(readerPodName,
+		types.NamespacedName{Namespace: o.Namespace, Name: restoredPvcMeta.GetName()}, o, uid),
+		"one for each target node, pinned to a different node of the source zone and to a node of each other zone")
+	p.permit("", "pods/exec", o.Namespace, internal.CreateVerb)
+}
+
+// planStoragePerformance plans the resources of storage performance check of the storage class being checked,
+// as performed by measureStoragePerformance.
+func (o *Run) planStoragePerformance(p *resourcePlan, state *dryRunClusterState) {
+	var (
+		uid        = resNameSuffix
+		nameSuffix = o.resourceNameSuffix(uid)
+	)
+
+	sourcePvcNsName := types.NamespacedName{Namespace: o.Namespace, Name: PerfPvcNamePrefix + nameSuffix}
+	pvc := createVolumeSnapshotPVCSpec(o, sourcePvcNsName, uid)
+	p.add(CheckStoragePerformance, pvc, "")
+	writerPodName := fmt.Sprintf("%s%s-%s", PerfPvcNamePrefix, "writer", nameSuffix)
+	p.add(CheckStoragePerformance, createPVCDataWriterPodSpec(writerPodName, sourcePvcNsName, o, uid), "")
+
+	snapshotNameNs := types.NamespacedName{Namespace: o.Namespace, Name: PerfVolumeSnapNamePrefix + nameSuffix}
+	p.add(CheckStoragePerformance, createVolumeSnapsotSpec(snapshotNameNs, o.snapshotClass(), state.snapshotVersion,
+		pvc.GetName(), uid), "")
+
+	restoredPvcMeta := &metav1.ObjectMeta{Name: PerfRestoredPvcNamePrefix + nameSuffix, Namespace: o.Namespace, Labels: pvc.Labels}
+	p.add(CheckStoragePerformance, createPVCFromSnapshotSpec(restoredPvcMeta, &pvc.Spec, snapshotNameNs.Name), "")
+	readerPodName := fmt.Sprintf("%s%s-%s", PerfRestoredPvcNamePrefix, "reader", nameSuffix)
+	p.add(CheckStoragePerformance, createPVCDataReaderPodSpec(readerPodName,
+		types.NamespacedName{Namespace: o.Namespace, Name: restoredPvcMeta.GetName()}, o, uid), "")
+	p.permit("", "pods/exec", o.Namespace, internal.CreateVerb)
+}
+
+// planAccessModes plans the resources of access mode check of the storage class being checked, as performed by
+// validateAccessMode.
+func (o *Run) planAccessModes(p *resourcePlan) {
+	for _, mode := range o.AccessModes {
+		pvc := createAccessModePVCSpec(o, mode, resNameSuffix)
+		p.add(CheckAccessModes, pvc, "")
+		p.add(CheckAccessModes, createAccessModePodSpec(o, pvc, 0, resNameSuffix), "")
+		note := "scheduled on a different node than the first pod"
+		if mode == corev1.ReadWriteOncePod {
+			note = "expected not to start"
+		}
+		p.add(CheckAccessModes, createAccessModePodSpec(o, pvc, 1, resNameSuffix), note)
+	}
+	p.permit("", "pods/exec", o.Namespace, internal.CreateVerb)
+}
+
+// planTarget plans the resources of backup target check, as performed by validateTarget.
+func (o *Run) planTarget(p *resourcePlan) {
+	if o.Target == nil {
+		return
+	}
+	p.add(CheckTarget, createTargetProbePodSpec(o, resNameSuffix), "")
+	if o.Target.NFS != nil {
+		p.add(CheckTarget, createTargetNFSPodSpec(o, resNameSuffix), "mounts the NFS export")
+	}
+	if o.Target.S3 != nil {
+		// secrets of S3 target are read to verify that they have the expected keys
+		p.permit("", "secrets", o.Namespace, "get")
+	}
+	p.permit("", "pods/exec", o.Namespace, internal.CreateVerb)
+}
+
+// planImageAvailability plans the pods pulling each of the images to verify.
+func (o *Run) planImageAvailability(p *resourcePlan) {
+	if o.ImageAvailability == nil {
+		return
+	}
+	results, err := o.imageAvailabilityResults()
+	if err != nil {
+		o.Logger.Errorf("%s Error listing images to verify :: %s", cross, err.Error())
+		return
+	}
+	for idx, result := range results {
+		p.add(CheckImageAvailability, createImagePullPodSpec(o, result.Image, idx, resNameSuffix),
+			fmt.Sprintf("pulls image - %s", result.Image))
+	}
+}
+
+// serverDryRun creates the planned resources with server-side dry-run, so that they are validated and admitted
+// by the cluster without being persisted. It returns the number of resources which failed validation.
+func (o *Run) serverDryRun(ctx context.Context, p *resourcePlan) (failed int) {
+	for _, res := range p.resources {
+		obj := res.object.DeepCopyObject().(client.Object)
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		err := kubeClient.RuntimeClient.Create(ctx, obj, client.DryRunAll)
+		switch {
+		case err == nil:
+			res.serverDryRun = dryRunPassed
+			o.Logger.Infof("%s Server-side dry-run of %s - %s passed", check, kind, res.object.GetName())
+		case k8serrors.IsNotFound(err) && p.namespaces.Has(res.object.GetNamespace()):
+			res.serverDryRun = "skipped, namespace is created by preflight checks"
+		case meta.IsNoMatchError(err) && p.crds.Len() != 0:
+			res.serverDryRun = "skipped, CRD is created by preflight checks"
+		default:
+			failed++
+			res.serverDryRun = "failed :: " + err.Error()
+			o.Logger.Errorf("%s Server-side dry-run of %s - %s failed :: %s", cross, kind, res.object.GetName(), err.Error())
+		}
+	}
+
+	return failed
+}
+
+// write renders the permissions required by the planned resources as a comment, followed by the resources as a
+// multi-document yaml annotated with the check creating them.
+func (p *resourcePlan) write(w io.Writer, uid string) error {
+	var buf strings.Builder
+	_, _ = fmt.Fprintf(&buf, "# Resources created by preflight checks with UID - %s, nothing is persisted in dry-run.\n", uid)
+	_, _ = fmt.Fprintln(&buf, "# Permissions required by the preflight checks:")
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "#\tAPI GROUP\tRESOURCE\tNAMESPACE\tVERBS")
+	for _, perm := range p.sortedPermissions() {
+		apiGroup, namespace := perm.apiGroup, perm.namespace
+		if apiGroup == "" {
+			apiGroup = coreAPIGroupColumn
+		}
+		if namespace == "" {
+			namespace = clusterScopedColumn
+		}
+		_, _ = fmt.Fprintf(tw, "#\t%s\t%s\t%s\t%s\n", apiGroup, perm.resource, namespace, strings.Join(perm.verbs.List(), ","))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, res := range p.resources {
+		data, err := yaml.Marshal(res.object)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(&buf, "---\n# check: %s\n", res.check)
+		if res.note != "" {
+			_, _ = fmt.Fprintf(&buf, "# note: %s\n", res.note)
+		}
+		if res.serverDryRun != "" {
+			_, _ = fmt.Fprintf(&buf, "# server dry-run: %s\n", strings.ReplaceAll(res.serverDryRun, "\n", " "))
+		}
+		buf.Write(data)
+	}
+
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
@@ -0,0 +1,167 @@
+package preflight
+
+import (
+	"bytes"
+	"strings"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+var _ = Describe("Preflight dry-run unit tests", func() {
+
+	var (
+		run    *Run
+		checks []*Check
+		state  *dryRunClusterState
+	)
+
+	snapshotClassOf := func(volSnap *unstructured.Unstructured) string {
+		name, _, _ := unstructured.NestedString(volSnap.Object, "spec", "volumeSnapshotClassName")
+		return name
+	}
+
+	kindsOf := func(p *resourcePlan) []string {
+		var kinds []string
+		for _, res := range p.resources {
+			kinds = append(kinds, res.object.GetObjectKind().GroupVersionKind().Kind)
+		}
+		return kinds
+	}
+
+	BeforeEach(func() {
+		resNameSuffix = testNameSuffix
+		run = runOps.copyRun()
+		run.Scope = internal.ClusterScope
+		run.SnapshotClass = ""
+		run.scMatrix = newStorageClassMatrix(run.storageClasses(), resNameSuffix)
+
+		var err error
+		checks, err = run.SelectChecks()
+		Expect(err).To(BeNil())
+		state = &dryRunClusterState{
+			crdVersion:      snapshotClassVersionV1,
+			snapshotVersion: snapshotClassVersionV1,
+			installedCRDs:   sets.NewString(),
+			provisioners:    map[string]string{defaultStorageClass: testDriver},
+		}
+	})
+
+	It("Should plan volume snapshot CRDs, snapshot class and backup namespace resources for cluster scope", func() {
+		p := run.planResources(checks, state)
+		Expect(kindsOf(p)).To(Equal([]string{
+			internal.CustomResourceDefinitionKind, internal.CustomResourceDefinitionKind, internal.CustomResourceDefinitionKind,
+			internal.VolumeSnapshotClassKind,
+			internal.PodKind, internal.PodKind, internal.PodKind, internal.PodKind, internal.PodKind, internal.PodKind,
+			internal.NamespaceKind, internal.PersistentVolumeClaimKind, internal.PodKind, internal.VolumeSnapshotKind,
+			internal.VolumeSnapshotContentKind, internal.VolumeSnapshotKind, internal.PersistentVolumeClaimKind, internal.PodKind,
+		}))
+
+		vsc := p.resources[3].object.(*unstructured.Unstructured)
+		Expect(vsc.Object["driver"]).To(Equal(testDriver))
+		sourceSnapshot := p.resources[13].object.(*unstructured.Unstructured)
+		Expect(sourceSnapshot.GetNamespace()).To(Equal(BackupNamespacePrefix + testNameSuffix))
+		Expect(snapshotClassOf(sourceSnapshot)).To(Equal(vsc.GetName()))
+
+		content := p.resources[14].object.(*snapshotv1.VolumeSnapshotContent)
+		Expect(content.Spec.Driver).To(Equal(testDriver))
+		restoredPVC := p.resources[16].object.(*corev1.PersistentVolumeClaim)
+		Expect(restoredPVC.GetNamespace()).To(Equal(installNs))
+		Expect(restoredPVC.Spec.DataSource.Name).To(Equal(VolumeSnapBackupNamePrefix + testNameSuffix))
+
+		Expect(p.permissions).To(HaveKey("/persistentvolumeclaims/" + allNamespaces))
+		Expect(p.permissions).To(HaveKey("/persistentvolumeclaims/" + installNs))
+		Expect(p.permissions["/namespaces/"].verbs.List()).To(ContainElements(internal.CreateVerb, internal.DeleteVerb))
+		Expect(p.permissions["/pods/exec/"+installNs].verbs.List()).To(Equal([]string{internal.CreateVerb}))
+	})
+
+	It("Should plan volume snapshot and restore of unmounted source pvc only with data integrity check", func() {
+		run.DataSize = resource.MustParse("100Mi")
+		p := run.planResources(checks, state)
+		Expect(kindsOf(p)[18:]).To(Equal([]string{
+			internal.VolumeSnapshotKind, internal.VolumeSnapshotContentKind, internal.VolumeSnapshotKind,
+			internal.PersistentVolumeClaimKind, internal.PodKind,
+		}))
+
+		unmountedSnapshot := p.resources[18].object.(*unstructured.Unstructured)
+		Expect(unmountedSnapshot.GetName()).To(Equal(UnmountedVolumeSnapSrcNamePrefix + testNameSuffix))
+		Expect(unmountedSnapshot.GetNamespace()).To(Equal(BackupNamespacePrefix + testNameSuffix))
+		unmountedRestoredPVC := p.resources[21].object.(*corev1.PersistentVolumeClaim)
+		Expect(unmountedRestoredPVC.GetName()).To(Equal(UnmountedRestorePvcNamePrefix + testNameSuffix))
+		Expect(unmountedRestoredPVC.Spec.DataSource.Name).To(Equal(UnmountedVolumeSnapRestoreNamePrefix + testNameSuffix))
+		Expect(p.permissions).To(HaveKey("/pods/exec/" + allNamespaces))
+	})
+
+	It("Should use installed CRDs and matching snapshot class, and create resources in install namespace for namespace scope", func() {
+		run.Scope = internal.NamespaceScope
+		state.installedCRDs.Insert(VolumeSnapshotCRDs[:]...)
+		vssc := unstructured.Unstructured{Object: map[string]interface{}{"driver": testDriver}}
+		vssc.SetName(testSnapshotClass)
+		state.snapshotClasses = []unstructured.Unstructured{vssc}
+
+		p := run.planResources(checks, state)
+		Expect(kindsOf(p)).ToNot(ContainElements(internal.CustomResourceDefinitionKind, internal.VolumeSnapshotClassKind,
+			internal.NamespaceKind, internal.VolumeSnapshotContentKind))
+		for _, res := range p.resources {
+			Expect(res.object.GetNamespace()).To(Equal(installNs))
+		}
+
+		sourceSnapshot := p.resources[len(p.resources)-3].object.(*unstructured.Unstructured)
+		Expect(snapshotClassOf(sourceSnapshot)).To(Equal(testSnapshotClass))
+		restoredPVC := p.resources[len(p.resources)-2].object.(*corev1.PersistentVolumeClaim)
+		Expect(restoredPVC.Spec.DataSource.Name).To(Equal(sourceSnapshot.GetName()))
+		Expect(p.permissions).ToNot(HaveKey("/persistentvolumeclaims/" + allNamespaces))
+	})
+
+	It("Should plan resources of storage performance check in install namespace when it's selected", func() {
+		run.Checks = []string{CheckStoragePerformance}
+		var err error
+		checks, err = run.SelectChecks()
+		Expect(err).To(BeNil())
+
+		p := run.planResources(checks, state)
+		perfResources := p.resources[len(p.resources)-5:]
+		Expect(kindsOf(&resourcePlan{resources: perfResources})).To(Equal([]string{internal.PersistentVolumeClaimKind,
+			internal.PodKind, internal.VolumeSnapshotKind, internal.PersistentVolumeClaimKind, internal.PodKind}))
+		for _, res := range perfResources {
+			Expect(res.check).To(Equal(CheckStoragePerformance))
+			Expect(res.object.GetNamespace()).To(Equal(installNs))
+		}
+		Expect(perfResources[0].object.GetName()).To(Equal(PerfPvcNamePrefix + testNameSuffix))
+		restoredPVC := perfResources[3].object.(*corev1.PersistentVolumeClaim)
+		Expect(restoredPVC.Spec.DataSource.Name).To(Equal(PerfVolumeSnapNamePrefix + testNameSuffix))
+		Expect(p.permissions["/pods/exec/"+installNs].verbs.List()).To(Equal([]string{internal.CreateVerb}))
+	})
+
+	It("Should write permissions followed by the resources as multi-document yaml", func() {
+		run.Checks = []string{CheckDNSResolution}
+		var err error
+		checks, err = run.SelectChecks()
+		Expect(err).To(BeNil())
+
+		p := run.planResources(checks, state)
+		p.resources[0].serverDryRun = dryRunPassed
+		var buf bytes.Buffer
+		Expect(p.write(&buf, testNameSuffix)).To(Succeed())
+
+		docs := strings.Split(buf.String(), "---\n")
+		Expect(docs).To(HaveLen(2))
+		Expect(docs[0]).To(ContainSubstring(testNameSuffix))
+		Expect(docs[0]).To(MatchRegexp(`#\s+core\s+pods/exec\s+` + installNs + `\s+create`))
+		Expect(docs[1]).To(ContainSubstring("# check: " + CheckDNSResolution))
+		Expect(docs[1]).To(ContainSubstring("# server dry-run: " + dryRunPassed))
+
+		pod := &corev1.Pod{}
+		Expect(yaml.Unmarshal([]byte(docs[1]), pod)).To(Succeed())
+		Expect(pod.Kind).To(Equal(internal.PodKind))
+		Expect(pod.GetName()).To(Equal(dnsUtils + testNameSuffix))
+	})
+})
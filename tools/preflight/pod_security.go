@@ -0,0 +1,327 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// PodSecurityLabelPrefix is the prefix of namespace labels configuring the Pod Security Admission modes.
+	PodSecurityLabelPrefix = "pod-security.kubernetes.io/"
+
+	PodSecurityModeEnforce = "enforce"
+	PodSecurityModeWarn    = "warn"
+	PodSecurityModeAudit   = "audit"
+
+	PodSecurityLevelPrivileged = "privileged"
+	PodSecurityLevelBaseline   = "baseline"
+	PodSecurityLevelRestricted = "restricted"
+
+	// podSecurityViolationMsg is contained in the error returned by the API server when Pod Security Admission rejects a pod.
+	podSecurityViolationMsg = "violates PodSecurity"
+)
+
+// podSecurityModes are the Pod Security Admission modes in the order they are reported.
+var podSecurityModes = []string{PodSecurityModeEnforce, PodSecurityModeWarn, PodSecurityModeAudit}
+
+// podSecurityLevelRanks orders the Pod Security Standards from the least to the most restrictive.
+var podSecurityLevelRanks = map[string]int{
+	PodSecurityLevelPrivileged: 0,
+	PodSecurityLevelBaseline:   1,
+	PodSecurityLevelRestricted: 2,
+}
+
+// baselineCapabilities are the capabilities which the baseline Pod Security Standard allows adding.
+var baselineCapabilities = sets.NewString("AUDIT_WRITE", "CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "KILL", "MKNOD",
+	"NET_BIND_SERVICE", "SETFCAP", "SETGID", "SETPCAP", "SETUID", "SYS_CHROOT")
+
+// podSecurityProfile is the security context of pods of a TVK component, validated by pod capability check.
+type podSecurityProfile struct {
+	component  string
+	capability capability
+}
+
+// tvkPodSecurityProfiles maps the pod capability validation cases to the TVK components running with them.
+var tvkPodSecurityProfiles = []podSecurityProfile{
+	{component: "data mover", capability: podCapabilityValidationCases[0]},
+	{component: "control plane", capability: podCapabilityValidationCases[1]},
+	{component: "ingress controller", capability: podCapabilityValidationCases[2]},
+}
+
+// PodSecurityResult is the Pod Security Admission configuration of the namespace and its outcome for TVK components.
+type PodSecurityResult struct {
+	Namespace string `json:"namespace"`
+	// Levels are the Pod Security Standards of each mode configured by the labels of namespace.
+	Levels   map[string]string           `json:"levels,omitempty"`
+	Profiles []*PodSecurityProfileResult `json:"profiles,omitempty"`
+}
+
+// PodSecurityProfileResult is the outcome of Pod Security Admission for pods of a TVK component.
+type PodSecurityProfileResult struct {
+	Component string `json:"component"`
+	// RequiredLevel is the most restrictive Pod Security Standard which the pods satisfy.
+	RequiredLevel string `json:"requiredLevel"`
+	// Violations are the reasons the pods don't satisfy a more restrictive Pod Security Standard.
+	Violations []string    `json:"violations,omitempty"`
+	Status     CheckStatus `json:"status"`
+	// Warnings are the modes other than enforce whose level the pods violate.
+	Warnings []string `json:"warnings,omitempty"`
+	DryRun   string   `json:"dryRun,omitempty"`
+	Fix      string   `json:"fix,omitempty"`
+}
+
+// podSecurityLevels returns the Pod Security Standard of each mode configured by the namespace labels.
+// Levels which aren't valid are evaluated as restricted, as done by Pod Security Admission.
+func podSecurityLevels(nsLabels map[string]string) map[string]string {
+	levels := make(map[string]string)
+	for _, mode := range podSecurityModes {
+		level, ok := nsLabels[PodSecurityLabelPrefix+mode]
+		if !ok {
+			continue
+		}
+		if _, valid := podSecurityLevelRanks[level]; !valid {
+			level = PodSecurityLevelRestricted
+		}
+		levels[mode] = level
+	}
+	return levels
+}
+
+// podSecurityViolations returns the reasons the pod doesn't satisfy the controls of the Pod Security Standard level
+// itself, controls of less restrictive levels aren't included.
+func podSecurityViolations(pod *corev1.Pod, level string) []string {
+	var violations []string
+	podSC := pod.Spec.SecurityContext
+	if podSC == nil {
+		podSC = &corev1.PodSecurityContext{}
+	}
+
+	switch level {
+	case PodSecurityLevelBaseline:
+		if pod.Spec.HostNetwork || pod.Spec.HostPID || pod.Spec.HostIPC {
+			violations = append(violations, "host namespaces")
+		}
+		for idx := range pod.Spec.Volumes {
+			if pod.Spec.Volumes[idx].HostPath != nil {
+				violations = append(violations, fmt.Sprintf("hostPath volume - %s", pod.Spec.Volumes[idx].Name))
+			}
+		}
+		for idx := range pod.Spec.Containers {
+			c := &pod.Spec.Containers[idx]
+			if c.SecurityContext == nil {
+				continue
+			}
+			if c.SecurityContext.Privileged != nil && *c.SecurityContext.Privileged {
+				violations = append(violations, fmt.Sprintf("container %s is privileged", c.Name))
+			}
+			if c.SecurityContext.Capabilities != nil {
+				var added []string
+				for _, capName := range c.SecurityContext.Capabilities.Add {
+					if !baselineCapabilities.Has(string(capName)) {
+						added = append(added, string(capName))
+					}
+				}
+				if len(added) != 0 {
+					violations = append(violations, fmt.Sprintf("container %s adds capabilities - %s", c.Name,
+						strings.Join(added, ", ")))
+				}
+			}
+		}
+
+	case PodSecurityLevelRestricted:
+		for idx := range pod.Spec.Containers {
+			c := &pod.Spec.Containers[idx]
+			sc := c.SecurityContext
+			if sc == nil {
+				sc = &corev1.SecurityContext{}
+			}
+			if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+				violations = append(violations, fmt.Sprintf("container %s allows privilege escalation", c.Name))
+			}
+			runAsNonRoot := sc.RunAsNonRoot
+			if runAsNonRoot == nil {
+				runAsNonRoot = podSC.RunAsNonRoot
+			}
+			if runAsNonRoot == nil || !*runAsNonRoot {
+				violations = append(violations, fmt.Sprintf("container %s may run as root", c.Name))
+			}
+			if !dropsAllCapabilities(sc.Capabilities) {
+				violations = append(violations, fmt.Sprintf("container %s doesn't drop ALL capabilities", c.Name))
+			}
+			seccomp := sc.SeccompProfile
+			if seccomp == nil {
+				seccomp = podSC.SeccompProfile
+			}
+			if seccomp == nil || seccomp.Type == corev1.SeccompProfileTypeUnconfined {
+				violations = append(violations, fmt.Sprintf("container %s doesn't set seccompProfile "+
+					"to RuntimeDefault or Localhost", c.Name))
+			}
+		}
+	}
+
+	return violations
+}
+
+// dropsAllCapabilities returns whether the capabilities drop ALL, and add none other than NET_BIND_SERVICE.
+func dropsAllCapabilities(caps *corev1.Capabilities) bool {
+	if caps == nil {
+		return false
+	}
+	for _, capName := range caps.Add {
+		if capName != "NET_BIND_SERVICE" {
+			return false
+		}
+	}
+	for _, capName := range caps.Drop {
+		if capName == "ALL" {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredPodSecurityLevel returns the most restrictive Pod Security Standard which the pod satisfies, and the
+// reasons it doesn't satisfy the next more restrictive one.
+func requiredPodSecurityLevel(pod *corev1.Pod) (level string, violations []string) {
+	if violations = podSecurityViolations(pod, PodSecurityLevelBaseline); len(violations) != 0 {
+		return PodSecurityLevelPrivileged, violations
+	}
+	if violations = podSecurityViolations(pod, PodSecurityLevelRestricted); len(violations) != 0 {
+		return PodSecurityLevelBaseline, violations
+	}
+	return PodSecurityLevelRestricted, nil
+}
+
+// podSecurityAllows returns whether pods satisfying the required level are admitted by the namespace level.
+func podSecurityAllows(nsLevel, requiredLevel string) bool {
+	return podSecurityLevelRanks[requiredLevel] >= podSecurityLevelRanks[nsLevel]
+}
+
+// predictPodSecurity evaluates the pods of TVK components against the Pod Security Standards of namespace labels.
+func predictPodSecurity(o *Run, levels map[string]string) []*PodSecurityProfileResult {
+	var results []*PodSecurityProfileResult
+	for idx, profile := range tvkPodSecurityProfiles {
+		pod := createPodSpecWithCapability(o, fmt.Sprintf("%d-%s", idx, resNameSuffix), profile.capability)
+		requiredLevel, violations := requiredPodSecurityLevel(pod)
+		result := &PodSecurityProfileResult{Component: profile.component, RequiredLevel: requiredLevel,
+			Violations: violations, Status: CheckStatusPass}
+		for _, mode := range podSecurityModes {
+			level, ok := levels[mode]
+			if !ok || podSecurityAllows(level, requiredLevel) {
+				continue
+			}
+			if mode == PodSecurityModeEnforce {
+				result.Status = CheckStatusFail
+			} else {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%s=%s", mode, level))
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// podSecurityFix returns the label change of namespace with which pods of the required level are admitted.
+func podSecurityFix(namespace, requiredLevel string) string {
+	return fmt.Sprintf("kubectl label namespace %s %s%s=%s --overwrite", namespace, PodSecurityLabelPrefix,
+		PodSecurityModeEnforce, requiredLevel)
+}
+
+// dryRunPodSecurity creates the pod with server-side dry-run, so that the cluster-wide defaults and exemptions of
+// Pod Security Admission are evaluated too. It returns whether the pod is rejected by Pod Security Admission.
+func dryRunPodSecurity(ctx context.Context, pod *corev1.Pod, runtimeClient client.Client) (rejected bool, outcome string) {
+	err := runtimeClient.Create(ctx, pod, client.DryRunAll)
+	switch {
+	case err == nil:
+		return false, dryRunPassed
+	case k8serrors.IsForbidden(err) && strings.Contains(err.Error(), podSecurityViolationMsg):
+		return true, "rejected :: " + err.Error()
+	default:
+		return false, "skipped :: " + err.Error()
+	}
+}
+
+func runPodSecurityCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	o.Logger.Infoln("Checking Pod Security Admission of namespace for TVK pods")
+
+	result := o.podSecurityResult
+	if result == nil {
+		result = &PodSecurityResult{}
+		o.podSecurityResult = result
+	}
+	result.Namespace = o.Namespace
+
+	var nsLabels map[string]string
+	ns, err := kubeClient.ClientSet.CoreV1().Namespaces().Get(ctx, o.Namespace, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		nsLabels = ns.GetLabels()
+	case k8serrors.IsNotFound(err):
+		res.Warn(fmt.Sprintf("Namespace - %s doesn't exist, Pod Security Admission is evaluated with cluster-wide defaults",
+			o.Namespace))
+	default:
+		return err
+	}
+	result.Levels = podSecurityLevels(nsLabels)
+	for _, mode := range podSecurityModes {
+		if level, ok := result.Levels[mode]; ok {
+			o.Logger.Infof("Namespace - %s has Pod Security Admission mode %s=%s", o.Namespace, mode, level)
+		}
+	}
+	result.Profiles = predictPodSecurity(o, result.Levels)
+
+	var (
+		rejected []string
+		fixLevel = PodSecurityLevelRestricted
+	)
+	for idx, profile := range result.Profiles {
+		// dry-run pods are named differently than the pods of pod capability check, which may exist concurrently
+		pod := createPodSpecWithCapability(o, fmt.Sprintf("psa-%d-%s", idx, resNameSuffix), tvkPodSecurityProfiles[idx].capability)
+		dryRunRejected, outcome := dryRunPodSecurity(ctx, pod, kubeClient.RuntimeClient)
+		profile.DryRun = outcome
+		// server-side dry-run is authoritative when performed, it includes cluster-wide defaults and exemptions
+		if dryRunRejected {
+			profile.Status = CheckStatusFail
+		} else if outcome == dryRunPassed {
+			profile.Status = CheckStatusPass
+		}
+
+		if profile.Status == CheckStatusFail {
+			profile.Fix = podSecurityFix(o.Namespace, profile.RequiredLevel)
+			rejected = append(rejected, profile.Component)
+			if !podSecurityAllows(fixLevel, profile.RequiredLevel) {
+				fixLevel = profile.RequiredLevel
+			}
+			o.Logger.Errorf("%s Pods of TVK %s, requiring Pod Security Standard %s, would be rejected :: %s", cross,
+				profile.Component, profile.RequiredLevel, strings.Join(profile.Violations, "; "))
+			continue
+		}
+		o.Logger.Infof("%s Pods of TVK %s, requiring Pod Security Standard %s, would be admitted", check,
+			profile.Component, profile.RequiredLevel)
+		for _, warning := range profile.Warnings {
+			res.Warn(fmt.Sprintf("Pods of TVK %s violate Pod Security Admission mode %s of namespace - %s",
+				profile.Component, warning, o.Namespace))
+		}
+	}
+
+	if len(rejected) != 0 {
+		if _, labeled := result.Levels[PodSecurityModeEnforce]; !labeled {
+			res.Warn(fmt.Sprintf("Namespace - %s has no %s%s label, pods of TVK are rejected by the cluster-wide "+
+				"default of Pod Security Admission", o.Namespace, PodSecurityLabelPrefix, PodSecurityModeEnforce))
+		}
+		res.Recommend(fmt.Sprintf("Allow the pods of TVK in namespace - %s by labeling it, or exempt the namespace "+
+			"in the Pod Security Admission configuration of the cluster :: %s", o.Namespace,
+			podSecurityFix(o.Namespace, fixLevel)))
+		return fmt.Errorf("pods of TVK components - [%s] would be rejected by Pod Security Admission of namespace - %s",
+			strings.Join(rejected, ", "), o.Namespace)
+	}
+
+	return nil
+}
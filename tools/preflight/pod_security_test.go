@@ -0,0 +1,94 @@
+package preflight
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("Pod security unit tests", func() {
+
+	var run *Run
+
+	BeforeEach(func() {
+		run = runOps.copyRun()
+		run.Namespace = "tvk"
+	})
+
+	Context("podSecurityLevels func test-cases", func() {
+
+		It("Should return levels of the modes labeled on namespace, evaluating invalid levels as restricted", func() {
+			Expect(podSecurityLevels(map[string]string{
+				PodSecurityLabelPrefix + PodSecurityModeEnforce:              PodSecurityLevelBaseline,
+				PodSecurityLabelPrefix + PodSecurityModeEnforce + "-version": "latest",
+				PodSecurityLabelPrefix + PodSecurityModeWarn:                 "strict",
+				"team": "backup",
+			})).To(Equal(map[string]string{
+				PodSecurityModeEnforce: PodSecurityLevelBaseline,
+				PodSecurityModeWarn:    PodSecurityLevelRestricted,
+			}))
+			Expect(podSecurityLevels(nil)).To(BeEmpty())
+		})
+	})
+
+	Context("requiredPodSecurityLevel func test-cases", func() {
+
+		It("Should require privileged level for pods of pod capability check adding SYS_ADMIN", func() {
+			for idx := range podCapabilityValidationCases {
+				level, violations := requiredPodSecurityLevel(createPodSpecWithCapability(run, "abcdef",
+					podCapabilityValidationCases[idx]))
+				Expect(level).To(Equal(PodSecurityLevelPrivileged))
+				Expect(violations).To(ContainElement(ContainSubstring("adds capabilities - SYS_ADMIN")))
+			}
+			_, violations := requiredPodSecurityLevel(createPodSpecWithCapability(run, "abcdef", podCapabilityValidationCases[0]))
+			Expect(violations).To(ContainElement(ContainSubstring("is privileged")))
+		})
+
+		It("Should require baseline level for pods running as root without additional capabilities", func() {
+			pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+			level, violations := requiredPodSecurityLevel(pod)
+			Expect(level).To(Equal(PodSecurityLevelBaseline))
+			Expect(violations).To(ContainElements(ContainSubstring("allows privilege escalation"),
+				ContainSubstring("may run as root"), ContainSubstring("seccompProfile")))
+		})
+
+		It("Should satisfy restricted level for pods following the restricted controls", func() {
+			runAsNonRoot, allowPrivilegeEscalation := true, false
+			pod := &corev1.Pod{Spec: corev1.PodSpec{
+				SecurityContext: &corev1.PodSecurityContext{RunAsNonRoot: &runAsNonRoot,
+					SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}},
+				Containers: []corev1.Container{{Name: "app", SecurityContext: &corev1.SecurityContext{
+					AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+					Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+				}}},
+			}}
+			level, violations := requiredPodSecurityLevel(pod)
+			Expect(level).To(Equal(PodSecurityLevelRestricted))
+			Expect(violations).To(BeEmpty())
+		})
+	})
+
+	Context("predictPodSecurity func test-cases", func() {
+
+		It("Should reject TVK pods on namespace enforcing baseline level and suggest the label change", func() {
+			results := predictPodSecurity(run, map[string]string{PodSecurityModeEnforce: PodSecurityLevelBaseline})
+			Expect(results).To(HaveLen(len(tvkPodSecurityProfiles)))
+			for _, res := range results {
+				Expect(res.Status).To(Equal(CheckStatusFail))
+				Expect(res.RequiredLevel).To(Equal(PodSecurityLevelPrivileged))
+			}
+			Expect(podSecurityFix(run.Namespace, PodSecurityLevelPrivileged)).To(Equal(
+				"kubectl label namespace tvk pod-security.kubernetes.io/enforce=privileged --overwrite"))
+		})
+
+		It("Should admit TVK pods on namespace enforcing privileged level, recording warn and audit modes", func() {
+			results := predictPodSecurity(run, map[string]string{
+				PodSecurityModeEnforce: PodSecurityLevelPrivileged,
+				PodSecurityModeWarn:    PodSecurityLevelRestricted,
+				PodSecurityModeAudit:   PodSecurityLevelPrivileged,
+			})
+			Expect(results[0].Status).To(Equal(CheckStatusPass))
+			Expect(results[0].Warnings).To(Equal([]string{"warn=restricted"}))
+		})
+	})
+})
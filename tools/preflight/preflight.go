@@ -7,13 +7,16 @@ import (
 	"fmt"
 	"math/big"
 	goexec "os/exec"
-	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	version "github.com/hashicorp/go-version"
 	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
 	log "github.com/sirupsen/logrus"
 	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -22,34 +25,81 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	k8swait "k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/yaml"
 
 	"github.com/trilioData/tvk-plugins/internal"
-	"github.com/trilioData/tvk-plugins/tools/preflight/exec"
 	"github.com/trilioData/tvk-plugins/tools/preflight/wait"
 )
 
 // RunOptions input options required for running preflight.
 type RunOptions struct {
-	StorageClass                string            `json:"storageClass"`
-	SnapshotClass               string            `json:"snapshotClass,omitempty"`
-	LocalRegistry               string            `json:"localRegistry,omitempty"`
-	ImagePullSecret             string            `json:"imagePullSecret,omitempty"`
-	ServiceAccountName          string            `json:"serviceAccount,omitempty"`
-	PerformCleanupOnFail        bool              `json:"cleanupOnFailure,omitempty"`
-	PVCStorageRequest           resource.Quantity `json:"pvcStorageRequest,omitempty"`
+	StorageClass                string                              `json:"storageClass"`
+	StorageClasses              []string                            `json:"storageClasses,omitempty"`
+	SnapshotClass               string                              `json:"snapshotClass,omitempty"`
+	LocalRegistry               string                              `json:"localRegistry,omitempty"`
+	ImagePullSecret             string                              `json:"imagePullSecret,omitempty"`
+	ImageOverrides              map[string]string                   `json:"imageOverrides,omitempty"`
+	ImageAvailability           *ImageAvailabilityOptions           `json:"imageAvailability,omitempty"`
+	DNS                         *DNSOptions                         `json:"dns,omitempty"`
+	ServiceAccountName          string                              `json:"serviceAccount,omitempty"`
+	PerformCleanupOnFail        bool                                `json:"cleanupOnFailure,omitempty"`
+	PVCStorageRequest           resource.Quantity                   `json:"pvcStorageRequest,omitempty"`
+	DataSize                    resource.Quantity                   `json:"dataSize,omitempty"`
+	DataFiles                   int                                 `json:"dataFiles,omitempty"`
+	BlockVolume                 bool                                `json:"blockVolume,omitempty"`
+	Topology                    bool                                `json:"topology,omitempty"`
+	StoragePerformance          *StoragePerformanceOptions          `json:"storagePerformance,omitempty"`
+	Target                      *TargetOptions                      `json:"target,omitempty"`
+	AccessModes                 []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
 	corev1.ResourceRequirements `json:"resources,omitempty"`
-	PodSchedOps                 podSchedulingOptions `json:"podSchedulingOptions"`
+	PodSchedOps                 podSchedulingOptions       `json:"podSchedulingOptions"`
+	Checks                      []string                   `json:"checks,omitempty"`
+	SkipChecks                  []string                   `json:"skipChecks,omitempty"`
+	Parallelism                 int                        `json:"parallelism,omitempty"`
+	Timeout                     metav1.Duration            `json:"timeout,omitempty"`
+	CheckTimeouts               map[string]metav1.Duration `json:"checkTimeouts,omitempty"`
+	OutputFormat                string                     `json:"output,omitempty"`
+	ReportFile                  string                     `json:"reportFile,omitempty"`
+	Discover                    bool                       `json:"discover,omitempty"`
+	DiscoverRoundTrip           bool                       `json:"discoverRoundTrip,omitempty"`
+	ResultConfigMap             string                     `json:"resultConfigMap,omitempty"`
+	DryRun                      string                     `json:"dryRun,omitempty"`
 }
 
 type Run struct {
 	RunOptions
 	CommonOptions
+
+	// checkResult is the result of the check being performed, created resources are recorded on it.
+	checkResult *CheckResult
+	// scMatrix holds snapshot and restore results of all the storage classes.
+	scMatrix *storageClassMatrix
+	// scResult is the result of the storage class being checked.
+	scResult *StorageClassResult
+	// blockMode is set when volume snapshot and restore are performed for raw block volumes.
+	blockMode bool
+	// targetResult holds the outcome of each step of the verification of backup target.
+	targetResult *TargetResult
+	// podSecurityResult holds the Pod Security Admission configuration of namespace and its outcome for TVK pods.
+	podSecurityResult *PodSecurityResult
+	// sccResult holds the SCCs usable by TVK service account and their outcome for pod capability cases.
+	sccResult *OpenShiftSCCResult
+	// webhooksResult holds the admission webhooks matching resources of TVK and the mutations of preflight pods.
+	webhooksResult *AdmissionWebhooksResult
+	// quotaResult holds the headroom of ResourceQuotas of namespace and the LimitRange constraints violated by preflight.
+	quotaResult *ResourceQuotaResult
+	// nodeInventory holds the architecture, OS and allocatable resources of nodes, and whether preflight pods fit them.
+	nodeInventory *NodeInventoryResult
+	// imagesResult holds the outcome of pulling each of the images verified by image availability check.
+	imagesResult *ImageAvailabilityResult
+	// networkResult holds the NetworkPolicies of namespace and the outcome of network flows needed by TVK.
+	networkResult *NetworkPolicyResult
+	// dnsResult holds the resolver configuration of DNS pod, readiness of cluster DNS pods and the outcome of resolving names.
+	dnsResult *DNSResult
 }
 
 // CreateResourceNameSuffix creates a unique 6-length hash for preflight check.
@@ -72,10 +122,22 @@ func CreateResourceNameSuffix() (string, error) {
 func (o *Run) logPreflightOptions() {
 	o.Logger.Infof("====PREFLIGHT RUN OPTIONS====")
 	o.logCommonOptions()
-	o.Logger.Infof("STORAGE-CLASS=\"%s\"", o.StorageClass)
+	o.Logger.Infof("STORAGE-CLASS=\"%s\"", strings.Join(o.storageClasses(), ","))
 	o.Logger.Infof("VOLUME-SNAPSHOT-CLASS=\"%s\"", o.SnapshotClass)
 	o.Logger.Infof("LOCAL-REGISTRY=\"%s\"", o.LocalRegistry)
 	o.Logger.Infof("IMAGE-PULL-SECRET=\"%s\"", o.ImagePullSecret)
+	for _, name := range sets.StringKeySet(o.ImageOverrides).List() {
+		o.Logger.Infof("IMAGE-OVERRIDE=\"%s=%s\"", name, o.ImageOverrides[name])
+	}
+	if o.ImageAvailability != nil {
+		o.Logger.Infof("TVK-VERSION=\"%s\"", o.ImageAvailability.TVKVersion)
+		o.Logger.Infof("IMAGES-FILE=\"%s\"", o.ImageAvailability.ImagesFile)
+	}
+	if o.DNS != nil {
+		o.Logger.Infof("DNS-NAMES=\"%s\"", strings.Join(o.DNS.Names, ","))
+		o.Logger.Infof("DNS-ATTEMPTS=\"%d\"", o.dnsAttempts())
+		o.Logger.Infof("DNS-MAX-LATENCY=\"%s\"", o.dnsMaxLatency())
+	}
 	o.Logger.Infof("SERVICE-ACCOUNT=\"%s\"", o.ServiceAccountName)
 	o.Logger.Infof("CLEANUP-ON-FAILURE=\"%v\"", o.PerformCleanupOnFail)
 	o.Logger.Infof("POD CPU REQUEST=\"%s\"", o.ResourceRequirements.Requests.Cpu().String())
@@ -83,202 +145,105 @@ func (o *Run) logPreflightOptions() {
 	o.Logger.Infof("POD CPU LIMIT=\"%s\"", o.ResourceRequirements.Limits.Cpu().String())
 	o.Logger.Infof("POD MEMORY LIMIT=\"%s\"", o.ResourceRequirements.Limits.Memory().String())
 	o.Logger.Infof("PVC STORAGE REQUEST=\"%s\"", o.PVCStorageRequest.String())
+	o.Logger.Infof("DATA-SIZE=\"%s\"", o.DataSize.String())
+	o.Logger.Infof("DATA-FILES=\"%d\"", o.dataFiles())
+	o.Logger.Infof("BLOCK-VOLUME=\"%v\"", o.BlockVolume)
+	o.Logger.Infof("TOPOLOGY=\"%v\"", o.Topology)
+	if o.StoragePerformance != nil {
+		o.Logger.Infof("STORAGE-PERFORMANCE=\"%s\"", o.storagePerformanceString())
+	}
+	if len(o.AccessModes) != 0 {
+		o.Logger.Infof("ACCESS-MODES=\"%s\"", o.accessModesString())
+	}
+	if o.Target != nil {
+		o.Logger.Infof("TARGET=\"%s\"", o.Target.endpoint())
+	}
+	o.Logger.Infof("CHECKS=\"%s\"", strings.Join(o.Checks, ","))
+	o.Logger.Infof("SKIP-CHECKS=\"%s\"", strings.Join(o.SkipChecks, ","))
+	o.Logger.Infof("PARALLELISM=\"%d\"", o.parallelism())
+	o.Logger.Infof("TIMEOUT=\"%s\"", o.Timeout.Duration)
+	o.Logger.Infof("CHECK-TIMEOUTS=\"%s\"", o.checkTimeoutsString())
+	o.Logger.Infof("OUTPUT=\"%s\"", o.OutputFormat)
+	o.Logger.Infof("REPORT-FILE=\"%s\"", o.ReportFile)
+	o.Logger.Infof("DISCOVER=\"%v\"", o.Discover)
+	o.Logger.Infof("DISCOVER-ROUND-TRIP=\"%v\"", o.DiscoverRoundTrip)
+	o.Logger.Infof("RESULT-CONFIGMAP=\"%s\"", o.ResultConfigMap)
+	o.Logger.Infof("DRY-RUN=\"%s\"", o.DryRun)
 	o.Logger.Infof("====PREFLIGHT RUN OPTIONS END====")
 }
 
-// PerformPreflightChecks performs all preflight checks.
-//
-//nolint:gocyclo // for future ref
-func (o *Run) PerformPreflightChecks(ctx context.Context) error {
-	o.logPreflightOptions()
-	var err error
-	preflightStatus := true
-	resNameSuffix, err = CreateResourceNameSuffix()
-	if err != nil {
-		o.Logger.Errorf("Error generating resource name suffix :: %s", err.Error())
-		return err
+// checkTimeoutsString returns the per-check timeouts in 'check=timeout' format, sorted by check name.
+func (o *Run) checkTimeoutsString() string {
+	var timeouts []string
+	for name, timeout := range o.CheckTimeouts {
+		timeouts = append(timeouts, fmt.Sprintf("%s=%s", name, timeout.Duration))
 	}
-	storageSnapshotSuccess := true
+	sort.Strings(timeouts)
+	return strings.Join(timeouts, ",")
+}
 
-	o.Logger.Infof("Generated UID for preflight check - %s\n", resNameSuffix)
+// PerformPreflightChecks performs the selected preflight checks.
+func (o *Run) PerformPreflightChecks(ctx context.Context) error {
+	o.logPreflightOptions()
+	startTime := time.Now()
 
-	//  check kubectl
-	if o.InCluster {
-		o.Logger.Infoln("In cluster flag enabled. Skipping check for kubectl...")
-	} else {
-		o.Logger.Infoln("Checking for kubectl")
-		err = o.validateKubectl(kubectlBinaryName)
+	// in discover mode, storage classes are evaluated without creating any resource. Volume snapshot and restore
+	// checks are performed for the candidates having a volume snapshot class only if round trip is requested.
+	var candidates []*StorageClassCandidate
+	if o.Discover {
+		var err error
+		candidates, err = o.discoverStorageClasses(ctx)
 		if err != nil {
-			o.Logger.Errorf("%s Preflight check for kubectl utility failed :: %s\n", cross, err.Error())
-			o.Logger.Errorf("%s Preflight check for kubectl utility failed :: %s\n", cross, err.Error())
-			preflightStatus = false
-		} else {
-			o.Logger.Infof("%s Preflight check for kubectl utility is successful\n", check)
+			o.Logger.Errorf("%s Error discovering storage classes :: %s", cross, err.Error())
+			return err
 		}
-	}
-
-	// check cluster default ns access
-	o.Logger.Infoln("Checking access to the default namespace of cluster")
-	err = o.validateClusterAccess(ctx, internal.DefaultNs, kubeClient.ClientSet)
-	if err != nil {
-		preflightStatus = false
-	} else {
-		o.Logger.Infof("%s Preflight check for kubectl access is successful\n", check)
-	}
-
-	// Helm check
-	if o.InCluster {
-		o.Logger.Infoln("In cluster flag enabled. Skipping check for helm...")
-	} else {
-		o.Logger.Infof("Checking for required Helm version (>= %s)\n", minHelmVersion)
-		err = o.validateSystemHelmVersion(HelmBinaryName, kubeClient.DiscClient)
-		if err != nil {
-			o.Logger.Errorf("%s Preflight check for helm version failed :: %s\n", cross, err.Error())
-			preflightStatus = false
+		if !o.DiscoverRoundTrip || !o.prepareDiscoveryRoundTrip(candidates) {
+			return o.completeDiscovery(ctx, candidates, startTime)
 		}
 	}
 
-	// kubernetes server version check
-	o.Logger.Infof("Checking for required kubernetes server version (>=%s)\n", minK8sVersion)
-	k8sVersionWarning, err := o.validateKubernetesVersion(minK8sVersion, kubeClient.ClientSet)
+	checks, err := o.SelectChecks()
 	if err != nil {
-		o.Logger.Errorf("%s Preflight check for kubernetes version failed :: %s\n", cross, err.Error())
-		preflightStatus = false
-	} else {
-		o.Logger.Infof("%s Preflight check for kubernetes version is successful\n", check)
+		o.Logger.Errorf("Error selecting preflight checks :: %s", err.Error())
+		return err
 	}
-
-	// rbac check
-	o.Logger.Infoln("Checking Kubernetes RBAC")
-	err = o.validateKubernetesRBAC(RBACAPIGroup, RBACAPIVersion, kubeClient.DiscClient)
+	resNameSuffix, err = CreateResourceNameSuffix()
 	if err != nil {
-		o.Logger.Errorf("%s Preflight check for kubernetes RBAC failed :: %s\n", cross, err.Error())
-		preflightStatus = false
-	} else {
-		o.Logger.Infof("%s Preflight check for kubernetes RBAC is successful\n", check)
-	}
-
-	//  Check VolumeSnapshot CRDs installation
-	o.Logger.Infoln("Checking if VolumeSnapshot CRDs are installed in the cluster or else create")
-	var skipSnapshotCRDCheck bool
-	serverVersion, sErr := kubeClient.DiscClient.ServerVersion()
-	if sErr != nil {
-		o.Logger.Errorf("Preflight check for VolumeSnapshot CRDs failed :: error getting server version: %s\n",
-			sErr.Error())
-		skipSnapshotCRDCheck = true
-		preflightStatus = false
-	}
-	if !skipSnapshotCRDCheck {
-		err = o.checkAndCreateVolumeSnapshotCRDs(ctx, serverVersion.String(), kubeClient.RuntimeClient)
-		if err != nil {
-			o.Logger.Errorf("Preflight check for VolumeSnapshot CRDs failed :: %s\n", err.Error())
-			o.Logger.Errorf("ACTION REQUIRED: Create VolumeSnapshotClass, VolumeSnapshotContent, VolumeSnapshot CRDs")
-			preflightStatus = false
-		} else {
-			o.Logger.Infof("%s Preflight check for VolumeSnapshot CRDs is successful\n", check)
-		}
+		o.Logger.Errorf("Error generating resource name suffix :: %s", err.Error())
+		return err
 	}
 
-	//  Check storage snapshot class
-	o.Logger.Infoln("Checking if a StorageClass and VolumeSnapshotClass are present")
-	var (
-		skipSnapshotClassCheck bool
-		prefVersion            string
-	)
-	sc, err := kubeClient.ClientSet.StorageV1().StorageClasses().Get(ctx, o.StorageClass, metav1.GetOptions{})
-	if err != nil {
-		if k8serrors.IsNotFound(err) {
-			o.Logger.Errorf("%s Preflight check for SnapshotClass failed :: not found storageclass -"+
-				" %s on cluster\n", cross, o.StorageClass)
-		}
-		o.Logger.Errorf("%s Preflight check for SnapshotClass failed :: %s\n", cross, err.Error())
-		skipSnapshotClassCheck = true
-		storageSnapshotSuccess = false
-		preflightStatus = false
-	} else {
-		o.warnIfLegacyNonSnapshotDriver(sc.Provisioner)
+	o.Logger.Infof("Generated UID for preflight check - %s\n", resNameSuffix)
+	o.scMatrix = newStorageClassMatrix(o.storageClasses(), resNameSuffix)
+	if o.Target != nil {
+		o.targetResult = &TargetResult{}
 	}
-
-	err = o.validateRequiredPodCapabilities(ctx, resNameSuffix, kubeClient)
-	if err != nil {
-		o.Logger.Errorf("%s Preflight check for pod capability failed :: %s\n", cross, err.Error())
-		preflightStatus = false
-	} else {
-		o.Logger.Infof("%s Preflight check for pod capability is successful\n", check)
+	if o.ImageAvailability != nil {
+		o.imagesResult = &ImageAvailabilityResult{}
 	}
+	o.podSecurityResult = &PodSecurityResult{}
+	o.sccResult = &OpenShiftSCCResult{}
+	o.webhooksResult = &AdmissionWebhooksResult{}
+	o.quotaResult = &ResourceQuotaResult{}
+	o.nodeInventory = &NodeInventoryResult{}
+	o.networkResult = &NetworkPolicyResult{}
+	o.dnsResult = &DNSResult{}
 
-	if !skipSnapshotClassCheck {
-		prefVersion, err = GetServerPreferredVersionForGroup(StorageSnapshotGroup, kubeClient.ClientSet)
-		if err != nil {
-			o.Logger.Errorf("%s Preflight check for SnapshotClass failed :: error getting preferred version for group"+
-				" - %s :: %s\n", cross, StorageSnapshotGroup, err.Error())
-			storageSnapshotSuccess = false
-			preflightStatus = false
-			skipSnapshotClassCheck = true
-		}
-
-		if !skipSnapshotClassCheck {
-			err = o.validateStorageSnapshotClass(ctx, sc.Provisioner, prefVersion,
-				kubeClient.ClientSet, kubeClient.RuntimeClient)
-			if err != nil {
-				o.Logger.Errorf("%s Preflight check for SnapshotClass failed :: %s\n", cross, err.Error())
-				o.Logger.Errorln("\nRecommendations:")
-				o.Logger.Errorf("1. Verify CSI driver supports snapshots")
-				o.Logger.Errorf("2. Check snapshot controller logs")
-				o.Logger.Errorf("4. Check CSI driver documentation for snapshot requirements")
-				storageSnapshotSuccess = false
-				preflightStatus = false
-			} else {
-				o.Logger.Infof("%s Preflight check for SnapshotClass is successful\n", check)
-			}
-		}
+	// in dry-run mode, resources which the checks would create are printed instead of performing the checks
+	if o.DryRun != "" {
+		return o.performDryRun(ctx, checks)
 	}
 
-	//  Check DNS resolution
-	err = o.validateDNSResolution(ctx, execDNSResolutionCmd, resNameSuffix, kubeClient)
-	o.Logger.Infoln("Checking if DNS resolution is working in k8s cluster")
-	if err != nil {
-		o.Logger.Errorf("%s Preflight check for DNS resolution failed :: %s\n", cross, err.Error())
-		preflightStatus = false
-	} else {
-		o.Logger.Infof("%s Preflight check for DNS resolution is successful\n", check)
+	// resources are cleaned up with the given context, even if the preflight run exceeds its timeout
+	runCtx := ctx
+	if o.Timeout.Duration > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, o.Timeout.Duration)
+		defer cancel()
 	}
 
-	//  Check volume snapshot and restore
-	if storageSnapshotSuccess {
-		// Check namespace permissions
-		o.Logger.Infoln("Checking create and delete namespace permissions")
-		err = o.validateNamespacePermissions(ctx, kubeClient.ClientSet)
-		if err != nil {
-			o.Logger.Errorf("%s Preflight check for namespace permissions failed :: %s\n", cross, err.Error())
-			preflightStatus = false
-		} else {
-			o.Logger.Infof("%s Preflight check for namespace permissions is successful\n", check)
-		}
-
-		o.Logger.Infoln("Checking if volume snapshot and restore is enabled in cluster")
-
-		if o.Scope == internal.ClusterScope {
-			err = o.validateClusterScopeVolumeSnapshot(ctx, resNameSuffix, kubeClient)
-			if err != nil {
-				o.Logger.Errorf("%s Preflight check for cluster scope volume snapshot and restore failed :: %s\n", cross, err.Error())
-				preflightStatus = false
-			} else {
-				o.Logger.Infof("%s Preflight check for cluster scope volume snapshot and restore is successful\n", check)
-			}
-		}
-		if o.Scope == internal.NamespaceScope {
-			err = o.validateNamespaceScopeVolumeSnapshot(ctx, resNameSuffix, kubeClient)
-			if err != nil {
-				o.Logger.Errorf("%s Preflight check for namespace scope volume snapshot and restore failed :: %s\n", cross, err.Error())
-				preflightStatus = false
-			} else {
-				o.Logger.Infof("%s Preflight check for namespace scope volume snapshot and restore is successful\n", check)
-			}
-		}
-	} else {
-		o.Logger.Errorf("Skipping volume snapshot and restore check as preflight check for SnapshotClass failed")
-	}
+	preflightStatus := o.runChecks(runCtx, checks)
+	report := o.newReport(checks, startTime, preflightStatus)
 
 	// Add the install, backup and restore namespace to perform cleanup of the cloned snapshot and pvc
 	co := &Cleanup{
@@ -297,12 +262,17 @@ func (o *Run) PerformPreflightChecks(ctx context.Context) error {
 		o.Logger.Infoln("All preflight checks succeeded!")
 	}
 
-	// Display kubernetes version warning at the end if present
-	if k8sVersionWarning != "" {
-		o.Logger.Warnln("========================================")
-		o.Logger.Warnf("⚠ WARNING: %s", k8sVersionWarning)
-		o.Logger.Warnln("========================================")
+	o.logStorageClassMatrix()
+
+	// Display warnings, like kubernetes version warning, at the end if present
+	o.logCheckWarnings(checks)
+
+	if o.Discover {
+		o.updateRoundTripResults(candidates)
+		o.logStorageClassRecommendation(candidates)
+		report.Discovery = candidates
 	}
+
 	if preflightStatus || o.PerformCleanupOnFail {
 		err = co.CleanupPreflightResources(ctx)
 		if err != nil {
@@ -310,6 +280,13 @@ func (o *Run) PerformPreflightChecks(ctx context.Context) error {
 		}
 	}
 
+	if err = o.outputReport(ctx, report); err != nil {
+		return err
+	}
+
+	if o.Discover {
+		return discoveryError(candidates)
+	}
 	if !preflightStatus {
 		return fmt.Errorf("some preflight checks failed. Check logs for more details")
 	}
@@ -317,8 +294,8 @@ func (o *Run) PerformPreflightChecks(ctx context.Context) error {
 	return nil
 }
 
-// warnIfLegacyNonSnapshotDriver checks if the CSI driver does not support snapshots
-func (o *Run) warnIfLegacyNonSnapshotDriver(provisioner string) {
+// warnIfLegacyNonSnapshotDriver checks if the CSI driver does not support snapshots, and returns true if so
+func (o *Run) warnIfLegacyNonSnapshotDriver(provisioner string) bool {
 	knownNonSnapshotDrivers := map[string]bool{
 		"kubernetes.io/aws-ebs":        true,
 		"kubernetes.io/azure-disk":     true,
@@ -330,7 +307,9 @@ func (o *Run) warnIfLegacyNonSnapshotDriver(provisioner string) {
 	}
 	if knownNonSnapshotDrivers[provisioner] {
 		o.Logger.Errorf("  ⚠ Provisioner '%s' is a legacy driver that does not support snapshots. Consider migrating to CSI driver.", provisioner)
+		return true
 	}
+	return false
 }
 
 // validateKubectl checks whether kubectl utility is installed.
@@ -360,14 +339,15 @@ func (o *Run) validateClusterAccess(ctx context.Context, namespace string, kubeC
 			o.Logger.Errorf("Unauthorized: authentication failed when accessing namespace '%s'", namespace)
 			return fmt.Errorf("unauthorized: unable to access namespace '%s' :: %s", namespace, err.Error())
 		}
-		o.Logger.Errorf("%s Preflight check for cluster access failed :: %s\n", cross, err.Error())
-		o.Logger.Info("Action Item: Provide service account OR kubeconfig file user with privilege to access namespace resource.")
 		return fmt.Errorf("unable to access default namespace of cluster :: %s", err.Error())
 	}
 
 	return nil
 }
 
+// namespacePermissionVerbs are the verbs on namespaces reviewed for the current user by namespace permissions check.
+var namespacePermissionVerbs = []string{internal.CreateVerb, internal.DeleteVerb}
+
 func (o *Run) validateNamespacePermissions(ctx context.Context, kubeClient *kubernetes.Clientset) error {
 	gvr := metav1.GroupVersionResource{
 		Group:    "",
@@ -375,21 +355,14 @@ func (o *Run) validateNamespacePermissions(ctx context.Context, kubeClient *kube
 		Resource: "namespaces",
 	}
 
-	allowed, reason, err := o.checkPermission(ctx, kubeClient, gvr, internal.CreateVerb, "")
-	if err != nil {
-		return fmt.Errorf("create namespace permission check failed: %v", err)
-	}
-	if !allowed {
-		return fmt.Errorf("create namespace not allowed: %s", reason)
-	}
-
-	// Clean up the created namespace
-	allowed, reason, err = o.checkPermission(ctx, kubeClient, gvr, internal.DeleteVerb, "")
-	if err != nil {
-		return fmt.Errorf("delete namespace permission check failed: %v", err)
-	}
-	if !allowed {
-		return fmt.Errorf("delete namespace not allowed: %s", reason)
+	for _, verb := range namespacePermissionVerbs {
+		allowed, reason, err := o.checkPermission(ctx, kubeClient, gvr, verb, "")
+		if err != nil {
+			return fmt.Errorf("%s namespace permission check failed: %v", verb, err)
+		}
+		if !allowed {
+			return fmt.Errorf("%s namespace not allowed: %s", verb, reason)
+		}
 	}
 	return nil
 }
@@ -442,13 +415,7 @@ func (o *Run) validateSystemHelmVersion(binaryName string, cl *discovery.Discove
 		return err
 	}
 
-	if err := o.validateHelmVersion(curVersion); err != nil {
-		return err
-	}
-
-	o.Logger.Infof("%s Preflight check for helm version is successful\n", check)
-
-	return nil
+	return o.validateHelmVersion(curVersion)
 }
 
 func (o *Run) validateHelmVersion(curVersion string) error {
@@ -544,14 +511,14 @@ func (o *Run) validateStorageSnapshotClass(ctx context.Context, provisioner, pre
 	kubeClient *kubernetes.Clientset, runtClient client.Client) error {
 	o.Logger.Infof("%s Storageclass - %s found on cluster\n", check, o.StorageClass)
 	if o.SnapshotClass == "" {
-		var err error
-		storageVolSnapClass, err = o.checkAndCreateSnapshotClassForProvisioner(ctx, prefVersion, provisioner, runtClient)
+		volSnapClass, err := o.checkAndCreateSnapshotClassForProvisioner(ctx, prefVersion, provisioner, runtClient)
 		if err != nil {
 			o.Logger.Errorf("%s %s\n", cross, err.Error())
 			return err
 		}
+		o.setSnapshotClass(volSnapClass)
 	} else {
-		storageVolSnapClass = o.SnapshotClass
+		o.setSnapshotClass(o.SnapshotClass)
 		vsc, err := clusterHasVolumeSnapshotClass(ctx, o.SnapshotClass, kubeClient, runtClient)
 		if err != nil {
 			o.Logger.Errorf("%s %s\n", cross, err.Error())
@@ -595,19 +562,12 @@ func (o *Run) checkAndCreateSnapshotClassForProvisioner(ctx context.Context, pre
 		return vscName, nil
 	}
 
-	sscName := ""
-	for idx := range vsscList.Items {
-		vssc := &vsscList.Items[idx]
-		if vssc.Object["driver"] == provisioner {
-			if v, ok, err := unstructured.NestedString(
-				vssc.Object, "metadata", "annotations", SnapshotClassIsDefaultAnnotation); err == nil && ok && v == "true" {
-				o.Logger.Infof("%s Default volume snapshot class - %s found in cluster", check, vssc.GetName())
-				o.Logger.Infof("%s Volume snapshot class - %s driver matches with given StorageClass's provisioner=%s\n",
-					check, vssc.GetName(), provisioner)
-				return vssc.GetName(), nil
-			}
-			sscName = vssc.GetName()
-		}
+	sscName, isDefault := matchSnapshotClassForProvisioner(vsscList.Items, provisioner)
+	if isDefault {
+		o.Logger.Infof("%s Default volume snapshot class - %s found in cluster", check, sscName)
+		o.Logger.Infof("%s Volume snapshot class - %s driver matches with given StorageClass's provisioner=%s\n",
+			check, sscName, provisioner)
+		return sscName, nil
 	}
 	if sscName == "" {
 		o.Logger.Infof("no matching volume snapshot class having driver "+
@@ -626,27 +586,36 @@ func (o *Run) checkAndCreateSnapshotClassForProvisioner(ctx context.Context, pre
 	return sscName, nil
 }
 
+// matchSnapshotClassForProvisioner returns the volume snapshot class having driver same as the provisioner,
+// preferring the default volume snapshot class, and whether the returned class is the default one.
+func matchSnapshotClassForProvisioner(vsscList []unstructured.Unstructured, provisioner string) (name string, isDefault bool) {
+	for idx := range vsscList {
+		vssc := &vsscList[idx]
+		if vssc.Object["driver"] != provisioner {
+			continue
+		}
+		if v, ok, err := unstructured.NestedString(
+			vssc.Object, "metadata", "annotations", SnapshotClassIsDefaultAnnotation); err == nil && ok && v == "true" {
+			return vssc.GetName(), true
+		}
+		name = vssc.GetName()
+	}
+
+	return name, false
+}
+
 func (o *Run) createVolumeSnapshotClass(ctx context.Context, driver, prefVersion string, cl client.Client) (string, error) {
-	vscUnstrObj := &unstructured.Unstructured{}
-	vscUnstrObj.SetUnstructuredContent(map[string]interface{}{
-		"driver":         driver,
-		"deletionPolicy": "Delete",
-	})
-	vscUnstrObj.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   StorageSnapshotGroup,
-		Version: prefVersion,
-		Kind:    internal.VolumeSnapshotClassKind,
-	})
 	randStr, err := CreateResourceNameSuffix()
 	if err != nil {
 		return "", fmt.Errorf("error generating resource name suffix: %s", err.Error())
 	}
 	vscName := defaultVSCNamePrefix + randStr
-	vscUnstrObj.SetName(vscName)
+	vscUnstrObj := createVolumeSnapshotClassSpec(vscName, driver, prefVersion)
 
 	if cErr := cl.Create(ctx, vscUnstrObj); cErr != nil {
 		return "", cErr
 	}
+	o.recordCreatedResource(vscUnstrObj)
 
 	o.Logger.Infof("%s Volume snapshot class with driver as - %s for version - %s successfully created",
 		check, driver, prefVersion)
@@ -678,26 +647,21 @@ func (o *Run) checkAndCreateVolumeSnapshotCRDs(ctx context.Context, serverVersio
 			}
 			o.Logger.Infof("Volume snapshot CRD: %s not found on cluster. Attempting installation...", crd)
 
-			fileBytes, rErr := crdYamlFiles.ReadFile(filepath.Join(volumeSnapshotCRDYamlDir, prefCRDVersion, crd+".yaml"))
+			unmarshalCRDObj, rErr := readVolumeSnapshotCRDSpec(prefCRDVersion, crd)
 			if rErr != nil {
 				errs = append(errs, rErr)
 				continue
 			}
 
-			unmarshalCRDObj := &apiextensions.CustomResourceDefinition{}
-			if uErr := yaml.Unmarshal(fileBytes, unmarshalCRDObj); uErr != nil {
-				errs = append(errs, uErr)
-				continue
-			}
-
 			if cErr := cl.Create(ctx, unmarshalCRDObj); cErr != nil {
 				errs = append(errs, cErr)
 				continue
 			}
+			o.recordCreatedResource(unmarshalCRDObj)
 
 			// if we are creating the volumesnapshotclass CRD, then any user provided volumesnapshotclass name should be
 			// overridden because no volumesnapshotclass will be existing without CRD.
-			if crd == "volumesnapshotclasses."+StorageSnapshotGroup {
+			if crd == volumeSnapshotClassCRD() {
 				o.SnapshotClass = ""
 			}
 
@@ -714,39 +678,6 @@ func (o *Run) checkAndCreateVolumeSnapshotCRDs(ctx context.Context, serverVersio
 	return nil
 }
 
-// validateDNSResolution checks whether DNS resolution is working on k8s cluster
-func (o *Run) validateDNSResolution(ctx context.Context, execCommand []string, podNameSuffix string, clients ServerClients) error {
-	pod, err := o.createDNSPodOnCluster(ctx, podNameSuffix, clients.ClientSet)
-	if err != nil {
-		return err
-	}
-
-	op := exec.Options{
-		Ctx:           ctx,
-		Namespace:     o.Namespace,
-		Command:       execCommand,
-		PodName:       pod.GetName(),
-		ContainerName: dnsContainerName,
-		Executor:      &exec.DefaultRemoteExecutor{},
-		Config:        clients.RestConfig,
-		ClientSet:     clients.ClientSet,
-	}
-	err = execInPod(&op, o.Logger)
-	if err != nil {
-		return fmt.Errorf("not able to resolve DNS '%s' service inside pods", execCommand[1])
-	}
-
-	// Delete DNS pod when resolution is successful
-	err = deleteK8sResource(ctx, pod, clients.RuntimeClient)
-	if err != nil {
-		o.Logger.Warnf("Problem occurred deleting DNS pod - '%s' :: %s", pod.GetName(), err.Error())
-	} else {
-		o.Logger.Infof("Deleted DNS pod - '%s' successfully", pod.GetName())
-	}
-
-	return nil
-}
-
 func (o *Run) createDNSPodOnCluster(ctx context.Context, podNameSuffix string, clientSet *kubernetes.Clientset) (*corev1.Pod, error) {
 	pod := createDNSPodSpec(o, podNameSuffix)
 	_, err := clientSet.CoreV1().Pods(o.Namespace).Create(ctx, pod, metav1.CreateOptions{})
@@ -759,14 +690,16 @@ func (o *Run) createDNSPodOnCluster(ctx context.Context, podNameSuffix string, c
 		}
 		return nil, err
 	}
+	o.recordCreatedResource(pod)
 	o.Logger.Infof("Pod %s created in cluster\n", pod.GetName())
 
 	waitOptions := &wait.PodWaitOptions{
-		Name:               pod.GetName(),
-		Namespace:          o.Namespace,
-		RetryBackoffParams: getDefaultRetryBackoffParams(),
-		PodCondition:       corev1.PodReady,
-		ClientSet:          clientSet,
+		Name:         pod.GetName(),
+		Namespace:    o.Namespace,
+		Timeout:      defaultWaitTimeout,
+		Logger:       o.Logger,
+		PodCondition: corev1.PodReady,
+		ClientSet:    clientSet,
 	}
 	o.Logger.Infoln("Waiting for dns pod to become ready")
 	err = waitUntilPodCondition(ctx, waitOptions)
@@ -791,12 +724,12 @@ func (o *Run) createDNSPodOnCluster(ctx context.Context, podNameSuffix string, c
 }
 
 // validateClusterScopeVolumeSnapshot checks if volume snapshot and restore is enabled in the cluster
-func (o *Run) validateClusterScopeVolumeSnapshot(ctx context.Context, nameSuffix string, clients ServerClients) error {
+func (o *Run) validateClusterScopeVolumeSnapshot(ctx context.Context, uid string, clients ServerClients) error {
 	var (
-		execOp          exec.Options
 		err             error
 		pvc             *corev1.PersistentVolumeClaim
 		prefSnapshotVer string
+		nameSuffix      = o.resourceNameSuffix(uid)
 	)
 
 	prefSnapshotVer, err = GetServerPreferredVersionForGroup(StorageSnapshotGroup, clients.ClientSet)
@@ -807,7 +740,7 @@ func (o *Run) validateClusterScopeVolumeSnapshot(ctx context.Context, nameSuffix
 	backupNamespace := BackupNamespacePrefix + nameSuffix
 
 	// create backup namespace
-	err = o.createNamespace(ctx, backupNamespace, nameSuffix, clients.ClientSet)
+	err = o.createNamespace(ctx, backupNamespace, uid, clients.ClientSet)
 	if err != nil {
 		return err
 	}
@@ -818,19 +751,23 @@ func (o *Run) validateClusterScopeVolumeSnapshot(ctx context.Context, nameSuffix
 		Name:      SourcePvcNamePrefix + nameSuffix,
 	}
 
-	pvc, err = o.createPVC(ctx, sourcePvcNsName, nameSuffix, clients.ClientSet)
+	pvc, err = o.createPVC(ctx, sourcePvcNsName, uid, clients.ClientSet)
 	if err != nil {
 		return err
 	}
 
 	writerPodName := fmt.Sprintf("%s%s-%s", SourcePvcNamePrefix, "writer", nameSuffix)
-	pod, err := o.createWriterPodAttachedWithPVC(ctx, writerPodName, nameSuffix, sourcePvcNsName, clients.ClientSet)
+	pod, err := o.createWriterPodAttachedWithPVC(ctx, writerPodName, uid, sourcePvcNsName, clients.ClientSet)
 	if err != nil {
 		return err
 	}
 	o.Logger.Infof("Successfully wrote data to PVC - %s by attaching data writer pod - %s to it ",
 		internal.GetNamespacedName(pvc.GetNamespace(), pvc.GetName()).String(),
 		internal.GetNamespacedName(pod.GetNamespace(), pod.GetName()).String())
+	sourceChecksums, err := o.recordSourceChecksums(ctx, pod, clients)
+	if err != nil {
+		return err
+	}
 
 	// Take a snapshot in backupnamespace
 	snapshotNameNs := types.NamespacedName{
@@ -838,7 +775,8 @@ func (o *Run) validateClusterScopeVolumeSnapshot(ctx context.Context, nameSuffix
 		Name:      VolumeSnapSrcNamePrefix + nameSuffix,
 	}
 
-	err = o.createSnapshotFromPVC(ctx, snapshotNameNs, storageVolSnapClass, prefSnapshotVer, pvc.GetName(), nameSuffix, clients)
+	err = o.createSnapshotFromPVC(ctx, snapshotNameNs, o.snapshotClass(), prefSnapshotVer, pvc.GetName(), uid, clients)
+	o.markStorageStage(stageMountedSnapshot, err)
 	if err != nil {
 		return err
 	}
@@ -860,28 +798,21 @@ func (o *Run) validateClusterScopeVolumeSnapshot(ctx context.Context, nameSuffix
 			internal.GetNamespacedName(backupPVCMeta.GetNamespace(), backupSnapshotName),
 			backupPvcNameNs,
 			err.Error())
+		o.markStorageStage(stageRestore, err)
 		return err
 	}
 
 	// create a reader pod and attach to cloned pvc
 	readerPodName := fmt.Sprintf("%s%s-%s", BackupPvcNamePrefix, "reader", nameSuffix)
-	readerPod, err := o.createReaderPodAttachedWithPVC(ctx, readerPodName, nameSuffix, backupPvcNameNs, clients.ClientSet)
+	readerPod, err := o.createReaderPodAttachedWithPVC(ctx, readerPodName, uid, backupPvcNameNs, clients.ClientSet)
+	o.markStorageStage(stageRestore, err)
 	if err != nil {
 		return err
 	}
 
 	// execInPod to verify data in cloned pvc
-	execOp = exec.Options{
-		Ctx:           ctx,
-		Namespace:     readerPod.GetNamespace(),
-		Command:       execDataCheckCommand,
-		PodName:       readerPod.GetName(),
-		ContainerName: readerPod.Spec.Containers[0].Name,
-		Executor:      &exec.DefaultRemoteExecutor{},
-		Config:        clients.RestConfig,
-		ClientSet:     clients.ClientSet,
-	}
-	err = execInPod(&execOp, o.Logger)
+	err = o.verifyRestoredData(ctx, readerPod, sourceChecksums, clients)
+	o.markStorageStage(stageDataVerified, err)
 	if err != nil {
 		return err
 	}
@@ -889,15 +820,15 @@ func (o *Run) validateClusterScopeVolumeSnapshot(ctx context.Context, nameSuffix
 		backupPvcNameNs.String(),
 		internal.GetNamespacedName(readerPod.GetNamespace(), readerPod.GetName()).String())
 
-	return nil
+	return o.validateUnmountedVolumeSnapshot(ctx, pod, pvc, sourceChecksums, prefSnapshotVer, uid, clients)
 }
 
-func (o *Run) validateNamespaceScopeVolumeSnapshot(ctx context.Context, nameSuffix string, clients ServerClients) error {
+func (o *Run) validateNamespaceScopeVolumeSnapshot(ctx context.Context, uid string, clients ServerClients) error {
 	var (
-		execOp          exec.Options
 		err             error
 		pvc             *corev1.PersistentVolumeClaim
 		prefSnapshotVer string
+		nameSuffix      = o.resourceNameSuffix(uid)
 	)
 
 	prefSnapshotVer, err = GetServerPreferredVersionForGroup(StorageSnapshotGroup, clients.ClientSet)
@@ -911,18 +842,22 @@ func (o *Run) validateNamespaceScopeVolumeSnapshot(ctx context.Context, nameSuff
 		Name:      SourcePvcNamePrefix + nameSuffix,
 	}
 
-	pvc, err = o.createPVC(ctx, sourcePvcNsName, nameSuffix, clients.ClientSet)
+	pvc, err = o.createPVC(ctx, sourcePvcNsName, uid, clients.ClientSet)
 	if err != nil {
 		return err
 	}
 	writerPodName := fmt.Sprintf("%s%s-%s", SourcePvcNamePrefix, "writer", nameSuffix)
-	pod, err := o.createWriterPodAttachedWithPVC(ctx, writerPodName, nameSuffix, sourcePvcNsName, clients.ClientSet)
+	pod, err := o.createWriterPodAttachedWithPVC(ctx, writerPodName, uid, sourcePvcNsName, clients.ClientSet)
 	if err != nil {
 		return err
 	}
 	o.Logger.Infof("Successfully wrote data to PVC - %s by attaching data writer pod - %s to it ",
 		internal.GetNamespacedName(pvc.GetNamespace(), pvc.GetName()).String(),
 		internal.GetNamespacedName(pod.GetNamespace(), pod.GetName()).String())
+	sourceChecksums, err := o.recordSourceChecksums(ctx, pod, clients)
+	if err != nil {
+		return err
+	}
 
 	// Take a snapshot in backupnamespace
 	snapshotNameNs := types.NamespacedName{
@@ -930,7 +865,8 @@ func (o *Run) validateNamespaceScopeVolumeSnapshot(ctx context.Context, nameSuff
 		Name:      VolumeSnapSrcNamePrefix + nameSuffix,
 	}
 
-	err = o.createSnapshotFromPVC(ctx, snapshotNameNs, storageVolSnapClass, prefSnapshotVer, pvc.GetName(), nameSuffix, clients)
+	err = o.createSnapshotFromPVC(ctx, snapshotNameNs, o.snapshotClass(), prefSnapshotVer, pvc.GetName(), uid, clients)
+	o.markStorageStage(stageMountedSnapshot, err)
 	if err != nil {
 		return err
 	}
@@ -945,28 +881,21 @@ func (o *Run) validateNamespaceScopeVolumeSnapshot(ctx context.Context, nameSuff
 
 	_, err = o.createPVCFromSnapshot(ctx, clients.RuntimeClient, backupPvcMeta, &pvc.Spec, snapshotNameNs.Name)
 	if err != nil {
+		o.markStorageStage(stageRestore, err)
 		return err
 	}
 
 	// create a pod and attach to cloned pvc
 	readerPodName := fmt.Sprintf("%s%s-%s", BackupPvcNamePrefix, "reader", nameSuffix)
-	readerPod, err := o.createReaderPodAttachedWithPVC(ctx, readerPodName, nameSuffix, backupPvcNameNs, clients.ClientSet)
+	readerPod, err := o.createReaderPodAttachedWithPVC(ctx, readerPodName, uid, backupPvcNameNs, clients.ClientSet)
+	o.markStorageStage(stageRestore, err)
 	if err != nil {
 		return err
 	}
 
 	// execInPod to verify data in cloned pvc
-	execOp = exec.Options{
-		Ctx:           ctx,
-		Namespace:     readerPod.GetNamespace(),
-		Command:       execDataCheckCommand,
-		PodName:       readerPod.GetName(),
-		ContainerName: readerPod.Spec.Containers[0].Name,
-		Executor:      &exec.DefaultRemoteExecutor{},
-		Config:        clients.RestConfig,
-		ClientSet:     clients.ClientSet,
-	}
-	err = execInPod(&execOp, o.Logger)
+	err = o.verifyRestoredData(ctx, readerPod, sourceChecksums, clients)
+	o.markStorageStage(stageDataVerified, err)
 	if err != nil {
 		return err
 	}
@@ -974,6 +903,85 @@ func (o *Run) validateNamespaceScopeVolumeSnapshot(ctx context.Context, nameSuff
 		backupPvcNameNs.String(),
 		internal.GetNamespacedName(readerPod.GetNamespace(), readerPod.GetName()).String())
 
+	return o.validateUnmountedVolumeSnapshot(ctx, pod, pvc, sourceChecksums, prefSnapshotVer, uid, clients)
+}
+
+// validateUnmountedVolumeSnapshot deletes the writer pod so that the source pvc is unmounted, takes its volume snapshot
+// and restores it to a pvc attached to a reader pod in the install namespace, on which the data written by the writer
+// pod is verified. Volume snapshot of source pvc of another namespace is cloned into the install namespace. It's
+// performed only with data integrity check, as it doubles the resources and duration of volume snapshot check.
+func (o *Run) validateUnmountedVolumeSnapshot(ctx context.Context, writerPod *corev1.Pod, pvc *corev1.PersistentVolumeClaim,
+	sourceChecksums map[string]string, prefSnapshotVer, uid string, clients ServerClients) error {
+	if !o.dataIntegrityEnabled() {
+		return nil
+	}
+	nameSuffix := o.resourceNameSuffix(uid)
+
+	if err := o.deletePod(ctx, writerPod, clients); err != nil {
+		o.markStorageStage(stageUnmountedSnapshot, err)
+		return err
+	}
+	snapshotNameNs := types.NamespacedName{
+		Namespace: pvc.GetNamespace(),
+		Name:      UnmountedVolumeSnapSrcNamePrefix + nameSuffix,
+	}
+	err := o.createSnapshotFromPVC(ctx, snapshotNameNs, o.snapshotClass(), prefSnapshotVer, pvc.GetName(), uid, clients)
+	o.markStorageStage(stageUnmountedSnapshot, err)
+	if err != nil {
+		return err
+	}
+
+	restorePvcNameNs := types.NamespacedName{Name: UnmountedRestorePvcNamePrefix + nameSuffix, Namespace: o.Namespace}
+	restorePvcMeta := &metav1.ObjectMeta{
+		Name:      restorePvcNameNs.Name,
+		Namespace: restorePvcNameNs.Namespace,
+		Labels:    pvc.Labels,
+	}
+	if pvc.GetNamespace() != o.Namespace {
+		_, _, err = o.cloneSnapshotAndPVCFromSource(ctx, snapshotNameNs, &pvc.Spec, restorePvcMeta,
+			UnmountedVolumeSnapRestoreNamePrefix+nameSuffix, clients.RuntimeClient)
+	} else {
+		_, err = o.createPVCFromSnapshot(ctx, clients.RuntimeClient, restorePvcMeta, &pvc.Spec, snapshotNameNs.Name)
+	}
+	if err != nil {
+		o.markStorageStage(stageUnmountedRestore, err)
+		return err
+	}
+
+	readerPodName := fmt.Sprintf("%s%s-%s", UnmountedRestorePvcNamePrefix, "reader", nameSuffix)
+	readerPod, err := o.createReaderPodAttachedWithPVC(ctx, readerPodName, uid, restorePvcNameNs, clients.ClientSet)
+	if err == nil {
+		err = o.verifyRestoredData(ctx, readerPod, sourceChecksums, clients)
+	}
+	o.markStorageStage(stageUnmountedRestore, err)
+	if err != nil {
+		return err
+	}
+	o.Logger.Infof("Successfully read data of unmounted PVC - %s from restored PVC - %s by attaching data reader pod - %s to it ",
+		internal.GetNamespacedName(pvc.GetNamespace(), pvc.GetName()).String(), restorePvcNameNs.String(),
+		internal.GetNamespacedName(readerPod.GetNamespace(), readerPod.GetName()).String())
+
+	return nil
+}
+
+// deletePod deletes the pod and waits until it's deleted, so that the volumes attached to it are unmounted.
+func (o *Run) deletePod(ctx context.Context, pod *corev1.Pod, clients ServerClients) error {
+	podNameNs := internal.GetNamespacedName(pod.GetNamespace(), pod.GetName())
+	if err := deleteK8sResource(ctx, pod, clients.RuntimeClient); err != nil {
+		return fmt.Errorf("error deleting pod - %s :: %w", podNameNs.String(), err)
+	}
+	o.Logger.Infof("Waiting for pod - %s to be deleted", podNameNs.String())
+	waitOptions := &wait.PodWaitOptions{
+		Name:      pod.GetName(),
+		Namespace: pod.GetNamespace(),
+		ClientSet: clients.ClientSet,
+		Timeout:   defaultWaitTimeout,
+	}
+	if err := waitOptions.WaitUntilDeleted(ctx); err != nil {
+		return fmt.Errorf("pod - %s is not deleted :: %w", podNameNs.String(), err)
+	}
+	o.Logger.Infof("Deleted pod - %s", podNameNs.String())
+
 	return nil
 }
 
@@ -1016,54 +1024,24 @@ func (o *Run) cloneSnapshotAndContent(ctx context.Context,
 	cloneVolSnapMeta *metav1.ObjectMeta,
 	k8sClient client.Client) (*snapshotv1.VolumeSnapshot, error) {
 
-	tempVolSnapCont := snapshotv1.VolumeSnapshotContent{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      cloneVolSnapMeta.GetName(),
-			Namespace: cloneVolSnapMeta.GetNamespace(),
-			Labels:    cloneVolSnapMeta.GetLabels(),
-		},
-		Spec: snapshotv1.VolumeSnapshotContentSpec{
-			Source: snapshotv1.VolumeSnapshotContentSource{
-				SnapshotHandle: srcVolSnapContent.Status.SnapshotHandle,
-			},
-			VolumeSnapshotRef: corev1.ObjectReference{
-				Name:      cloneVolSnapMeta.GetName(),
-				Namespace: cloneVolSnapMeta.GetNamespace(),
-			},
-			DeletionPolicy:          snapshotv1.VolumeSnapshotContentDelete,
-			Driver:                  srcVolSnapContent.Spec.Driver,
-			VolumeSnapshotClassName: srcVolSnapContent.Spec.VolumeSnapshotClassName,
-		},
-	}
-
-	tempVolSnap := snapshotv1.VolumeSnapshot{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      cloneVolSnapMeta.GetName(),
-			Namespace: cloneVolSnapMeta.GetNamespace(),
-			Labels:    cloneVolSnapMeta.GetLabels(),
-		},
-		Spec: snapshotv1.VolumeSnapshotSpec{
-			Source: snapshotv1.VolumeSnapshotSource{
-				VolumeSnapshotContentName: &tempVolSnapCont.Name,
-			},
-			VolumeSnapshotClassName: tempVolSnapCont.Spec.VolumeSnapshotClassName,
-		},
-	}
+	tempVolSnapCont, tempVolSnap := createClonedSnapshotAndContentSpec(srcVolSnapContent, cloneVolSnapMeta)
 
-	if err := k8sClient.Create(ctx, &tempVolSnapCont); err != nil {
+	if err := k8sClient.Create(ctx, tempVolSnapCont); err != nil {
 		return nil, err
 	}
+	o.recordCreatedResource(tempVolSnapCont)
 
 	o.Logger.Infof("Snapshot content: %s cloned to Snapshot Content: %s", srcVolSnapContent.Name, tempVolSnapCont.Name)
 
-	if err := k8sClient.Create(ctx, &tempVolSnap); err != nil {
+	if err := k8sClient.Create(ctx, tempVolSnap); err != nil {
 		return nil, err
 	}
+	o.recordCreatedResource(tempVolSnap)
 
 	o.Logger.Infof("Cloned snapshot to %s namespace",
 		cloneVolSnapMeta.GetNamespace())
 
-	return &tempVolSnap, nil
+	return tempVolSnap, nil
 }
 
 func (o *Run) createPVCFromSnapshot(ctx context.Context,
@@ -1073,25 +1051,7 @@ func (o *Run) createPVCFromSnapshot(ctx context.Context,
 	sourceSnapshotName string) (*corev1.PersistentVolumeClaim, error) {
 
 	// PVC to be used in destination namespace
-	pvc := &corev1.PersistentVolumeClaim{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      newPVCMeta.GetName(),
-			Namespace: newPVCMeta.GetNamespace(),
-			Labels:    newPVCMeta.GetLabels(),
-		},
-		Spec: corev1.PersistentVolumeClaimSpec{
-			AccessModes:      sourcePVCSpec.AccessModes,
-			StorageClassName: sourcePVCSpec.StorageClassName,
-			Resources: corev1.VolumeResourceRequirements{
-				Requests: corev1.ResourceList{corev1.ResourceStorage: sourcePVCSpec.Resources.Requests[corev1.ResourceStorage]},
-			},
-			DataSource: &corev1.TypedLocalObjectReference{
-				APIGroup: ptr.To(snapshotv1.GroupName),
-				Kind:     internal.VolumeSnapshotKind,
-				Name:     sourceSnapshotName,
-			},
-		},
-	}
+	pvc := createPVCFromSnapshotSpec(newPVCMeta, sourcePVCSpec, sourceSnapshotName)
 
 	pvcNsName := types.NamespacedName{Name: pvc.Name, Namespace: pvc.Namespace}
 	sourceSnapshotNsName := types.NamespacedName{Name: sourceSnapshotName, Namespace: pvc.Namespace}
@@ -1107,6 +1067,7 @@ func (o *Run) createPVCFromSnapshot(ctx context.Context,
 		}
 		return nil, err
 	}
+	o.recordCreatedResource(pvc)
 	o.Logger.Infof("Created PVC %s from snapshot %s \n", pvcNsName.String(), sourceSnapshotNsName.String())
 
 	return pvc, nil
@@ -1115,16 +1076,12 @@ func (o *Run) createPVCFromSnapshot(ctx context.Context,
 // createNamespace creates a namespace in the cluster
 func (o *Run) createNamespace(ctx context.Context, nsName, uid string,
 	k8sClient *kubernetes.Clientset) error {
-	ns := &corev1.Namespace{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:   nsName,
-			Labels: getPreflightResourceLabels(uid),
-		},
-	}
+	ns := createNamespaceSpec(nsName, uid)
 	_, err := k8sClient.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
 	if err != nil {
 		return err
 	}
+	o.recordCreatedResource(ns)
 	o.Logger.Infof("Created namespace - %s\n", nsName)
 
 	return nil
@@ -1167,8 +1124,13 @@ func (o *Run) getVolumeSnapshotAndContent(
 // createPVC creates pvc for volume snapshot checks
 func (o *Run) createPVC(ctx context.Context, nsName types.NamespacedName,
 	nameSuffix string, k8sClient *kubernetes.Clientset) (pvc *corev1.PersistentVolumeClaim, err error) {
-	pvc = createVolumeSnapshotPVCSpec(o, nsName, nameSuffix)
-	pvc, err = k8sClient.CoreV1().PersistentVolumeClaims(nsName.Namespace).Create(ctx, pvc, metav1.CreateOptions{})
+	return o.createPVCFromSpec(ctx, createVolumeSnapshotPVCSpec(o, nsName, nameSuffix), k8sClient)
+}
+
+// createPVCFromSpec creates the pvc, and waits until it's bound if the storage class binds volumes immediately.
+func (o *Run) createPVCFromSpec(ctx context.Context, pvc *corev1.PersistentVolumeClaim,
+	k8sClient *kubernetes.Clientset) (*corev1.PersistentVolumeClaim, error) {
+	pvc, err := k8sClient.CoreV1().PersistentVolumeClaims(pvc.GetNamespace()).Create(ctx, pvc, metav1.CreateOptions{})
 	if err != nil {
 		pvcYaml, yErr := objToYAML(pvc)
 		if yErr != nil {
@@ -1178,11 +1140,39 @@ func (o *Run) createPVC(ctx context.Context, nsName types.NamespacedName,
 		}
 		return nil, err
 	}
+	o.recordCreatedResource(pvc)
 	o.Logger.Infof("Created pvc - %s", internal.GetNamespacedName(pvc.GetNamespace(), pvc.GetName()).String())
 
+	// pvc of a storage class binding volumes immediately is waited upon to be bound, so that provisioning
+	// failures are reported instead of the pod attached to it being unschedulable
+	if !o.bindsVolumesImmediately(ctx, k8sClient) {
+		return pvc, nil
+	}
+	o.Logger.Infof("Waiting for pvc - %s to be bound", internal.GetNamespacedName(pvc.GetNamespace(), pvc.GetName()).String())
+	waitOptions := &wait.PVCWaitOptions{
+		Name:      pvc.GetName(),
+		Namespace: pvc.GetNamespace(),
+		ClientSet: k8sClient,
+		Timeout:   defaultWaitTimeout,
+		Logger:    o.Logger,
+	}
+	if err = waitOptions.WaitUntilBound(ctx); err != nil {
+		return pvc, err
+	}
+
 	return pvc, nil
 }
 
+// bindsVolumesImmediately returns whether the storage class of the run binds volumes as soon as pvc is created.
+func (o *Run) bindsVolumesImmediately(ctx context.Context, k8sClient *kubernetes.Clientset) bool {
+	sc, err := k8sClient.StorageV1().StorageClasses().Get(ctx, o.StorageClass, metav1.GetOptions{})
+	if err != nil {
+		o.Logger.Debugf("Unable to get storage class - %s :: %s", o.StorageClass, err.Error())
+		return false
+	}
+	return sc.VolumeBindingMode == nil || *sc.VolumeBindingMode == storagev1.VolumeBindingImmediate
+}
+
 func (o *Run) createSnapshotFromPVC(ctx context.Context, volSnapNameNs types.NamespacedName, volSnapClass, snapshotVer,
 	pvcName, uid string, clients ServerClients) error {
 	volSnap := createVolumeSnapsotSpec(volSnapNameNs, volSnapClass, snapshotVer, pvcName, uid)
@@ -1195,13 +1185,14 @@ func (o *Run) createSnapshotFromPVC(ctx context.Context, volSnapNameNs types.Nam
 		}
 		return fmt.Errorf("%s error creating volume snapshot from pvc :: %s", cross, err.Error())
 	}
+	o.recordCreatedResource(volSnap)
 	o.Logger.Infof("Created volume snapshot - %s from pvc - %s",
 		volSnapNameNs.String(),
 		internal.GetNamespacedName(volSnapNameNs.Namespace, pvcName).String(),
 	)
 
 	o.Logger.Infof("Waiting for volume snapshot - %s created from pvc to become 'readyToUse:true'", volSnapNameNs.String())
-	err := waitUntilVolSnapReadyToUse(volSnap, snapshotVer, getDefaultRetryBackoffParams(), clients.RuntimeClient)
+	err := waitUntilVolSnapReadyToUse(ctx, volSnap, snapshotVer, clients.DynamicClient, o.Logger)
 	if err != nil {
 		if k8swait.Interrupted(err) {
 			volSnapYAML, yErr := objToYAML(volSnap)
@@ -1210,8 +1201,8 @@ func (o *Run) createSnapshotFromPVC(ctx context.Context, volSnapNameNs types.Nam
 			} else {
 				o.Logger.Warnf("Volume snapshot failed to reach into ready state. Volume snapshot yaml: \n%s", string(volSnapYAML))
 			}
-			return fmt.Errorf("volume snapshot - %s not readyToUse (waited 600 sec) :: %s",
-				volSnapNameNs.String(), err.Error())
+			return fmt.Errorf("volume snapshot - %s not readyToUse (waited %s) :: %w",
+				volSnapNameNs.String(), defaultWaitTimeout, err)
 		}
 		return err
 	}
@@ -1247,15 +1238,17 @@ func (o *Run) createPod(ctx context.Context, pod *corev1.Pod, k8sClient *kuberne
 		}
 		return nil, err
 	}
+	o.recordCreatedResource(pod)
 	o.Logger.Infof("Created pod - %s", podNameNs.String())
 
 	//  Wait for snapshot pod to become ready.
 	waitOptions := &wait.PodWaitOptions{
-		Name:               pod.GetName(),
-		Namespace:          pod.GetNamespace(),
-		RetryBackoffParams: getDefaultRetryBackoffParams(),
-		PodCondition:       corev1.PodReady,
-		ClientSet:          k8sClient,
+		Name:         pod.GetName(),
+		Namespace:    pod.GetNamespace(),
+		Timeout:      defaultWaitTimeout,
+		Logger:       o.Logger,
+		PodCondition: corev1.PodReady,
+		ClientSet:    k8sClient,
 	}
 	o.Logger.Infof("Waiting for pod - %s to become ready\n", podNameNs.String())
 	err = waitUntilPodCondition(ctx, waitOptions)
@@ -1266,7 +1259,7 @@ func (o *Run) createPod(ctx context.Context, pod *corev1.Pod, k8sClient *kuberne
 		} else {
 			o.Logger.Warnf("Pod: %s, failed to reach ready state. Pod yaml: \n%s", podNameNs.String(), string(podYaml))
 		}
-		return pod, fmt.Errorf("pod: %s, hasn't reached into ready state", podNameNs.String())
+		return pod, fmt.Errorf("pod: %s, hasn't reached into ready state :: %w", podNameNs.String(), err)
 	}
 	o.Logger.Infof("Pod: %s, has reached into ready state\n", podNameNs.String())
 
@@ -1279,36 +1272,51 @@ func (o *Run) createPod(ctx context.Context, pod *corev1.Pod, k8sClient *kuberne
 	return pod, err
 }
 
+// podCapabilityValidationCases are the security contexts with which pods are validated by pod capability check.
+var podCapabilityValidationCases = []capability{
+	{
+		userID:                   0,
+		allowPrivilegeEscalation: true,
+		privileged:               true,
+	},
+	{
+		userID:                   1001,
+		allowPrivilegeEscalation: false,
+		privileged:               false,
+	},
+	{
+		userID:                   101,
+		allowPrivilegeEscalation: true,
+		privileged:               false,
+	},
+}
+
 func (o *Run) validateRequiredPodCapabilities(ctx context.Context, podNameSuffix string, clients ServerClients) error {
-	validationCases := []capability{
-		{
-			userID:                   0,
-			allowPrivilegeEscalation: true,
-			privileged:               true,
-		},
-		{
-			userID:                   1001,
-			allowPrivilegeEscalation: false,
-			privileged:               false,
-		},
-		{
-			userID:                   101,
-			allowPrivilegeEscalation: true,
-			privileged:               false,
-		},
-	}
-	for index, validationCase := range validationCases {
-		o.Logger.Infof("Checking pod capability validation case %d/3", index+1)
-		err := o.validatePodCapability(ctx, fmt.Sprintf("%d-%s", index, podNameSuffix), clients, validationCase)
+	validationCases := podCapabilityValidationCases
+	// validation cases are independent of each other, they are performed concurrently if parallelism allows
+	errs := o.performConcurrently(len(validationCases), func(index int, r *Run) error {
+		return r.validatePodCapabilityCase(ctx, index, podNameSuffix, clients, validationCases[index])
+	})
+	for _, err := range errs {
 		if err != nil {
-			o.Logger.Errorf("Pod capability validation case %d/3 failed (userID: %d, privileged: %t, allowPrivilegeEscalation: %t) :: %s",
-				index+1, validationCase.userID, validationCase.privileged, validationCase.allowPrivilegeEscalation, err.Error())
 			return err
 		}
 	}
 	return nil
 }
 
+func (o *Run) validatePodCapabilityCase(ctx context.Context, index int, podNameSuffix string, clients ServerClients,
+	validationCase capability) error {
+	o.Logger.Infof("Checking pod capability validation case %d/3", index+1)
+	err := o.validatePodCapability(ctx, fmt.Sprintf("%d-%s", index, podNameSuffix), clients, validationCase)
+	if err != nil {
+		o.Logger.Errorf("Pod capability validation case %d/3 failed (userID: %d, privileged: %t, allowPrivilegeEscalation: %t) :: %s",
+			index+1, validationCase.userID, validationCase.privileged, validationCase.allowPrivilegeEscalation, err.Error())
+		return err
+	}
+	return nil
+}
+
 func (o *Run) validatePodCapability(ctx context.Context, podNameSuffix string, clients ServerClients, validationCase capability) error {
 	capabilityValidatorPod := createPodSpecWithCapability(o, podNameSuffix, validationCase)
 	_, err := clients.ClientSet.CoreV1().Pods(o.Namespace).Create(ctx, capabilityValidatorPod, metav1.CreateOptions{})
@@ -1321,14 +1329,16 @@ func (o *Run) validatePodCapability(ctx context.Context, podNameSuffix string, c
 		}
 		return err
 	}
+	o.recordCreatedResource(capabilityValidatorPod)
 	o.Logger.Infof("Pod %s created in cluster\n", capabilityValidatorPod.GetName())
 
 	waitOptions := &wait.PodWaitOptions{
-		Name:               capabilityValidatorPod.GetName(),
-		Namespace:          o.Namespace,
-		RetryBackoffParams: getDefaultRetryBackoffParams(),
-		PodCondition:       corev1.PodReady,
-		ClientSet:          clients.ClientSet,
+		Name:         capabilityValidatorPod.GetName(),
+		Namespace:    o.Namespace,
+		Timeout:      defaultWaitTimeout,
+		Logger:       o.Logger,
+		PodCondition: corev1.PodReady,
+		ClientSet:    clients.ClientSet,
 	}
 	o.Logger.Infoln("Waiting for capability validator pod to become ready")
 	err = waitUntilPodCondition(ctx, waitOptions)
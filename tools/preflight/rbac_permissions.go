@@ -0,0 +1,234 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	_ "embed" // embeds manifest of TVK permissions
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+const (
+	serviceAccountUserPrefix = "system:serviceaccount:"
+	serviceAccountsGroup     = "system:serviceaccounts"
+
+	// access reviews are performed for every verb of every resource TVK needs, client side throttling of
+	// default client would slow them down.
+	accessReviewQPS   = 50
+	accessReviewBurst = 100
+)
+
+var (
+	//go:embed tvkpermissions/tvk-permissions.yaml
+	tvkPermissionsYaml []byte
+)
+
+// TVKPermissions is the versioned manifest of permissions needed by TVK with each scope.
+type TVKPermissions struct {
+	Version string                      `json:"version"`
+	Scopes  map[string]ScopePermissions `json:"scopes"`
+}
+
+// ScopePermissions are the permissions needed by TVK installed with a scope. Cluster rules are evaluated across
+// the cluster, and namespace rules in the install namespace.
+type ScopePermissions struct {
+	ClusterRules   []rbacv1.PolicyRule `json:"clusterRules,omitempty"`
+	NamespaceRules []rbacv1.PolicyRule `json:"namespaceRules,omitempty"`
+}
+
+// missingPermission is a verb on a resource needed by TVK which is not allowed to the subject.
+type missingPermission struct {
+	attributes *authorizationv1.ResourceAttributes
+	reason     string
+}
+
+// accessReviewer returns whether the subject is allowed the access described by resource attributes, and the reason.
+type accessReviewer func(ctx context.Context, attrs *authorizationv1.ResourceAttributes) (allowed bool, reason string, err error)
+
+// loadTVKPermissions returns the embedded manifest of permissions needed by TVK.
+func loadTVKPermissions() (*TVKPermissions, error) {
+	perms := &TVKPermissions{}
+	if err := yaml.UnmarshalStrict(tvkPermissionsYaml, perms); err != nil {
+		return nil, fmt.Errorf("error reading manifest of TVK permissions :: %s", err.Error())
+	}
+
+	return perms, nil
+}
+
+// resourceAttributes returns the resource attributes of every verb on every resource of the permissions needed with
+// the scope, in the order of rules. Cluster rules are evaluated in all the namespaces, namespace rules in the given one.
+func (p *TVKPermissions) resourceAttributes(scope, namespace string) ([]*authorizationv1.ResourceAttributes, error) {
+	scopePerms, ok := p.Scopes[scope]
+	if !ok {
+		return nil, fmt.Errorf("permissions for scope - %s not found in manifest of TVK permissions version - %s", scope, p.Version)
+	}
+
+	var attrsList []*authorizationv1.ResourceAttributes
+	expand := func(rules []rbacv1.PolicyRule, ns string) {
+		for _, rule := range rules {
+			for _, group := range rule.APIGroups {
+				for _, res := range rule.Resources {
+					resource, subresource, _ := strings.Cut(res, "/")
+					for _, verb := range rule.Verbs {
+						attrsList = append(attrsList, &authorizationv1.ResourceAttributes{
+							Namespace:   ns,
+							Verb:        verb,
+							Group:       group,
+							Resource:    resource,
+							Subresource: subresource,
+						})
+					}
+				}
+			}
+		}
+	}
+	expand(scopePerms.ClusterRules, "")
+	expand(scopePerms.NamespaceRules, namespace)
+
+	return attrsList, nil
+}
+
+// validateTVKPermissions evaluates the permissions needed by TVK with the run scope, for the current user or the
+// given service account, and returns the missing ones.
+func (o *Run) validateTVKPermissions(ctx context.Context, review accessReviewer) ([]missingPermission, error) {
+	perms, err := loadTVKPermissions()
+	if err != nil {
+		return nil, err
+	}
+	attrsList, err := perms.resourceAttributes(o.Scope, o.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	o.Logger.Infof("Evaluating %d permissions of TVK permissions manifest version - %s for %s",
+		len(attrsList), perms.Version, o.accessReviewSubject())
+
+	var missing []missingPermission
+	for _, attrs := range attrsList {
+		allowed, reason, rErr := review(ctx, attrs)
+		if rErr != nil {
+			return nil, fmt.Errorf("error reviewing access to %s :: %s", describeAttributes(attrs), rErr.Error())
+		}
+		if !allowed {
+			missing = append(missing, missingPermission{attributes: attrs, reason: reason})
+		}
+	}
+
+	return missing, nil
+}
+
+// accessReviewSubject returns the subject whose permissions are evaluated.
+func (o *Run) accessReviewSubject() string {
+	if o.ServiceAccountName == "" {
+		return "current user"
+	}
+	return fmt.Sprintf("service account - %s", internal.GetNamespacedName(o.Namespace, o.ServiceAccountName))
+}
+
+// newAccessReviewer returns a reviewer performing SelfSubjectAccessReview for the current user, or SubjectAccessReview
+// for the service account if given.
+func (o *Run) newAccessReviewer(clientSet kubernetes.Interface) accessReviewer {
+	if o.ServiceAccountName == "" {
+		return func(ctx context.Context, attrs *authorizationv1.ResourceAttributes) (bool, string, error) {
+			ssar := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attrs},
+			}
+			result, err := clientSet.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, ssar, metav1.CreateOptions{})
+			if err != nil {
+				return false, "", err
+			}
+			return result.Status.Allowed, result.Status.Reason, nil
+		}
+	}
+
+	user := serviceAccountUserPrefix + o.Namespace + ":" + o.ServiceAccountName
+	groups := []string{serviceAccountsGroup, serviceAccountsGroup + ":" + o.Namespace, "system:authenticated"}
+	return func(ctx context.Context, attrs *authorizationv1.ResourceAttributes) (bool, string, error) {
+		sar := &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{ResourceAttributes: attrs, User: user, Groups: groups},
+		}
+		result, err := clientSet.AuthorizationV1().SubjectAccessReviews().Create(ctx, sar, metav1.CreateOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		return result.Status.Allowed, result.Status.Reason, nil
+	}
+}
+
+// accessReviewClientSet returns a client set for access reviews, having higher rate limits than the default one.
+func accessReviewClientSet(clients ServerClients) kubernetes.Interface {
+	if clients.RestConfig == nil {
+		return clients.ClientSet
+	}
+	cfg := rest.CopyConfig(clients.RestConfig)
+	cfg.QPS, cfg.Burst = accessReviewQPS, accessReviewBurst
+	clientSet, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return clients.ClientSet
+	}
+
+	return clientSet
+}
+
+// logMissingPermissions displays the missing permissions as a table.
+func (o *Run) logMissingPermissions(missing []missingPermission) {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "API GROUP\tRESOURCE\tNAMESPACE\tVERB\tREASON")
+	for _, mp := range missing {
+		attrs := mp.attributes
+		group, namespace, reason := attrs.Group, attrs.Namespace, mp.reason
+		if group == "" {
+			group = coreAPIGroupColumn
+		}
+		if namespace == "" {
+			namespace = allNamespaces
+		}
+		if reason == "" {
+			reason = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", group, resourceWithSubresource(attrs), namespace, attrs.Verb, reason)
+	}
+	_ = w.Flush()
+
+	o.Logger.Errorf("%s Missing permissions needed by TVK for %s:", cross, o.accessReviewSubject())
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		o.Logger.Errorln(line)
+	}
+}
+
+// missingPermissionsError returns the error listing missing permissions as '<verb> <resource>.<group> [in <namespace>]'.
+func missingPermissionsError(subject string, missing []missingPermission) error {
+	var perms []string
+	for _, mp := range missing {
+		perms = append(perms, describeAttributes(mp.attributes))
+	}
+	return fmt.Errorf("%d permissions needed by TVK are missing for %s - [%s]", len(missing), subject, strings.Join(perms, ", "))
+}
+
+func describeAttributes(attrs *authorizationv1.ResourceAttributes) string {
+	desc := attrs.Verb + " " + resourceWithSubresource(attrs)
+	if attrs.Group != "" {
+		desc += "." + attrs.Group
+	}
+	if attrs.Namespace != "" {
+		desc += " in " + attrs.Namespace
+	}
+	return desc
+}
+
+func resourceWithSubresource(attrs *authorizationv1.ResourceAttributes) string {
+	if attrs.Subresource == "" {
+		return attrs.Resource
+	}
+	return attrs.Resource + "/" + attrs.Subresource
+}
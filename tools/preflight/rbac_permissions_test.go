@@ -0,0 +1,85 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	authorizationv1 "k8s.io/api/authorization/v1"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+var _ = Describe("TVK RBAC permissions unit tests", func() {
+
+	var run *Run
+
+	BeforeEach(func() {
+		run = runOps.copyRun()
+		run.Scope = internal.NamespaceScope
+		run.ServiceAccountName = ""
+	})
+
+	It("Should expand permissions of the scope into resource attributes of each verb", func() {
+		perms, err := loadTVKPermissions()
+		Expect(err).To(BeNil())
+		Expect(perms.Version).ToNot(BeEmpty())
+		Expect(perms.Scopes).To(HaveKey(internal.ClusterScope))
+
+		attrsList, err := perms.resourceAttributes(internal.NamespaceScope, installNs)
+		Expect(err).To(BeNil())
+		Expect(attrsList).To(ContainElement(&authorizationv1.ResourceAttributes{
+			Verb: internal.CreateVerb, Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions"}))
+		Expect(attrsList).To(ContainElement(&authorizationv1.ResourceAttributes{
+			Namespace: installNs, Verb: internal.CreateVerb, Resource: "pods", Subresource: "exec"}))
+
+		attrsList, err = perms.resourceAttributes(internal.ClusterScope, installNs)
+		Expect(err).To(BeNil())
+		for _, attrs := range attrsList {
+			Expect(attrs.Namespace).To(BeEmpty())
+		}
+
+		_, err = perms.resourceAttributes("invalid", installNs)
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("Should return the permissions not allowed to the subject", func() {
+		review := func(_ context.Context, attrs *authorizationv1.ResourceAttributes) (bool, string, error) {
+			if attrs.Resource == "mutatingwebhookconfigurations" && attrs.Verb == internal.DeleteVerb {
+				return false, "forbidden by test", nil
+			}
+			return attrs.Subresource != "exec", "", nil
+		}
+
+		missing, err := run.validateTVKPermissions(ctx, review)
+		Expect(err).To(BeNil())
+		Expect(missing).To(HaveLen(3))
+		Expect(missing[0].reason).To(Equal("forbidden by test"))
+
+		err = missingPermissionsError(run.accessReviewSubject(), missing)
+		Expect(err.Error()).To(ContainSubstring("3 permissions needed by TVK are missing for current user"))
+		Expect(err.Error()).To(ContainSubstring("delete mutatingwebhookconfigurations.admissionregistration.k8s.io"))
+		Expect(err.Error()).To(ContainSubstring("get pods/exec in " + installNs))
+	})
+
+	It("Should return error when access review fails, and name the service account as subject", func() {
+		run.ServiceAccountName = "tvk-sa"
+		review := func(context.Context, *authorizationv1.ResourceAttributes) (bool, string, error) {
+			return false, "", errors.New("review failed")
+		}
+
+		_, err := run.validateTVKPermissions(ctx, review)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("review failed"))
+		Expect(run.accessReviewSubject()).To(Equal("service account - " + installNs + "/tvk-sa"))
+	})
+
+	It("Should skip the check in cluster unless service account is given", func() {
+		run.InCluster = true
+		res := &CheckResult{}
+		Expect(runRBACPermissionsCheck(ctx, run, res)).To(Succeed())
+		Expect(res.Status).To(Equal(CheckStatusSkipped))
+		Expect(res.Message).To(ContainSubstring("--service-account"))
+	})
+})
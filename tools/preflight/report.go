@@ -0,0 +1,359 @@
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+const (
+	// FormatJUnit renders the preflight report as JUnit XML, consumable by CI systems.
+	FormatJUnit = "junit"
+
+	reportFilePermission = 0600
+	junitSuiteName       = "tvk-preflight"
+)
+
+// AllowedReportFormats are the formats in which preflight report can be rendered.
+var AllowedReportFormats = sets.NewString(internal.FormatJSON, internal.FormatYAML, FormatJUnit)
+
+// ResourceRef identifies a resource created on the cluster by a preflight check.
+type ResourceRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// CheckReport is the machine-readable record of a single preflight check.
+type CheckReport struct {
+	ID              string        `json:"id"`
+	Description     string        `json:"description"`
+	Status          CheckStatus   `json:"status"`
+	DurationSeconds float64       `json:"durationSeconds"`
+	TimedOut        bool          `json:"timedOut,omitempty"`
+	Error           string        `json:"error,omitempty"`
+	Message         string        `json:"message,omitempty"`
+	Warnings        []string      `json:"warnings,omitempty"`
+	Resources       []ResourceRef `json:"resources,omitempty"`
+	Recommendations []string      `json:"recommendations,omitempty"`
+}
+
+// RunReport is the machine-readable result of a preflight run.
+type RunReport struct {
+	UID             string        `json:"uid"`
+	Namespace       string        `json:"namespace"`
+	Scope           string        `json:"scope"`
+	StorageClass    string        `json:"storageClass"`
+	SnapshotClass   string        `json:"snapshotClass,omitempty"`
+	Status          CheckStatus   `json:"status"`
+	StartTime       time.Time     `json:"startTime"`
+	DurationSeconds float64       `json:"durationSeconds"`
+	Checks          []CheckReport `json:"checks"`
+	// StorageClasses is the snapshot and restore matrix of storage classes, when more than one is checked.
+	StorageClasses []*StorageClassResult `json:"storageClasses,omitempty"`
+	// StoragePerformance is the throughput and snapshot latency measured for each storage class.
+	StoragePerformance []*StoragePerformanceResult `json:"storagePerformance,omitempty"`
+	// Topology is the outcome of restores of volume snapshot across nodes and zones for each storage class.
+	Topology []*TopologyResult `json:"topology,omitempty"`
+	// AccessModes is the outcome of access mode check for each storage class and access mode.
+	AccessModes []*AccessModeResult `json:"accessModes,omitempty"`
+	// Target is the outcome of each step of the verification of backup target.
+	Target *TargetResult `json:"target,omitempty"`
+	// PodSecurity is the Pod Security Admission configuration of namespace and its outcome for TVK pods.
+	PodSecurity *PodSecurityResult `json:"podSecurity,omitempty"`
+	// OpenShiftSCC is the outcome of SCC admission of pod capability cases for the TVK service account.
+	OpenShiftSCC *OpenShiftSCCResult `json:"openShiftSCC,omitempty"`
+	// AdmissionWebhooks are the webhooks matching resources of TVK and the mutations of preflight pods.
+	AdmissionWebhooks *AdmissionWebhooksResult `json:"admissionWebhooks,omitempty"`
+	// ResourceQuota is the headroom of ResourceQuotas of namespace and the LimitRange constraints violated by preflight.
+	ResourceQuota *ResourceQuotaResult `json:"resourceQuota,omitempty"`
+	// Nodes is the architecture, OS and allocatable resources of nodes, and whether preflight pods can be scheduled on them.
+	Nodes *NodeInventoryResult `json:"nodes,omitempty"`
+	// ImageAvailability is the outcome of pulling each of the images verified by image availability check.
+	ImageAvailability *ImageAvailabilityResult `json:"imageAvailability,omitempty"`
+	// NetworkPolicy is the NetworkPolicies of namespace and the outcome of network flows needed by TVK.
+	NetworkPolicy *NetworkPolicyResult `json:"networkPolicy,omitempty"`
+	// DNS is the resolver configuration of DNS pod, readiness of cluster DNS pods and the outcome of resolving names.
+	DNS *DNSResult `json:"dns,omitempty"`
+	// Discovery is the ranked list of storage classes evaluated in discover mode.
+	Discovery []*StorageClassCandidate `json:"discovery,omitempty"`
+}
+
+// newReport builds the report of a preflight run from the results of performed checks.
+func (o *Run) newReport(checks []*Check, startTime time.Time, preflightStatus bool) *RunReport {
+	report := &RunReport{
+		UID:             resNameSuffix,
+		Namespace:       o.Namespace,
+		Scope:           o.Scope,
+		StorageClass:    o.StorageClass,
+		SnapshotClass:   o.SnapshotClass,
+		Status:          CheckStatusPass,
+		StartTime:       startTime,
+		DurationSeconds: time.Since(startTime).Seconds(),
+	}
+	if !preflightStatus {
+		report.Status = CheckStatusFail
+	}
+
+	for _, c := range checks {
+		report.Checks = append(report.Checks, newCheckReport(c))
+	}
+	if o.scMatrix != nil && len(o.scMatrix.results) > 1 {
+		report.StorageClasses = o.scMatrix.results
+	}
+	if o.scMatrix != nil {
+		for _, res := range o.scMatrix.results {
+			if res.Performance != nil {
+				report.StoragePerformance = append(report.StoragePerformance, res.Performance)
+			}
+			if res.Topology != nil {
+				report.Topology = append(report.Topology, res.Topology)
+			}
+			report.AccessModes = append(report.AccessModes, res.AccessModes...)
+		}
+	}
+	if o.targetResult != nil && len(o.targetResult.Steps) != 0 {
+		report.Target = o.targetResult
+	}
+	if o.podSecurityResult != nil && len(o.podSecurityResult.Profiles) != 0 {
+		report.PodSecurity = o.podSecurityResult
+	}
+	if o.sccResult != nil && len(o.sccResult.Cases) != 0 {
+		report.OpenShiftSCC = o.sccResult
+	}
+	if o.webhooksResult != nil && (len(o.webhooksResult.Webhooks) != 0 || len(o.webhooksResult.Mutations) != 0) {
+		report.AdmissionWebhooks = o.webhooksResult
+	}
+	if o.quotaResult != nil && (len(o.quotaResult.Headroom) != 0 || len(o.quotaResult.LimitRangeViolations) != 0) {
+		report.ResourceQuota = o.quotaResult
+	}
+	if o.nodeInventory != nil && len(o.nodeInventory.Nodes) != 0 {
+		report.Nodes = o.nodeInventory
+	}
+	if o.imagesResult != nil && len(o.imagesResult.Images) != 0 {
+		report.ImageAvailability = o.imagesResult
+	}
+	if o.networkResult != nil && len(o.networkResult.Flows) != 0 {
+		report.NetworkPolicy = o.networkResult
+	}
+	if o.dnsResult != nil && len(o.dnsResult.Names) != 0 {
+		report.DNS = o.dnsResult
+	}
+
+	return report
+}
+
+func newCheckReport(c *Check) CheckReport {
+	cr := CheckReport{ID: c.Name, Description: c.Description, Status: CheckStatusSkipped}
+	if c.Result == nil {
+		return cr
+	}
+
+	cr.Status = c.Result.Status
+	cr.DurationSeconds = c.Result.Duration.Seconds()
+	cr.TimedOut = c.Result.TimedOut
+	cr.Message = c.Result.Message
+	cr.Warnings = c.Result.Warnings
+	cr.Resources = c.Result.Resources
+	cr.Recommendations = c.Result.Recommendations
+	if c.Result.Err != nil {
+		cr.Error = c.Result.Err.Error()
+	}
+
+	return cr
+}
+
+// Render returns the report in the given format.
+func (r *RunReport) Render(format string) ([]byte, error) {
+	switch format {
+	case internal.FormatJSON:
+		return json.MarshalIndent(r, "", "  ")
+
+	case internal.FormatYAML:
+		return yaml.Marshal(r)
+
+	case FormatJUnit:
+		return r.renderJUnit()
+	}
+
+	return nil, fmt.Errorf("unsupported report format - %s. Allowed formats are - %s",
+		format, AllowedReportFormats.List())
+}
+
+// outputReport writes the report if an output format or report file is given, and persists it into the result
+// ConfigMap if the run is performed by a preflight job.
+func (o *Run) outputReport(ctx context.Context, report *RunReport) error {
+	if o.OutputFormat != "" || o.ReportFile != "" {
+		if err := o.writeReport(report); err != nil {
+			o.Logger.Errorf("%s Failed to write preflight report :: %s\n", cross, err.Error())
+			return err
+		}
+	}
+	if o.ResultConfigMap != "" {
+		if err := o.persistResult(ctx, report); err != nil {
+			o.Logger.Errorf("%s Failed to write preflight result to ConfigMap - %s :: %s\n", cross, o.ResultConfigMap, err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeReport renders the report in the requested format and writes it to the report file, if provided,
+// or to the standard output.
+func (o *Run) writeReport(report *RunReport) error {
+	format := o.OutputFormat
+	if format == "" {
+		format = internal.FormatJSON
+	}
+
+	data, err := report.Render(format)
+	if err != nil {
+		return err
+	}
+
+	if o.ReportFile != "" {
+		if err = os.WriteFile(o.ReportFile, data, reportFilePermission); err != nil {
+			return err
+		}
+		o.Logger.Infof("Preflight report written to file - %s", o.ReportFile)
+		return nil
+	}
+
+	return writeLine(os.Stdout, data)
+}
+
+func writeLine(w io.Writer, data []byte) error {
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if len(data) == 0 || data[len(data)-1] != '\n' {
+		_, err := w.Write([]byte("\n"))
+		return err
+	}
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Content string `xml:",chardata"`
+}
+
+func (r *RunReport) renderJUnit() ([]byte, error) {
+	suite := junitTestSuite{
+		Name:      junitSuiteName,
+		Tests:     len(r.Checks),
+		Time:      formatSeconds(r.DurationSeconds),
+		Timestamp: r.StartTime.UTC().Format(time.RFC3339),
+		Properties: []junitProperty{
+			{Name: "uid", Value: r.UID},
+			{Name: "namespace", Value: r.Namespace},
+			{Name: "scope", Value: r.Scope},
+			{Name: "storageClass", Value: r.StorageClass},
+			{Name: "snapshotClass", Value: r.SnapshotClass},
+		},
+	}
+
+	for idx := range r.Checks {
+		cr := &r.Checks[idx]
+		tc := junitTestCase{
+			Name:      cr.ID,
+			ClassName: junitSuiteName,
+			Time:      formatSeconds(cr.DurationSeconds),
+			SystemOut: cr.details(),
+		}
+		switch cr.Status {
+		case CheckStatusFail:
+			suite.Failures++
+			tc.Failure = &junitMessage{Message: cr.Error, Content: cr.details()}
+		case CheckStatusSkipped:
+			suite.Skipped++
+			tc.Skipped = &junitMessage{Message: cr.Message}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	suites := junitTestSuites{
+		Name:     junitSuiteName,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+// details returns the human-readable warnings, created resources and recommendations of the check.
+func (cr *CheckReport) details() string {
+	var out string
+	for _, w := range cr.Warnings {
+		out += fmt.Sprintf("Warning: %s\n", w)
+	}
+	for _, res := range cr.Resources {
+		name := res.Name
+		if res.Namespace != "" {
+			name = internal.GetNamespacedName(res.Namespace, res.Name).String()
+		}
+		out += fmt.Sprintf("Created %s: %s\n", res.Kind, name)
+	}
+	for idx, rec := range cr.Recommendations {
+		out += fmt.Sprintf("Recommendation %d: %s\n", idx+1, rec)
+	}
+	return out
+}
+
+func formatSeconds(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
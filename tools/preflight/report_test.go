@@ -0,0 +1,97 @@
+package preflight
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+var _ = Describe("Preflight report unit tests", func() {
+
+	var (
+		checks []*Check
+		report *RunReport
+	)
+
+	BeforeEach(func() {
+		passed := &CheckResult{Status: CheckStatusPass, Duration: time.Second}
+		passed.addResource(getPodTemplate(types.NamespacedName{Name: "dns-pod", Namespace: runOps.Namespace}, "abcdef", &runOps))
+		failed := &CheckResult{Status: CheckStatusFail, Err: errors.New("snapshot class not found")}
+		failed.Recommend("Verify CSI driver supports snapshots")
+		skipped := &CheckResult{}
+		skipped.Skip("preflight check for SnapshotClass failed")
+
+		checks = []*Check{
+			{Name: CheckDNSResolution, Description: "DNS resolution", Result: passed},
+			{Name: CheckStorageSnapshotClass, Description: "SnapshotClass", Result: failed},
+			{Name: CheckVolumeSnapshot, Description: "volume snapshot", Result: skipped},
+		}
+		report = runOps.newReport(checks, time.Now(), false)
+	})
+
+	It("Should build one record per check with status, error, resources and recommendations", func() {
+		Expect(report.Status).To(Equal(CheckStatusFail))
+		Expect(report.Checks).To(HaveLen(len(checks)))
+
+		Expect(report.Checks[0].ID).To(Equal(CheckDNSResolution))
+		Expect(report.Checks[0].Status).To(Equal(CheckStatusPass))
+		Expect(report.Checks[0].DurationSeconds).To(Equal(float64(1)))
+		Expect(report.Checks[0].Resources).To(ConsistOf(ResourceRef{
+			APIVersion: "v1", Kind: internal.PodKind, Namespace: runOps.Namespace, Name: "dns-pod",
+		}))
+
+		Expect(report.Checks[1].Error).To(Equal("snapshot class not found"))
+		Expect(report.Checks[1].Recommendations).To(ConsistOf("Verify CSI driver supports snapshots"))
+
+		Expect(report.Checks[2].Status).To(Equal(CheckStatusSkipped))
+		Expect(report.Checks[2].Message).To(Equal("preflight check for SnapshotClass failed"))
+	})
+
+	It("Should render report in json and yaml format", func() {
+		data, err := report.Render(internal.FormatJSON)
+		Expect(err).To(BeNil())
+		jsonReport := &RunReport{}
+		Expect(json.Unmarshal(data, jsonReport)).To(Succeed())
+		Expect(jsonReport.Checks).To(Equal(report.Checks))
+
+		data, err = report.Render(internal.FormatYAML)
+		Expect(err).To(BeNil())
+		yamlReport := &RunReport{}
+		Expect(yaml.Unmarshal(data, yamlReport)).To(Succeed())
+		Expect(yamlReport.Checks).To(Equal(report.Checks))
+	})
+
+	It("Should render report in junit format with failures and skipped checks", func() {
+		data, err := report.Render(FormatJUnit)
+		Expect(err).To(BeNil())
+
+		suites := &junitTestSuites{}
+		Expect(xml.Unmarshal(data, suites)).To(Succeed())
+		Expect(suites.Tests).To(Equal(len(checks)))
+		Expect(suites.Failures).To(Equal(1))
+		Expect(suites.Skipped).To(Equal(1))
+		Expect(suites.Suites).To(HaveLen(1))
+
+		testCases := suites.Suites[0].TestCases
+		Expect(testCases[0].Failure).To(BeNil())
+		Expect(testCases[0].SystemOut).To(ContainSubstring("Created Pod"))
+		Expect(testCases[1].Failure).ToNot(BeNil())
+		Expect(testCases[1].Failure.Message).To(Equal("snapshot class not found"))
+		Expect(testCases[1].Failure.Content).To(ContainSubstring("Verify CSI driver supports snapshots"))
+		Expect(testCases[2].Skipped).ToNot(BeNil())
+	})
+
+	It("Should return error for unsupported report format", func() {
+		_, err := report.Render(internal.FormatWIDE)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("unsupported report format"))
+	})
+})
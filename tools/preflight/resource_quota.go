@@ -0,0 +1,367 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+const (
+	// QuotaVolumeSnapshots is the object count quota of volume snapshots.
+	QuotaVolumeSnapshots corev1.ResourceName = "count/volumesnapshots." + StorageSnapshotGroup
+)
+
+// quotaResources are the quota resources whose headroom is reported, in the order they are reported.
+var quotaResources = []corev1.ResourceName{
+	corev1.ResourceRequestsCPU,
+	corev1.ResourceRequestsMemory,
+	corev1.ResourceLimitsCPU,
+	corev1.ResourceLimitsMemory,
+	corev1.ResourcePods,
+	corev1.ResourcePersistentVolumeClaims,
+	corev1.ResourceRequestsStorage,
+	QuotaVolumeSnapshots,
+}
+
+// quotaResourceAliases are the quota resources which are the same as the reported ones.
+var quotaResourceAliases = map[corev1.ResourceName]corev1.ResourceName{
+	corev1.ResourceCPU:    corev1.ResourceRequestsCPU,
+	corev1.ResourceMemory: corev1.ResourceRequestsMemory,
+}
+
+// quotaScopesMatchingPreflight are the quota scopes matching pods of preflight and TVK, which have resource requests
+// and no active deadline.
+var quotaScopesMatchingPreflight = map[corev1.ResourceQuotaScope]bool{
+	corev1.ResourceQuotaScopeNotBestEffort:  true,
+	corev1.ResourceQuotaScopeNotTerminating: true,
+}
+
+// TVKControlPlaneFootprint is the approximate footprint of TVK control plane installed with default values.
+var TVKControlPlaneFootprint = corev1.ResourceList{
+	corev1.ResourceRequestsCPU:            resource.MustParse("1"),
+	corev1.ResourceRequestsMemory:         resource.MustParse("2Gi"),
+	corev1.ResourceLimitsCPU:              resource.MustParse("4"),
+	corev1.ResourceLimitsMemory:           resource.MustParse("8Gi"),
+	corev1.ResourcePods:                   resource.MustParse("10"),
+	corev1.ResourcePersistentVolumeClaims: resource.MustParse("0"),
+	corev1.ResourceRequestsStorage:        resource.MustParse("0"),
+	QuotaVolumeSnapshots:                  resource.MustParse("0"),
+}
+
+// ResourceQuotaResult holds the headroom of quotas of namespace and the LimitRange constraints violated by preflight.
+type ResourceQuotaResult struct {
+	Headroom             []*QuotaHeadroom `json:"headroom,omitempty"`
+	LimitRangeViolations []string         `json:"limitRangeViolations,omitempty"`
+}
+
+// QuotaHeadroom is the headroom of a resource of ResourceQuota, compared to the footprint of preflight and TVK.
+type QuotaHeadroom struct {
+	Quota     string      `json:"quota"`
+	Resource  string      `json:"resource"`
+	Hard      string      `json:"hard"`
+	Used      string      `json:"used"`
+	Headroom  string      `json:"headroom"`
+	Preflight string      `json:"preflight"`
+	TVK       string      `json:"tvk"`
+	Status    CheckStatus `json:"status"`
+}
+
+// effectiveResources returns the requests and limits of preflight containers after the defaults of LimitRanges are
+// applied to the ones not set.
+func effectiveResources(requirements corev1.ResourceRequirements, limitRanges []corev1.LimitRange) (requests, limits corev1.ResourceList) {
+	requests, limits = requirements.Requests.DeepCopy(), requirements.Limits.DeepCopy()
+	if requests == nil {
+		requests = corev1.ResourceList{}
+	}
+	if limits == nil {
+		limits = corev1.ResourceList{}
+	}
+	for idx := range limitRanges {
+		for _, item := range limitRanges[idx].Spec.Limits {
+			if item.Type != corev1.LimitTypeContainer {
+				continue
+			}
+			for name, value := range item.Default {
+				if _, ok := limits[name]; !ok {
+					limits[name] = value
+				}
+			}
+			for name, value := range item.DefaultRequest {
+				if _, ok := requests[name]; !ok {
+					requests[name] = value
+				}
+			}
+		}
+	}
+	// request defaults to the limit when only the limit is set
+	for name, value := range limits {
+		if _, ok := requests[name]; !ok {
+			requests[name] = value
+		}
+	}
+	return requests, limits
+}
+
+// scaled returns the quantity multiplied by count.
+func scaled(q resource.Quantity, count int64) resource.Quantity {
+	return *resource.NewMilliQuantity(q.MilliValue()*count, q.Format)
+}
+
+// preflightObjects are the objects which preflight checks create in the namespace. They're cleaned up once the
+// checks are complete, so all of them exist at the same time.
+type preflightObjects struct {
+	pods      int64
+	pvcs      int64
+	snapshots int64
+	// storage is the total storage request of the pvcs.
+	storage resource.Quantity
+}
+
+// plannedObjects returns the objects created in the namespace by the checks, as planned for dry-run. The cluster state
+// isn't read, as it changes only the cluster scoped objects planned. Topology check is planned with a single target
+// node, as target nodes are chosen at run time.
+func (o *Run) plannedObjects(checks []*Check) preflightObjects {
+	plan := o.planResources(checks, &dryRunClusterState{installedCRDs: sets.NewString(), provisioners: make(map[string]string)})
+
+	var objects preflightObjects
+	for _, res := range plan.resources {
+		if res.object.GetNamespace() != o.Namespace {
+			continue
+		}
+		switch res.object.GetObjectKind().GroupVersionKind().Kind {
+		case internal.PodKind:
+			objects.pods++
+		case internal.PersistentVolumeClaimKind:
+			objects.pvcs++
+			if pvc, ok := res.object.(*corev1.PersistentVolumeClaim); ok {
+				objects.storage.Add(*pvc.Spec.Resources.Requests.Storage())
+			}
+		case internal.VolumeSnapshotKind:
+			objects.snapshots++
+		}
+	}
+	return objects
+}
+
+// preflightFootprint returns the resources consumed in the namespace by the objects of preflight checks, with the
+// given container requests and limits.
+func preflightFootprint(requests, limits corev1.ResourceList, objects preflightObjects) corev1.ResourceList {
+	return corev1.ResourceList{
+		corev1.ResourceRequestsCPU:            scaled(requests[corev1.ResourceCPU], objects.pods),
+		corev1.ResourceRequestsMemory:         scaled(requests[corev1.ResourceMemory], objects.pods),
+		corev1.ResourceLimitsCPU:              scaled(limits[corev1.ResourceCPU], objects.pods),
+		corev1.ResourceLimitsMemory:           scaled(limits[corev1.ResourceMemory], objects.pods),
+		corev1.ResourcePods:                   *resource.NewQuantity(objects.pods, resource.DecimalSI),
+		corev1.ResourcePersistentVolumeClaims: *resource.NewQuantity(objects.pvcs, resource.DecimalSI),
+		corev1.ResourceRequestsStorage:        objects.storage,
+		QuotaVolumeSnapshots:                  *resource.NewQuantity(objects.snapshots, resource.DecimalSI),
+	}
+}
+
+// quotaApplies returns whether the quota applies to the pods of preflight and TVK.
+func quotaApplies(quota *corev1.ResourceQuota) bool {
+	scopes := quota.Spec.Scopes
+	if quota.Spec.ScopeSelector != nil {
+		for _, expr := range quota.Spec.ScopeSelector.MatchExpressions {
+			if expr.Operator != corev1.ScopeSelectorOpExists {
+				return false
+			}
+			scopes = append(scopes, expr.ScopeName)
+		}
+	}
+	for _, scope := range scopes {
+		if !quotaScopesMatchingPreflight[scope] {
+			return false
+		}
+	}
+	return true
+}
+
+// quotaHeadroom returns the headroom of each reported resource of quota, compared with the footprints of preflight
+// and TVK. Preflight not fitting in the headroom fails, and TVK not fitting is a warning.
+func quotaHeadroom(quota *corev1.ResourceQuota, preflight, tvk corev1.ResourceList) []*QuotaHeadroom {
+	hard := corev1.ResourceList{}
+	used := corev1.ResourceList{}
+	for name, value := range quota.Status.Hard {
+		if alias, ok := quotaResourceAliases[name]; ok {
+			name = alias
+		}
+		hard[name] = value
+	}
+	// hard of spec is used until the quota controller computes the status
+	for name, value := range quota.Spec.Hard {
+		if alias, ok := quotaResourceAliases[name]; ok {
+			name = alias
+		}
+		if _, ok := hard[name]; !ok {
+			hard[name] = value
+		}
+	}
+	for name, value := range quota.Status.Used {
+		if alias, ok := quotaResourceAliases[name]; ok {
+			name = alias
+		}
+		used[name] = value
+	}
+
+	var headroom []*QuotaHeadroom
+	for _, name := range quotaResources {
+		hardValue, ok := hard[name]
+		if !ok {
+			continue
+		}
+		usedValue := used[name]
+		free := hardValue.DeepCopy()
+		free.Sub(usedValue)
+		preflightValue, tvkValue := preflight[name], tvk[name]
+
+		status := CheckStatusPass
+		switch {
+		case preflightValue.Cmp(free) > 0:
+			status = CheckStatusFail
+		case tvkValue.Cmp(free) > 0:
+			status = CheckStatusWarn
+		}
+		headroom = append(headroom, &QuotaHeadroom{
+			Quota:     quota.GetName(),
+			Resource:  string(name),
+			Hard:      hardValue.String(),
+			Used:      usedValue.String(),
+			Headroom:  free.String(),
+			Preflight: preflightValue.String(),
+			TVK:       tvkValue.String(),
+			Status:    status,
+		})
+	}
+	return headroom
+}
+
+// limitRangeViolations returns the constraints of LimitRanges violated by the containers and PVCs of preflight.
+func limitRangeViolations(limitRanges []corev1.LimitRange, requests, limits corev1.ResourceList,
+	pvcRequest resource.Quantity) []string {
+	var violations []string
+	for idx := range limitRanges {
+		lrName := limitRanges[idx].GetName()
+		for _, item := range limitRanges[idx].Spec.Limits {
+			switch item.Type {
+			case corev1.LimitTypeContainer, corev1.LimitTypePod:
+				for name, maxValue := range item.Max {
+					if value, ok := limits[name]; ok && value.Cmp(maxValue) > 0 {
+						violations = append(violations, fmt.Sprintf("%s: %s limit %s of preflight pods exceeds max %s of %s",
+							lrName, name, value.String(), maxValue.String(), strings.ToLower(string(item.Type))))
+					}
+				}
+				for name, minValue := range item.Min {
+					if value, ok := requests[name]; ok && value.Cmp(minValue) < 0 {
+						violations = append(violations, fmt.Sprintf("%s: %s request %s of preflight pods is less than min %s of %s",
+							lrName, name, value.String(), minValue.String(), strings.ToLower(string(item.Type))))
+					}
+				}
+				for name, maxRatio := range item.MaxLimitRequestRatio {
+					limit, request := limits[name], requests[name]
+					if request.IsZero() || limit.IsZero() {
+						continue
+					}
+					if float64(limit.MilliValue())/float64(request.MilliValue()) > maxRatio.AsApproximateFloat64() {
+						violations = append(violations, fmt.Sprintf("%s: %s limit to request ratio of preflight pods exceeds %s",
+							lrName, name, maxRatio.String()))
+					}
+				}
+			case corev1.LimitTypePersistentVolumeClaim:
+				if maxValue, ok := item.Max[corev1.ResourceStorage]; ok && pvcRequest.Cmp(maxValue) > 0 {
+					violations = append(violations, fmt.Sprintf("%s: PVC storage request %s exceeds max %s",
+						lrName, pvcRequest.String(), maxValue.String()))
+				}
+				if minValue, ok := item.Min[corev1.ResourceStorage]; ok && pvcRequest.Cmp(minValue) < 0 {
+					violations = append(violations, fmt.Sprintf("%s: PVC storage request %s is less than min %s",
+						lrName, pvcRequest.String(), minValue.String()))
+				}
+			}
+		}
+	}
+	return violations
+}
+
+func runResourceQuotaCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	o.Logger.Infof("Checking ResourceQuotas and LimitRanges of namespace - %s", o.Namespace)
+
+	result := o.quotaResult
+	if result == nil {
+		result = &ResourceQuotaResult{}
+		o.quotaResult = result
+	}
+
+	quotas, err := kubeClient.ClientSet.CoreV1().ResourceQuotas(o.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	limitRanges, err := kubeClient.ClientSet.CoreV1().LimitRanges(o.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	if len(quotas.Items) == 0 && len(limitRanges.Items) == 0 {
+		o.Logger.Infof("%s No ResourceQuota or LimitRange found in namespace - %s", check, o.Namespace)
+		return nil
+	}
+
+	requests, limits := effectiveResources(o.ResourceRequirements, limitRanges.Items)
+	var (
+		failed      []string
+		quotaFailed bool
+	)
+	result.LimitRangeViolations = limitRangeViolations(limitRanges.Items, requests, limits, o.PVCStorageRequest)
+	for _, violation := range result.LimitRangeViolations {
+		o.Logger.Errorf("%s LimitRange %s", cross, violation)
+	}
+	if len(result.LimitRangeViolations) != 0 {
+		failed = append(failed, "LimitRange constraints")
+		res.Recommend("Set the resource requests and limits of preflight pods, or the PVC storage request, within " +
+			"the constraints of LimitRanges of the namespace")
+	}
+
+	checks, err := o.SelectChecks()
+	if err != nil {
+		return err
+	}
+	preflight := preflightFootprint(requests, limits, o.plannedObjects(checks))
+	for idx := range quotas.Items {
+		quota := &quotas.Items[idx]
+		if !quotaApplies(quota) {
+			o.Logger.Infof("ResourceQuota - %s has scopes not matching preflight and TVK pods, skipping it", quota.GetName())
+			continue
+		}
+		for _, headroom := range quotaHeadroom(quota, preflight, TVKControlPlaneFootprint) {
+			result.Headroom = append(result.Headroom, headroom)
+			switch headroom.Status {
+			case CheckStatusFail:
+				quotaFailed = true
+				failed = append(failed, fmt.Sprintf("%s of %s", headroom.Resource, headroom.Quota))
+				o.Logger.Errorf("%s ResourceQuota - %s has headroom %s of %s, less than %s needed by preflight", cross,
+					headroom.Quota, headroom.Headroom, headroom.Resource, headroom.Preflight)
+			case CheckStatusWarn:
+				res.Warn(fmt.Sprintf("ResourceQuota - %s has headroom %s of %s, less than %s needed by TVK control plane",
+					headroom.Quota, headroom.Headroom, headroom.Resource, headroom.TVK))
+			default:
+				o.Logger.Infof("%s ResourceQuota - %s has headroom %s of %s", check, headroom.Quota, headroom.Headroom,
+					headroom.Resource)
+			}
+		}
+	}
+
+	if quotaFailed {
+		res.Recommend(fmt.Sprintf("Increase the ResourceQuotas of namespace - %s, or perform fewer checks or check fewer "+
+			"storage classes at once", o.Namespace))
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("preflight resources don't fit in namespace - %s :: [%s]", o.Namespace, strings.Join(failed, ", "))
+	}
+
+	return nil
+}
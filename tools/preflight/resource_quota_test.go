@@ -0,0 +1,164 @@
+package preflight
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+var _ = Describe("Resource quota unit tests", func() {
+
+	var (
+		requirements corev1.ResourceRequirements
+		limitRange   corev1.LimitRange
+	)
+
+	BeforeEach(func() {
+		requirements = corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("25m")},
+			Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+		}
+		limitRange = corev1.LimitRange{
+			ObjectMeta: metav1.ObjectMeta{Name: "limits"},
+			Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{
+				{
+					Type:           corev1.LimitTypeContainer,
+					Default:        corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+					DefaultRequest: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+					Max:            corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")},
+				},
+				{
+					Type: corev1.LimitTypePersistentVolumeClaim,
+					Min:  corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("5Gi")},
+				},
+			}},
+		}
+	})
+
+	Context("effectiveResources func test-cases", func() {
+
+		It("Should apply defaults of LimitRange to requests and limits not set", func() {
+			requests, limits := effectiveResources(requirements, []corev1.LimitRange{limitRange})
+			Expect(requests.Cpu().String()).To(Equal("25m"))
+			Expect(requests.Memory().String()).To(Equal("256Mi"))
+			Expect(limits.Cpu().String()).To(Equal("500m"))
+			Expect(limits.Memory().String()).To(Equal("256Mi"))
+			Expect(requirements.Requests).To(HaveLen(1))
+		})
+	})
+
+	Context("limitRangeViolations func test-cases", func() {
+
+		It("Should report limits above max and PVC storage request below min", func() {
+			requests, limits := effectiveResources(requirements, []corev1.LimitRange{limitRange})
+			Expect(limitRangeViolations([]corev1.LimitRange{limitRange}, requests, limits, resource.MustParse("1Gi"))).To(
+				ConsistOf("limits: cpu limit 500m of preflight pods exceeds max 250m of container",
+					"limits: PVC storage request 1Gi is less than min 5Gi"))
+			Expect(limitRangeViolations(nil, requests, limits, resource.MustParse("1Gi"))).To(BeEmpty())
+		})
+	})
+
+	Context("plannedObjects func test-cases", func() {
+
+		var run *Run
+
+		BeforeEach(func() {
+			resNameSuffix = testNameSuffix
+			run = runOps.copyRun()
+			run.Scope = internal.NamespaceScope
+			run.StorageClasses, run.AccessModes, run.SnapshotClass = nil, nil, ""
+			run.PVCStorageRequest = resource.MustParse("1Gi")
+		})
+
+		plannedObjects := func() preflightObjects {
+			checks, err := run.SelectChecks()
+			Expect(err).To(BeNil())
+			run.scMatrix = newStorageClassMatrix(run.storageClasses(), resNameSuffix)
+			return run.plannedObjects(checks)
+		}
+
+		It("Should count the pods, PVCs and volume snapshots of volume snapshot check for each storage class", func() {
+			single := plannedObjects()
+			Expect(single.storage.Cmp(*resource.NewQuantity(single.pvcs*(1<<30), resource.BinarySI))).To(BeZero())
+
+			run.StorageClasses = []string{"sc-2"}
+			multiple := plannedObjects()
+			// source and restored pvcs, with their writer and reader pods, and the volume snapshot of source pvc
+			Expect(multiple.pods - single.pods).To(Equal(int64(2)))
+			Expect(multiple.pvcs - single.pvcs).To(Equal(int64(2)))
+			Expect(multiple.snapshots - single.snapshots).To(Equal(int64(1)))
+
+			// unmounted source pvc is also snapshotted and restored to a pvc with its reader pod
+			run.DataSize = resource.MustParse("100Mi")
+			integrity := plannedObjects()
+			Expect(integrity.pods - multiple.pods).To(Equal(int64(2)))
+			Expect(integrity.pvcs - multiple.pvcs).To(Equal(int64(2)))
+			Expect(integrity.snapshots - multiple.snapshots).To(Equal(int64(2)))
+		})
+
+		It("Should count the objects of the optional checks selected", func() {
+			defaults := plannedObjects()
+			run.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+			withAccessModes := plannedObjects()
+			Expect(withAccessModes.pods - defaults.pods).To(Equal(int64(2)))
+			Expect(withAccessModes.pvcs - defaults.pvcs).To(Equal(int64(1)))
+			Expect(withAccessModes.snapshots).To(Equal(defaults.snapshots))
+		})
+	})
+
+	Context("quotaHeadroom func test-cases", func() {
+
+		var preflight corev1.ResourceList
+
+		BeforeEach(func() {
+			requests, limits := effectiveResources(requirements, nil)
+			preflight = preflightFootprint(requests, limits, preflightObjects{pods: 10, pvcs: 6, snapshots: 4,
+				storage: resource.MustParse("6Gi")})
+		})
+
+		It("Should compute footprint of preflight for the objects created by checks", func() {
+			Expect(preflight.Pods().String()).To(Equal("10"))
+			Expect(preflight.Name(corev1.ResourceRequestsCPU, resource.DecimalSI).String()).To(Equal("250m"))
+			Expect(preflight.Name(corev1.ResourcePersistentVolumeClaims, resource.DecimalSI).String()).To(Equal("6"))
+			Expect(preflight.Name(corev1.ResourceRequestsStorage, resource.BinarySI).String()).To(Equal("6Gi"))
+			Expect(preflight.Name(QuotaVolumeSnapshots, resource.DecimalSI).String()).To(Equal("4"))
+		})
+
+		It("Should fail resources not fitting preflight, and warn for resources not fitting TVK", func() {
+			quota := &corev1.ResourceQuota{
+				ObjectMeta: metav1.ObjectMeta{Name: "compute"},
+				Spec: corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{
+					corev1.ResourcePods:            resource.MustParse("20"),
+					corev1.ResourceCPU:             resource.MustParse("2"),
+					corev1.ResourceConfigMaps:      resource.MustParse("10"),
+					corev1.ResourceRequestsStorage: resource.MustParse("10Gi"),
+				}},
+				Status: corev1.ResourceQuotaStatus{
+					Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("20"), corev1.ResourceCPU: resource.MustParse("2")},
+					Used: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("12"), corev1.ResourceCPU: resource.MustParse("1500m"),
+						corev1.ResourceRequestsStorage: resource.MustParse("5Gi")},
+				},
+			}
+			headroom := quotaHeadroom(quota, preflight, TVKControlPlaneFootprint)
+			Expect(headroom).To(HaveLen(3))
+			Expect(*headroom[0]).To(Equal(QuotaHeadroom{Quota: "compute", Resource: string(corev1.ResourceRequestsCPU),
+				Hard: "2", Used: "1500m", Headroom: "500m", Preflight: "250m", TVK: "1", Status: CheckStatusWarn}))
+			Expect(headroom[1].Resource).To(Equal(string(corev1.ResourcePods)))
+			Expect(headroom[1].Status).To(Equal(CheckStatusFail))
+			Expect(headroom[2].Headroom).To(Equal("5Gi"))
+			Expect(headroom[2].Status).To(Equal(CheckStatusFail))
+		})
+
+		It("Should not apply quotas of scopes not matching preflight pods", func() {
+			Expect(quotaApplies(&corev1.ResourceQuota{})).To(BeTrue())
+			Expect(quotaApplies(&corev1.ResourceQuota{Spec: corev1.ResourceQuotaSpec{
+				Scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeNotBestEffort}}})).To(BeTrue())
+			Expect(quotaApplies(&corev1.ResourceQuota{Spec: corev1.ResourceQuotaSpec{
+				Scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort}}})).To(BeFalse())
+		})
+	})
+})
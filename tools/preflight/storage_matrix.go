@@ -0,0 +1,237 @@
+package preflight
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// storageStage is a stage of the volume snapshot and restore flow performed for a storage class.
+type storageStage int
+
+const (
+	stageSnapshotClassFound storageStage = iota
+	stageMountedSnapshot
+	stageRestore
+	stageDataVerified
+	stageUnmountedSnapshot
+	stageUnmountedRestore
+)
+
+// StorageClassResult is the outcome of snapshot class, volume snapshot and restore checks for a storage class.
+type StorageClassResult struct {
+	StorageClass       string      `json:"storageClass"`
+	SnapshotClass      string      `json:"snapshotClass,omitempty"`
+	SnapshotClassFound CheckStatus `json:"snapshotClassFound"`
+	MountedSnapshot    CheckStatus `json:"mountedSnapshot"`
+	Restore            CheckStatus `json:"restore"`
+	DataVerified       CheckStatus `json:"dataVerified"`
+	UnmountedSnapshot  CheckStatus `json:"unmountedSnapshot"`
+	// UnmountedRestore is the outcome of restoring the volume snapshot of unmounted pvc, and verifying its data.
+	UnmountedRestore CheckStatus `json:"unmountedRestore"`
+	// BlockVolume is the outcome of snapshot and restore of raw block volume, if block volume snapshot check is performed.
+	BlockVolume CheckStatus `json:"blockVolume,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	// Performance is measured by storage performance check, if performed. It's reported separately.
+	Performance *StoragePerformanceResult `json:"-"`
+	// Topology is the outcome of restores across nodes and zones by topology check, if performed. It's reported separately.
+	Topology *TopologyResult `json:"-"`
+	// AccessModes are verified by access mode check, if performed. They're reported separately.
+	AccessModes []*AccessModeResult `json:"-"`
+
+	// nameSuffix is appended to the names of resources created for the storage class
+	nameSuffix string
+}
+
+// storageClassMatrix holds results of all the storage classes of a preflight run. It is shared by the
+// copies of run options with which checks are performed.
+type storageClassMatrix struct {
+	results []*StorageClassResult
+}
+
+// newStorageClassMatrix returns a matrix for the given storage classes. Resources created for the first
+// storage class are suffixed with the run UID, and for others with the run UID and index of the storage class.
+func newStorageClassMatrix(storageClasses []string, uid string) *storageClassMatrix {
+	m := &storageClassMatrix{}
+	for idx, sc := range storageClasses {
+		nameSuffix := uid
+		if idx != 0 {
+			nameSuffix = fmt.Sprintf("%s-%d", uid, idx)
+		}
+		m.results = append(m.results, &StorageClassResult{
+			StorageClass:       sc,
+			SnapshotClassFound: CheckStatusSkipped,
+			MountedSnapshot:    CheckStatusSkipped,
+			Restore:            CheckStatusSkipped,
+			DataVerified:       CheckStatusSkipped,
+			UnmountedSnapshot:  CheckStatusSkipped,
+			UnmountedRestore:   CheckStatusSkipped,
+			nameSuffix:         nameSuffix,
+		})
+	}
+
+	return m
+}
+
+// storageClasses returns the storage classes to perform preflight checks with, without duplicates.
+func (o *Run) storageClasses() []string {
+	var (
+		storageClasses []string
+		seen           = make(map[string]bool)
+	)
+	for _, sc := range append([]string{o.StorageClass}, o.StorageClasses...) {
+		if sc == "" || seen[sc] {
+			continue
+		}
+		seen[sc] = true
+		storageClasses = append(storageClasses, sc)
+	}
+
+	return storageClasses
+}
+
+// storageMatrix returns the storage class matrix of the run, it is created if the run doesn't have one.
+func (o *Run) storageMatrix() *storageClassMatrix {
+	if o.scMatrix == nil {
+		o.scMatrix = newStorageClassMatrix(o.storageClasses(), resNameSuffix)
+	}
+	return o.scMatrix
+}
+
+// forEachStorageClass calls fn for each storage class with a copy of run options for the storage class.
+// It returns the error of fn as is if there is a single storage class, otherwise the errors of all
+// storage classes prefixed by their name.
+func (o *Run) forEachStorageClass(fn func(r *Run) error) error {
+	results := o.storageMatrix().results
+	errs := o.performConcurrently(len(results), func(index int, r *Run) error {
+		scRun := r.copyRun()
+		scRun.StorageClass = results[index].StorageClass
+		scRun.scResult = results[index]
+		err := fn(scRun)
+		if err != nil && results[index].Error == "" {
+			results[index].Error = err.Error()
+		}
+		return err
+	})
+	if len(errs) == 1 {
+		return errs[0]
+	}
+
+	var failed []string
+	for idx, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("storage class %s :: %s", results[idx].StorageClass, err.Error()))
+		}
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("%d of %d storage classes failed - [%s]", len(failed), len(errs), strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+// anySnapshotClassFound returns true if a volume snapshot class is found for any of the storage classes.
+func (m *storageClassMatrix) anySnapshotClassFound() bool {
+	for _, res := range m.results {
+		if res.SnapshotClassFound == CheckStatusPass {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotClass returns the volume snapshot class found for the storage class being checked, or the
+// one provided by user if the storage class isn't checked for a volume snapshot class.
+func (o *Run) snapshotClass() string {
+	if o.scResult == nil || o.scResult.SnapshotClass == "" {
+		return o.SnapshotClass
+	}
+	return o.scResult.SnapshotClass
+}
+
+// setSnapshotClass records the volume snapshot class found for the storage class being checked.
+func (o *Run) setSnapshotClass(snapshotClass string) {
+	if o.scResult != nil {
+		o.scResult.SnapshotClass = snapshotClass
+	}
+}
+
+// resourceNameSuffix returns the suffix for names of the resources created for the storage class being checked.
+func (o *Run) resourceNameSuffix(uid string) string {
+	suffix := uid
+	if o.scResult != nil && o.scResult.nameSuffix != "" {
+		suffix = o.scResult.nameSuffix
+	}
+	if o.blockMode {
+		return blockNamePrefix + suffix
+	}
+	return suffix
+}
+
+// markStorageStage records the outcome of a stage of the flow performed for the storage class being checked.
+func (o *Run) markStorageStage(stage storageStage, err error) {
+	if o.scResult == nil {
+		return
+	}
+
+	status := CheckStatusPass
+	if err != nil {
+		status = CheckStatusFail
+	}
+	// stages of raw block volume are recorded together, the volume passes once its data is verified
+	if o.blockMode {
+		if err != nil || stage == stageDataVerified {
+			o.scResult.BlockVolume = status
+		}
+		return
+	}
+	switch stage {
+	case stageSnapshotClassFound:
+		o.scResult.SnapshotClassFound = status
+	case stageMountedSnapshot:
+		o.scResult.MountedSnapshot = status
+	case stageRestore:
+		o.scResult.Restore = status
+	case stageDataVerified:
+		o.scResult.DataVerified = status
+	case stageUnmountedSnapshot:
+		o.scResult.UnmountedSnapshot = status
+	case stageUnmountedRestore:
+		o.scResult.UnmountedRestore = status
+	}
+}
+
+// logStorageClassMatrix displays the outcome of snapshot and restore checks of each storage class as a table.
+func (o *Run) logStorageClassMatrix() {
+	if o.scMatrix == nil || len(o.scMatrix.results) < 2 {
+		return
+	}
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "STORAGE CLASS\tSNAPSHOT CLASS\tSNAPSHOT CLASS FOUND\tMOUNTED SNAPSHOT\tRESTORE\tDATA VERIFIED\t"+
+		"UNMOUNTED SNAPSHOT\tUNMOUNTED RESTORE")
+	for _, res := range o.scMatrix.results {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", res.StorageClass, res.SnapshotClass,
+			statusGlyph(res.SnapshotClassFound), statusGlyph(res.MountedSnapshot),
+			statusGlyph(res.Restore), statusGlyph(res.DataVerified),
+			statusGlyph(res.UnmountedSnapshot), statusGlyph(res.UnmountedRestore))
+	}
+	_ = w.Flush()
+
+	o.Logger.Infoln("Storage class snapshot and restore matrix:")
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		o.Logger.Infoln(line)
+	}
+}
+
+func statusGlyph(status CheckStatus) string {
+	switch status {
+	case CheckStatusPass:
+		return check
+	case CheckStatusFail:
+		return cross
+	}
+	return "-"
+}
@@ -0,0 +1,74 @@
+package preflight
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Storage class matrix unit tests", func() {
+
+	var run *Run
+
+	BeforeEach(func() {
+		run = runOps.copyRun()
+		run.Parallelism = 1
+		run.StorageClass = "sc-1"
+		run.StorageClasses = []string{"sc-2", "sc-1", "sc-3"}
+		run.scMatrix = newStorageClassMatrix(run.storageClasses(), "abcdef")
+	})
+
+	It("Should return storage classes without duplicates in the given order", func() {
+		Expect(run.storageClasses()).To(Equal([]string{"sc-1", "sc-2", "sc-3"}))
+	})
+
+	It("Should suffix names of resources of storage classes other than the first with their index", func() {
+		var suffixes []string
+		Expect(run.forEachStorageClass(func(r *Run) error {
+			suffixes = append(suffixes, r.resourceNameSuffix("abcdef"))
+			return nil
+		})).To(Succeed())
+		Expect(suffixes).To(Equal([]string{"abcdef", "abcdef-1", "abcdef-2"}))
+	})
+
+	It("Should record stages of each storage class and report failed storage classes", func() {
+		err := run.forEachStorageClass(func(r *Run) error {
+			r.markStorageStage(stageSnapshotClassFound, nil)
+			r.setSnapshotClass("vsc-" + r.StorageClass)
+			if r.StorageClass == "sc-2" {
+				sErr := errors.New("snapshot not ready")
+				r.markStorageStage(stageMountedSnapshot, sErr)
+				return sErr
+			}
+			r.markStorageStage(stageMountedSnapshot, nil)
+			r.markStorageStage(stageRestore, nil)
+			r.markStorageStage(stageDataVerified, nil)
+			r.markStorageStage(stageUnmountedSnapshot, nil)
+			r.markStorageStage(stageUnmountedRestore, nil)
+			return nil
+		})
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("1 of 3 storage classes failed"))
+		Expect(err.Error()).To(ContainSubstring("storage class sc-2 :: snapshot not ready"))
+
+		results := run.scMatrix.results
+		Expect(results[0].SnapshotClass).To(Equal("vsc-sc-1"))
+		Expect(results[0].DataVerified).To(Equal(CheckStatusPass))
+		Expect(results[1].MountedSnapshot).To(Equal(CheckStatusFail))
+		Expect(results[0].UnmountedRestore).To(Equal(CheckStatusPass))
+		Expect(results[1].Restore).To(Equal(CheckStatusSkipped))
+		Expect(results[1].UnmountedSnapshot).To(Equal(CheckStatusSkipped))
+		Expect(results[1].Error).To(Equal("snapshot not ready"))
+		Expect(run.scMatrix.anySnapshotClassFound()).To(BeTrue())
+	})
+
+	It("Should return the error as is when there is a single storage class", func() {
+		run.StorageClasses = nil
+		run.scMatrix = newStorageClassMatrix(run.storageClasses(), "abcdef")
+		err := run.forEachStorageClass(func(r *Run) error {
+			return errors.New("not found storageclass - sc-1 on cluster")
+		})
+		Expect(err).To(MatchError("not found storageclass - sc-1 on cluster"))
+	})
+})
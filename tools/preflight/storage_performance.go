@@ -0,0 +1,246 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+const (
+	// DefaultPerformanceDataSize is the size of data written and read by storage performance check, if not given.
+	DefaultPerformanceDataSize = "256Mi"
+
+	PerfPvcNamePrefix         = "perf-source-pvc-"
+	PerfRestoredPvcNamePrefix = "perf-restored-pvc-"
+	PerfVolumeSnapNamePrefix  = "snapshot-perf-source-pvc-"
+
+	perfDataFilePath = VolMountPath + "/perf-data"
+	perfBlockSize    = 1024 * 1024
+	mebibyte         = 1024 * 1024
+)
+
+// ddOutputRegex matches the transfer summary of busybox and GNU dd, e.g
+// '268435456 bytes (256.0MB) copied, 1.234567 seconds, 207.4MB/s' and
+// '268435456 bytes (268 MB, 256 MiB) copied, 1.2 s, 218 MB/s'
+var ddOutputRegex = regexp.MustCompile(`(\d+) bytes \(.*\) copied, ([0-9.]+) s`)
+
+// StoragePerformanceOptions configures the data measured by storage performance check and the thresholds it must meet.
+// Thresholds which aren't given are not evaluated.
+type StoragePerformanceOptions struct {
+	DataSize resource.Quantity `json:"dataSize,omitempty"`
+	// MinWriteThroughput and MinReadThroughput are in bytes per second, e.g 50Mi
+	MinWriteThroughput resource.Quantity `json:"minWriteThroughput,omitempty"`
+	MinReadThroughput  resource.Quantity `json:"minReadThroughput,omitempty"`
+	MaxSnapshotLatency metav1.Duration   `json:"maxSnapshotLatency,omitempty"`
+	MaxRestoreLatency  metav1.Duration   `json:"maxRestoreLatency,omitempty"`
+}
+
+// StoragePerformanceResult is the throughput and snapshot latency measured for a storage class.
+type StoragePerformanceResult struct {
+	StorageClass                  string   `json:"storageClass"`
+	DataSize                      string   `json:"dataSize"`
+	WriteThroughputBytesPerSecond int64    `json:"writeThroughputBytesPerSecond,omitempty"`
+	ReadThroughputBytesPerSecond  int64    `json:"readThroughputBytesPerSecond,omitempty"`
+	SnapshotLatencySeconds        float64  `json:"snapshotLatencySeconds,omitempty"`
+	RestoreLatencySeconds         float64  `json:"restoreLatencySeconds,omitempty"`
+	ThresholdsNotMet              []string `json:"thresholdsNotMet,omitempty"`
+}
+
+// performanceDataSize returns the size of data written and read by storage performance check.
+func (o *Run) performanceDataSize() resource.Quantity {
+	if o.StoragePerformance != nil && o.StoragePerformance.DataSize.Value() > 0 {
+		return o.StoragePerformance.DataSize
+	}
+	return resource.MustParse(DefaultPerformanceDataSize)
+}
+
+// storagePerformanceString returns the data size and the configured thresholds of storage performance check.
+func (o *Run) storagePerformanceString() string {
+	opts := o.StoragePerformance
+	dataSize := o.performanceDataSize()
+	return fmt.Sprintf("dataSize=%s,minWriteThroughput=%s,minReadThroughput=%s,maxSnapshotLatency=%s,maxRestoreLatency=%s",
+		dataSize.String(), opts.MinWriteThroughput.String(), opts.MinReadThroughput.String(),
+		opts.MaxSnapshotLatency.Duration, opts.MaxRestoreLatency.Duration)
+}
+
+// perfWriteCommand writes the data in blocks of 1Mi and flushes it to the volume before dd completes.
+func (o *Run) perfWriteCommand() []string {
+	size := o.performanceDataSize()
+	blocks := (size.Value() + perfBlockSize - 1) / perfBlockSize
+	return []string{"/bin/sh", "-c",
+		fmt.Sprintf("dd if=/dev/zero of=%s bs=%d count=%d conv=fsync 2>&1", perfDataFilePath, perfBlockSize, blocks)}
+}
+
+// perfReadCommand reads the data written by perfWriteCommand, from a volume restored from snapshot so that it's not cached.
+func perfReadCommand() []string {
+	return []string{"/bin/sh", "-c", fmt.Sprintf("dd if=%s of=/dev/null bs=%d 2>&1", perfDataFilePath, perfBlockSize)}
+}
+
+// parseDDThroughput returns the throughput in bytes per second from the transfer summary printed by dd.
+func parseDDThroughput(out string) (int64, error) {
+	match := ddOutputRegex.FindStringSubmatch(out)
+	if match == nil {
+		return 0, fmt.Errorf("unable to parse transfer summary of dd - '%s'", strings.TrimSpace(out))
+	}
+	bytes, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	seconds, err := strconv.ParseFloat(match[2], 64)
+	if err != nil {
+		return 0, err
+	}
+	if seconds <= 0 {
+		return 0, fmt.Errorf("transfer of %d bytes by dd took too little time to measure throughput", bytes)
+	}
+
+	return int64(float64(bytes) / seconds), nil
+}
+
+func formatThroughput(bytesPerSecond int64) string {
+	return fmt.Sprintf("%.1f MiB/s", float64(bytesPerSecond)/mebibyte)
+}
+
+// evaluate records the thresholds which the measured performance doesn't meet.
+func (p *StoragePerformanceResult) evaluate(opts *StoragePerformanceOptions) {
+	p.ThresholdsNotMet = nil
+	if opts == nil {
+		return
+	}
+	if minWrite := opts.MinWriteThroughput.Value(); minWrite > 0 && p.WriteThroughputBytesPerSecond < minWrite {
+		p.ThresholdsNotMet = append(p.ThresholdsNotMet, fmt.Sprintf("write throughput %s is below minimum %s",
+			formatThroughput(p.WriteThroughputBytesPerSecond), formatThroughput(minWrite)))
+	}
+	if minRead := opts.MinReadThroughput.Value(); minRead > 0 && p.ReadThroughputBytesPerSecond < minRead {
+		p.ThresholdsNotMet = append(p.ThresholdsNotMet, fmt.Sprintf("read throughput %s is below minimum %s",
+			formatThroughput(p.ReadThroughputBytesPerSecond), formatThroughput(minRead)))
+	}
+	if maxSnap := opts.MaxSnapshotLatency.Duration; maxSnap > 0 && p.SnapshotLatencySeconds > maxSnap.Seconds() {
+		p.ThresholdsNotMet = append(p.ThresholdsNotMet, fmt.Sprintf("snapshot latency %.1fs is above maximum %s",
+			p.SnapshotLatencySeconds, maxSnap))
+	}
+	if maxRestore := opts.MaxRestoreLatency.Duration; maxRestore > 0 && p.RestoreLatencySeconds > maxRestore.Seconds() {
+		p.ThresholdsNotMet = append(p.ThresholdsNotMet, fmt.Sprintf("restore latency %.1fs is above maximum %s",
+			p.RestoreLatencySeconds, maxRestore))
+	}
+}
+
+func runStoragePerformanceCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	o.Logger.Infoln("Measuring storage throughput and volume snapshot latency")
+
+	err := o.forEachStorageClass(func(r *Run) error {
+		if r.scResult != nil && r.scResult.SnapshotClassFound == CheckStatusFail {
+			return fmt.Errorf("volume snapshot class not found for storage class - %s", r.StorageClass)
+		}
+		// data of integrity check is not written, so that only the measured data occupies the volume
+		r.DataSize = resource.Quantity{}
+		perf, pErr := r.measureStoragePerformance(ctx, resNameSuffix, kubeClient)
+		if r.scResult != nil {
+			r.scResult.Performance = perf
+		}
+		if pErr != nil {
+			return pErr
+		}
+		perf.evaluate(r.StoragePerformance)
+		if len(perf.ThresholdsNotMet) != 0 {
+			return fmt.Errorf("storage class - %s doesn't meet performance thresholds - [%s]",
+				r.StorageClass, strings.Join(perf.ThresholdsNotMet, ", "))
+		}
+		return nil
+	})
+	if err != nil {
+		res.Recommend("Use a storage class backed by faster volumes, or relax the thresholds in " +
+			"'storagePerformance' of run section of config file")
+		return err
+	}
+
+	return nil
+}
+
+// measureStoragePerformance writes data to a pvc of the storage class and reads it from a pvc restored from its volume
+// snapshot, measuring the write and read throughput, and the time taken to snapshot and restore the pvc.
+func (o *Run) measureStoragePerformance(ctx context.Context, uid string, clients ServerClients) (*StoragePerformanceResult, error) {
+	var (
+		nameSuffix = o.resourceNameSuffix(uid)
+		dataSize   = o.performanceDataSize()
+		perf       = &StoragePerformanceResult{StorageClass: o.StorageClass, DataSize: dataSize.String()}
+	)
+
+	prefSnapshotVer, err := GetServerPreferredVersionForGroup(StorageSnapshotGroup, clients.ClientSet)
+	if err != nil {
+		return perf, err
+	}
+
+	sourcePvcNsName := types.NamespacedName{Namespace: o.Namespace, Name: PerfPvcNamePrefix + nameSuffix}
+	pvc, err := o.createPVC(ctx, sourcePvcNsName, uid, clients.ClientSet)
+	if err != nil {
+		return perf, err
+	}
+	writerPodName := fmt.Sprintf("%s%s-%s", PerfPvcNamePrefix, "writer", nameSuffix)
+	writerPod, err := o.createWriterPodAttachedWithPVC(ctx, writerPodName, uid, sourcePvcNsName, clients.ClientSet)
+	if err != nil {
+		return perf, err
+	}
+
+	perf.WriteThroughputBytesPerSecond, err = o.measureThroughput(ctx, writerPod, o.perfWriteCommand(), clients)
+	if err != nil {
+		return perf, fmt.Errorf("error measuring write throughput :: %w", err)
+	}
+	o.Logger.Infof("%s Write throughput of %s of data to pvc - %s is %s", check, dataSize.String(),
+		sourcePvcNsName.String(), formatThroughput(perf.WriteThroughputBytesPerSecond))
+
+	snapshotNameNs := types.NamespacedName{Namespace: o.Namespace, Name: PerfVolumeSnapNamePrefix + nameSuffix}
+	start := time.Now()
+	if err = o.createSnapshotFromPVC(ctx, snapshotNameNs, o.snapshotClass(), prefSnapshotVer, pvc.GetName(), uid, clients); err != nil {
+		return perf, err
+	}
+	perf.SnapshotLatencySeconds = time.Since(start).Seconds()
+	o.Logger.Infof("%s Volume snapshot - %s became ready-to-use in %.1fs", check, snapshotNameNs.String(),
+		perf.SnapshotLatencySeconds)
+
+	restoredPvcNsName := types.NamespacedName{Namespace: o.Namespace, Name: PerfRestoredPvcNamePrefix + nameSuffix}
+	restoredPvcMeta := &metav1.ObjectMeta{Name: restoredPvcNsName.Name, Namespace: restoredPvcNsName.Namespace, Labels: pvc.Labels}
+	start = time.Now()
+	if _, err = o.createPVCFromSnapshot(ctx, clients.RuntimeClient, restoredPvcMeta, &pvc.Spec, snapshotNameNs.Name); err != nil {
+		return perf, err
+	}
+	readerPodName := fmt.Sprintf("%s%s-%s", PerfRestoredPvcNamePrefix, "reader", nameSuffix)
+	readerPod, err := o.createReaderPodAttachedWithPVC(ctx, readerPodName, uid, restoredPvcNsName, clients.ClientSet)
+	if err != nil {
+		return perf, err
+	}
+	perf.RestoreLatencySeconds = time.Since(start).Seconds()
+	o.Logger.Infof("%s PVC - %s restored from volume snapshot and attached to pod - %s in %.1fs", check,
+		restoredPvcNsName.String(), internal.GetNamespacedName(readerPod.GetNamespace(), readerPod.GetName()).String(),
+		perf.RestoreLatencySeconds)
+
+	perf.ReadThroughputBytesPerSecond, err = o.measureThroughput(ctx, readerPod, perfReadCommand(), clients)
+	if err != nil {
+		return perf, fmt.Errorf("error measuring read throughput :: %w", err)
+	}
+	o.Logger.Infof("%s Read throughput of %s of data from pvc - %s is %s", check, dataSize.String(),
+		restoredPvcNsName.String(), formatThroughput(perf.ReadThroughputBytesPerSecond))
+
+	return perf, nil
+}
+
+// measureThroughput executes the dd command in the pod and returns the throughput reported by it.
+func (o *Run) measureThroughput(ctx context.Context, pod *corev1.Pod, command []string, clients ServerClients) (int64, error) {
+	execOp := o.dataExecOptions(ctx, pod, command, clients)
+	res, err := execInPodWithResponse(ctx, &execOp, o.Logger)
+	if err != nil {
+		return 0, err
+	}
+
+	return parseDDThroughput(res.Stdout)
+}
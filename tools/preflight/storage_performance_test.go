@@ -0,0 +1,83 @@
+package preflight
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Storage performance unit tests", func() {
+
+	Context("parseDDThroughput func test-cases", func() {
+
+		It("Should parse throughput from transfer summary of busybox dd", func() {
+			out := "256+0 records in\n256+0 records out\n268435456 bytes (256.0MB) copied, 2.000000 seconds, 128.0MB/s\n"
+			throughput, err := parseDDThroughput(out)
+			Expect(err).To(BeNil())
+			Expect(throughput).To(Equal(int64(134217728)))
+		})
+
+		It("Should parse throughput from transfer summary of GNU dd", func() {
+			out := "256+0 records in\n256+0 records out\n268435456 bytes (268 MB, 256 MiB) copied, 0.5 s, 537 MB/s\n"
+			throughput, err := parseDDThroughput(out)
+			Expect(err).To(BeNil())
+			Expect(throughput).To(Equal(int64(536870912)))
+		})
+
+		It("Should return error when transfer summary is not present", func() {
+			_, err := parseDDThroughput("dd: can't open '/demo/data/perf-data': No such file or directory")
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("unable to parse transfer summary of dd"))
+		})
+	})
+
+	Context("perfWriteCommand func test-cases", func() {
+
+		It("Should write default data size when it's not configured", func() {
+			run := &Run{}
+			Expect(run.perfWriteCommand()[2]).To(ContainSubstring("bs=1048576 count=256 conv=fsync"))
+		})
+
+		It("Should round up the configured data size to blocks of 1Mi", func() {
+			run := &Run{RunOptions: RunOptions{StoragePerformance: &StoragePerformanceOptions{DataSize: resource.MustParse("1500Ki")}}}
+			Expect(run.perfWriteCommand()[2]).To(ContainSubstring("count=2 "))
+		})
+	})
+
+	Context("evaluate func test-cases", func() {
+
+		var perf *StoragePerformanceResult
+
+		BeforeEach(func() {
+			perf = &StoragePerformanceResult{
+				WriteThroughputBytesPerSecond: 40 * mebibyte,
+				ReadThroughputBytesPerSecond:  100 * mebibyte,
+				SnapshotLatencySeconds:        90,
+				RestoreLatencySeconds:         30,
+			}
+		})
+
+		It("Should not record any threshold when thresholds are not configured", func() {
+			perf.evaluate(nil)
+			Expect(perf.ThresholdsNotMet).To(BeEmpty())
+			perf.evaluate(&StoragePerformanceOptions{})
+			Expect(perf.ThresholdsNotMet).To(BeEmpty())
+		})
+
+		It("Should record the thresholds which are not met", func() {
+			perf.evaluate(&StoragePerformanceOptions{
+				MinWriteThroughput: resource.MustParse("50Mi"),
+				MinReadThroughput:  resource.MustParse("50Mi"),
+				MaxSnapshotLatency: metav1.Duration{Duration: time.Minute},
+				MaxRestoreLatency:  metav1.Duration{Duration: time.Minute},
+			})
+			Expect(perf.ThresholdsNotMet).To(Equal([]string{
+				"write throughput 40.0 MiB/s is below minimum 50.0 MiB/s",
+				"snapshot latency 90.0s is above maximum 1m0s",
+			}))
+		})
+	})
+})
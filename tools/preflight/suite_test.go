@@ -15,6 +15,7 @@ import (
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
 	goclient "k8s.io/client-go/kubernetes"
 	clientGoScheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -110,6 +111,8 @@ var _ = BeforeSuite(func() {
 	Expect(testClient.ClientSet).ToNot(BeNil())
 	testClient.DiscClient = testClient.ClientSet.DiscoveryClient
 	Expect(testClient.DiscClient).ToNot(BeNil())
+	testClient.DynamicClient, err = dynamic.NewForConfig(cfg)
+	Expect(err).ToNot(HaveOccurred())
 	testClient.RuntimeClient = k8sManager.GetClient()
 	Expect(testClient.RuntimeClient).ToNot(BeNil())
 
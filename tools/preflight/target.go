@@ -0,0 +1,496 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/trilioData/tvk-plugins/internal"
+	"github.com/trilioData/tvk-plugins/tools/preflight/wait"
+)
+
+const (
+	// TargetTypeS3 and TargetTypeNFS are the types of backup target verified by target check.
+	TargetTypeS3  = "s3"
+	TargetTypeNFS = "nfs"
+
+	// TargetAccessKeyKey and TargetSecretKeyKey are the keys of credentials in the secret of S3 target.
+	TargetAccessKeyKey = "accessKey"
+	TargetSecretKeyKey = "secretKey"
+	// TargetCAKey is the key of CA certificate bundle in the CA secret of S3 target.
+	TargetCAKey = "ca.crt"
+
+	CurlRegistry = "docker.io/curlimages"
+	CurlImage    = "curl:8.10.1"
+
+	TargetProbePodNamePrefix = "target-probe-"
+	TargetNFSPodNamePrefix   = "target-nfs-"
+	targetObjectPrefix       = "tvk-preflight-"
+
+	targetProbeContainerName = "target-probe"
+	targetCAVolumeName       = "target-ca"
+	targetCAMountPath        = "/etc/tvk-target-ca"
+	targetNFSVolumeName      = "target-nfs"
+	targetNFSMountPath       = "/target"
+	targetAccessKeyEnv       = "TARGET_ACCESS_KEY"
+	targetSecretKeyEnv       = "TARGET_SECRET_KEY"
+	defaultS3Region          = "us-east-1"
+	nfsPort                  = 2049
+	targetConnectTimeoutSecs = 10
+	targetRequestTimeoutSecs = 60
+	// NFS pod is waited upon for a shorter duration, as a failed mount is retried by kubelet indefinitely
+	targetNFSPodTimeout = 2 * time.Minute
+)
+
+// Steps of the verification of backup target, in order of execution.
+const (
+	targetStepDNS          = "dns"
+	targetStepTCP          = "tcp"
+	targetStepTLS          = "tls"
+	targetStepListBucket   = "list-bucket"
+	targetStepPutObject    = "put-object"
+	targetStepDeleteObject = "delete-object"
+	targetStepNFSMount     = "nfs-mount"
+	targetStepNFSWrite     = "nfs-write"
+)
+
+// TargetOptions is the backup target verified from within the cluster by target check, either S3 or NFS.
+type TargetOptions struct {
+	S3  *S3TargetOptions  `json:"s3,omitempty"`
+	NFS *NFSTargetOptions `json:"nfs,omitempty"`
+}
+
+// S3TargetOptions is an S3 compatible object store. Credentials are read from the secret in the namespace of preflight,
+// having the keys 'accessKey' and 'secretKey'.
+type S3TargetOptions struct {
+	// Endpoint is the URL of object store, e.g https://s3.us-east-1.amazonaws.com or http://minio.minio:9000
+	Endpoint          string `json:"endpoint"`
+	Bucket            string `json:"bucket"`
+	Region            string `json:"region,omitempty"`
+	CredentialsSecret string `json:"credentialsSecret"`
+	// CASecret is the secret having CA certificate bundle with key 'ca.crt', with which the certificate of endpoint is verified.
+	CASecret           string `json:"caSecret,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+}
+
+// NFSTargetOptions is an NFS export.
+type NFSTargetOptions struct {
+	Server string `json:"server"`
+	Path   string `json:"path"`
+}
+
+// TargetResult is the outcome of each step of the verification of backup target.
+type TargetResult struct {
+	Type     string       `json:"type"`
+	Endpoint string       `json:"endpoint"`
+	Steps    []TargetStep `json:"steps"`
+}
+
+// TargetStep is the outcome of a step of the verification of backup target.
+type TargetStep struct {
+	Name   string      `json:"name"`
+	Status CheckStatus `json:"status"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// targetStep is a step of the verification of backup target, performed by executing the command in the probe pod,
+// unless it's performed on the NFS pod.
+type targetStep struct {
+	name           string
+	description    string
+	command        []string
+	recommendation string
+	// skipReason is set if the step isn't applicable to the target
+	skipReason string
+}
+
+// Validate returns error if the backup target is not completely specified.
+func (t *TargetOptions) Validate() error {
+	switch {
+	case t.S3 != nil && t.NFS != nil:
+		return fmt.Errorf("only one of s3 and nfs backup target can be given")
+	case t.S3 != nil:
+		if t.S3.Endpoint == "" || t.S3.Bucket == "" || t.S3.CredentialsSecret == "" {
+			return fmt.Errorf("endpoint, bucket and credentials secret of s3 backup target are required")
+		}
+		if _, _, _, err := parseTargetEndpoint(t.S3.Endpoint); err != nil {
+			return err
+		}
+	case t.NFS != nil:
+		if t.NFS.Server == "" || t.NFS.Path == "" {
+			return fmt.Errorf("server and path of nfs backup target are required")
+		}
+	default:
+		return fmt.Errorf("either s3 or nfs backup target is required")
+	}
+	return nil
+}
+
+func (t *TargetOptions) targetType() string {
+	if t.S3 != nil {
+		return TargetTypeS3
+	}
+	return TargetTypeNFS
+}
+
+func (t *TargetOptions) endpoint() string {
+	if t.S3 != nil {
+		return fmt.Sprintf("%s/%s", strings.TrimRight(t.S3.Endpoint, "/"), t.S3.Bucket)
+	}
+	return fmt.Sprintf("%s:%s", t.NFS.Server, t.NFS.Path)
+}
+
+// secretNames returns the names of the secrets of S3 target, which are read by target check.
+func (t *TargetOptions) secretNames() []string {
+	if t.S3 == nil {
+		return nil
+	}
+	names := []string{t.S3.CredentialsSecret}
+	if t.S3.CASecret != "" {
+		names = append(names, t.S3.CASecret)
+	}
+	return names
+}
+
+// parseTargetEndpoint returns the scheme, host and port of the endpoint URL of S3 target.
+func parseTargetEndpoint(endpoint string) (scheme, host, port string, err error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid endpoint of s3 backup target - %s :: %s", endpoint, err.Error())
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return "", "", "", fmt.Errorf("invalid endpoint of s3 backup target - %s. It must be an http or https URL", endpoint)
+	}
+	port = u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return u.Scheme, u.Hostname(), port, nil
+}
+
+// networkSteps returns the steps verifying that host of the target is resolvable and reachable on the port.
+func networkSteps(host, port string) []targetStep {
+	dns := targetStep{
+		name:        targetStepDNS,
+		description: fmt.Sprintf("Resolving host - %s", host),
+		command:     []string{"nslookup", host},
+		recommendation: "Verify the host of backup target is resolvable from the pods of cluster, " +
+			"e.g. the DNS records of host and the upstream servers of cluster DNS",
+	}
+	if net.ParseIP(host) != nil {
+		dns.skipReason = "host is an IP address"
+	}
+	tcp := targetStep{
+		name:        targetStepTCP,
+		description: fmt.Sprintf("Connecting to %s", net.JoinHostPort(host, port)),
+		command:     []string{"nc", "-z", "-w", strconv.Itoa(targetConnectTimeoutSecs), host, port},
+		recommendation: fmt.Sprintf("Verify the backup target is reachable from the pods of cluster on port %s, "+
+			"e.g. the firewall rules, network policies and egress proxies", port),
+	}
+	return []targetStep{dns, tcp}
+}
+
+// s3TargetSteps returns the steps verifying the S3 target, the object is written and deleted from the bucket.
+func s3TargetSteps(s3 *S3TargetOptions, objectName string) ([]targetStep, error) {
+	scheme, host, port, err := parseTargetEndpoint(s3.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	region := s3.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+
+	curl := fmt.Sprintf("curl -sS -o /dev/null --connect-timeout %d --max-time %d", targetConnectTimeoutSecs, targetRequestTimeoutSecs)
+	switch {
+	case s3.InsecureSkipVerify:
+		curl += " -k"
+	case s3.CASecret != "":
+		curl += fmt.Sprintf(" --cacert %s/%s", targetCAMountPath, TargetCAKey)
+	}
+	// credentials are expanded from the environment of probe pod, so that they aren't part of the command
+	s3Curl := fmt.Sprintf("%s -f --aws-sigv4 'aws:amz:%s:s3' --user \"${%s}:${%s}\" -H 'x-amz-content-sha256: UNSIGNED-PAYLOAD'",
+		curl, region, targetAccessKeyEnv, targetSecretKeyEnv)
+	bucketURL := fmt.Sprintf("%s/%s", strings.TrimRight(s3.Endpoint, "/"), s3.Bucket)
+	objectURL := fmt.Sprintf("%s/%s", bucketURL, objectName)
+	credentialsRecommendation := fmt.Sprintf("Verify the bucket - %s exists in region - %s, and the credentials in secret - %s",
+		s3.Bucket, region, s3.CredentialsSecret)
+
+	tls := targetStep{
+		name:        targetStepTLS,
+		description: fmt.Sprintf("Verifying TLS certificate of %s", net.JoinHostPort(host, port)),
+		command:     []string{"/bin/sh", "-c", fmt.Sprintf("%s 'https://%s/'", curl, net.JoinHostPort(host, port))},
+		recommendation: "Verify the certificate of backup target is valid for its host, and is signed by a CA trusted by the " +
+			"probe pod or given using 'caSecret' of s3 target",
+	}
+	switch {
+	case scheme != "https":
+		tls.skipReason = "endpoint doesn't use TLS"
+	case s3.InsecureSkipVerify:
+		tls.skipReason = "certificate verification is disabled using insecureSkipVerify"
+	}
+
+	steps := append(networkSteps(host, port), tls,
+		targetStep{
+			name:           targetStepListBucket,
+			description:    fmt.Sprintf("Listing objects of bucket - %s", s3.Bucket),
+			command:        []string{"/bin/sh", "-c", fmt.Sprintf("%s '%s?list-type=2&max-keys=1'", s3Curl, bucketURL)},
+			recommendation: credentialsRecommendation + " are valid and allowed to list the bucket",
+		},
+		targetStep{
+			name:        targetStepPutObject,
+			description: fmt.Sprintf("Writing object - %s to bucket - %s", objectName, s3.Bucket),
+			command: []string{"/bin/sh", "-c", fmt.Sprintf("%s -X PUT --data-binary '%s' '%s'",
+				s3Curl, VolSnapPodFileData, objectURL)},
+			recommendation: credentialsRecommendation + " are allowed to write objects, and the bucket isn't read-only",
+		},
+		targetStep{
+			name:           targetStepDeleteObject,
+			description:    fmt.Sprintf("Deleting object - %s from bucket - %s", objectName, s3.Bucket),
+			command:        []string{"/bin/sh", "-c", fmt.Sprintf("%s -X DELETE '%s'", s3Curl, objectURL)},
+			recommendation: credentialsRecommendation + " are allowed to delete objects, and the bucket has no retention lock",
+		})
+
+	return steps, nil
+}
+
+// nfsTargetSteps returns the steps verifying the NFS target. The export is mounted by the NFS pod, on which
+// the file is written and deleted.
+func nfsTargetSteps(nfs *NFSTargetOptions, fileName string) []targetStep {
+	filePath := fmt.Sprintf("%s/%s", targetNFSMountPath, fileName)
+	return append(networkSteps(nfs.Server, strconv.Itoa(nfsPort)),
+		targetStep{
+			name:        targetStepNFSMount,
+			description: fmt.Sprintf("Mounting NFS export - %s:%s", nfs.Server, nfs.Path),
+			recommendation: fmt.Sprintf("Verify the NFS export - %s:%s exists and allows the nodes of cluster to mount it, "+
+				"and NFS client utilities are installed on the nodes", nfs.Server, nfs.Path),
+		},
+		targetStep{
+			name:        targetStepNFSWrite,
+			description: fmt.Sprintf("Writing file - %s to NFS export", fileName),
+			command: []string{"/bin/sh", "-c",
+				fmt.Sprintf("echo '%s' > %s && sync && rm -f %s", VolSnapPodFileData, filePath, filePath)},
+			recommendation: "Verify the NFS export is writable by the pods, e.g. the permissions of exported directory and root squash",
+		})
+}
+
+// runTargetSteps performs the steps in order until one fails, recording their outcome on the target result.
+// Steps following a failed step are skipped.
+func (o *Run) runTargetSteps(ctx context.Context, steps []targetStep, result *TargetResult, res *CheckResult,
+	perform func(ctx context.Context, step targetStep) error) error {
+	var failed error
+	for _, step := range steps {
+		recorded := TargetStep{Name: step.name, Status: CheckStatusSkipped}
+		switch {
+		case failed != nil:
+			recorded.Error = "previous step failed"
+		case step.skipReason != "":
+			o.Logger.Infof("Skipping step - %s :: %s", step.name, step.skipReason)
+		default:
+			o.Logger.Infoln(step.description)
+			if err := perform(ctx, step); err != nil {
+				recorded.Status, recorded.Error = CheckStatusFail, err.Error()
+				failed = fmt.Errorf("step %s of backup target verification failed :: %s :: %w", step.name, step.description, err)
+				res.Recommend(step.recommendation)
+				o.Logger.Errorf("%s %s failed :: %s", cross, step.description, err.Error())
+				break
+			}
+			recorded.Status = CheckStatusPass
+			o.Logger.Infof("%s %s succeeded", check, step.description)
+		}
+		result.Steps = append(result.Steps, recorded)
+	}
+
+	return failed
+}
+
+func runTargetCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	if o.Target == nil {
+		res.Recommend("Give S3 or NFS backup target using 'target' in run section of config file, or --target-* flags")
+		return fmt.Errorf("backup target is not given")
+	}
+	if err := o.Target.Validate(); err != nil {
+		return err
+	}
+	o.Logger.Infof("Checking connectivity to %s backup target - %s from within the cluster",
+		o.Target.targetType(), o.Target.endpoint())
+
+	return o.validateTarget(ctx, resNameSuffix, kubeClient, res)
+}
+
+// validateTarget verifies the backup target from the probe pod, and the NFS pod for NFS target.
+func (o *Run) validateTarget(ctx context.Context, uid string, clients ServerClients, res *CheckResult) error {
+	if o.targetResult == nil {
+		o.targetResult = &TargetResult{}
+	}
+	o.targetResult.Type, o.targetResult.Endpoint = o.Target.targetType(), o.Target.endpoint()
+	o.targetResult.Steps = nil
+
+	objectName := targetObjectPrefix + uid
+	var steps []targetStep
+	if o.Target.S3 != nil {
+		if err := o.validateTargetSecrets(ctx, clients); err != nil {
+			res.Recommend(fmt.Sprintf("Create the secret in namespace - %s having keys - %s, %s", o.Namespace,
+				TargetAccessKeyKey, TargetSecretKeyKey))
+			return err
+		}
+		var err error
+		if steps, err = s3TargetSteps(o.Target.S3, objectName); err != nil {
+			return err
+		}
+	} else {
+		steps = nfsTargetSteps(o.Target.NFS, objectName)
+	}
+
+	probePod, err := o.createPod(ctx, createTargetProbePodSpec(o, uid), clients.ClientSet)
+	if err != nil {
+		return err
+	}
+
+	var nfsPod *corev1.Pod
+	return o.runTargetSteps(ctx, steps, o.targetResult, res, func(ctx context.Context, step targetStep) error {
+		switch step.name {
+		case targetStepNFSMount:
+			var mErr error
+			nfsPod, mErr = o.createTargetNFSPod(ctx, uid, clients)
+			return mErr
+		case targetStepNFSWrite:
+			return o.execTargetStep(ctx, nfsPod, step, clients)
+		}
+		return o.execTargetStep(ctx, probePod, step, clients)
+	})
+}
+
+// validateTargetSecrets verifies that the credentials and CA secrets of S3 target exist and have the expected keys,
+// so that the probe pod doesn't fail to start.
+func (o *Run) validateTargetSecrets(ctx context.Context, clients ServerClients) error {
+	secrets := map[string][]string{o.Target.S3.CredentialsSecret: {TargetAccessKeyKey, TargetSecretKeyKey}}
+	if o.Target.S3.CASecret != "" {
+		secrets[o.Target.S3.CASecret] = append(secrets[o.Target.S3.CASecret], TargetCAKey)
+	}
+	for name, keys := range secrets {
+		secret, err := clients.ClientSet.CoreV1().Secrets(o.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				return fmt.Errorf("secret - %s of backup target not found in namespace - %s", name, o.Namespace)
+			}
+			return err
+		}
+		for _, key := range keys {
+			if _, ok := secret.Data[key]; !ok {
+				return fmt.Errorf("secret - %s of backup target doesn't have key - %s", name, key)
+			}
+		}
+	}
+	return nil
+}
+
+// execTargetStep executes the command of step in the pod, the error includes the standard error of command.
+func (o *Run) execTargetStep(ctx context.Context, pod *corev1.Pod, step targetStep, clients ServerClients) error {
+	execOp := o.dataExecOptions(ctx, pod, step.command, clients)
+	execRes, err := execInPodWithResponse(ctx, &execOp, o.Logger)
+	if err != nil && execRes != nil && strings.TrimSpace(execRes.Stderr) != "" {
+		return fmt.Errorf("%s :: %w", strings.TrimSpace(execRes.Stderr), err)
+	}
+	return err
+}
+
+// createTargetNFSPod creates a pod mounting the NFS export, and waits until it's ready. The latest mount failure
+// is returned if the pod doesn't become ready.
+func (o *Run) createTargetNFSPod(ctx context.Context, uid string, clients ServerClients) (*corev1.Pod, error) {
+	pod := createTargetNFSPodSpec(o, uid)
+	podNsName := types.NamespacedName{Namespace: pod.GetNamespace(), Name: pod.GetName()}
+	pod, err := clients.ClientSet.CoreV1().Pods(pod.GetNamespace()).Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	o.recordCreatedResource(pod)
+	o.Logger.Infof("Created pod - %s mounting NFS export", podNsName.String())
+
+	waitOptions := &wait.PodWaitOptions{
+		Name:         pod.GetName(),
+		Namespace:    pod.GetNamespace(),
+		PodCondition: corev1.PodReady,
+		ClientSet:    clients.ClientSet,
+		Timeout:      targetNFSPodTimeout,
+		Logger:       o.Logger,
+	}
+	if err = waitUntilPodCondition(ctx, waitOptions); err != nil {
+		if msg := wait.LatestWarningEvent(ctx, clients.ClientSet, internal.PodKind, pod, "FailedMount"); msg != "" {
+			return nil, fmt.Errorf("%s :: %w", msg, err)
+		}
+		return nil, err
+	}
+
+	return pod, nil
+}
+
+// createTargetProbePodSpec returns the pod from which the network and S3 steps of target verification are performed.
+// Credentials of S3 target are exposed to it as environment variables, and the CA bundle is mounted.
+func createTargetProbePodSpec(op *Run, uid string) *corev1.Pod {
+	image := op.preflightImage(PreflightImageCurl)
+	pod := getPodTemplate(types.NamespacedName{Name: TargetProbePodNamePrefix + uid, Namespace: op.Namespace}, uid, op)
+	container := corev1.Container{
+		Name:            targetProbeContainerName,
+		Image:           image,
+		Command:         CommandSleep3600,
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Resources:       op.ResourceRequirements,
+	}
+
+	if s3 := op.Target.S3; s3 != nil {
+		secretEnv := func(name, key string) corev1.EnvVar {
+			return corev1.EnvVar{Name: name, ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: s3.CredentialsSecret},
+				Key:                  key,
+			}}}
+		}
+		container.Env = []corev1.EnvVar{
+			secretEnv(targetAccessKeyEnv, TargetAccessKeyKey),
+			secretEnv(targetSecretKeyEnv, TargetSecretKeyKey),
+		}
+		if s3.CASecret != "" {
+			container.VolumeMounts = []corev1.VolumeMount{{Name: targetCAVolumeName, MountPath: targetCAMountPath, ReadOnly: true}}
+			pod.Spec.Volumes = []corev1.Volume{{
+				Name:         targetCAVolumeName,
+				VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: s3.CASecret}},
+			}}
+		}
+	}
+	pod.Spec.Containers = []corev1.Container{container}
+
+	return pod
+}
+
+// createTargetNFSPodSpec returns the pod mounting the NFS export of target.
+func createTargetNFSPodSpec(op *Run, uid string) *corev1.Pod {
+	image := op.preflightImage(PreflightImageBusybox)
+	pod := getPodTemplate(types.NamespacedName{Name: TargetNFSPodNamePrefix + uid, Namespace: op.Namespace}, uid, op)
+	pod.Spec.Containers = []corev1.Container{{
+		Name:         BusyboxContainerName,
+		Image:        image,
+		Command:      CommandSleep3600,
+		Resources:    op.ResourceRequirements,
+		VolumeMounts: []corev1.VolumeMount{{Name: targetNFSVolumeName, MountPath: targetNFSMountPath}},
+	}}
+	pod.Spec.Volumes = []corev1.Volume{{
+		Name: targetNFSVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			NFS: &corev1.NFSVolumeSource{Server: op.Target.NFS.Server, Path: op.Target.NFS.Path},
+		},
+	}}
+
+	return pod
+}
@@ -0,0 +1,365 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/trilioData/tvk-plugins/internal"
+)
+
+const (
+	TopologyPvcNamePrefix         = "topology-source-pvc-"
+	TopologyRestoredPvcNamePrefix = "topology-restored-pvc-"
+	TopologyVolumeSnapNamePrefix  = "snapshot-topology-source-pvc-"
+
+	// noZone is the zone of nodes which don't have a zone label.
+	noZone = ""
+)
+
+// TopologyResult is the outcome of restores of a volume snapshot of a storage class across nodes and zones.
+type TopologyResult struct {
+	StorageClass      string `json:"storageClass"`
+	VolumeBindingMode string `json:"volumeBindingMode,omitempty"`
+	// AllowedZones are the zones the storage class is restricted to using allowedTopologies, if any.
+	AllowedZones []string          `json:"allowedZones,omitempty"`
+	Restores     []TopologyRestore `json:"restores,omitempty"`
+}
+
+// TopologyRestore is the outcome of restoring a volume snapshot taken on the source node to a pod on the target node.
+type TopologyRestore struct {
+	SourceNode string      `json:"sourceNode"`
+	SourceZone string      `json:"sourceZone,omitempty"`
+	TargetNode string      `json:"targetNode"`
+	TargetZone string      `json:"targetZone,omitempty"`
+	CrossZone  bool        `json:"crossZone"`
+	Status     CheckStatus `json:"status"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// topologyPlacement is a node on which a pod of topology check is pinned.
+type topologyPlacement struct {
+	node string
+	zone string
+}
+
+// nodeZone returns the zone of node from its well-known topology labels.
+func nodeZone(node *corev1.Node) string {
+	if zone, ok := node.Labels[corev1.LabelTopologyZone]; ok {
+		return zone
+	}
+	return node.Labels[corev1.LabelFailureDomainBetaZone]
+}
+
+// isNodeSchedulable returns whether the node is ready and accepts new pods.
+func isNodeSchedulable(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodesByZone groups the nodes on which preflight pods can be scheduled by their zone, restricted to the allowed zones
+// if given. Nodes of each zone are sorted by name.
+func nodesByZone(nodes []corev1.Node, ops podSchedulingOptions, allowedZones sets.String) map[string][]string {
+	zones := make(map[string][]string)
+	for idx := range nodes {
+		node := &nodes[idx]
+		if schedulingMismatch(node, ops) != "" {
+			continue
+		}
+		zone := nodeZone(node)
+		if allowedZones.Len() != 0 && !allowedZones.Has(zone) {
+			continue
+		}
+		zones[zone] = append(zones[zone], node.GetName())
+	}
+	for zone := range zones {
+		sort.Strings(zones[zone])
+	}
+
+	return zones
+}
+
+// allowedZonesOf returns the zones to which the storage class restricts its volumes using allowedTopologies.
+func allowedZonesOf(sc *storagev1.StorageClass) sets.String {
+	zones := sets.NewString()
+	for _, term := range sc.AllowedTopologies {
+		for _, expr := range term.MatchLabelExpressions {
+			if expr.Key == corev1.LabelTopologyZone || expr.Key == corev1.LabelFailureDomainBetaZone {
+				zones.Insert(expr.Values...)
+			}
+		}
+	}
+	return zones
+}
+
+// volumeZones returns the zones to which the persistent volume is restricted using its node affinity.
+func volumeZones(pv *corev1.PersistentVolume) sets.String {
+	zones := sets.NewString()
+	if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+		return zones
+	}
+	for _, term := range pv.Spec.NodeAffinity.Required.NodeSelectorTerms {
+		for _, expr := range term.MatchExpressions {
+			if (expr.Key == corev1.LabelTopologyZone || expr.Key == corev1.LabelFailureDomainBetaZone) &&
+				expr.Operator == corev1.NodeSelectorOpIn {
+				zones.Insert(expr.Values...)
+			}
+		}
+	}
+	return zones
+}
+
+// volumeSourceZone returns the zone of the nodes on which the bound volume can be attached, preferring a zone having
+// nodes for preflight pods. It returns false if the volume isn't restricted to zones.
+func volumeSourceZone(pv *corev1.PersistentVolume, zones map[string][]string) (string, bool) {
+	pvZones := volumeZones(pv).List()
+	if len(pvZones) == 0 {
+		return "", false
+	}
+	for _, zone := range pvZones {
+		if _, ok := zones[zone]; ok {
+			return zone, true
+		}
+	}
+	return pvZones[0], true
+}
+
+// largestZone returns the zone having most nodes, so that a restore on a different node of the same zone is verified.
+func largestZone(zones map[string][]string) string {
+	largest, found := noZone, false
+	for zone, nodes := range zones {
+		if !found || len(nodes) > len(zones[largest]) || (len(nodes) == len(zones[largest]) && zone < largest) {
+			largest, found = zone, true
+		}
+	}
+	return largest
+}
+
+// topologyPlacements returns the node of the writer pod in the source zone, and the nodes of reader pods - a different
+// node in the source zone, and a node in each of the other zones.
+func topologyPlacements(zones map[string][]string, sourceZone string) (source topologyPlacement, targets []topologyPlacement,
+	err error) {
+	zoneNames := make([]string, 0, len(zones))
+	for zone := range zones {
+		zoneNames = append(zoneNames, zone)
+	}
+	sort.Strings(zoneNames)
+	if len(zoneNames) == 0 {
+		return source, nil, fmt.Errorf("no node found on which preflight pods can be scheduled")
+	}
+	if _, ok := zones[sourceZone]; !ok {
+		return source, nil, fmt.Errorf("no node found in zone %s of the volume on which preflight pods can be scheduled",
+			sourceZone)
+	}
+
+	source = topologyPlacement{node: zones[sourceZone][0], zone: sourceZone}
+	if len(zones[sourceZone]) > 1 {
+		targets = append(targets, topologyPlacement{node: zones[sourceZone][1], zone: sourceZone})
+	}
+	for _, zone := range zoneNames {
+		if zone != sourceZone {
+			targets = append(targets, topologyPlacement{node: zones[zone][0], zone: zone})
+		}
+	}
+	if len(targets) == 0 {
+		return source, nil, fmt.Errorf("only a single schedulable node - %s found, restore across nodes can't be verified",
+			source.node)
+	}
+
+	return source, targets, nil
+}
+
+// pinToNode returns the pod scheduled on the node, in addition to the node selector of preflight pods.
+func pinToNode(pod *corev1.Pod, node string) *corev1.Pod {
+	nodeSelector := make(map[string]string, len(pod.Spec.NodeSelector)+1)
+	for key, value := range pod.Spec.NodeSelector {
+		nodeSelector[key] = value
+	}
+	nodeSelector[corev1.LabelHostname] = node
+	pod.Spec.NodeSelector = nodeSelector
+	return pod
+}
+
+func (p topologyPlacement) String() string {
+	if p.zone == noZone {
+		return fmt.Sprintf("node %s", p.node)
+	}
+	return fmt.Sprintf("node %s of zone %s", p.node, p.zone)
+}
+
+func runTopologyCheck(ctx context.Context, o *Run, res *CheckResult) error {
+	o.Logger.Infoln("Checking restore of volume snapshots across nodes and zones")
+
+	err := o.forEachStorageClass(func(r *Run) error {
+		if r.scResult != nil && r.scResult.SnapshotClassFound == CheckStatusFail {
+			return fmt.Errorf("volume snapshot class not found for storage class - %s", r.StorageClass)
+		}
+		topology, tErr := r.validateTopologyRestore(ctx, resNameSuffix, kubeClient)
+		if r.scResult != nil {
+			r.scResult.Topology = topology
+		}
+		return tErr
+	})
+
+	// restores failing only across zones are warnings, as volumes of zonal storage can't be restored in other zones
+	for _, scRes := range o.storageMatrix().results {
+		if scRes.Topology == nil {
+			continue
+		}
+		for _, restore := range scRes.Topology.Restores {
+			if restore.CrossZone && restore.Status == CheckStatusFail {
+				res.Warn(fmt.Sprintf("Volume snapshot of storage class - %s taken in zone %s can't be restored in zone %s",
+					scRes.StorageClass, restore.SourceZone, restore.TargetZone))
+			}
+		}
+	}
+	if len(res.Warnings) != 0 {
+		res.Recommend("Restore backups of zonal storage classes in the zone of the backed up volume, or use a storage " +
+			"class with 'volumeBindingMode: WaitForFirstConsumer' whose snapshots are regional")
+	}
+	if err != nil {
+		res.Recommend("Verify the storage class can provision volumes on all the nodes selected for preflight pods, " +
+			"and its allowedTopologies include their zones")
+	}
+
+	return err
+}
+
+// validateTopologyRestore writes data to a pvc attached to a pod pinned to a node, and restores its volume snapshot
+// to pods pinned to a different node of the same zone and to a node of each other zone. It returns error if the
+// restore on the same zone fails, failures across zones are recorded in the result.
+func (o *Run) validateTopologyRestore(ctx context.Context, uid string, clients ServerClients) (*TopologyResult, error) {
+	var (
+		nameSuffix = o.resourceNameSuffix(uid)
+		result     = &TopologyResult{StorageClass: o.StorageClass}
+	)
+
+	sc, err := clients.ClientSet.StorageV1().StorageClasses().Get(ctx, o.StorageClass, metav1.GetOptions{})
+	if err != nil {
+		return result, err
+	}
+	if sc.VolumeBindingMode != nil {
+		result.VolumeBindingMode = string(*sc.VolumeBindingMode)
+	}
+	allowedZones := allowedZonesOf(sc)
+	result.AllowedZones = allowedZones.List()
+
+	nodes, err := clients.ClientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return result, err
+	}
+	zones := nodesByZone(nodes.Items, o.PodSchedOps, allowedZones)
+	source, targets, err := topologyPlacements(zones, largestZone(zones))
+	if err != nil {
+		return result, err
+	}
+
+	prefSnapshotVer, err := GetServerPreferredVersionForGroup(StorageSnapshotGroup, clients.ClientSet)
+	if err != nil {
+		return result, err
+	}
+	sourcePvcNsName := types.NamespacedName{Namespace: o.Namespace, Name: TopologyPvcNamePrefix + nameSuffix}
+	pvc, err := o.createPVC(ctx, sourcePvcNsName, uid, clients.ClientSet)
+	if err != nil {
+		return result, err
+	}
+	// volume of a storage class binding volumes immediately is provisioned in a zone chosen by the provisioner, so the
+	// writer pod is placed in the zone of the bound volume, and restores are verified relative to it
+	if sc.VolumeBindingMode == nil || *sc.VolumeBindingMode == storagev1.VolumeBindingImmediate {
+		pv, pvErr := boundVolume(ctx, sourcePvcNsName, clients)
+		if pvErr != nil {
+			return result, pvErr
+		}
+		if zone, ok := volumeSourceZone(pv, zones); ok {
+			if source, targets, err = topologyPlacements(zones, zone); err != nil {
+				return result, err
+			}
+		}
+	}
+	o.Logger.Infof("Writing data on %s, and restoring it on %d nodes", source.String(), len(targets))
+	writerPodName := fmt.Sprintf("%s%s-%s", TopologyPvcNamePrefix, "writer", nameSuffix)
+	writerPod, err := o.createPod(ctx, pinToNode(createPVCDataWriterPodSpec(writerPodName, sourcePvcNsName, o, uid), source.node),
+		clients.ClientSet)
+	if err != nil {
+		return result, fmt.Errorf("error writing data on %s :: %w", source.String(), err)
+	}
+	sourceChecksums, err := o.recordSourceChecksums(ctx, writerPod, clients)
+	if err != nil {
+		return result, err
+	}
+
+	snapshotNameNs := types.NamespacedName{Namespace: o.Namespace, Name: TopologyVolumeSnapNamePrefix + nameSuffix}
+	if err = o.createSnapshotFromPVC(ctx, snapshotNameNs, o.snapshotClass(), prefSnapshotVer, pvc.GetName(), uid, clients); err != nil {
+		return result, err
+	}
+
+	var failed []string
+	for idx, target := range targets {
+		restore := TopologyRestore{SourceNode: source.node, SourceZone: source.zone, TargetNode: target.node,
+			TargetZone: target.zone, CrossZone: target.zone != source.zone, Status: CheckStatusPass}
+		if rErr := o.restoreOnNode(ctx, idx, target, pvc, snapshotNameNs.Name, sourceChecksums, uid, clients); rErr != nil {
+			restore.Status, restore.Error = CheckStatusFail, rErr.Error()
+			o.Logger.Errorf("%s Restore on %s failed :: %s", cross, target.String(), rErr.Error())
+			if !restore.CrossZone {
+				failed = append(failed, fmt.Sprintf("%s :: %s", target.String(), rErr.Error()))
+			}
+		} else {
+			o.Logger.Infof("%s Volume snapshot taken on %s is restored on %s", check, source.String(), target.String())
+		}
+		result.Restores = append(result.Restores, restore)
+	}
+	if len(failed) != 0 {
+		return result, fmt.Errorf("volume snapshot taken on %s can't be restored on - [%s]", source.String(), strings.Join(failed, "; "))
+	}
+
+	return result, nil
+}
+
+// boundVolume returns the persistent volume bound to the pvc.
+func boundVolume(ctx context.Context, pvcNsName types.NamespacedName, clients ServerClients) (*corev1.PersistentVolume, error) {
+	pvc, err := clients.ClientSet.CoreV1().PersistentVolumeClaims(pvcNsName.Namespace).Get(ctx, pvcNsName.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if pvc.Spec.VolumeName == "" {
+		return nil, fmt.Errorf("pvc - %s isn't bound to a volume", pvcNsName.String())
+	}
+	return clients.ClientSet.CoreV1().PersistentVolumes().Get(ctx, pvc.Spec.VolumeName, metav1.GetOptions{})
+}
+
+// restoreOnNode restores the volume snapshot to a pvc attached to a reader pod pinned to the node, and verifies its data.
+func (o *Run) restoreOnNode(ctx context.Context, index int, target topologyPlacement, sourcePvc *corev1.PersistentVolumeClaim,
+	snapshotName string, sourceChecksums map[string]string, uid string, clients ServerClients) error {
+	nameSuffix := fmt.Sprintf("%d-%s", index, o.resourceNameSuffix(uid))
+	restoredPvcNsName := types.NamespacedName{Namespace: o.Namespace, Name: TopologyRestoredPvcNamePrefix + nameSuffix}
+	restoredPvcMeta := &metav1.ObjectMeta{Name: restoredPvcNsName.Name, Namespace: restoredPvcNsName.Namespace,
+		Labels: sourcePvc.Labels}
+	if _, err := o.createPVCFromSnapshot(ctx, clients.RuntimeClient, restoredPvcMeta, &sourcePvc.Spec, snapshotName); err != nil {
+		return err
+	}
+
+	readerPodName := fmt.Sprintf("%s%s-%s", TopologyRestoredPvcNamePrefix, "reader", nameSuffix)
+	readerPod, err := o.createPod(ctx, pinToNode(createPVCDataReaderPodSpec(readerPodName, restoredPvcNsName, o, uid), target.node),
+		clients.ClientSet)
+	if err != nil {
+		return err
+	}
+	o.Logger.Infof("Restored pvc - %s is attached to pod - %s on %s", restoredPvcNsName.String(),
+		internal.GetNamespacedName(readerPod.GetNamespace(), readerPod.GetName()).String(), target.String())
+
+	return o.verifyRestoredData(ctx, readerPod, sourceChecksums, clients)
+}
@@ -0,0 +1,174 @@
+package preflight
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func topologyNode(name string, nodeLabels map[string]string, ready bool) corev1.Node {
+	status := corev1.ConditionTrue
+	if !ready {
+		status = corev1.ConditionFalse
+	}
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nodeLabels},
+		Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}}},
+	}
+}
+
+var _ = Describe("Topology unit tests", func() {
+
+	Context("nodesByZone func test-cases", func() {
+
+		var nodes []corev1.Node
+
+		BeforeEach(func() {
+			cordoned := topologyNode("node-a3", map[string]string{corev1.LabelTopologyZone: "zone-a"}, true)
+			cordoned.Spec.Unschedulable = true
+			nodes = []corev1.Node{
+				topologyNode("node-a2", map[string]string{corev1.LabelTopologyZone: "zone-a", "pool": "tvk"}, true),
+				topologyNode("node-a1", map[string]string{corev1.LabelTopologyZone: "zone-a", "pool": "tvk"}, true),
+				topologyNode("node-b1", map[string]string{corev1.LabelFailureDomainBetaZone: "zone-b", "pool": "tvk"}, true),
+				topologyNode("node-c1", map[string]string{corev1.LabelTopologyZone: "zone-c", "pool": "tvk"}, false),
+				topologyNode("node-d1", map[string]string{corev1.LabelTopologyZone: "zone-d"}, true),
+				cordoned,
+			}
+		})
+
+		It("Should group schedulable nodes by zone, sorted by name", func() {
+			Expect(nodesByZone(nodes, podSchedulingOptions{}, nil)).To(Equal(map[string][]string{
+				"zone-a": {"node-a1", "node-a2"},
+				"zone-b": {"node-b1"},
+				"zone-d": {"node-d1"},
+			}))
+		})
+
+		It("Should skip nodes not matching the node selector or not in the allowed zones", func() {
+			Expect(nodesByZone(nodes, podSchedulingOptions{NodeSelector: map[string]string{"pool": "tvk"}},
+				sets.NewString("zone-b"))).To(Equal(map[string][]string{"zone-b": {"node-b1"}}))
+		})
+
+		It("Should skip nodes not matching the required node affinity or having taints not tolerated", func() {
+			nodes[0].Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "db", Effect: corev1.TaintEffectNoSchedule}}
+			ops := podSchedulingOptions{Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpIn,
+						Values: []string{"tvk"}}},
+				}}},
+			}}}
+			Expect(nodesByZone(nodes, ops, nil)).To(Equal(map[string][]string{
+				"zone-a": {"node-a1"},
+				"zone-b": {"node-b1"},
+			}))
+
+			ops.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
+			Expect(nodesByZone(nodes, ops, nil)).To(HaveKeyWithValue("zone-a", []string{"node-a1", "node-a2"}))
+		})
+	})
+
+	Context("allowedZonesOf func test-cases", func() {
+
+		It("Should return zones of allowed topologies of storage class", func() {
+			sc := &storagev1.StorageClass{AllowedTopologies: []corev1.TopologySelectorTerm{{
+				MatchLabelExpressions: []corev1.TopologySelectorLabelRequirement{
+					{Key: corev1.LabelTopologyZone, Values: []string{"zone-a", "zone-b"}},
+					{Key: corev1.LabelHostname, Values: []string{"node-a1"}},
+				},
+			}}}
+			Expect(allowedZonesOf(sc).List()).To(Equal([]string{"zone-a", "zone-b"}))
+			Expect(allowedZonesOf(&storagev1.StorageClass{}).Len()).To(BeZero())
+		})
+	})
+
+	Context("volumeSourceZone func test-cases", func() {
+
+		zones := map[string][]string{"zone-a": {"node-a1"}, "zone-b": {"node-b1"}}
+		pvInZones := func(key string, values ...string) *corev1.PersistentVolume {
+			return &corev1.PersistentVolume{Spec: corev1.PersistentVolumeSpec{NodeAffinity: &corev1.VolumeNodeAffinity{
+				Required: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchExpressions: []corev1.NodeSelectorRequirement{{Key: key, Operator: corev1.NodeSelectorOpIn, Values: values}},
+				}}},
+			}}}
+		}
+
+		It("Should return the zone of the volume having nodes for preflight pods", func() {
+			zone, ok := volumeSourceZone(pvInZones(corev1.LabelTopologyZone, "zone-x", "zone-b"), zones)
+			Expect(ok).To(BeTrue())
+			Expect(zone).To(Equal("zone-b"))
+
+			zone, ok = volumeSourceZone(pvInZones(corev1.LabelFailureDomainBetaZone, "zone-x"), zones)
+			Expect(ok).To(BeTrue())
+			Expect(zone).To(Equal("zone-x"))
+		})
+
+		It("Should return false for volume not restricted to zones", func() {
+			_, ok := volumeSourceZone(&corev1.PersistentVolume{}, zones)
+			Expect(ok).To(BeFalse())
+			_, ok = volumeSourceZone(pvInZones(corev1.LabelHostname, "node-a1"), zones)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("topologyPlacements func test-cases", func() {
+
+		It("Should restore on another node of the zone having most nodes and in each of the other zones", func() {
+			zones := map[string][]string{
+				"zone-a": {"node-a1"},
+				"zone-b": {"node-b1", "node-b2"},
+				"zone-c": {"node-c1"},
+			}
+			source, targets, err := topologyPlacements(zones, largestZone(zones))
+			Expect(err).To(BeNil())
+			Expect(source).To(Equal(topologyPlacement{node: "node-b1", zone: "zone-b"}))
+			Expect(targets).To(Equal([]topologyPlacement{
+				{node: "node-b2", zone: "zone-b"},
+				{node: "node-a1", zone: "zone-a"},
+				{node: "node-c1", zone: "zone-c"},
+			}))
+		})
+
+		It("Should restore on another node of the zone of the volume and in each of the other zones", func() {
+			source, targets, err := topologyPlacements(map[string][]string{
+				"zone-a": {"node-a1"},
+				"zone-b": {"node-b1", "node-b2"},
+			}, "zone-a")
+			Expect(err).To(BeNil())
+			Expect(source).To(Equal(topologyPlacement{node: "node-a1", zone: "zone-a"}))
+			Expect(targets).To(Equal([]topologyPlacement{{node: "node-b1", zone: "zone-b"}}))
+
+			_, _, err = topologyPlacements(map[string][]string{"zone-a": {"node-a1"}}, "zone-x")
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("no node found in zone zone-x"))
+		})
+
+		It("Should restore on another node for cluster without zones", func() {
+			source, targets, err := topologyPlacements(map[string][]string{noZone: {"node-1", "node-2", "node-3"}}, noZone)
+			Expect(err).To(BeNil())
+			Expect(source.String()).To(Equal("node node-1"))
+			Expect(targets).To(Equal([]topologyPlacement{{node: "node-2"}}))
+		})
+
+		It("Should return error when restore across nodes can't be verified", func() {
+			_, _, err := topologyPlacements(map[string][]string{"zone-a": {"node-a1"}}, "zone-a")
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("only a single schedulable node - node-a1 found"))
+
+			_, _, err = topologyPlacements(map[string][]string{}, largestZone(map[string][]string{}))
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("pinToNode func test-cases", func() {
+
+		It("Should schedule pod on the node keeping the node selector, without changing the shared selector", func() {
+			nodeSelector := map[string]string{"pool": "tvk"}
+			pod := pinToNode(&corev1.Pod{Spec: corev1.PodSpec{NodeSelector: nodeSelector}}, "node-a1")
+			Expect(pod.Spec.NodeSelector).To(Equal(map[string]string{"pool": "tvk", corev1.LabelHostname: "node-a1"}))
+			Expect(nodeSelector).To(HaveLen(1))
+		})
+	})
+})
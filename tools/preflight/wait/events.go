@@ -0,0 +1,65 @@
+package wait
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	client "k8s.io/client-go/kubernetes"
+)
+
+// LatestWarningEvent returns message of the latest warning event of the object, having the given reason if not empty.
+// Events are best-effort, an empty message is returned if they can't be listed.
+func LatestWarningEvent(ctx context.Context, clientSet *client.Clientset, kind string, obj metav1.Object, reason string) string {
+	selectorSet := fields.Set{
+		"involvedObject.kind": kind,
+		"involvedObject.name": obj.GetName(),
+		"involvedObject.uid":  string(obj.GetUID()),
+		"type":                corev1.EventTypeWarning,
+	}
+	if reason != "" {
+		selectorSet["reason"] = reason
+	}
+	events, err := clientSet.CoreV1().Events(obj.GetNamespace()).List(ctx,
+		metav1.ListOptions{FieldSelector: selectorSet.AsSelector().String()})
+	if err != nil {
+		return ""
+	}
+
+	return LatestEventMessage(events.Items)
+}
+
+// LatestEventMessage returns message of the most recently observed event. Messages which only name the error,
+// e.g. 'Error: ErrImagePull', are ignored in favour of the ones describing it.
+func LatestEventMessage(events []corev1.Event) string {
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(&events[i]).After(eventTime(&events[j]))
+	})
+	for i := range events {
+		msg := strings.TrimSpace(events[i].Message)
+		if errName, ok := strings.CutPrefix(msg, "Error: "); ok && !strings.Contains(errName, " ") {
+			continue
+		}
+		if msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+func eventTime(event *corev1.Event) time.Time {
+	switch {
+	case event.Series != nil:
+		return event.Series.LastObservedTime.Time
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
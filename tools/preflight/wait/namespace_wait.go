@@ -0,0 +1,71 @@
+package wait
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	client "k8s.io/client-go/kubernetes"
+)
+
+// NamespaceWaitOptions waits until the namespace is deleted or times-out.
+type NamespaceWaitOptions struct {
+	Name      string
+	ClientSet *client.Clientset
+	// Timeout of the wait, DefaultTimeout is used if not set.
+	Timeout time.Duration
+	// Logger, if set, logs the reasons due to which deletion of the namespace is pending.
+	Logger *logrus.Logger
+}
+
+// WaitUntilDeleted watches the namespace until it's deleted, the timeout elapses or the context is done.
+// If the wait times out, the reason due to which deletion is pending, e.g. the remaining resources
+// or finalizers, is included in the error.
+func (o *NamespaceWaitOptions) WaitUntilDeleted(ctx context.Context) error {
+	var pendingReason string
+	lw := namedListWatch(o.Name,
+		func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return o.ClientSet.CoreV1().Namespaces().List(ctx, options)
+		},
+		func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			return o.ClientSet.CoreV1().Namespaces().Watch(ctx, options)
+		})
+	err := untilCondition(ctx, o.Timeout, lw, &corev1.Namespace{}, func(_ context.Context, obj runtime.Object) (bool, error) {
+		ns, ok := obj.(*corev1.Namespace)
+		if !ok {
+			return true, nil
+		}
+		if reason := namespaceDeletionPendingReason(ns); reason != pendingReason {
+			pendingReason = reason
+			if o.Logger != nil && reason != "" {
+				o.Logger.Infof("Deletion of namespace - %s is pending :: %s", o.Name, reason)
+			}
+		}
+		return false, nil
+	})
+
+	if wait.Interrupted(err) && ctx.Err() == nil && pendingReason != "" {
+		return fmt.Errorf("namespace - %s is not deleted :: %s :: %w", o.Name, pendingReason, err)
+	}
+	return err
+}
+
+// namespaceDeletionPendingReason returns the message of the first true deletion condition of the namespace.
+func namespaceDeletionPendingReason(ns *corev1.Namespace) string {
+	for i := range ns.Status.Conditions {
+		cond := ns.Status.Conditions[i]
+		if cond.Status == corev1.ConditionTrue && cond.Message != "" {
+			return string(cond.Type) + " - " + cond.Message
+		}
+	}
+	if ns.Status.Phase == corev1.NamespaceTerminating {
+		return string(corev1.NamespaceTerminating)
+	}
+	return ""
+}
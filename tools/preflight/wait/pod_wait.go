@@ -2,51 +2,216 @@ package wait
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	client "k8s.io/client-go/kubernetes"
 )
 
+const (
+	// DefaultUnschedulableGracePeriod is the duration for which a pod may remain unschedulable before the wait is aborted,
+	// so that the cluster autoscaler can scale up the nodes.
+	DefaultUnschedulableGracePeriod = 30 * time.Second
+
+	podKind                 = "Pod"
+	reasonUnschedulable     = corev1.PodReasonUnschedulable
+	eventReasonFailedSched  = "FailedScheduling"
+	eventReasonFailed       = "Failed"
+	unboundImmediatePVCsMsg = "unbound immediate PersistentVolumeClaims"
+)
+
+// pulledWaitingReasons are the waiting reasons of containers whose image has been pulled, but which can't be started.
+var pulledWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":     true,
+	"CreateContainerError": true,
+	"RunContainerError":    true,
+}
+
+// terminalWaitingReasons are the waiting reasons of containers which won't become ready without user intervention.
+var terminalWaitingReasons = map[string]bool{
+	"ImagePullBackOff":           true,
+	"ErrImageNeverPull":          true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+}
+
 type Response struct {
 	//  boolean flag.
 	// True signifies that the pod has reached the desired `PodCondition` within timeout value.
 	// False signifies that pod could not achieve the desired `PodCondition` within timeout value.
 	ReachedCondn bool
 
+	// PendingReason is the last observed reason due to which the pod hadn't reached the desired condition,
+	// if the wait timed out.
+	PendingReason string
+
 	Err error
 }
 
+// PodFailedError is returned when the pod can't reach the desired condition without user intervention.
+type PodFailedError struct {
+	Name      string
+	Namespace string
+	// Container is the container which can't be created, if the failure is specific to a container
+	Container string
+	// Reason is a machine-readable cause, e.g. Unschedulable, ImagePullBackOff, CreateContainerConfigError
+	Reason  string
+	Message string
+}
+
+func (e *PodFailedError) Error() string {
+	msg := fmt.Sprintf("pod %s/%s failed with reason %s", e.Namespace, e.Name, e.Reason)
+	if e.Container != "" {
+		msg = fmt.Sprintf("container %s of %s", e.Container, msg)
+	}
+	if e.Message != "" {
+		msg += " :: " + e.Message
+	}
+	return msg
+}
+
+// Recommendation returns the action recommended to the user to resolve the failure.
+func (e *PodFailedError) Recommendation() string {
+	switch e.Reason {
+	case reasonUnschedulable:
+		return "Verify the nodes have enough resources, and the node selector, affinity and tolerations of preflight pods " +
+			"match the nodes of the cluster"
+	case "ImagePullBackOff", "ErrImageNeverPull", "InvalidImageName":
+		return "Verify the image is present in the registry, the registry is reachable from the nodes, and the image pull " +
+			"secret is valid"
+	case "CreateContainerConfigError":
+		return "Verify the secrets and config maps referred by preflight pods exist, and the security context is valid"
+	default:
+		return "Inspect the events of the pod for the cause of failure"
+	}
+}
+
 // PodWaitOptions  waits until the pod reaches the desired condition or times-out.
 type PodWaitOptions struct {
 	//  Name - pod name
-	Name               string
-	Namespace          string
-	RetryBackoffParams wait.Backoff
-	PodCondition       corev1.PodConditionType
-	ClientSet          *client.Clientset
+	Name         string
+	Namespace    string
+	PodCondition corev1.PodConditionType
+	ClientSet    *client.Clientset
+	// Timeout of the wait, DefaultTimeout is used if not set.
+	Timeout time.Duration
+	// UnschedulableGracePeriod is the duration for which the pod may remain unschedulable,
+	// DefaultUnschedulableGracePeriod is used if not set.
+	UnschedulableGracePeriod time.Duration
+	// Logger, if set, logs the transitions of pod status while waiting.
+	Logger *logrus.Logger
 }
 
-func (o *PodWaitOptions) WaitOnPod(ctx context.Context, retryBackoff wait.Backoff) *Response {
-	retErr := wait.ExponentialBackoff(retryBackoff, func() (done bool, err error) {
-		pod, err := o.ClientSet.CoreV1().Pods(o.Namespace).Get(ctx, o.Name, metav1.GetOptions{})
-		if err != nil {
-			return false, err
-		}
+// WaitOnPod watches the pod until it reaches the desired condition, the timeout elapses or the context is done.
+// The wait is aborted with PodFailedError if the pod fails, can't be scheduled beyond the grace period, or
+// a container can't be created due to image pull or configuration errors.
+func (o *PodWaitOptions) WaitOnPod(ctx context.Context) *Response {
+	return o.waitUntil(ctx, func(pod *corev1.Pod) bool {
 		podConds := pod.Status.Conditions
 		for i := range podConds {
 			if podConds[i].Status == corev1.ConditionTrue && podConds[i].Type == o.PodCondition {
-				return true, nil
+				return true
 			}
 		}
-		return false, nil
+		return false
+	})
+}
+
+// WaitUntilImagesPulled watches the pod until the images of all its containers are pulled, the timeout elapses or
+// the context is done. PodCondition is ignored. The wait is aborted with PodFailedError if an image can't be pulled,
+// or the pod can't be scheduled beyond the grace period.
+func (o *PodWaitOptions) WaitUntilImagesPulled(ctx context.Context) *Response {
+	return o.waitUntil(ctx, ImagesPulled)
+}
+
+// WaitUntilDeleted watches the pod until it's deleted, the timeout elapses or the context is done. PodCondition is ignored.
+func (o *PodWaitOptions) WaitUntilDeleted(ctx context.Context) error {
+	lw := namedListWatch(o.Name,
+		func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return o.ClientSet.CoreV1().Pods(o.Namespace).List(ctx, options)
+		},
+		func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			return o.ClientSet.CoreV1().Pods(o.Namespace).Watch(ctx, options)
+		})
+	return untilCondition(ctx, o.Timeout, lw, &corev1.Pod{}, func(_ context.Context, obj runtime.Object) (bool, error) {
+		_, ok := obj.(*corev1.Pod)
+		return !ok, nil
+	})
+}
+
+// waitUntil watches the pod until reached returns true for it, the pod fails, the timeout elapses or the context is done.
+func (o *PodWaitOptions) waitUntil(ctx context.Context, reached func(pod *corev1.Pod) bool) *Response {
+	var (
+		pendingReason      string
+		lastStatus         string
+		unschedulableSince time.Time
+	)
+	gracePeriod := o.UnschedulableGracePeriod
+	if gracePeriod == 0 {
+		gracePeriod = DefaultUnschedulableGracePeriod
+	}
+
+	lw := namedListWatch(o.Name,
+		func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return o.ClientSet.CoreV1().Pods(o.Namespace).List(ctx, options)
+		},
+		func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			return o.ClientSet.CoreV1().Pods(o.Namespace).Watch(ctx, options)
+		})
+	retErr := untilCondition(ctx, o.Timeout, lw, &corev1.Pod{}, func(ctx context.Context, obj runtime.Object) (bool, error) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return false, k8serrors.NewNotFound(corev1.Resource("pods"), o.Name)
+		}
+		if status := podStatus(pod); status != lastStatus {
+			lastStatus = status
+			o.logf("Pod - %s/%s is %s", o.Namespace, o.Name, status)
+		}
+		if reached(pod) {
+			return true, nil
+		}
+
+		failure, terminal := PodFailure(pod)
+		if failure == nil {
+			pendingReason, unschedulableSince = "", time.Time{}
+			return false, nil
+		}
+		if failure.Reason == reasonUnschedulable {
+			if unschedulableSince.IsZero() {
+				unschedulableSince = time.Now()
+			}
+			terminal = terminal && time.Since(unschedulableSince) >= gracePeriod
+		}
+		if !terminal {
+			pendingReason = failure.Reason
+			if failure.Message != "" {
+				pendingReason += " :: " + failure.Message
+			}
+			return false, nil
+		}
+
+		eventReason := eventReasonFailed
+		if failure.Reason == reasonUnschedulable {
+			eventReason = eventReasonFailedSched
+		}
+		if msg := LatestWarningEvent(ctx, o.ClientSet, podKind, pod, eventReason); msg != "" {
+			failure.Message = msg
+		}
+		return false, failure
 	})
 
 	if retErr != nil {
 		return &Response{
-			ReachedCondn: false,
-			Err:          retErr,
+			ReachedCondn:  false,
+			PendingReason: pendingReason,
+			Err:           retErr,
 		}
 	}
 
@@ -55,3 +220,76 @@ func (o *PodWaitOptions) WaitOnPod(ctx context.Context, retryBackoff wait.Backof
 		Err:          nil,
 	}
 }
+
+func (o *PodWaitOptions) logf(format string, args ...interface{}) {
+	if o.Logger != nil {
+		o.Logger.Infof(format, args...)
+	}
+}
+
+// podStatus summarizes the status of pod as its phase, followed by the reason of a waiting container or
+// the scheduling failure, if any.
+func podStatus(pod *corev1.Pod) string {
+	status := string(pod.Status.Phase)
+	for i := range pod.Status.Conditions {
+		cond := pod.Status.Conditions[i]
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse && cond.Reason != "" {
+			return status + " (" + cond.Reason + ")"
+		}
+	}
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for i := range statuses {
+		if waiting := statuses[i].State.Waiting; waiting != nil && waiting.Reason != "" {
+			return status + " (" + statuses[i].Name + ": " + waiting.Reason + ")"
+		}
+	}
+	return status
+}
+
+// ImagesPulled returns whether the images of all containers of pod have been pulled, i.e. the containers have been
+// created or failed to start after the image was pulled.
+func ImagesPulled(pod *corev1.Pod) bool {
+	if len(pod.Status.ContainerStatuses) < len(pod.Spec.Containers) {
+		return false
+	}
+	for i := range pod.Status.ContainerStatuses {
+		status := pod.Status.ContainerStatuses[i]
+		waiting := status.State.Waiting
+		if status.ImageID == "" && waiting != nil && !pulledWaitingReasons[waiting.Reason] {
+			return false
+		}
+	}
+	return true
+}
+
+// PodFailure returns the cause due to which the pod hasn't become ready, if any, and whether the cause is terminal.
+// A pod unschedulable due to unbound immediate PVCs is not considered terminal, as the PVCs may be provisioned later.
+func PodFailure(pod *corev1.Pod) (*PodFailedError, bool) {
+	failure := &PodFailedError{Name: pod.GetName(), Namespace: pod.GetNamespace()}
+	if pod.Status.Phase == corev1.PodFailed {
+		failure.Reason, failure.Message = pod.Status.Reason, pod.Status.Message
+		if failure.Reason == "" {
+			failure.Reason = string(corev1.PodFailed)
+		}
+		return failure, true
+	}
+
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for i := range statuses {
+		waiting := statuses[i].State.Waiting
+		if waiting != nil && terminalWaitingReasons[waiting.Reason] {
+			failure.Container, failure.Reason, failure.Message = statuses[i].Name, waiting.Reason, waiting.Message
+			return failure, true
+		}
+	}
+
+	for i := range pod.Status.Conditions {
+		cond := pod.Status.Conditions[i]
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse && cond.Reason == reasonUnschedulable {
+			failure.Reason, failure.Message = reasonUnschedulable, cond.Message
+			return failure, !strings.Contains(cond.Message, unboundImmediatePVCsMsg)
+		}
+	}
+
+	return nil, false
+}
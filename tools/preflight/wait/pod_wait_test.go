@@ -0,0 +1,108 @@
+package wait
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Pod wait unit tests", func() {
+
+	var pod *corev1.Pod
+
+	BeforeEach(func() {
+		pod = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "dnsutils-abcdef", Namespace: "default"}}
+		pod.Status.Phase = corev1.PodPending
+	})
+
+	Context("Failure of a pending pod", func() {
+
+		It("Should not return failure for a pod being created", func() {
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "dnsutils", State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}}}
+			failure, terminal := PodFailure(pod)
+			Expect(failure).To(BeNil())
+			Expect(terminal).To(BeFalse())
+		})
+
+		It("Should return terminal failure with the container when image can't be pulled", func() {
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "dnsutils", State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: `Back-off pulling image "dnsutils:404"`}}}}
+			failure, terminal := PodFailure(pod)
+			Expect(terminal).To(BeTrue())
+			Expect(failure.Reason).To(Equal("ImagePullBackOff"))
+			Expect(failure.Error()).To(Equal(`container dnsutils of pod default/dnsutils-abcdef failed with reason ` +
+				`ImagePullBackOff :: Back-off pulling image "dnsutils:404"`))
+			Expect(failure.Recommendation()).To(ContainSubstring("image pull secret"))
+		})
+
+		It("Should return terminal failure when init container can't be configured", func() {
+			pod.Status.InitContainerStatuses = []corev1.ContainerStatus{{Name: "init", State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "CreateContainerConfigError", Message: `secret "creds" not found`}}}}
+			failure, terminal := PodFailure(pod)
+			Expect(terminal).To(BeTrue())
+			Expect(failure.Container).To(Equal("init"))
+			Expect(failure.Reason).To(Equal("CreateContainerConfigError"))
+		})
+
+		It("Should return scheduling failure, which is not terminal for unbound immediate PVCs", func() {
+			cond := corev1.PodCondition{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable,
+				Message: "0/3 nodes are available: 3 node(s) had untolerated taint {node-role: infra}."}
+			pod.Status.Conditions = []corev1.PodCondition{cond}
+			failure, terminal := PodFailure(pod)
+			Expect(terminal).To(BeTrue())
+			Expect(failure.Error()).To(ContainSubstring("failed with reason Unschedulable :: 0/3 nodes are available"))
+
+			pod.Status.Conditions[0].Message = "0/3 nodes are available: pod has unbound immediate PersistentVolumeClaims."
+			failure, terminal = PodFailure(pod)
+			Expect(terminal).To(BeFalse())
+			Expect(failure.Reason).To(Equal(corev1.PodReasonUnschedulable))
+		})
+
+		It("Should return terminal failure for a failed pod", func() {
+			pod.Status.Phase, pod.Status.Reason = corev1.PodFailed, "Evicted"
+			failure, terminal := PodFailure(pod)
+			Expect(terminal).To(BeTrue())
+			Expect(failure.Reason).To(Equal("Evicted"))
+		})
+	})
+
+	Context("Images of pod containers", func() {
+
+		It("Should consider images pulled once containers are created or fail to start", func() {
+			pod.Spec.Containers = []corev1.Container{{Name: "image"}}
+			Expect(ImagesPulled(pod)).To(BeFalse())
+
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "image", State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}}}
+			Expect(ImagesPulled(pod)).To(BeFalse())
+
+			pod.Status.ContainerStatuses[0].State.Waiting.Reason = "ErrImagePull"
+			Expect(ImagesPulled(pod)).To(BeFalse())
+
+			pod.Status.ContainerStatuses[0].State.Waiting.Reason = "RunContainerError"
+			Expect(ImagesPulled(pod)).To(BeTrue())
+
+			pod.Status.ContainerStatuses[0].State = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+				Reason: "StartError"}}
+			Expect(ImagesPulled(pod)).To(BeTrue())
+		})
+	})
+
+	Context("Message of pod events", func() {
+
+		It("Should return message of the latest event describing the error", func() {
+			now := time.Now()
+			events := []corev1.Event{
+				{Message: "Error: ErrImagePull", LastTimestamp: metav1.NewTime(now)},
+				{Message: `Failed to pull image "dnsutils:404": not found`, LastTimestamp: metav1.NewTime(now.Add(-time.Second))},
+				{Message: `Failed to pull image "dnsutils:403": unauthorized`, LastTimestamp: metav1.NewTime(now.Add(-time.Minute))},
+			}
+			Expect(LatestEventMessage(events)).To(Equal(`Failed to pull image "dnsutils:404": not found`))
+			Expect(LatestEventMessage(nil)).To(BeEmpty())
+		})
+	})
+})
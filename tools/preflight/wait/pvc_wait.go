@@ -0,0 +1,74 @@
+package wait
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	client "k8s.io/client-go/kubernetes"
+)
+
+const pvcKind = "PersistentVolumeClaim"
+
+// PVCWaitOptions waits until the PVC is bound or times-out.
+type PVCWaitOptions struct {
+	Name      string
+	Namespace string
+	ClientSet *client.Clientset
+	// Timeout of the wait, DefaultTimeout is used if not set.
+	Timeout time.Duration
+	// Logger, if set, logs the transitions of PVC phase while waiting.
+	Logger *logrus.Logger
+}
+
+// WaitUntilBound watches the PVC until it's bound, the timeout elapses or the context is done.
+// The wait is aborted if the PVC is lost or deleted. If the wait times out, the latest warning event of the PVC,
+// e.g. the provisioning failure, is included in the error.
+func (o *PVCWaitOptions) WaitUntilBound(ctx context.Context) error {
+	var (
+		lastPhase corev1.PersistentVolumeClaimPhase
+		lastPVC   *corev1.PersistentVolumeClaim
+	)
+	lw := namedListWatch(o.Name,
+		func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return o.ClientSet.CoreV1().PersistentVolumeClaims(o.Namespace).List(ctx, options)
+		},
+		func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			return o.ClientSet.CoreV1().PersistentVolumeClaims(o.Namespace).Watch(ctx, options)
+		})
+	err := untilCondition(ctx, o.Timeout, lw, &corev1.PersistentVolumeClaim{}, func(_ context.Context, obj runtime.Object) (bool, error) {
+		pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+		if !ok {
+			return false, k8serrors.NewNotFound(corev1.Resource("persistentvolumeclaims"), o.Name)
+		}
+		lastPVC = pvc
+		if pvc.Status.Phase != lastPhase {
+			lastPhase = pvc.Status.Phase
+			if o.Logger != nil {
+				o.Logger.Infof("PVC - %s/%s is %s", o.Namespace, o.Name, lastPhase)
+			}
+		}
+		switch pvc.Status.Phase {
+		case corev1.ClaimBound:
+			return true, nil
+		case corev1.ClaimLost:
+			return false, fmt.Errorf("pvc - %s/%s is lost, its volume - %s doesn't exist", o.Namespace, o.Name, pvc.Spec.VolumeName)
+		default:
+			return false, nil
+		}
+	})
+
+	if wait.Interrupted(err) && ctx.Err() == nil && lastPVC != nil {
+		if msg := LatestWarningEvent(ctx, o.ClientSet, pvcKind, lastPVC, ""); msg != "" {
+			return fmt.Errorf("pvc - %s/%s is %s :: %s :: %w", o.Namespace, o.Name, lastPhase, msg, err)
+		}
+	}
+	return err
+}
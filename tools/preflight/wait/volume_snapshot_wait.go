@@ -0,0 +1,80 @@
+package wait
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	snapshotGroup          = "snapshot.storage.k8s.io"
+	volumeSnapshotResource = "volumesnapshots"
+)
+
+// VolumeSnapshotWaitOptions waits until the volume snapshot is ready to use or times-out.
+type VolumeSnapshotWaitOptions struct {
+	Name      string
+	Namespace string
+	// Version of snapshot.storage.k8s.io API group served by the cluster
+	Version       string
+	DynamicClient dynamic.Interface
+	// Timeout of the wait, DefaultTimeout is used if not set.
+	Timeout time.Duration
+	// Logger, if set, logs the transitions of volume snapshot status while waiting.
+	Logger *logrus.Logger
+}
+
+// WaitUntilReadyToUse watches the volume snapshot until it's 'readyToUse:true', the timeout elapses or the context is done.
+// If the wait times out, the error reported in the status of the volume snapshot is included in the error.
+func (o *VolumeSnapshotWaitOptions) WaitUntilReadyToUse(ctx context.Context) error {
+	var lastStatus string
+	resource := o.DynamicClient.Resource(schema.GroupVersionResource{
+		Group: snapshotGroup, Version: o.Version, Resource: volumeSnapshotResource}).Namespace(o.Namespace)
+	lw := namedListWatch(o.Name,
+		func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return resource.List(ctx, options)
+		},
+		func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			return resource.Watch(ctx, options)
+		})
+	err := untilCondition(ctx, o.Timeout, lw, &unstructured.Unstructured{}, func(_ context.Context, obj runtime.Object) (bool, error) {
+		volSnap, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return false, k8serrors.NewNotFound(schema.GroupResource{Group: snapshotGroup, Resource: volumeSnapshotResource}, o.Name)
+		}
+		ready, status := volumeSnapshotStatus(volSnap)
+		if status != lastStatus {
+			lastStatus = status
+			if o.Logger != nil {
+				o.Logger.Infof("Volume snapshot - %s/%s is %s", o.Namespace, o.Name, status)
+			}
+		}
+		return ready, nil
+	})
+
+	if wait.Interrupted(err) && ctx.Err() == nil && lastStatus != "" {
+		return fmt.Errorf("volume snapshot - %s/%s is %s :: %w", o.Namespace, o.Name, lastStatus, err)
+	}
+	return err
+}
+
+// volumeSnapshotStatus returns whether the volume snapshot is ready to use, and its status summarized as
+// 'readyToUse:<bool>', followed by the error reported by the snapshot controller, if any.
+func volumeSnapshotStatus(volSnap *unstructured.Unstructured) (ready bool, status string) {
+	ready, _, _ = unstructured.NestedBool(volSnap.Object, "status", "readyToUse")
+	status = fmt.Sprintf("readyToUse:%t", ready)
+	if msg, found, _ := unstructured.NestedString(volSnap.Object, "status", "error", "message"); found && msg != "" {
+		status += ", error - " + msg
+	}
+	return ready, status
+}
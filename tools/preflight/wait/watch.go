@@ -0,0 +1,104 @@
+package wait
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// DefaultTimeout is the duration for which a resource is waited upon if the wait options don't have a timeout.
+	DefaultTimeout = 10 * time.Minute
+
+	// the condition is re-evaluated on the cached object after resyncPeriod, without a change of the object, so that
+	// conditions depending on elapsed time are evaluated.
+	resyncPeriod = 5 * time.Second
+)
+
+// conditionFunc is called with every observed state of the watched object, and with nil if the object doesn't exist.
+type conditionFunc func(ctx context.Context, obj runtime.Object) (done bool, err error)
+
+type listFunc func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error)
+
+type watchFunc func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error)
+
+// namedListWatch returns a list-watch of the object with the given name.
+func namedListWatch(name string, list listFunc, watchObj watchFunc) *cache.ListWatch {
+	nameSelector := fields.OneTermEqualSelector(metav1.ObjectNameField, name).String()
+	return &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = nameSelector
+			return list(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = nameSelector
+			return watchObj(ctx, options)
+		},
+	}
+}
+
+// untilCondition watches the object using an informer, and evaluates the condition on every change of the object
+// until it's satisfied, returns an error, the timeout elapses or the context is done.
+// An error for which wait.Interrupted is true is returned if the timeout elapses, and the context's error if it's done.
+func untilCondition(ctx context.Context, timeout time.Duration, lw cache.ListerWatcher, objType runtime.Object,
+	condition conditionFunc) error {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	watchCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		finished bool
+		result   = make(chan error, 1)
+	)
+	// the condition is evaluated by informer's handler as well as after the initial sync, it's serialized so that
+	// conditions can keep state across the observed changes
+	evaluate := func(obj interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		if finished {
+			return
+		}
+		rObj, _ := obj.(runtime.Object)
+		done, err := condition(watchCtx, rObj)
+		if done || err != nil {
+			finished = true
+			result <- err
+		}
+	}
+
+	informer := cache.NewSharedIndexInformer(lw, objType, resyncPeriod, cache.Indexers{})
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    evaluate,
+		UpdateFunc: func(_, newObj interface{}) { evaluate(newObj) },
+		DeleteFunc: func(interface{}) { evaluate(nil) },
+	})
+	if err != nil {
+		return err
+	}
+	go informer.Run(watchCtx.Done())
+
+	if cache.WaitForCacheSync(watchCtx.Done(), informer.HasSynced) && len(informer.GetStore().List()) == 0 {
+		evaluate(nil)
+	}
+
+	select {
+	case err = <-result:
+		return err
+	case <-watchCtx.Done():
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return wait.ErrorInterrupted(fmt.Errorf("timed out after %s waiting for the condition", timeout))
+	}
+}
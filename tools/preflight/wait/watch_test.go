@@ -0,0 +1,99 @@
+package wait
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+var _ = Describe("Watch based wait unit tests", func() {
+
+	var (
+		ctx     context.Context
+		cancel  context.CancelFunc
+		watcher *watch.FakeWatcher
+		pod     *corev1.Pod
+	)
+
+	podListWatch := func(pods ...corev1.Pod) (listFunc, watchFunc) {
+		// informer of the previous spec may still be stopping, so the watcher isn't read from the spec's variable
+		fakeWatcher := watcher
+		return func(context.Context, metav1.ListOptions) (runtime.Object, error) {
+				return &corev1.PodList{Items: pods}, nil
+			}, func(context.Context, metav1.ListOptions) (watch.Interface, error) {
+				return fakeWatcher, nil
+			}
+	}
+
+	isReady := func(_ context.Context, obj runtime.Object) (bool, error) {
+		p, ok := obj.(*corev1.Pod)
+		return ok && p.Status.Phase == corev1.PodRunning, nil
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		watcher = watch.NewFake()
+		pod = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "dnsutils-abcdef", Namespace: "default", ResourceVersion: "1"}}
+		pod.Status.Phase = corev1.PodPending
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("Should return once the watched object satisfies the condition", func() {
+		list, watchObj := podListWatch(*pod)
+		go func() {
+			defer GinkgoRecover()
+			running := pod.DeepCopy()
+			running.ResourceVersion, running.Status.Phase = "2", corev1.PodRunning
+			watcher.Modify(running)
+		}()
+		Expect(untilCondition(ctx, time.Minute, namedListWatch(pod.Name, list, watchObj), &corev1.Pod{}, isReady)).To(Succeed())
+	})
+
+	It("Should evaluate the condition with nil object if it doesn't exist", func() {
+		list, watchObj := podListWatch()
+		deleted := func(_ context.Context, obj runtime.Object) (bool, error) {
+			return obj == nil, nil
+		}
+		Expect(untilCondition(ctx, time.Minute, namedListWatch(pod.Name, list, watchObj), &corev1.Pod{}, deleted)).To(Succeed())
+	})
+
+	It("Should return interrupted error on timeout, and the context's error if it's done", func() {
+		list, watchObj := podListWatch(*pod)
+		err := untilCondition(ctx, 100*time.Millisecond, namedListWatch(pod.Name, list, watchObj), &corev1.Pod{}, isReady)
+		Expect(wait.Interrupted(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("timed out after 100ms"))
+
+		cancel()
+		err = untilCondition(ctx, time.Minute, namedListWatch(pod.Name, list, watchObj), &corev1.Pod{}, isReady)
+		Expect(err).To(Equal(context.Canceled))
+	})
+
+	It("Should summarize status of pod, volume snapshot and namespace", func() {
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "dnsutils", State: corev1.ContainerState{
+			Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}}}
+		Expect(podStatus(pod)).To(Equal("Pending (dnsutils: ContainerCreating)"))
+
+		volSnap := &unstructured.Unstructured{Object: map[string]interface{}{"status": map[string]interface{}{
+			"readyToUse": false, "error": map[string]interface{}{"message": "snapshot controller failed"}}}}
+		ready, status := volumeSnapshotStatus(volSnap)
+		Expect(ready).To(BeFalse())
+		Expect(status).To(Equal("readyToUse:false, error - snapshot controller failed"))
+
+		ns := &corev1.Namespace{Status: corev1.NamespaceStatus{Phase: corev1.NamespaceTerminating}}
+		Expect(namespaceDeletionPendingReason(ns)).To(Equal("Terminating"))
+		ns.Status.Conditions = []corev1.NamespaceCondition{{Type: corev1.NamespaceFinalizersRemaining,
+			Status: corev1.ConditionTrue, Message: "Some content in the namespace has finalizers remaining"}}
+		Expect(namespaceDeletionPendingReason(ns)).To(HavePrefix("NamespaceFinalizersRemaining - Some content"))
+	})
+})